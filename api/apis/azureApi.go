@@ -3,15 +3,33 @@ package apis
 import (
 	"context"
 	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 	"io"
+	"mime"
 	"mime/multipart"
 	"os"
+	"time"
 )
 
 type IAzureApi interface {
 	UploadGame(blobContainerName string, gameID string, fileHeader *multipart.FileHeader) (string, error)
 	DeleteGame(blobContainerName string, gameID string) error
+	DownloadGame(blobContainerName string, blobName string) (io.ReadCloser, error)
+	DownloadGameRange(blobContainerName string, blobName string, offset int64, count int64) (io.ReadCloser, error)
+	GameProperties(blobContainerName string, blobName string) (BlobProperties, error)
+	SignedGameUrl(blobContainerName string, blobName string, fileName string, expiry time.Time) (string, error)
+	ListGames(blobContainerName string) ([]BlobProperties, error)
+}
+
+// BlobProperties are the properties of a blob which are needed to serve it
+type BlobProperties struct {
+	//Name is only set by ListGames
+	Name         string
+	Size         int64
+	LastModified time.Time
 }
 
 func (g azureApi) UploadGame(blobContainerName string, gameID string, fileHeader *multipart.FileHeader) (string, error) {
@@ -48,6 +66,114 @@ func (g azureApi) DeleteGame(blobContainerName string, gameID string) error {
 	return nil
 }
 
+// DownloadGame returns the content of a blob, the caller has to close it.
+func (g azureApi) DownloadGame(blobContainerName string, blobName string) (io.ReadCloser, error) {
+	ctx := context.Background()
+
+	response, err := g.azure.DownloadStream(ctx, blobContainerName, blobName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+// DownloadGameRange returns count bytes of a blob, starting at offset. If count is 0, the rest of the blob is returned.
+// The caller has to close it.
+func (g azureApi) DownloadGameRange(blobContainerName string, blobName string, offset int64, count int64) (io.ReadCloser, error) {
+	ctx := context.Background()
+
+	response, err := g.azure.DownloadStream(ctx, blobContainerName, blobName, &azblob.DownloadStreamOptions{
+		Range: azblob.HTTPRange{Offset: offset, Count: count},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+func (g azureApi) GameProperties(blobContainerName string, blobName string) (BlobProperties, error) {
+	ctx := context.Background()
+
+	response, err := g.azure.ServiceClient().NewContainerClient(blobContainerName).NewBlobClient(blobName).GetProperties(ctx, nil)
+	if err != nil {
+		return BlobProperties{}, err
+	}
+
+	properties := BlobProperties{}
+	if response.ContentLength != nil {
+		properties.Size = *response.ContentLength
+	}
+	if response.LastModified != nil {
+		properties.LastModified = *response.LastModified
+	}
+	return properties, nil
+}
+
+// ListGames returns the properties of all blobs of the container.
+func (g azureApi) ListGames(blobContainerName string) ([]BlobProperties, error) {
+	ctx := context.Background()
+
+	blobs := []BlobProperties{}
+	pager := g.azure.NewListBlobsFlatPager(blobContainerName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			properties := BlobProperties{Name: *item.Name}
+			if item.Properties != nil {
+				if item.Properties.ContentLength != nil {
+					properties.Size = *item.Properties.ContentLength
+				}
+				if item.Properties.LastModified != nil {
+					properties.LastModified = *item.Properties.LastModified
+				}
+			}
+			blobs = append(blobs, properties)
+		}
+	}
+	return blobs, nil
+}
+
+// SignedGameUrl creates a read-only user delegation SAS url for a blob, which is valid until expiry.
+// The blob is downloaded as fileName.
+func (g azureApi) SignedGameUrl(blobContainerName string, blobName string, fileName string, expiry time.Time) (string, error) {
+	ctx := context.Background()
+
+	//Allow some clock skew between the api and azure
+	start := time.Now().UTC().Add(-5 * time.Minute)
+	expiry = expiry.UTC()
+	credential, err := g.azure.ServiceClient().GetUserDelegationCredential(ctx, service.KeyInfo{
+		Start:  to.Ptr(start.Format(sas.TimeFormat)),
+		Expiry: to.Ptr(expiry.Format(sas.TimeFormat)),
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	query, err := sas.BlobSignatureValues{
+		Protocol:           sas.ProtocolHTTPS,
+		StartTime:          start,
+		ExpiryTime:         expiry,
+		Permissions:        (&sas.BlobPermissions{Read: true}).String(),
+		ContainerName:      blobContainerName,
+		BlobName:           blobName,
+		ContentDisposition: mime.FormatMediaType("attachment", map[string]string{"filename": fileName}),
+	}.SignWithUserDelegation(credential)
+	if err != nil {
+		return "", err
+	}
+
+	blobUrl := g.azure.ServiceClient().NewContainerClient(blobContainerName).NewBlobClient(blobName).URL()
+	return fmt.Sprintf("%s?%s", blobUrl, query.Encode()), nil
+}
+
 type azureApi struct {
 	azure *azblob.Client
 }
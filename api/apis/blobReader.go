@@ -0,0 +1,78 @@
+package apis
+
+import (
+	"errors"
+	"io"
+)
+
+// blobReader reads a blob with ranged downloads, so it can be used with http.ServeContent.
+// A new download is started on the first read after a seek.
+type blobReader struct {
+	azure     IAzureApi
+	container string
+	blobName  string
+	size      int64
+	offset    int64
+	body      io.ReadCloser
+}
+
+// BlobReader returns an io.ReadSeekCloser for a blob of the given size.
+func BlobReader(azure IAzureApi, blobContainerName string, blobName string, size int64) io.ReadSeekCloser {
+	return &blobReader{
+		azure:     azure,
+		container: blobContainerName,
+		blobName:  blobName,
+		size:      size,
+	}
+}
+
+func (b *blobReader) Read(p []byte) (int, error) {
+	if b.offset >= b.size {
+		return 0, io.EOF
+	}
+	if b.body == nil {
+		body, err := b.azure.DownloadGameRange(b.container, b.blobName, b.offset, 0)
+		if err != nil {
+			return 0, err
+		}
+		b.body = body
+	}
+
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+	return n, err
+}
+
+func (b *blobReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if offset != b.offset {
+		//The current download can not be used anymore
+		err := b.Close()
+		if err != nil {
+			return 0, err
+		}
+		b.offset = offset
+	}
+	return offset, nil
+}
+
+func (b *blobReader) Close() error {
+	if b.body == nil {
+		return nil
+	}
+	err := b.body.Close()
+	b.body = nil
+	return err
+}
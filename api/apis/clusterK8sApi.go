@@ -0,0 +1,158 @@
+package apis
+
+import (
+	"api/models"
+	"api/shared"
+	"fmt"
+)
+
+// IClusters resolves the registered clusters for the game resources.
+type IClusters interface {
+	//Place chooses the cluster of a game whose cluster is not set yet and sets game.Cluster
+	Place(game *models.Game) error
+	//Api returns the api of a registered cluster or shared.ErrClusterNotFound
+	Api(cluster string) (IK8sApi, error)
+	//Names returns the names of all registered clusters
+	Names() ([]string, error)
+}
+
+// clusterK8sApi routes every call to the api of the cluster of the game
+type clusterK8sApi struct {
+	clusters IClusters
+}
+
+// DeployGame places a new game on a cluster first. A game which already has a cluster, e.g. a restored game
+// or a game whose cluster has been pinned by the uploader, is deployed on its cluster.
+func (c clusterK8sApi) DeployGame(game *models.Game) error {
+	if game.Cluster == "" {
+		err := c.clusters.Place(game)
+		if err != nil {
+			return err
+		}
+	}
+	api, err := c.apiOf(game)
+	if err != nil {
+		return err
+	}
+	return api.DeployGame(game)
+}
+
+func (c clusterK8sApi) ReadGameUrl(game *models.Game) (string, error) {
+	api, err := c.apiOf(game)
+	if err != nil {
+		return "", err
+	}
+	return api.ReadGameUrl(game)
+}
+
+func (c clusterK8sApi) ReadGamePhase(game *models.Game) (string, error) {
+	api, err := c.apiOf(game)
+	if err != nil {
+		return "", err
+	}
+	return api.ReadGamePhase(game)
+}
+
+func (c clusterK8sApi) SuspendGame(game *models.Game, suspend bool) error {
+	api, err := c.apiOf(game)
+	if err != nil {
+		return err
+	}
+	return api.SuspendGame(game, suspend)
+}
+
+func (c clusterK8sApi) DeleteGame(game *models.Game) error {
+	api, err := c.apiOf(game)
+	if err != nil {
+		return err
+	}
+	return api.DeleteGame(game)
+}
+
+func (c clusterK8sApi) UpdateGame(game *models.Game) error {
+	api, err := c.apiOf(game)
+	if err != nil {
+		return err
+	}
+	return api.UpdateGame(game)
+}
+
+func (c clusterK8sApi) DeployBeta(game *models.Game, version *models.GameVersion) error {
+	api, err := c.apiOf(game)
+	if err != nil {
+		return err
+	}
+	return api.DeployBeta(game, version)
+}
+
+func (c clusterK8sApi) ReadBetaUrl(game *models.Game) (string, error) {
+	api, err := c.apiOf(game)
+	if err != nil {
+		return "", err
+	}
+	return api.ReadBetaUrl(game)
+}
+
+func (c clusterK8sApi) DeleteBeta(game *models.Game) error {
+	api, err := c.apiOf(game)
+	if err != nil {
+		return err
+	}
+	return api.DeleteBeta(game)
+}
+
+// ListGameResources returns the game resources of all registered clusters. It fails if a cluster can not be listed,
+// otherwise its games would be reported as missing.
+func (c clusterK8sApi) ListGameResources() ([]GameResource, error) {
+	names, err := c.clusters.Names()
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []GameResource{}
+	for _, name := range names {
+		api, err := c.clusters.Api(name)
+		if err != nil {
+			return nil, err
+		}
+		clusterResources, err := api.ListGameResources()
+		if err != nil {
+			return nil, fmt.Errorf("listing the game resources of cluster %s failed: %w", name, err)
+		}
+		for _, resource := range clusterResources {
+			resource.Cluster = name
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+// DeleteGameResource deletes the resource in its cluster, resources without cluster are deleted in the default cluster.
+func (c clusterK8sApi) DeleteGameResource(resource GameResource) error {
+	api, err := c.clusters.Api(clusterName(resource.Cluster))
+	if err != nil {
+		return err
+	}
+	return api.DeleteGameResource(resource)
+}
+
+// apiOf returns the api of the cluster of the game, games which have been created before clusters were registered
+// belong to the default cluster
+func (c clusterK8sApi) apiOf(game *models.Game) (IK8sApi, error) {
+	return c.clusters.Api(clusterName(game.Cluster))
+}
+
+func clusterName(cluster string) string {
+	if cluster == "" {
+		return shared.DefaultCluster
+	}
+	return cluster
+}
+
+// ClusterK8sService creates the game resources on the registered clusters, every game is placed on one cluster
+// when it is deployed and all later calls are sent to this cluster.
+func ClusterK8sService(clusters IClusters) IK8sApi {
+	return &clusterK8sApi{
+		clusters: clusters,
+	}
+}
@@ -0,0 +1,168 @@
+package apis
+
+import (
+	"api/shared"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileStorageApi stores the blobs as files below a directory, every container is a sub directory.
+// It is used by the development mode instead of azure.
+type fileStorageApi struct {
+	root string
+}
+
+func (f fileStorageApi) UploadGame(blobContainerName string, blobName string, fileHeader *multipart.FileHeader) (string, error) {
+	path, err := f.blobPath(blobContainerName, blobName)
+	if err != nil {
+		return "", err
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return "", err
+	}
+	//The content is written into a temporary file first, so a blob is never read partly
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, file)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return "", err
+	}
+	return "file://" + filepath.ToSlash(path), nil
+}
+
+func (f fileStorageApi) DeleteGame(blobContainerName string, blobName string) error {
+	path, err := f.blobPath(blobContainerName, blobName)
+	if err != nil {
+		return err
+	}
+	return notFound(blobName, os.Remove(path))
+}
+
+func (f fileStorageApi) DownloadGame(blobContainerName string, blobName string) (io.ReadCloser, error) {
+	path, err := f.blobPath(blobContainerName, blobName)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, notFound(blobName, err)
+	}
+	return file, nil
+}
+
+// DownloadGameRange returns count bytes of a blob, starting at offset. If count is 0, the rest of the blob is returned.
+func (f fileStorageApi) DownloadGameRange(blobContainerName string, blobName string, offset int64, count int64) (io.ReadCloser, error) {
+	path, err := f.blobPath(blobContainerName, blobName)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, notFound(blobName, err)
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if count <= 0 {
+		return file, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, count), file}, nil
+}
+
+func (f fileStorageApi) GameProperties(blobContainerName string, blobName string) (BlobProperties, error) {
+	path, err := f.blobPath(blobContainerName, blobName)
+	if err != nil {
+		return BlobProperties{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return BlobProperties{}, notFound(blobName, err)
+	}
+	return BlobProperties{Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+// SignedGameUrl is not supported, the roms are sent by the api instead.
+func (f fileStorageApi) SignedGameUrl(blobContainerName string, blobName string, fileName string, expiry time.Time) (string, error) {
+	return "", shared.ErrSignedUrlNotSupported
+}
+
+func (f fileStorageApi) ListGames(blobContainerName string) ([]BlobProperties, error) {
+	container := filepath.Join(f.root, blobContainerName)
+	blobs := []BlobProperties{}
+	err := filepath.WalkDir(container, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(container, path)
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, BlobProperties{Name: filepath.ToSlash(name), Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	return blobs, err
+}
+
+// blobPath returns the path of a blob, blob names must not leave the directory of their container
+func (f fileStorageApi) blobPath(blobContainerName string, blobName string) (string, error) {
+	container := filepath.Join(f.root, blobContainerName)
+	path := filepath.Join(container, filepath.FromSlash(blobName))
+	if !strings.HasPrefix(path, container+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob name %s", blobName)
+	}
+	return path, nil
+}
+
+// notFound reports missing files like azure reports missing blobs, so the services can ignore them
+func notFound(blobName string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("BlobNotFound: %s", blobName)
+	}
+	return err
+}
+
+// FileStorageApi stores the blobs in the directory root instead of azure.
+func FileStorageApi(root string) IAzureApi {
+	return &fileStorageApi{
+		root: root,
+	}
+}
@@ -2,8 +2,10 @@ package apis
 
 import (
 	"api/models"
+	"api/shared"
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
@@ -15,12 +17,29 @@ import (
 
 type IK8sApi interface {
 	DeployGame(game *models.Game) error
-	ReadGameUrl(gameId uuid.UUID) (string, error)
+	ReadGameUrl(game *models.Game) (string, error)
+	ReadGamePhase(game *models.Game) (string, error)
+	SuspendGame(game *models.Game, suspend bool) error
 	DeleteGame(game *models.Game) error
+	UpdateGame(game *models.Game) error
+	DeployBeta(game *models.Game, version *models.GameVersion) error
+	ReadBetaUrl(game *models.Game) (string, error)
+	DeleteBeta(game *models.Game) error
+	ListGameResources() ([]GameResource, error)
+	DeleteGameResource(resource GameResource) error
+}
+
+// GameResource is a game resource in a cluster, the resources of beta channels are named "<id>-beta".
+// Cluster is only set by the api of the registered clusters, see ClusterK8sService.
+type GameResource struct {
+	Cluster   string
+	Namespace string
+	Name      string
+	CreatedAt time.Time
 }
 
 func (g k8sApi) DeleteGame(game *models.Game) error {
-	resource, err := createAndVerifyGameResource(game)
+	resource, err := createAndVerifyGameResource(game, g.namespaces.namespaceOf(game))
 	if err != nil {
 		return err
 	}
@@ -33,10 +52,14 @@ func (g k8sApi) DeployGame(game *models.Game) error {
 
 	//Definitions
 	ctx := context.Background()
-	key := typeNamespacedName(game.ID.String())
+	namespace, err := g.namespaces.ensure(ctx, game)
+	if err != nil {
+		return err
+	}
+	key := typeNamespacedName(game.ID.String(), namespace)
 
 	//Check if the custom resource is already existing
-	err := g.k8sClient.Get(ctx, key, &streamv1.Game{})
+	err = g.k8sClient.Get(ctx, key, &streamv1.Game{})
 	if err == nil {
 		return errors.New("resource is already created")
 	} else if !k8serrors.IsNotFound(err) {
@@ -44,16 +67,131 @@ func (g k8sApi) DeployGame(game *models.Game) error {
 	}
 
 	//Define the custom resource
-	resource, err := createAndVerifyGameResource(game)
+	resource, err := createAndVerifyGameResource(game, namespace)
+	if err != nil {
+		return err
+	}
+
+	err = g.k8sClient.Create(ctx, resource)
+	if err != nil {
+		return err
+	}
+	//The namespace is saved with the deployment, so the game stays in it if the strategy is changed
+	game.Namespace = namespace
+	return nil
+}
+
+// UpdateGame updates the spec of an existing game resource.
+// A changed deploy revision makes the operator roll out new pods, the url of the game stays the same.
+func (g k8sApi) UpdateGame(game *models.Game) error {
+	ctx := context.Background()
+	key := typeNamespacedName(game.ID.String(), g.namespaces.namespaceOf(game))
+
+	resource := streamv1.Game{}
+	err := g.k8sClient.Get(ctx, key, &resource)
 	if err != nil {
 		return err
 	}
 
-	return g.k8sClient.Create(ctx, resource)
+	resource.Spec.Name = game.Title
+	resource.Spec.FileName = game.FileName
+	resource.Spec.Revision = int64(game.DeployRevision)
+	resource.Spec.StoragePath = game.BlobName
+	resource.Spec.Directory = shared.IsBundle(game.BlobName)
+	resource.Spec.Bios = game.Bios
+
+	return g.k8sClient.Update(ctx, &resource)
+}
+
+// DeployBeta creates or updates the resource of the beta channel of a game.
+// The beta channel runs next to the live version with its own url.
+func (g k8sApi) DeployBeta(game *models.Game, version *models.GameVersion) error {
+	ctx := context.Background()
+	namespace, err := g.namespaces.ensure(ctx, game)
+	if err != nil {
+		return err
+	}
+	key := typeNamespacedName(betaResourceName(game.ID), namespace)
+
+	spec := streamv1.GameSpec{
+		Name:        game.Title,
+		FileName:    version.FileName,
+		Revision:    int64(version.Version),
+		StoragePath: version.BlobName,
+		Directory:   shared.IsBundle(version.BlobName),
+		Bios:        version.Bios,
+	}
+
+	resource := streamv1.Game{}
+	err = g.k8sClient.Get(ctx, key, &resource)
+	if err == nil {
+		resource.Spec = spec
+		return g.k8sClient.Update(ctx, &resource)
+	} else if !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	return g.k8sClient.Create(ctx, &streamv1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+		},
+		Spec: spec,
+	})
+}
+
+func (g k8sApi) ReadBetaUrl(game *models.Game) (string, error) {
+	key := typeNamespacedName(betaResourceName(game.ID), g.namespaces.namespaceOf(game))
+	resource := streamv1.Game{}
+
+	err := g.k8sClient.Get(context.Background(), key, &resource)
+	if err != nil {
+		return "", err
+	}
+	return resource.Status.URL, nil
+}
+
+func (g k8sApi) DeleteBeta(game *models.Game) error {
+	key := typeNamespacedName(betaResourceName(game.ID), g.namespaces.namespaceOf(game))
+	return g.k8sClient.Delete(context.Background(), &streamv1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+		},
+	})
+}
+
+// ListGameResources returns the game resources of all namespaces, including the resources of beta channels.
+func (g k8sApi) ListGameResources() ([]GameResource, error) {
+	list := streamv1.GameList{}
+	err := g.k8sClient.List(context.Background(), &list)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]GameResource, len(list.Items))
+	for i, item := range list.Items {
+		resources[i] = GameResource{Namespace: item.Namespace, Name: item.Name, CreatedAt: item.CreationTimestamp.Time}
+	}
+	return resources, nil
+}
+
+// DeleteGameResource deletes a game resource, which is not necessarily known to the database.
+func (g k8sApi) DeleteGameResource(resource GameResource) error {
+	return g.k8sClient.Delete(context.Background(), &streamv1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resource.Name,
+			Namespace: resource.Namespace,
+		},
+	})
+}
+
+func betaResourceName(gameId uuid.UUID) string {
+	return gameId.String() + "-beta"
 }
 
-func (g k8sApi) ReadGameUrl(gameId uuid.UUID) (string, error) {
-	key := typeNamespacedName(gameId.String())
+func (g k8sApi) ReadGameUrl(game *models.Game) (string, error) {
+	key := typeNamespacedName(game.ID.String(), g.namespaces.namespaceOf(game))
 	resource := streamv1.Game{}
 
 	err := g.k8sClient.Get(context.Background(), key, &resource)
@@ -64,14 +202,44 @@ func (g k8sApi) ReadGameUrl(gameId uuid.UUID) (string, error) {
 	}
 }
 
-func typeNamespacedName(resourceName string) types.NamespacedName {
+// ReadGamePhase returns the phase of the game which is reported by the operator, e.g. streamv1.GamePhaseStopped.
+func (g k8sApi) ReadGamePhase(game *models.Game) (string, error) {
+	key := typeNamespacedName(game.ID.String(), g.namespaces.namespaceOf(game))
+	resource := streamv1.Game{}
+
+	err := g.k8sClient.Get(context.Background(), key, &resource)
+	if err != nil {
+		return "", err
+	}
+	return resource.Status.Phase, nil
+}
+
+// SuspendGame stops or starts a game. The operator scales its deployments to zero while it is suspended.
+func (g k8sApi) SuspendGame(game *models.Game, suspend bool) error {
+	ctx := context.Background()
+	key := typeNamespacedName(game.ID.String(), g.namespaces.namespaceOf(game))
+
+	resource := streamv1.Game{}
+	err := g.k8sClient.Get(ctx, key, &resource)
+	if err != nil {
+		return err
+	}
+	if resource.Spec.Suspend == suspend {
+		return nil
+	}
+
+	resource.Spec.Suspend = suspend
+	return g.k8sClient.Update(ctx, &resource)
+}
+
+func typeNamespacedName(resourceName string, namespace string) types.NamespacedName {
 	return types.NamespacedName{
 		Name:      resourceName,
-		Namespace: "default",
+		Namespace: namespace,
 	}
 }
 
-func createAndVerifyGameResource(game *models.Game) (*streamv1.Game, error) {
+func createAndVerifyGameResource(game *models.Game, namespace string) (*streamv1.Game, error) {
 	if game.ID == uuid.Nil {
 		return nil, errors.New("game id is not set")
 	}
@@ -85,21 +253,31 @@ func createAndVerifyGameResource(game *models.Game) (*streamv1.Game, error) {
 	return &streamv1.Game{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      game.ID.String(),
-			Namespace: "default",
+			Namespace: namespace,
 		},
 		Spec: streamv1.GameSpec{
-			Name:     game.Title,
-			FileName: game.FileName,
+			Name:        game.Title,
+			FileName:    game.FileName,
+			Revision:    int64(game.DeployRevision),
+			StoragePath: game.BlobName,
+			Directory:   shared.IsBundle(game.BlobName),
+			Bios:        game.Bios,
 		},
 	}, nil
 }
 
 type k8sApi struct {
-	k8sClient client.Client
+	k8sClient  client.Client
+	namespaces *namespaces
 }
 
-func K8sService(k8sClient client.Client) IK8sApi {
+// K8sService creates the game resources in the namespaces of the strategy of the config.
+func K8sService(k8sClient client.Client, config NamespaceConfig) IK8sApi {
 	return &k8sApi{
 		k8sClient: k8sClient,
+		namespaces: &namespaces{
+			config:    config,
+			k8sClient: k8sClient,
+		},
 	}
 }
@@ -0,0 +1,270 @@
+package apis
+
+import (
+	"api/models"
+	"api/shared"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NamespaceStrategy decides in which namespace the resources of a game are created
+type NamespaceStrategy string
+
+const (
+	//Namespace_Single puts all games into one namespace
+	Namespace_Single NamespaceStrategy = "single"
+	//Namespace_PerOwner creates a namespace for every user and every organization
+	Namespace_PerOwner NamespaceStrategy = "per-owner"
+	//Namespace_PerOrg creates a namespace for every organization, the games of users stay in the shared namespace
+	Namespace_PerOrg NamespaceStrategy = "per-org"
+)
+
+// GameStorageClaim is the PersistentVolumeClaim of the game storage, which the operator mounts into the pods of the games
+const GameStorageClaim = "azure-blob-pvc"
+
+const (
+	labelManagedBy = "app.kubernetes.io/managed-by"
+	labelOwner     = "indiegamestream.com/owner"
+	managedBy      = "indiegamestream-api"
+)
+
+// invalidNameCharacters matches everything which must not be part of a namespace name
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// NamespaceConfig configures the namespaces of the games.
+type NamespaceConfig struct {
+	Strategy NamespaceStrategy
+	//Namespace of the single strategy and of the games of users with the per-org strategy
+	Namespace string
+	//Prefix of the namespaces which are created by the api
+	Prefix string
+	//Quota is the hard limit of the ResourceQuota of created namespaces, no quota is created if it is empty
+	Quota corev1.ResourceList
+	//DefaultLimits and DefaultRequests are set by the LimitRange of created namespaces for containers without resources
+	DefaultLimits   corev1.ResourceList
+	DefaultRequests corev1.ResourceList
+	//StorageClass provisions the GameStorageClaim of created namespaces, no claim is created if it is empty
+	StorageClass string
+}
+
+// NamespaceConfigFromEnv reads the config from the environment variables K8S_NAMESPACE_STRATEGY, K8S_NAMESPACE,
+// K8S_NAMESPACE_PREFIX, K8S_QUOTA_CPU, K8S_QUOTA_MEMORY, K8S_QUOTA_PODS, K8S_CONTAINER_CPU, K8S_CONTAINER_MEMORY,
+// K8S_CONTAINER_CPU_REQUEST, K8S_CONTAINER_MEMORY_REQUEST and K8S_STORAGE_CLASS.
+func NamespaceConfigFromEnv() NamespaceConfig {
+	config := NamespaceConfig{
+		Strategy:        NamespaceStrategy(os.Getenv("K8S_NAMESPACE_STRATEGY")),
+		Namespace:       os.Getenv("K8S_NAMESPACE"),
+		Prefix:          os.Getenv("K8S_NAMESPACE_PREFIX"),
+		Quota:           corev1.ResourceList{},
+		DefaultLimits:   corev1.ResourceList{},
+		DefaultRequests: corev1.ResourceList{},
+		StorageClass:    os.Getenv("K8S_STORAGE_CLASS"),
+	}
+	if config.Strategy == "" {
+		config.Strategy = Namespace_Single
+	}
+	if config.Strategy != Namespace_Single && config.Strategy != Namespace_PerOwner && config.Strategy != Namespace_PerOrg {
+		log.Fatalf("Unknown K8S_NAMESPACE_STRATEGY %s", config.Strategy)
+	}
+	if config.Prefix == "" {
+		config.Prefix = "igs-"
+	}
+	//The storage class of scripts/azure-csi, "none" if the claims are created otherwise
+	switch config.StorageClass {
+	case "":
+		config.StorageClass = "azureblob-sc"
+	case "none":
+		config.StorageClass = ""
+	}
+
+	quantityFromEnv(config.Quota, corev1.ResourceLimitsCPU, "K8S_QUOTA_CPU")
+	quantityFromEnv(config.Quota, corev1.ResourceLimitsMemory, "K8S_QUOTA_MEMORY")
+	quantityFromEnv(config.Quota, corev1.ResourcePods, "K8S_QUOTA_PODS")
+	quantityFromEnv(config.DefaultLimits, corev1.ResourceCPU, "K8S_CONTAINER_CPU")
+	quantityFromEnv(config.DefaultLimits, corev1.ResourceMemory, "K8S_CONTAINER_MEMORY")
+	quantityFromEnv(config.DefaultRequests, corev1.ResourceCPU, "K8S_CONTAINER_CPU_REQUEST")
+	quantityFromEnv(config.DefaultRequests, corev1.ResourceMemory, "K8S_CONTAINER_MEMORY_REQUEST")
+	if err := config.Validate(); err != nil {
+		log.Fatal(err.Error())
+	}
+	return config
+}
+
+// Validate checks that a quota of the limits has default limits. Kubernetes rejects the pods of a namespace with such a quota
+// if their containers have no limits, which the containers of the games don't have.
+func (c NamespaceConfig) Validate() error {
+	if _, ok := c.Quota[corev1.ResourceLimitsCPU]; ok {
+		if _, ok = c.DefaultLimits[corev1.ResourceCPU]; !ok {
+			return errors.New("K8S_QUOTA_CPU needs K8S_CONTAINER_CPU, otherwise the pods of the games are rejected by the quota")
+		}
+	}
+	if _, ok := c.Quota[corev1.ResourceLimitsMemory]; ok {
+		if _, ok = c.DefaultLimits[corev1.ResourceMemory]; !ok {
+			return errors.New("K8S_QUOTA_MEMORY needs K8S_CONTAINER_MEMORY, otherwise the pods of the games are rejected by the quota")
+		}
+	}
+	return nil
+}
+
+// quantityFromEnv adds the quantity of the environment variable to the list, if it is set
+func quantityFromEnv(list corev1.ResourceList, name corev1.ResourceName, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		log.Fatalf("Invalid %s %s: %s", key, value, err)
+	}
+	list[name] = quantity
+}
+
+// namespaces resolves and creates the namespaces of the games
+type namespaces struct {
+	config    NamespaceConfig
+	k8sClient client.Client
+	//ensured contains the namespaces which are known to exist
+	ensured sync.Map
+}
+
+// namespaceOf returns the namespace of a game. A deployed game keeps its namespace, even if the strategy has been changed since,
+// the namespace of a game which has not been deployed yet is the namespace of its owner.
+func (n *namespaces) namespaceOf(game *models.Game) string {
+	if game.Namespace != "" {
+		return game.Namespace
+	}
+	return n.namespaceOfOwner(game.Owner)
+}
+
+// namespaceOfOwner returns the namespace of the games of the owner according to the strategy.
+func (n *namespaces) namespaceOfOwner(owner string) string {
+	_, isOrg := shared.ParseOrgOwner(owner)
+	switch {
+	case n.config.Strategy == Namespace_PerOwner, n.config.Strategy == Namespace_PerOrg && isOrg:
+		return n.config.Prefix + ownerName(owner, 63-len(n.config.Prefix))
+	case n.config.Namespace != "":
+		return n.config.Namespace
+	default:
+		return "default"
+	}
+}
+
+// ensure creates the namespace of the game together with its ResourceQuota, LimitRange and the claim of the game storage,
+// if it is not existing. Namespaces which are not created by the api, like the shared namespace, must already exist.
+func (n *namespaces) ensure(ctx context.Context, game *models.Game) (string, error) {
+	name := n.namespaceOf(game)
+	owner := game.Owner
+	if name == n.config.Namespace || name == "default" {
+		return name, nil
+	}
+	if _, ok := n.ensured.Load(name); ok {
+		return name, nil
+	}
+
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				labelManagedBy: managedBy,
+				labelOwner:     ownerName(owner, 63),
+			},
+			//Labels can not contain every subject, so the owner is annotated too
+			Annotations: map[string]string{labelOwner: owner},
+		},
+	}
+	err := n.createIfMissing(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	if len(n.config.Quota) > 0 {
+		err = n.createIfMissing(ctx, &corev1.ResourceQuota{
+			ObjectMeta: n.objectMeta(name, "game-quota"),
+			Spec:       corev1.ResourceQuotaSpec{Hard: n.config.Quota},
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(n.config.DefaultLimits) > 0 || len(n.config.DefaultRequests) > 0 {
+		err = n.createIfMissing(ctx, &corev1.LimitRange{
+			ObjectMeta: n.objectMeta(name, "game-limits"),
+			Spec: corev1.LimitRangeSpec{
+				Limits: []corev1.LimitRangeItem{{
+					Type:           corev1.LimitTypeContainer,
+					Default:        n.config.DefaultLimits,
+					DefaultRequest: n.config.DefaultRequests,
+				}},
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	//The claims are bound to their own volumes, which all provision the same container of the storage class
+	if n.config.StorageClass != "" {
+		err = n.createIfMissing(ctx, &corev1.PersistentVolumeClaim{
+			ObjectMeta: n.objectMeta(name, GameStorageClaim),
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany},
+				StorageClassName: &n.config.StorageClass,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+				},
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	log.Printf("Namespace %s of %s is ready", name, owner)
+	n.ensured.Store(name, true)
+	return name, nil
+}
+
+// createIfMissing creates the object and ignores that it is already existing
+func (n *namespaces) createIfMissing(ctx context.Context, object client.Object) error {
+	err := n.k8sClient.Create(ctx, object)
+	if k8serrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+func (n *namespaces) objectMeta(namespace string, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{labelManagedBy: managedBy},
+	}
+}
+
+// ownerName converts the owner into a valid DNS label of at most maxLength characters.
+// The readable part is shortened and a hash of the owner is appended, so different owners never share a name.
+func ownerName(owner string, maxLength int) string {
+	if orgID, ok := shared.ParseOrgOwner(owner); ok {
+		return "org-" + orgID.String()
+	}
+
+	hash := sha256.Sum256([]byte(owner))
+	suffix := hex.EncodeToString(hash[:])[:10]
+	readable := strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(owner), "-"), "-")
+	if limit := maxLength - len(suffix) - 3; len(readable) > limit {
+		readable = strings.Trim(readable[:limit], "-")
+	}
+	return "u-" + readable + "-" + suffix
+}
@@ -0,0 +1,126 @@
+package apis
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+type IScannerApi interface {
+	Scan(name string, content io.Reader) (ScanResult, error)
+}
+
+// ScanResult is the verdict of a scanner, Reason names what has been found if the content is not clean
+type ScanResult struct {
+	Clean  bool
+	Reason string
+}
+
+// clamdChunkSize is the size of the chunks of the INSTREAM command, clamd accepts chunks up to its StreamMaxLength
+const clamdChunkSize = 64 * 1024
+
+// clamdScanner sends the content to clamd with the INSTREAM command
+type clamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// Scan streams the content in chunks, each prefixed with its length, and reads the reply of clamd,
+// e.g. "stream: OK" or "stream: Win.Test.EICAR_HDB-1 FOUND". Content which exceeds the StreamMaxLength of clamd is not clean.
+func (c clamdScanner) Scan(name string, content io.Reader) (ScanResult, error) {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer conn.Close()
+	err = conn.SetDeadline(time.Now().Add(c.timeout))
+	if err != nil {
+		return ScanResult{}, err
+	}
+
+	errWrite := c.stream(conn, content)
+	//clamd replies and closes the connection if the size limit is exceeded, so the reply is read even if writing has failed.
+	//The reply of a command with the prefix "z" ends with a null byte.
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if len(reply) == 0 {
+		if errWrite != nil {
+			return ScanResult{}, errWrite
+		}
+		if err == io.EOF {
+			err = errors.New("clamd closed the connection without a reply")
+		}
+		return ScanResult{}, err
+	}
+	return parseClamdReply(name, string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// stream sends the INSTREAM command with the content, a chunk of length 0 ends the stream
+func (c clamdScanner) stream(conn net.Conn, content io.Reader) error {
+	_, err := conn.Write([]byte("zINSTREAM\x00"))
+	if err != nil {
+		return err
+	}
+
+	buffer := make([]byte, 4+clamdChunkSize)
+	for {
+		n, errRead := content.Read(buffer[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buffer[:4], uint32(n))
+			if _, err = conn.Write(buffer[:4+n]); err != nil {
+				return err
+			}
+		}
+		if errRead == io.EOF {
+			break
+		}
+		if errRead != nil {
+			return errRead
+		}
+	}
+	_, err = conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseClamdReply converts the reply of clamd into a result
+func parseClamdReply(name string, reply string) (ScanResult, error) {
+	verdict := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case verdict == "OK":
+		return ScanResult{Clean: true}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return ScanResult{Reason: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.Contains(verdict, "size limit exceeded"):
+		return ScanResult{Reason: "the file exceeds the size limit of the scanner"}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd could not scan %s: %s", name, verdict)
+	}
+}
+
+// noopScanner accepts all content without reading it
+type noopScanner struct{}
+
+func (noopScanner) Scan(_ string, _ io.Reader) (ScanResult, error) {
+	return ScanResult{Clean: true}, nil
+}
+
+// ClamdScanner scans the content with the clamd daemon of ClamAV at the address, e.g. "tcp", "clamav:3310" or "unix", "/run/clamd.sock".
+// The timeout limits the whole scan of a file.
+func ClamdScanner(network string, address string, timeout time.Duration) IScannerApi {
+	return &clamdScanner{
+		network: network,
+		address: address,
+		timeout: timeout,
+	}
+}
+
+// NoopScanner reports all content as clean, it is used if no scanner is configured.
+func NoopScanner() IScannerApi {
+	return &noopScanner{}
+}
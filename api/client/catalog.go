@@ -0,0 +1,57 @@
+package client
+
+import (
+	"api/dtos"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ExportCatalog returns the games with their versions and files as tar.gz archive, only the games of owner if it is not empty.
+// It can only be called by administrators. The returned reader has to be closed.
+func (c *Client) ExportCatalog(ctx context.Context, owner string) (io.ReadCloser, error) {
+	query := url.Values{}
+	if owner != "" {
+		query.Set("owner", owner)
+	}
+	response, err := c.do(ctx, request{method: http.MethodGet, path: "/admin/export", query: query, replayable: true})
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// ImportCatalog imports an archive of ExportCatalog. With the mode "preserve" the games keep their ids,
+// with "remap" they get new ids. The request is only retried if the archive is an io.Seeker.
+func (c *Client) ImportCatalog(ctx context.Context, archive io.Reader, mode string) (*dtos.CatalogImportResponseBody, error) {
+	query := url.Values{}
+	if mode != "" {
+		query.Set("mode", mode)
+	}
+	body, replayable := readerBody(archive, "application/gzip")
+	result := &dtos.CatalogImportResponseBody{}
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: "/admin/import", query: query, body: body, replayable: replayable}, result)
+	return result, err
+}
+
+// readerBody sends the content of a reader. The request can be sent again if the reader is an io.Seeker,
+// it is then rewound for every attempt.
+func readerBody(content io.Reader, contentType string) (body, bool) {
+	seeker, replayable := content.(io.Seeker)
+	sent := false
+	return func() (io.Reader, string, error) {
+		if sent {
+			if !replayable {
+				return nil, "", errors.New("the body can not be sent again")
+			}
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, "", err
+			}
+		}
+		sent = true
+		//The reader is wrapped, so the http client does not close it
+		return io.NopCloser(content), contentType, nil
+	}, replayable
+}
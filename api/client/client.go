@@ -0,0 +1,313 @@
+// Package client is the Go client of the IndieGameStream api.
+//
+// It uses the request and response types of the api (package dtos), so it stays in sync with the api.
+// Every method takes a context, failed requests are retried with backoff and the token is read from a TokenSource
+// before every request:
+//
+//	c := client.New("https://api.example.com", client.Options{TokenSource: client.StaticToken(token)})
+//	games, err := c.ListGames(ctx, "")
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the retries
+const (
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Options configure a Client. The zero value is usable, but requests are sent without a token.
+type Options struct {
+	//HTTPClient sends the requests, defaults to a client without timeout, because uploads and downloads can take long
+	HTTPClient *http.Client
+	//TokenSource returns the bearer token of every request, requests are sent without a token if it is nil
+	TokenSource TokenSource
+	//MaxRetries is the number of retries of a request which failed with 429 or, if it is idempotent, with 5xx, defaults to DefaultMaxRetries.
+	//A negative value disables retries.
+	MaxRetries int
+	//MinBackoff is the wait before the first retry, it is doubled for every further retry
+	MinBackoff time.Duration
+	//MaxBackoff is the longest wait between two retries, also if the api requests a longer wait by Retry-After
+	MaxBackoff time.Duration
+	//UserAgent is sent with every request
+	UserAgent string
+}
+
+// Client sends requests to the api. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	token      TokenSource
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	userAgent  string
+}
+
+// New creates a client of the api at baseURL, e.g. https://api.example.com
+func New(baseURL string, options Options) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		http:       options.HTTPClient,
+		token:      options.TokenSource,
+		maxRetries: options.MaxRetries,
+		minBackoff: options.MinBackoff,
+		maxBackoff: options.MaxBackoff,
+		userAgent:  options.UserAgent,
+	}
+	if c.http == nil {
+		c.http = &http.Client{}
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = DefaultMaxRetries
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.minBackoff <= 0 {
+		c.minBackoff = DefaultMinBackoff
+	}
+	if c.maxBackoff <= 0 {
+		c.maxBackoff = DefaultMaxBackoff
+	}
+	return c
+}
+
+// Error is returned if the api answers with an error status
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("the api answered with %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("the api answered with %d: %s", e.StatusCode, e.Message)
+}
+
+// IsStatus returns true if err is an Error of the api with the status code
+func IsStatus(err error, statusCode int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// body creates the body of a request and its content type. It is called again for every retry.
+type body func() (io.Reader, string, error)
+
+// request describes a request to the api
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   body
+	//replayable is false if the body can only be read once, such requests are not retried
+	replayable bool
+}
+
+// jsonBody encodes value as json body
+func jsonBody(value any) body {
+	return func() (io.Reader, string, error) {
+		content, err := json.Marshal(value)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(content), "application/json", nil
+	}
+}
+
+// do sends a request and retries it if it fails with 429 or, if it is idempotent, with 5xx. The response is returned if its status is below 400,
+// otherwise an Error is returned. The caller has to close the body of the response.
+func (c *Client) do(ctx context.Context, r request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		response, err := c.send(ctx, r)
+		if err != nil {
+			//Connection errors are only retried if the request can be sent again without side effects
+			if ctx.Err() != nil || !r.replayable || !idempotent(r.method) || attempt >= c.maxRetries {
+				return nil, err
+			}
+			if err = c.wait(ctx, attempt, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if response.StatusCode < http.StatusBadRequest {
+			return response, nil
+		}
+
+		if r.replayable && retryable(r.method, response.StatusCode) && attempt < c.maxRetries {
+			//The body is drained, so the connection can be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+			response.Body.Close()
+			if err = c.wait(ctx, attempt, response); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return nil, readError(response)
+	}
+}
+
+// send sends a request once
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	token := ""
+	if c.token != nil {
+		var err error
+		token, err = c.token.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get a token: %w", err)
+		}
+	}
+
+	var content io.Reader
+	contentType := ""
+	if r.body != nil {
+		var err error
+		content, contentType, err = r.body()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	target := c.baseURL + r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, r.method, target, content)
+	if err != nil {
+		//Stops the writer of a streamed body
+		if closer, ok := content.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
+	for key, values := range r.header {
+		httpRequest.Header[key] = values
+	}
+	if contentType != "" {
+		httpRequest.Header.Set("Content-Type", contentType)
+	}
+	if c.userAgent != "" {
+		httpRequest.Header.Set("User-Agent", c.userAgent)
+	}
+	if token != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.http.Do(httpRequest)
+}
+
+// wait waits before a retry. The wait is doubled for every attempt and randomized by up to a fourth,
+// a Retry-After header of the response is used instead if it is set.
+func (c *Client) wait(ctx context.Context, attempt int, response *http.Response) error {
+	backoff := c.minBackoff << attempt
+	if backoff <= 0 || backoff > c.maxBackoff {
+		backoff = c.maxBackoff
+	}
+	backoff -= time.Duration(rand.Int63n(int64(backoff)/4 + 1))
+	if response != nil {
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+			backoff = min(retryAfter, c.maxBackoff)
+		}
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter parses the seconds or the date of a Retry-After header
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// retryable returns true for the statuses which are retried. A request which is rejected with 429 has not been processed,
+// so it is retried with every method. Server errors are only retried for idempotent requests, because the api may have
+// persisted a part of the request, e.g. the version of an upload. 501 is not retried, because the api uses it
+// for operations which are not supported.
+func retryable(method string, statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests ||
+		(idempotent(method) && statusCode >= http.StatusInternalServerError && statusCode != http.StatusNotImplemented)
+}
+
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodPut || method == http.MethodDelete
+}
+
+// readError reads the message of an error response and closes it
+func readError(response *http.Response) error {
+	defer response.Body.Close()
+	var message struct {
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(response.Body, 64<<10)).Decode(&message)
+	return &Error{StatusCode: response.StatusCode, Message: message.Message}
+}
+
+// getJSON sends a GET request and decodes the json response into result
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, result any) error {
+	return c.doJSON(ctx, request{method: http.MethodGet, path: path, query: query, replayable: true}, result)
+}
+
+// doJSON sends a request and decodes the json response into result, the response is discarded if result is nil
+func (c *Client) doJSON(ctx context.Context, r request, result any) error {
+	_, err := c.doJSONWithHeader(ctx, r, result)
+	return err
+}
+
+// doJSONWithHeader is doJSON which also returns the header of the response
+func (c *Client) doJSONWithHeader(ctx context.Context, r request, result any) (http.Header, error) {
+	response, err := c.do(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if result == nil {
+		_, err = io.Copy(io.Discard, response.Body)
+		return response.Header, err
+	}
+	if err = json.NewDecoder(response.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("failed to decode the response: %w", err)
+	}
+	return response.Header, nil
+}
+
+// ifMatchHeader returns the If-Match header of a request, no header is set if etag is empty
+func ifMatchHeader(etag string) http.Header {
+	if etag == "" {
+		return nil
+	}
+	return http.Header{"If-Match": []string{etag}}
+}
+
+// Ping checks if the api is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.doJSON(ctx, request{method: http.MethodGet, path: "/ping", replayable: true}, nil)
+}
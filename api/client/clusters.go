@@ -0,0 +1,33 @@
+package client
+
+import (
+	"api/dtos"
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// clusterPath returns the path of a registered cluster
+func clusterPath(name string) string {
+	return "/admin/clusters/" + url.PathEscape(name)
+}
+
+// ListClusters returns the registered clusters with the number of their games.
+// It can only be called by administrators.
+func (c *Client) ListClusters(ctx context.Context) ([]dtos.ClusterResponseBody, error) {
+	clusters := []dtos.ClusterResponseBody{}
+	err := c.getJSON(ctx, "/admin/clusters", nil, &clusters)
+	return clusters, err
+}
+
+// SaveCluster registers a cluster or changes a registered cluster, e.g. cordons it
+func (c *Client) SaveCluster(ctx context.Context, name string, cluster dtos.SaveClusterRequestBody) (*dtos.ClusterResponseBody, error) {
+	result := &dtos.ClusterResponseBody{}
+	err := c.doJSON(ctx, request{method: http.MethodPut, path: clusterPath(name), body: jsonBody(cluster), replayable: true}, result)
+	return result, err
+}
+
+// DeleteCluster removes a cluster from the registry, it fails with 409 while games belong to the cluster
+func (c *Client) DeleteCluster(ctx context.Context, name string) error {
+	return c.doJSON(ctx, request{method: http.MethodDelete, path: clusterPath(name), replayable: true}, nil)
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"api/dtos"
+	"context"
+	"net/http"
+)
+
+// CheckConsistency compares the games with the blob storage and the game resources of the clusters, nothing is repaired.
+// It can only be called by administrators.
+func (c *Client) CheckConsistency(ctx context.Context) (*dtos.ConsistencyReportResponseBody, error) {
+	report := &dtos.ConsistencyReportResponseBody{}
+	err := c.getJSON(ctx, "/admin/consistency", nil, report)
+	return report, err
+}
+
+// RepairConsistency checks the consistency and repairs the kinds of inconsistencies which are enabled by CONSISTENCY_REPAIR
+// of the api, it fails with 409 if no repair is enabled
+func (c *Client) RepairConsistency(ctx context.Context) (*dtos.ConsistencyReportResponseBody, error) {
+	report := &dtos.ConsistencyReportResponseBody{}
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: "/admin/consistency/repair", replayable: true}, report)
+	return report, err
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"api/dtos"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Game is a game with its ETag. The ETag can be passed as ifMatch to change the game only if it has not been changed since.
+type Game struct {
+	dtos.GetGameByIdResponseBody
+	ETag string `json:"-"`
+}
+
+// Upload is a game file which is uploaded as a new game
+type Upload struct {
+	//FileName is the name of the game file, e.g. game.nes
+	FileName string
+	//File is streamed to the api. If it is an io.Seeker, e.g. an *os.File, the upload is retried if it fails.
+	File        io.Reader
+	Title       string
+	Description string
+	Tags        []string
+	Platform    string
+	//Owner uploads the game for an organization (org:<id>), defaults to the user
+	Owner string
+	//Region places the game on a cluster of this region, Cluster pins it to a registered cluster which is not cordoned.
+	//Only administrators can pin games.
+	Region  string
+	Cluster string
+	//Archive extracts the uploaded zip archive into a game with several files,
+	//Entry overrides the entry file of the manifest of the archive
+	Archive bool
+	Entry   string
+}
+
+func gamePath(id uuid.UUID) string {
+	return "/games/" + id.String()
+}
+
+// UploadGame uploads a game and returns its id. The game is installed in the background, its status shows the progress.
+func (c *Client) UploadGame(ctx context.Context, upload Upload) (uuid.UUID, error) {
+	if upload.File == nil || upload.FileName == "" {
+		return uuid.Nil, errors.New("the upload needs a file and its name")
+	}
+	title := upload.Title
+	if title == "" {
+		title = strings.TrimSuffix(upload.FileName, path.Ext(upload.FileName))
+	}
+	fields := map[string]string{
+		"title":       title,
+		"description": upload.Description,
+		"tags":        strings.Join(upload.Tags, ","),
+		"platform":    upload.Platform,
+		"owner":       upload.Owner,
+		"region":      upload.Region,
+		"cluster":     upload.Cluster,
+		"archive":     archiveValue(upload.Archive),
+		"entry":       upload.Entry,
+	}
+	body, replayable := multipartBody(fields, upload.FileName, upload.File)
+
+	header, err := c.doJSONWithHeader(ctx, request{method: http.MethodPost, path: "/games", body: body, replayable: replayable}, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	//The api returns the location of the new game, e.g. api.example.com/games/<id>
+	id, err := uuid.Parse(path.Base(header.Get("Content-Location")))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("the api did not return the location of the game: %w", err)
+	}
+	return id, nil
+}
+
+// ListGames returns the games of the user and of the organizations of the user.
+// If owner is set, only the games of this owner are returned, e.g. org:<id>.
+func (c *Client) ListGames(ctx context.Context, owner string) ([]dtos.GetAllGamesResponseBody, error) {
+	query := url.Values{}
+	if owner != "" {
+		query.Set("owner", owner)
+	}
+	games := []dtos.GetAllGamesResponseBody{}
+	err := c.getJSON(ctx, "/games", query, &games)
+	return games, err
+}
+
+// GetGame returns a game and its ETag
+func (c *Client) GetGame(ctx context.Context, id uuid.UUID) (*Game, error) {
+	return c.gameRequest(ctx, request{method: http.MethodGet, path: gamePath(id), replayable: true})
+}
+
+// UpdateGame changes the metadata of a game, fields which are nil are not changed.
+// If ifMatch is set, the game is only changed if its ETag matches, otherwise an Error with 412 is returned.
+func (c *Client) UpdateGame(ctx context.Context, id uuid.UUID, update dtos.UpdateGameRequestBody, ifMatch string) (*Game, error) {
+	return c.gameRequest(ctx, request{method: http.MethodPatch, path: gamePath(id), header: ifMatchHeader(ifMatch), body: jsonBody(update), replayable: true})
+}
+
+// DeleteGame moves a game to the trash
+func (c *Client) DeleteGame(ctx context.Context, id uuid.UUID) error {
+	return c.doJSON(ctx, request{method: http.MethodDelete, path: gamePath(id), replayable: true}, nil)
+}
+
+// ListTrash returns the games in the trash, filtered by owner like ListGames
+func (c *Client) ListTrash(ctx context.Context, owner string) ([]dtos.GetDeletedGameResponseBody, error) {
+	query := url.Values{}
+	if owner != "" {
+		query.Set("owner", owner)
+	}
+	games := []dtos.GetDeletedGameResponseBody{}
+	err := c.getJSON(ctx, "/trash", query, &games)
+	return games, err
+}
+
+// RestoreGame moves a game out of the trash and deploys it again
+func (c *Client) RestoreGame(ctx context.Context, id uuid.UUID) (*Game, error) {
+	return c.gameRequest(ctx, request{method: http.MethodPost, path: gamePath(id) + "/restore", replayable: true})
+}
+
+// StopGame scales a game down, it keeps its url
+func (c *Client) StopGame(ctx context.Context, id uuid.UUID) (*Game, error) {
+	return c.gameRequest(ctx, request{method: http.MethodPost, path: gamePath(id) + "/stop", replayable: true})
+}
+
+// StartGame scales a stopped game up again
+func (c *Client) StartGame(ctx context.Context, id uuid.UUID) (*Game, error) {
+	return c.gameRequest(ctx, request{method: http.MethodPost, path: gamePath(id) + "/start", replayable: true})
+}
+
+// RestartGame starts a stopped game or rolls out new pods of a running game
+func (c *Client) RestartGame(ctx context.Context, id uuid.UUID) (*Game, error) {
+	return c.gameRequest(ctx, request{method: http.MethodPost, path: gamePath(id) + "/restart", replayable: true})
+}
+
+// gameRequest sends a request which responds with a game and its ETag
+func (c *Client) gameRequest(ctx context.Context, r request) (*Game, error) {
+	game := &Game{}
+	header, err := c.doJSONWithHeader(ctx, r, &game.GetGameByIdResponseBody)
+	if err != nil {
+		return nil, err
+	}
+	game.ETag = header.Get("ETag")
+	return game, nil
+}
+
+// Search returns the own and the public games matching the query, ordered by relevance
+func (c *Client) Search(ctx context.Context, q string, limit int, offset int) ([]dtos.SearchResultResponseBody, error) {
+	query := url.Values{"q": []string{q}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	results := []dtos.SearchResultResponseBody{}
+	err := c.getJSON(ctx, "/search", query, &results)
+	return results, err
+}
+
+// BatchGames runs an operation on many games. Small batches are done when the job is returned,
+// the progress of larger batches can be read with GetJob.
+func (c *Client) BatchGames(ctx context.Context, batch dtos.BatchGamesRequestBody) (*dtos.BatchJobResponseBody, error) {
+	job := &dtos.BatchJobResponseBody{}
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: "/games:batch", body: jsonBody(batch), replayable: true}, job)
+	return job, err
+}
+
+// GetJob returns the progress of a batch job
+func (c *Client) GetJob(ctx context.Context, id uuid.UUID) (*dtos.BatchJobResponseBody, error) {
+	job := &dtos.BatchJobResponseBody{}
+	err := c.getJSON(ctx, "/jobs/"+id.String(), nil, job)
+	return job, err
+}
+
+// ListCollaborators returns the users with whom a game has been shared
+func (c *Client) ListCollaborators(ctx context.Context, id uuid.UUID) ([]dtos.CollaboratorResponseBody, error) {
+	collaborators := []dtos.CollaboratorResponseBody{}
+	err := c.getJSON(ctx, gamePath(id)+"/collaborators", nil, &collaborators)
+	return collaborators, err
+}
+
+// GrantCollaborator shares a game with a user, the permissions of an existing collaborator are replaced
+func (c *Client) GrantCollaborator(ctx context.Context, id uuid.UUID, grant dtos.GrantCollaboratorRequestBody) (*dtos.CollaboratorResponseBody, error) {
+	collaborator := &dtos.CollaboratorResponseBody{}
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: gamePath(id) + "/collaborators", body: jsonBody(grant), replayable: true}, collaborator)
+	return collaborator, err
+}
+
+// RevokeCollaborator revokes all permissions of a collaborator
+func (c *Client) RevokeCollaborator(ctx context.Context, id uuid.UUID, collaboratorID uuid.UUID) error {
+	return c.doJSON(ctx, request{method: http.MethodDelete, path: gamePath(id) + "/collaborators/" + collaboratorID.String(), replayable: true}, nil)
+}
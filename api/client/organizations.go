@@ -0,0 +1,82 @@
+package client
+
+import (
+	"api/dtos"
+	"api/shared"
+	"context"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+)
+
+func orgPath(id uuid.UUID) string {
+	return "/orgs/" + id.String()
+}
+
+// CreateOrganization creates an organization, the user becomes its owner
+func (c *Client) CreateOrganization(ctx context.Context, name string) (*dtos.OrganizationResponseBody, error) {
+	organization := &dtos.OrganizationResponseBody{}
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: "/orgs",
+		body: jsonBody(dtos.CreateOrganizationRequestBody{Name: name}), replayable: true}, organization)
+	return organization, err
+}
+
+// ListOrganizations returns the organizations of the user
+func (c *Client) ListOrganizations(ctx context.Context) ([]dtos.OrganizationResponseBody, error) {
+	organizations := []dtos.OrganizationResponseBody{}
+	err := c.getJSON(ctx, "/orgs", nil, &organizations)
+	return organizations, err
+}
+
+// GetOrganization returns an organization of the user
+func (c *Client) GetOrganization(ctx context.Context, id uuid.UUID) (*dtos.OrganizationResponseBody, error) {
+	organization := &dtos.OrganizationResponseBody{}
+	err := c.getJSON(ctx, orgPath(id), nil, organization)
+	return organization, err
+}
+
+// ListMembers returns the members of an organization
+func (c *Client) ListMembers(ctx context.Context, id uuid.UUID) ([]dtos.OrgMemberResponseBody, error) {
+	members := []dtos.OrgMemberResponseBody{}
+	err := c.getJSON(ctx, orgPath(id)+"/members", nil, &members)
+	return members, err
+}
+
+// UpdateMember changes the role of a member
+func (c *Client) UpdateMember(ctx context.Context, id uuid.UUID, subject string, role shared.OrgRole) error {
+	return c.doJSON(ctx, request{method: http.MethodPut, path: orgPath(id) + "/members/" + url.PathEscape(subject),
+		body: jsonBody(dtos.UpdateOrgMemberRequestBody{Role: role}), replayable: true}, nil)
+}
+
+// RemoveMember removes a member, the user leaves the organization if subject is the own subject
+func (c *Client) RemoveMember(ctx context.Context, id uuid.UUID, subject string) error {
+	return c.doJSON(ctx, request{method: http.MethodDelete, path: orgPath(id) + "/members/" + url.PathEscape(subject), replayable: true}, nil)
+}
+
+// CreateInvitation invites a user by email. The token of the invitation has to be sent to the user, it can not be read again.
+func (c *Client) CreateInvitation(ctx context.Context, id uuid.UUID, email string, role shared.OrgRole) (*dtos.CreateOrgInvitationResponseBody, error) {
+	invitation := &dtos.CreateOrgInvitationResponseBody{}
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: orgPath(id) + "/invitations",
+		body: jsonBody(dtos.CreateOrgInvitationRequestBody{Email: email, Role: role}), replayable: true}, invitation)
+	return invitation, err
+}
+
+// ListInvitations returns the pending invitations of an organization
+func (c *Client) ListInvitations(ctx context.Context, id uuid.UUID) ([]dtos.OrgInvitationResponseBody, error) {
+	invitations := []dtos.OrgInvitationResponseBody{}
+	err := c.getJSON(ctx, orgPath(id)+"/invitations", nil, &invitations)
+	return invitations, err
+}
+
+// RevokeInvitation revokes an invitation
+func (c *Client) RevokeInvitation(ctx context.Context, id uuid.UUID, invitationID uuid.UUID) error {
+	return c.doJSON(ctx, request{method: http.MethodDelete, path: orgPath(id) + "/invitations/" + invitationID.String(), replayable: true}, nil)
+}
+
+// AcceptInvitation joins the organization of an invitation
+func (c *Client) AcceptInvitation(ctx context.Context, token string) (*dtos.OrganizationResponseBody, error) {
+	organization := &dtos.OrganizationResponseBody{}
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: "/invitations/accept",
+		body: jsonBody(dtos.AcceptOrgInvitationRequestBody{Token: token}), replayable: true}, organization)
+	return organization, err
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"golang.org/x/oauth2"
+)
+
+// TokenSource returns the bearer token of a request. It is called before every request, including retries,
+// so it can refresh expired tokens.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a token which never changes, e.g. the USAGE_REPORT_TOKEN of the api
+type StaticToken string
+
+func (s StaticToken) Token(_ context.Context) (string, error) {
+	return string(s), nil
+}
+
+// TokenSourceFunc adapts a function to a TokenSource
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// OAuth2 uses the access tokens of an oauth2.TokenSource, which refreshes them when they expire.
+// The api accepts Google ID tokens, e.g. of the token source of google.golang.org/api/idtoken for service accounts.
+func OAuth2(source oauth2.TokenSource) TokenSource {
+	return TokenSourceFunc(func(_ context.Context) (string, error) {
+		token, err := source.Token()
+		if err != nil {
+			return "", err
+		}
+		return token.AccessToken, nil
+	})
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+)
+
+// multipartBody streams a multipart form with the fields and the file, so large files are not loaded into memory.
+// The request can be sent again if the file is an io.Seeker, it is then rewound for every attempt.
+func multipartBody(fields map[string]string, fileName string, file io.Reader) (body, bool) {
+	seeker, replayable := file.(io.Seeker)
+	var previous *io.PipeReader
+	var done chan struct{}
+	return func() (io.Reader, string, error) {
+		if previous != nil {
+			if !replayable {
+				return nil, "", errors.New("the file can not be sent again")
+			}
+			//The file must not be rewound while the previous attempt still reads it
+			previous.Close()
+			<-done
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, "", err
+			}
+		}
+
+		reader, writer := io.Pipe()
+		form := multipart.NewWriter(writer)
+		previous, done = reader, make(chan struct{})
+		go func(done chan struct{}) {
+			defer close(done)
+			for key, value := range fields {
+				if value == "" {
+					continue
+				}
+				if err := form.WriteField(key, value); err != nil {
+					writer.CloseWithError(err)
+					return
+				}
+			}
+			part, err := form.CreateFormFile("file", fileName)
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if _, err = io.Copy(part, file); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			writer.CloseWithError(form.Close())
+		}(done)
+		return reader, form.FormDataContentType(), nil
+	}, replayable
+}
+
+// archiveValue returns the form value "archive", it is not sent for single files
+func archiveValue(archive bool) string {
+	if archive {
+		return "true"
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"api/dtos"
+	"context"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// usageQuery returns the range of a usage request, the api uses the last 30 days for times which are zero
+func usageQuery(from time.Time, to time.Time) url.Values {
+	query := url.Values{}
+	if !from.IsZero() {
+		query.Set("from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(time.RFC3339))
+	}
+	return query
+}
+
+// GameUsage returns the play time of a game between from and to, in total and per day
+func (c *Client) GameUsage(ctx context.Context, id uuid.UUID, from time.Time, to time.Time) (*dtos.GameUsageResponseBody, error) {
+	usage := &dtos.GameUsageResponseBody{}
+	err := c.getJSON(ctx, gamePath(id)+"/usage", usageQuery(from, to), usage)
+	return usage, err
+}
+
+// UsageReport returns the play time of all games between from and to per game, owner and day.
+// It can only be read by administrators.
+func (c *Client) UsageReport(ctx context.Context, from time.Time, to time.Time) ([]dtos.UsageEntryResponseBody, error) {
+	entries := []dtos.UsageEntryResponseBody{}
+	err := c.getJSON(ctx, "/admin/usage", usageQuery(from, to), &entries)
+	return entries, err
+}
+
+// UsageReportCSV returns the usage report as csv, the returned reader has to be closed
+func (c *Client) UsageReportCSV(ctx context.Context, from time.Time, to time.Time) (io.ReadCloser, error) {
+	query := usageQuery(from, to)
+	query.Set("format", "csv")
+	response, err := c.do(ctx, request{method: http.MethodGet, path: "/admin/usage", query: query, replayable: true})
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// RecordPlaySession reports a play session. The client must use the USAGE_REPORT_TOKEN of the api as StaticToken.
+func (c *Client) RecordPlaySession(ctx context.Context, session dtos.PlaySessionRequestBody) error {
+	return c.doJSON(ctx, request{method: http.MethodPost, path: "/usage/sessions", body: jsonBody(session), replayable: true}, nil)
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"api/dtos"
+	"api/shared"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// VersionUpload is a game file which is uploaded as a new version of a game
+type VersionUpload struct {
+	//FileName is the name of the game file, e.g. game.nes
+	FileName string
+	//File is streamed to the api. If it is an io.Seeker, e.g. an *os.File, the upload is retried if it fails.
+	File      io.Reader
+	Changelog string
+	//Channel deploys the version to the live or the beta channel, by default the version is not deployed
+	Channel shared.Channel
+	//Archive extracts the uploaded zip archive into a version with several files,
+	//Entry overrides the entry file of the manifest of the archive
+	Archive bool
+	Entry   string
+}
+
+// CreatedVersion is an uploaded version and the game after the upload
+type CreatedVersion struct {
+	Game    Game
+	Version dtos.GameVersionResponseBody
+}
+
+// Rom is the download of a game file. Body has to be closed.
+type Rom struct {
+	Body     io.ReadCloser
+	FileName string
+	//Size is -1 if it is unknown
+	Size int64
+}
+
+// ListVersions returns all versions of a game, the newest version first
+func (c *Client) ListVersions(ctx context.Context, id uuid.UUID) ([]dtos.GameVersionResponseBody, error) {
+	versions := []dtos.GameVersionResponseBody{}
+	err := c.getJSON(ctx, gamePath(id)+"/versions", nil, &versions)
+	return versions, err
+}
+
+// UploadVersion uploads a new version of a game and deploys it to the channel of the upload.
+// If ifMatch is set, the version is only created if the ETag of the game matches.
+func (c *Client) UploadVersion(ctx context.Context, id uuid.UUID, upload VersionUpload, ifMatch string) (*CreatedVersion, error) {
+	if upload.File == nil || upload.FileName == "" {
+		return nil, errors.New("the upload needs a file and its name")
+	}
+	fields := map[string]string{"changelog": upload.Changelog, "channel": string(upload.Channel),
+		"archive": archiveValue(upload.Archive), "entry": upload.Entry}
+	body, replayable := multipartBody(fields, upload.FileName, upload.File)
+
+	created := dtos.CreateGameVersionResponseBody{}
+	header, err := c.doJSONWithHeader(ctx, request{method: http.MethodPost, path: gamePath(id) + "/versions",
+		header: ifMatchHeader(ifMatch), body: body, replayable: replayable}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &CreatedVersion{
+		Game:    Game{GetGameByIdResponseBody: created.Game, ETag: header.Get("ETag")},
+		Version: created.Version,
+	}, nil
+}
+
+// ReplaceRom uploads a new version of a game and deploys it to the live channel, the channel of the upload is ignored.
+// If ifMatch is set, the game file is only replaced if the ETag of the game matches.
+func (c *Client) ReplaceRom(ctx context.Context, id uuid.UUID, upload VersionUpload, ifMatch string) (*Game, error) {
+	if upload.File == nil || upload.FileName == "" {
+		return nil, errors.New("the upload needs a file and its name")
+	}
+	fields := map[string]string{"changelog": upload.Changelog, "archive": archiveValue(upload.Archive), "entry": upload.Entry}
+	body, replayable := multipartBody(fields, upload.FileName, upload.File)
+	return c.gameRequest(ctx, request{method: http.MethodPut, path: gamePath(id) + "/rom",
+		header: ifMatchHeader(ifMatch), body: body, replayable: replayable})
+}
+
+// DownloadRom downloads the game file of a version, version 0 downloads the live version.
+// Redirects to signed urls of the storage are followed.
+func (c *Client) DownloadRom(ctx context.Context, id uuid.UUID, version int) (*Rom, error) {
+	query := url.Values{}
+	if version > 0 {
+		query.Set("version", strconv.Itoa(version))
+	}
+	response, err := c.do(ctx, request{method: http.MethodGet, path: gamePath(id) + "/rom", query: query, replayable: true})
+	if err != nil {
+		return nil, err
+	}
+
+	rom := &Rom{Body: response.Body, Size: response.ContentLength}
+	if _, params, err := mime.ParseMediaType(response.Header.Get("Content-Disposition")); err == nil {
+		rom.FileName = params["filename"]
+	}
+	return rom, nil
+}
+
+// PromoteVersion deploys an existing version to the live or the beta channel
+func (c *Client) PromoteVersion(ctx context.Context, id uuid.UUID, version int, channel shared.Channel, ifMatch string) (*Game, error) {
+	return c.gameRequest(ctx, request{method: http.MethodPost, path: gamePath(id) + "/versions/" + strconv.Itoa(version) + "/promote",
+		header: ifMatchHeader(ifMatch), body: jsonBody(dtos.PromoteGameVersionRequestBody{Channel: channel}), replayable: true})
+}
+
+// Rollback deploys the version before the live version to the live channel
+func (c *Client) Rollback(ctx context.Context, id uuid.UUID, ifMatch string) (*Game, error) {
+	return c.gameRequest(ctx, request{method: http.MethodPost, path: gamePath(id) + "/rollback", header: ifMatchHeader(ifMatch), replayable: true})
+}
+
+// RemoveBeta removes the beta deployment of a game
+func (c *Client) RemoveBeta(ctx context.Context, id uuid.UUID, ifMatch string) (*Game, error) {
+	return c.gameRequest(ctx, request{method: http.MethodDelete, path: gamePath(id) + "/channels/beta", header: ifMatchHeader(ifMatch), replayable: true})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"api/dtos"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func adminCommand(flags *globalFlags) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "admin",
+		Short: "Commands of the administrators",
+	}
+	command.AddCommand(adminExportCommand(flags), adminImportCommand(flags))
+	return command
+}
+
+func adminExportCommand(flags *globalFlags) *cobra.Command {
+	var owner string
+	var destination string
+	var noProgress bool
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Export the games with their versions and files as archive, e.g. to import them into another environment",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, _, err := newClient(flags)
+			if err != nil {
+				return err
+			}
+
+			archive, err := client.ExportCatalog(cmd.Context(), owner)
+			if err != nil {
+				return err
+			}
+			defer archive.Close()
+
+			var out io.Writer = cmd.OutOrStdout()
+			if destination != "-" {
+				if destination == "" {
+					destination = fmt.Sprintf("catalog-%s.tar.gz", time.Now().Format("20060102-150405"))
+				}
+				file, err := os.Create(destination)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+
+			//The size of the archive is not known before it has been written
+			_, err = io.Copy(out, withProgress(archive, "Exporting", 0, !noProgress && destination != "-"))
+			if err != nil {
+				return err
+			}
+			if destination != "-" {
+				fmt.Fprintf(os.Stderr, "Saved %s\n", destination)
+			}
+			return nil
+		},
+	}
+	command.Flags().StringVar(&owner, "owner", "", "Only export the games of this owner, e.g. org:<id>")
+	command.Flags().StringVar(&destination, "dest", "", "File to write, \"-\" writes to stdout. Defaults to catalog-<time>.tar.gz")
+	command.Flags().BoolVar(&noProgress, "no-progress", false, "Don't show the progress bar")
+	return command
+}
+
+func adminImportCommand(flags *globalFlags) *cobra.Command {
+	var mode string
+	var noProgress bool
+	command := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Import an exported archive, the games are uploaded and deployed again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, config, err := newClient(flags)
+			if err != nil {
+				return err
+			}
+			format, err := outputFormat(flags, config)
+			if err != nil {
+				return err
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			info, err := file.Stat()
+			if err != nil {
+				return err
+			}
+
+			result, err := client.ImportCatalog(cmd.Context(), withProgress(file, "Importing", info.Size(), !noProgress), mode)
+			if err != nil {
+				return err
+			}
+			if err = printImportResult(cmd.OutOrStdout(), format, result); err != nil {
+				return err
+			}
+			if len(result.Failed) > 0 {
+				return fmt.Errorf("%d games could not be imported", len(result.Failed))
+			}
+			return nil
+		},
+	}
+	command.Flags().StringVar(&mode, "mode", "preserve", "\"preserve\" keeps the ids of the games and skips games whose id is used, \"remap\" creates new ids")
+	command.Flags().BoolVar(&noProgress, "no-progress", false, "Don't show the progress bar")
+	return command
+}
+
+// printImportResult writes the games of an import as table or as json object
+func printImportResult(out io.Writer, format string, result *dtos.CatalogImportResponseBody) error {
+	if format == outputJSON {
+		return printJSON(out, result)
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "RESULT\tSOURCE ID\tID\tTITLE\tREASON")
+	for _, group := range []struct {
+		name  string
+		items []dtos.CatalogImportItemResponseBody
+	}{{"imported", result.Imported}, {"skipped", result.Skipped}, {"failed", result.Failed}} {
+		for _, item := range group.items {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", group.name, item.SourceID, item.ID, item.Title, item.Reason)
+		}
+	}
+	return writer.Flush()
+}
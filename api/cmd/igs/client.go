@@ -0,0 +1,47 @@
+package main
+
+import (
+	apiclient "api/client"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"os"
+	"time"
+)
+
+// newClient creates a client with the url and the token of the flags, the environment or the config
+func newClient(flags *globalFlags) (*apiclient.Client, *cliConfig, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	baseURL := flags.apiURL
+	if baseURL == "" {
+		baseURL = os.Getenv("IGS_API_URL")
+	}
+	if baseURL == "" {
+		baseURL = config.APIURL
+	}
+	if baseURL == "" {
+		return nil, nil, errors.New("no api url configured, run \"igs config set api-url <url>\"")
+	}
+	return apiclient.New(baseURL, apiclient.Options{
+		//Uploads and downloads can take long, so only the response header has a timeout
+		HTTPClient: &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, ResponseHeaderTimeout: 10 * time.Minute}},
+		TokenSource: apiclient.TokenSourceFunc(func(ctx context.Context) (string, error) {
+			return currentToken(ctx, flags, config)
+		}),
+		UserAgent: "igs",
+	}), config, nil
+}
+
+// parseID parses the id of a game of the arguments
+func parseID(arg string) (uuid.UUID, error) {
+	id, err := uuid.Parse(arg)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid id %q", arg)
+	}
+	return id, nil
+}
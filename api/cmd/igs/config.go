@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Endpoints of the device flow of Google, which issues the tokens of the api
+const (
+	defaultDeviceAuthURL = "https://oauth2.googleapis.com/device/code"
+	defaultTokenURL      = "https://oauth2.googleapis.com/token"
+)
+
+// cliConfig is saved as json in the config directory of the user, e.g. ~/.config/igs/config.json
+type cliConfig struct {
+	APIURL string `json:"apiUrl,omitempty"`
+	Output string `json:"output,omitempty"`
+	//OAuth client of the device flow, it must be accepted by the api (AUTH_AUDIENCES)
+	ClientID      string `json:"clientId,omitempty"`
+	ClientSecret  string `json:"clientSecret,omitempty"`
+	DeviceAuthURL string `json:"deviceAuthUrl,omitempty"`
+	TokenURL      string `json:"tokenUrl,omitempty"`
+	//Credentials of the login
+	Token        string    `json:"token,omitempty"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// settings returns the keys of the settings, which can be changed with "igs config set"
+func (c *cliConfig) settings() map[string]*string {
+	return map[string]*string{
+		"api-url":         &c.APIURL,
+		"output":          &c.Output,
+		"client-id":       &c.ClientID,
+		"client-secret":   &c.ClientSecret,
+		"device-auth-url": &c.DeviceAuthURL,
+		"token-url":       &c.TokenURL,
+	}
+}
+
+// configPath returns the path of the config file, which is set by IGS_CONFIG or in the config directory of the user
+func configPath() (string, error) {
+	if path := os.Getenv("IGS_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "igs", "config.json"), nil
+}
+
+// loadConfig reads the config file, a missing file is an empty config
+func loadConfig() (*cliConfig, error) {
+	config := &cliConfig{}
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// saveConfig writes the config file, it is only readable by the user because it contains the credentials
+func saveConfig(config *cliConfig) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+func configCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Show and change the settings of the cli",
+	}
+
+	command.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List all settings",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			settings := config.settings()
+			keys := make([]string, 0, len(settings))
+			for key := range settings {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				value := *settings[key]
+				if key == "client-secret" && value != "" {
+					value = "********"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s=%s\n", key, value)
+			}
+			return nil
+		},
+	})
+
+	command.AddCommand(&cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a setting",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			value, ok := config.settings()[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown setting %s", args[0])
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), *value)
+			return nil
+		},
+	})
+
+	command.AddCommand(&cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Change a setting, e.g. igs config set api-url https://api.example.com",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			value, ok := config.settings()[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown setting %s", args[0])
+			}
+			if args[0] == "output" && args[1] != outputTable && args[1] != outputJSON {
+				return fmt.Errorf("output must be %s or %s", outputTable, outputJSON)
+			}
+			*value = args[1]
+			return saveConfig(config)
+		},
+	})
+
+	command.AddCommand(&cobra.Command{
+		Use:   "path",
+		Short: "Print the path of the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := configPath()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), path)
+			return nil
+		},
+	})
+
+	return command
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	apiclient "api/client"
+	"api/shared"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func gamesCommand(flags *globalFlags) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "games",
+		Short: "List, upload and manage games",
+	}
+	command.AddCommand(gamesListCommand(flags), gamesGetCommand(flags), gamesUploadCommand(flags),
+		gamesDeleteCommand(flags), gamesWatchCommand(flags))
+	return command
+}
+
+func gamesListCommand(flags *globalFlags) *cobra.Command {
+	var owner string
+	command := &cobra.Command{
+		Use:   "list",
+		Short: "List the games of the user and of the organizations of the user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, config, err := newClient(flags)
+			if err != nil {
+				return err
+			}
+			format, err := outputFormat(flags, config)
+			if err != nil {
+				return err
+			}
+
+			games, err := client.ListGames(cmd.Context(), owner)
+			if err != nil {
+				return err
+			}
+			return printGames(cmd.OutOrStdout(), format, games)
+		},
+	}
+	command.Flags().StringVar(&owner, "owner", "", "Only list the games of this owner, e.g. org:<id>")
+	return command
+}
+
+func gamesGetCommand(flags *globalFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <id>",
+		Short: "Show a game",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, config, err := newClient(flags)
+			if err != nil {
+				return err
+			}
+			format, err := outputFormat(flags, config)
+			if err != nil {
+				return err
+			}
+
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			result, err := client.GetGame(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return printGame(cmd.OutOrStdout(), format, result)
+		},
+	}
+}
+
+// uploadOptions are the flags of "games upload"
+type uploadOptions struct {
+	title       string
+	description string
+	tags        []string
+	platform    string
+	owner       string
+	region      string
+	cluster     string
+	archive     bool
+	entry       string
+	noProgress  bool
+	wait        bool
+	watch       watchOptions
+}
+
+func gamesUploadCommand(flags *globalFlags) *cobra.Command {
+	options := &uploadOptions{}
+	command := &cobra.Command{
+		Use:   "upload <file>",
+		Short: "Upload a game, optionally wait until it is installed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, config, err := newClient(flags)
+			if err != nil {
+				return err
+			}
+			format, err := outputFormat(flags, config)
+			if err != nil {
+				return err
+			}
+
+			id, err := uploadGame(cmd.Context(), client, args[0], options)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Uploaded game %s\n", id)
+
+			var result *apiclient.Game
+			if options.wait {
+				result, err = watchGame(cmd.Context(), client, id, options.watch)
+			} else {
+				result, err = client.GetGame(cmd.Context(), id)
+			}
+			if result != nil {
+				if printErr := printGame(cmd.OutOrStdout(), format, result); printErr != nil && err == nil {
+					err = printErr
+				}
+			}
+			return err
+		},
+	}
+	command.Flags().StringVar(&options.title, "title", "", "Title of the game, defaults to the file name")
+	command.Flags().StringVar(&options.description, "description", "", "Description of the game")
+	command.Flags().StringSliceVar(&options.tags, "tags", nil, "Tags of the game, separated by commas")
+	command.Flags().StringVar(&options.platform, "platform", "", "Platform of the game, e.g. nes")
+	command.Flags().StringVar(&options.owner, "owner", "", "Upload the game for an organization (org:<id>)")
+	command.Flags().StringVar(&options.region, "region", "", "Place the game on a cluster of this region")
+	command.Flags().StringVar(&options.cluster, "cluster", "", "Place the game on this cluster, only for administrators")
+	command.Flags().BoolVar(&options.archive, "archive", false, "Extract the zip archive into a game with several files, e.g. a cue sheet with its tracks")
+	command.Flags().StringVar(&options.entry, "entry", "", "Path of the file in the archive which is loaded by the emulator, defaults to the manifest of the archive")
+	command.Flags().BoolVar(&options.noProgress, "no-progress", false, "Don't show the progress bar")
+	command.Flags().BoolVar(&options.wait, "wait", false, "Wait until the game is installed")
+	addWatchFlags(command, &options.watch)
+	return command
+}
+
+// uploadGame streams the file to the api and returns the id of the new game
+func uploadGame(ctx context.Context, client *apiclient.Client, fileName string, options *uploadOptions) (uuid.UUID, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	title := options.title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	}
+	return client.UploadGame(ctx, apiclient.Upload{
+		FileName:    filepath.Base(fileName),
+		File:        withProgress(file, "Uploading", info.Size(), !options.noProgress),
+		Title:       title,
+		Description: options.description,
+		Tags:        options.tags,
+		Platform:    options.platform,
+		Owner:       options.owner,
+		Region:      options.region,
+		Cluster:     options.cluster,
+		Archive:     options.archive,
+		Entry:       options.entry,
+	})
+}
+
+func gamesDeleteCommand(flags *globalFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Move a game to the trash",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, _, err := newClient(flags)
+			if err != nil {
+				return err
+			}
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			if err = client.DeleteGame(cmd.Context(), id); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Moved game %s to the trash\n", args[0])
+			return nil
+		},
+	}
+}
+
+// watchOptions are the flags of "games watch" and "games upload --wait"
+type watchOptions struct {
+	status   string
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func addWatchFlags(command *cobra.Command, options *watchOptions) {
+	command.Flags().StringVar(&options.status, "status", string(shared.Status_Installed), "Status to wait for")
+	command.Flags().DurationVar(&options.interval, "interval", 5*time.Second, "Interval in which the status is read")
+	command.Flags().DurationVar(&options.timeout, "timeout", 15*time.Minute, "Fail if the status has not been reached after this time")
+}
+
+func gamesWatchCommand(flags *globalFlags) *cobra.Command {
+	options := &watchOptions{}
+	command := &cobra.Command{
+		Use:   "watch <id>",
+		Short: "Wait until a game has a status, by default until it is installed",
+		Long: "Prints every change of the status. Exits with 3 if the game has the status error " +
+			"and with 4 if the status has not been reached in time.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, config, err := newClient(flags)
+			if err != nil {
+				return err
+			}
+			format, err := outputFormat(flags, config)
+			if err != nil {
+				return err
+			}
+
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			result, err := watchGame(cmd.Context(), client, id, *options)
+			if result != nil {
+				if printErr := printGame(cmd.OutOrStdout(), format, result); printErr != nil && err == nil {
+					err = printErr
+				}
+			}
+			return err
+		},
+	}
+	addWatchFlags(command, options)
+	return command
+}
+
+// watchGame reads the game until it has the status of the options. It returns an exitError if the game
+// has the status error or quarantined or if the timeout has been reached, the last read game is returned in any case.
+func watchGame(ctx context.Context, client *apiclient.Client, id uuid.UUID, options watchOptions) (*apiclient.Game, error) {
+	ctx, cancel := context.WithTimeout(ctx, options.timeout)
+	defer cancel()
+
+	var last *apiclient.Game
+	for {
+		current, err := client.GetGame(ctx, id)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return last, exitError{code: exitTimeout, err: fmt.Errorf("game %s has not reached the status %s in %s", id, options.status, options.timeout)}
+		} else if err != nil {
+			return last, err
+		}
+		if last == nil || last.Status != current.Status {
+			fmt.Fprintf(os.Stderr, "Status: %s\n", current.Status)
+		}
+		last = current
+
+		if string(current.Status) == options.status {
+			return current, nil
+		}
+		if current.Status == shared.Status_Error || current.Status == shared.Status_Quarantined {
+			return current, exitError{code: exitGameFailed, err: fmt.Errorf("game %s has the status %s", id, current.Status)}
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, exitError{code: exitTimeout, err: fmt.Errorf("game %s has not reached the status %s in %s", id, options.status, options.timeout)}
+		case <-time.After(options.interval):
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+	"os"
+	"time"
+)
+
+func loginCommand(flags *globalFlags) *cobra.Command {
+	var token string
+	command := &cobra.Command{
+		Use:   "login",
+		Short: "Log in with the OIDC device flow or save a token",
+		Long: "Without --token the device flow is started: open the shown url, enter the code and the cli saves the token.\n" +
+			"The OAuth client of the device flow is set with \"igs config set client-id\" and \"igs config set client-secret\".",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if flags.apiURL != "" {
+				config.APIURL = flags.apiURL
+			}
+
+			if token != "" {
+				config.Token, config.RefreshToken, config.Expiry = token, "", time.Time{}
+				return saveConfig(config)
+			}
+
+			oauthConfig, err := deviceFlowConfig(config)
+			if err != nil {
+				return err
+			}
+			response, err := oauthConfig.DeviceAuth(cmd.Context())
+			if err != nil {
+				return err
+			}
+			verificationURL := response.VerificationURIComplete
+			if verificationURL == "" {
+				verificationURL = response.VerificationURI
+			}
+			fmt.Fprintf(os.Stderr, "Open %s and enter the code %s\n", verificationURL, response.UserCode)
+
+			oauthToken, err := oauthConfig.DeviceAccessToken(cmd.Context(), response)
+			if err != nil {
+				return err
+			}
+			if err = saveToken(config, oauthToken); err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, "Logged in")
+			return nil
+		},
+	}
+	command.Flags().StringVar(&token, "token", "", "Save this token instead of starting the device flow, e.g. in CI pipelines")
+	return command
+}
+
+func logoutCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the saved token",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			config.Token, config.RefreshToken, config.Expiry = "", "", time.Time{}
+			return saveConfig(config)
+		},
+	}
+}
+
+// deviceFlowConfig returns the OAuth config of the device flow, the client must have been configured
+func deviceFlowConfig(config *cliConfig) (*oauth2.Config, error) {
+	if config.ClientID == "" {
+		return nil, errors.New("no OAuth client configured, run \"igs config set client-id <id>\" or use \"igs login --token\"")
+	}
+	deviceAuthURL, tokenURL := config.DeviceAuthURL, config.TokenURL
+	if deviceAuthURL == "" {
+		deviceAuthURL = defaultDeviceAuthURL
+	}
+	if tokenURL == "" {
+		tokenURL = defaultTokenURL
+	}
+	return &oauth2.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			DeviceAuthURL: deviceAuthURL,
+			TokenURL:      tokenURL,
+		},
+		Scopes: []string{"openid", "email"},
+	}, nil
+}
+
+// saveToken saves the id token of the OAuth token, which is the token of the api
+func saveToken(config *cliConfig, oauthToken *oauth2.Token) error {
+	idToken, _ := oauthToken.Extra("id_token").(string)
+	if idToken == "" {
+		return errors.New("the identity provider did not return an id token")
+	}
+	config.Token = idToken
+	config.Expiry = oauthToken.Expiry
+	//Providers don't always return a new refresh token, the previous one stays valid
+	if oauthToken.RefreshToken != "" {
+		config.RefreshToken = oauthToken.RefreshToken
+	}
+	return saveConfig(config)
+}
+
+// currentToken returns the token of the api. The token of the flag or IGS_TOKEN is preferred,
+// otherwise the token of the login is used and refreshed if it has expired.
+func currentToken(ctx context.Context, flags *globalFlags, config *cliConfig) (string, error) {
+	if flags.token != "" {
+		return flags.token, nil
+	}
+	if token := os.Getenv("IGS_TOKEN"); token != "" {
+		return token, nil
+	}
+	if config.Token == "" {
+		return "", errors.New("not logged in, run \"igs login\"")
+	}
+	if config.RefreshToken == "" || config.Expiry.IsZero() || time.Until(config.Expiry) > time.Minute {
+		return config.Token, nil
+	}
+
+	oauthConfig, err := deviceFlowConfig(config)
+	if err != nil {
+		return "", err
+	}
+	oauthToken, err := oauthConfig.TokenSource(ctx, &oauth2.Token{RefreshToken: config.RefreshToken}).Token()
+	if err != nil {
+		return "", fmt.Errorf("the login has expired, run \"igs login\": %w", err)
+	}
+	if err = saveToken(config, oauthToken); err != nil {
+		return "", err
+	}
+	return config.Token, nil
+}
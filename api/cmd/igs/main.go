@@ -0,0 +1,72 @@
+// igs is the command-line client of the IndieGameStream api.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+// Exit codes of the cli
+const (
+	exitFailure = 1
+	//The game has reached the status "error" while waiting for it
+	exitGameFailed = 3
+	//Waiting for the game has timed out
+	exitTimeout = 4
+)
+
+// exitError is an error with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e exitError) Unwrap() error {
+	return e.err
+}
+
+// globalFlags are the flags of all commands
+type globalFlags struct {
+	apiURL string
+	token  string
+	output string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout))
+}
+
+// run executes the command of the arguments and returns the exit code, the results are written to stdout
+func run(args []string, stdout io.Writer) int {
+	flags := &globalFlags{}
+	root := &cobra.Command{
+		Use:           "igs",
+		Short:         "Upload and manage games of IndieGameStream",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&flags.apiURL, "api-url", "", "Url of the api, overrides the config and IGS_API_URL")
+	root.PersistentFlags().StringVar(&flags.token, "token", "", "Token of the api, overrides the login and IGS_TOKEN")
+	root.PersistentFlags().StringVarP(&flags.output, "output", "o", "", "Output format, \"table\" or \"json\"")
+
+	root.AddCommand(loginCommand(flags), logoutCommand(), configCommand(), gamesCommand(flags), romCommand(flags), adminCommand(flags))
+	root.SetArgs(args)
+	root.SetOut(stdout)
+
+	if err := root.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		var exit exitError
+		if errors.As(err, &exit) {
+			return exit.code
+		}
+		return exitFailure
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"api/dtos"
+	"api/shared"
+	"bytes"
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_Upload_Should_Exit_With_Failure_If_The_Api_Rejects_The_Game(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	var uploads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/games" {
+			uploads.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"message": "Upload failed"}`))
+			return
+		}
+		t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+	fileName := gameFile(t)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	code := run([]string{"games", "upload", fileName, "--api-url", server.URL, "--token", "token", "--no-progress"}, &bytes.Buffer{})
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if code != exitFailure {
+		t.Errorf("Expected the exit code %d, got %d", exitFailure, code)
+	}
+	if uploads.Load() != 1 {
+		t.Errorf("Expected the upload to be sent once, got %d", uploads.Load())
+	}
+}
+
+func Test_Watch_Should_Stop_When_The_Game_Is_Installed(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	id := uuid.New()
+	server, reads := gameServer(t, id, shared.Status_Installing, shared.Status_Installing, shared.Status_Installed, shared.Status_Error)
+	defer server.Close()
+	stdout := &bytes.Buffer{}
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	code := run([]string{"games", "watch", id.String(), "--api-url", server.URL, "--token", "token", "--interval", "1ms", "-o", "json"}, stdout)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if code != 0 {
+		t.Errorf("Expected the exit code 0, got %d", code)
+	}
+	if reads.Load() != 3 {
+		t.Errorf("Expected the game to be read until it is installed, got %d reads", reads.Load())
+	}
+	game := dtos.GetGameByIdResponseBody{}
+	if err := json.Unmarshal(stdout.Bytes(), &game); err != nil || game.Status != shared.Status_Installed {
+		t.Errorf("Expected the installed game to be printed, got %q", stdout.String())
+	}
+}
+
+func Test_Watch_Should_Stop_With_Game_Failed_When_The_Game_Has_An_Error(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	id := uuid.New()
+	server, reads := gameServer(t, id, shared.Status_Installing, shared.Status_Error, shared.Status_Installed)
+	defer server.Close()
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	code := run([]string{"games", "watch", id.String(), "--api-url", server.URL, "--token", "token", "--interval", "1ms"}, &bytes.Buffer{})
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if code != exitGameFailed {
+		t.Errorf("Expected the exit code %d, got %d", exitGameFailed, code)
+	}
+	if reads.Load() != 2 {
+		t.Errorf("Expected the game not to be read after the error, got %d reads", reads.Load())
+	}
+}
+
+// gameServer serves a game, whose status changes on every read to the next of the statuses. It counts the reads.
+func gameServer(t *testing.T, id uuid.UUID, statuses ...shared.GameStatus) (*httptest.Server, *atomic.Int32) {
+	t.Setenv("IGS_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	reads := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/games/"+id.String() {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		read := int(reads.Add(1))
+		status := statuses[min(read, len(statuses))-1]
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(dtos.GetGameByIdResponseBody{ID: id, Title: "game", Status: status, Tags: []string{}})
+	}))
+	return server, reads
+}
+
+// gameFile creates a game file and an empty config of the cli
+func gameFile(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("IGS_CONFIG", filepath.Join(dir, "config.json"))
+	fileName := filepath.Join(dir, "game.nes")
+	if err := os.WriteFile(fileName, []byte(strings.Repeat("game", 16)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	apiclient "api/client"
+	"api/dtos"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// outputFormat returns the format of the flag or the config, which defaults to table
+func outputFormat(flags *globalFlags, config *cliConfig) (string, error) {
+	format := flags.output
+	if format == "" {
+		format = config.Output
+	}
+	switch format {
+	case "", outputTable:
+		return outputTable, nil
+	case outputJSON:
+		return outputJSON, nil
+	}
+	return "", fmt.Errorf("output must be %s or %s", outputTable, outputJSON)
+}
+
+// printGames writes the games as table or as json array
+func printGames(out io.Writer, format string, games []dtos.GetAllGamesResponseBody) error {
+	if format == outputJSON {
+		return printJSON(out, games)
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tTITLE\tSTATUS\tVERSION\tVISIBILITY\tPLATFORM\tURL")
+	for _, g := range games {
+		version := "-"
+		if g.LiveVersion > 0 {
+			version = strconv.Itoa(g.LiveVersion)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", g.ID, g.Title, g.Status, version, g.Visibility, g.Platform, g.Url)
+	}
+	return writer.Flush()
+}
+
+// printGame writes one game as list of its fields or as json object
+func printGame(out io.Writer, format string, g *apiclient.Game) error {
+	if format == outputJSON {
+		return printJSON(out, g)
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "ID:\t%s\n", g.ID)
+	fmt.Fprintf(writer, "Title:\t%s\n", g.Title)
+	fmt.Fprintf(writer, "Status:\t%s\n", g.Status)
+	if g.StatusReason != "" {
+		fmt.Fprintf(writer, "Reason:\t%s\n", g.StatusReason)
+	}
+	fmt.Fprintf(writer, "Url:\t%s\n", g.Url)
+	fmt.Fprintf(writer, "Live version:\t%d\n", g.LiveVersion)
+	if g.BetaVersion > 0 {
+		fmt.Fprintf(writer, "Beta version:\t%d (%s)\n", g.BetaVersion, g.BetaUrl)
+	}
+	fmt.Fprintf(writer, "Visibility:\t%s\n", g.Visibility)
+	fmt.Fprintf(writer, "Platform:\t%s\n", g.Platform)
+	if g.Cluster != "" {
+		fmt.Fprintf(writer, "Cluster:\t%s %s\n", g.Cluster, g.Region)
+	}
+	fmt.Fprintf(writer, "Tags:\t%s\n", strings.Join(g.Tags, ", "))
+	fmt.Fprintf(writer, "Checksum:\t%s\n", g.Checksum)
+	fmt.Fprintf(writer, "Description:\t%s\n", g.Description)
+	return writer.Flush()
+}
+
+func printJSON(out io.Writer, value any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressWidth is the number of characters of the bar
+const progressWidth = 30
+
+// progressReader draws a progress bar on stderr while its reader is read
+type progressReader struct {
+	reader    io.Reader
+	label     string
+	total     int64
+	read      int64
+	lastDrawn time.Time
+}
+
+// withProgress wraps the reader into a progress bar, if stderr is a terminal and progress is enabled.
+// A total of 0 or less means that the size is unknown.
+func withProgress(reader io.Reader, label string, total int64, enabled bool) io.Reader {
+	if !enabled || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return reader
+	}
+	return &progressReader{reader: reader, label: label, total: total}
+}
+
+func (p *progressReader) Read(buffer []byte) (int, error) {
+	n, err := p.reader.Read(buffer)
+	p.read += int64(n)
+	if err == io.EOF {
+		p.draw()
+		fmt.Fprintln(os.Stderr)
+	} else if time.Since(p.lastDrawn) > 100*time.Millisecond {
+		p.draw()
+	}
+	return n, err
+}
+
+func (p *progressReader) draw() {
+	p.lastDrawn = time.Now()
+	if p.total <= 0 {
+		fmt.Fprintf(os.Stderr, "\r%s %s", p.label, formatBytes(p.read))
+		return
+	}
+	done := int(p.read * progressWidth / p.total)
+	if done > progressWidth {
+		done = progressWidth
+	}
+	fmt.Fprintf(os.Stderr, "\r%s [%s%s] %3d%% %s/%s", p.label, strings.Repeat("=", done), strings.Repeat(" ", progressWidth-done),
+		p.read*100/p.total, formatBytes(p.read), formatBytes(p.total))
+}
+
+// formatBytes formats a size with a binary unit, e.g. 1.5 MiB
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	apiclient "api/client"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func romCommand(flags *globalFlags) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "rom",
+		Short: "Download the game files",
+	}
+	command.AddCommand(romDownloadCommand(flags))
+	return command
+}
+
+func romDownloadCommand(flags *globalFlags) *cobra.Command {
+	var version int
+	var destination string
+	var noProgress bool
+	command := &cobra.Command{
+		Use:   "download <id>",
+		Short: "Download the file of the live version or of another version of a game",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, _, err := newClient(flags)
+			if err != nil {
+				return err
+			}
+
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			rom, err := client.DownloadRom(cmd.Context(), id, version)
+			if err != nil {
+				return err
+			}
+			defer rom.Body.Close()
+
+			var out io.Writer = cmd.OutOrStdout()
+			if destination != "-" {
+				if destination == "" {
+					destination = downloadFileName(rom, args[0])
+				}
+				file, err := os.Create(destination)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+
+			_, err = io.Copy(out, withProgress(rom.Body, "Downloading", rom.Size, !noProgress && destination != "-"))
+			if err != nil {
+				return err
+			}
+			if destination != "-" {
+				fmt.Fprintf(os.Stderr, "Saved %s\n", destination)
+			}
+			return nil
+		},
+	}
+	command.Flags().IntVar(&version, "version", 0, "Version to download, defaults to the live version")
+	command.Flags().StringVar(&destination, "dest", "", "File to write, \"-\" writes to stdout. Defaults to the name of the game file")
+	command.Flags().BoolVar(&noProgress, "no-progress", false, "Don't show the progress bar")
+	return command
+}
+
+// downloadFileName returns the file name of the game file or the id of the game
+func downloadFileName(rom *apiclient.Rom, id string) string {
+	if rom.FileName != "" {
+		return filepath.Base(rom.FileName)
+	}
+	return id
+}
@@ -2,15 +2,17 @@ package main
 
 import (
 	"api/apis"
-	"api/controllers"
-	"api/repositories"
+	"api/dev"
+	"api/kube"
+	"api/router"
+	"api/rpc"
 	"api/scripts"
 	"api/services"
 	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
-	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v5"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
@@ -18,51 +20,13 @@ import (
 	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/tools/clientcmd"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
-func setupRouter(db *sql.DB, azClient *azblob.Client) *gin.Engine {
-	//Setup Gin
-	r := gin.Default()
-	//Cors
-	r.Use(CORSMiddleware())
-
-	//Repositories
-	gamesRepository := repositories.GameRepository(db)
-
-	//Apis
-	k8sApi := apis.K8sService(k8sClient())
-	azureApi := apis.AzureService(azClient)
-
-	//Services
-	gamesService := services.GameService(gamesRepository, k8sApi, azureApi)
-	authService := services.AuthService()
-
-	//Controllers
-	gamesController := controllers.GameController(gamesService)
-
-	// Ping test
-	r.GET("/ping", func(c *gin.Context) {
-		c.String(http.StatusOK, "pong")
-	})
-
-	//Upload a game
-	r.POST("/games", authService.Authorize, gamesController.UploadGame)
-	//Get all uploaded games
-	r.GET("/games", authService.Authorize, gamesController.GetAllGames)
-	//Get a specific game by its id
-	r.GET("/games/:id", authService.Authorize, gamesController.GetGameById)
-	//Delete a specific game, identified by its id
-	r.DELETE("/games/:id", authService.Authorize, gamesController.DeleteGameById)
-
-	return r
-}
-
 func loadConfig() {
 	err := godotenv.Load(".env")
 	if err != nil {
@@ -87,69 +51,16 @@ func setupDatabase() *sql.DB {
 	return db
 }
 
-func setupManagedClustersClient() *armcontainerservice.ManagedClustersClient {
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
-	if err != nil {
-		log.Fatalf("failed to obtain a credential: %v", err)
-	}
-	clientFactory, err := armcontainerservice.NewClientFactory(
-		os.Getenv("AZURERM_SUBSCRIPTION_ID"), cred, nil)
-	if err != nil {
-		log.Fatalf("failed to create client: %v", err)
-	}
-	return clientFactory.NewManagedClustersClient()
-}
-
-func getKubeConfig() armcontainerservice.ManagedClustersClientListClusterUserCredentialsResponse {
-	managedClustersClient := setupManagedClustersClient()
-
-	res, err := managedClustersClient.ListClusterUserCredentials(context.Background(),
-		os.Getenv("AZURERM_RESOURCE_GROUP_NAME"),
-		os.Getenv("AZURE_AKS_CLUSTER_NAME"),
-		&armcontainerservice.ManagedClustersClientListClusterUserCredentialsOptions{ServerFqdn: nil, Format: nil})
-	if err != nil {
-		log.Fatalf("failed to finish the request: %v", err)
-	}
-
-	return res
-}
-
+// k8sClient connects to the cluster of the provider KUBE_PROVIDER, the credentials are refreshed when they expire
 func k8sClient() client.Client {
-	//Try to read k8s config directly from environment
-	restConfig, err := config.GetConfig()
-	if err != nil || restConfig == nil {
-		//If it fails, try to get it from azure
-		kubeConfig := getKubeConfig()
-		if len(kubeConfig.Kubeconfigs) == 0 {
-			log.Fatalf("The kubeconfig request was successful but it's response body is empty")
-		}
-		if len(kubeConfig.Kubeconfigs) > 1 {
-			log.Println("WARNING: Multiple kube-config's have been found. The first one will be used.")
-		}
-
-		clientConfig, err := clientcmd.NewClientConfigFromBytes(kubeConfig.Kubeconfigs[0].Value)
-		if err != nil {
-			log.Fatalf("failed to create client config: %v", err)
-		}
-		restConfig, err = clientConfig.ClientConfig()
-		if err != nil {
-			log.Fatalf("failed to load kube config: %v", err)
-		}
-	}
-
 	scheme, err := createScheme()
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
-	k8sc, err := client.New(
-		restConfig,
-		client.Options{Scheme: scheme},
-	)
-	if err != nil {
-		log.Fatal(err.Error())
-	}
-	return k8sc
+	config := kube.ConfigFromEnv()
+	log.Println(fmt.Sprintf("Connecting to kubernetes with the provider %s", config.Provider))
+	return kube.Client(kube.Provider(config), scheme, config.RefreshInterval)
 }
 
 func createScheme() (*runtime.Scheme, error) {
@@ -169,23 +80,6 @@ func createScheme() (*runtime.Scheme, error) {
 	return scheme, nil
 }
 
-func CORSMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(200)
-		} else {
-			c.Next()
-		}
-	}
-}
-
 func setupAzureBlobContainer(azClient *azblob.Client) {
 
 	containerName := os.Getenv("AZURE_CONTAINER_NAME")
@@ -203,12 +97,25 @@ func setupAzureBlobContainer(azClient *azblob.Client) {
 }
 
 func main() {
+	devMode := flag.Bool("dev", false, "run without azure and kubernetes, with a filesystem blob store, a simulated cluster and a demo user")
+	flag.Parse()
+
 	//Load config file
 	loadConfig()
 
-	//Setup Azure
-	azClient := setupAzureBlobClient()
-	setupAzureBlobContainer(azClient)
+	//Setup Azure, kubernetes and the authorization or their replacements of the development mode
+	var azureApi apis.IAzureApi
+	var k8s client.Client
+	var authService services.IAuthService
+	if *devMode {
+		azureApi, k8s, authService = setupDevMode()
+	} else {
+		azClient := setupAzureBlobClient()
+		setupAzureBlobContainer(azClient)
+		azureApi = apis.AzureService(azClient)
+		k8s = k8sClient()
+		authService = services.AuthService(services.AuthAudiencesFromEnv())
+	}
 
 	//Setup database
 	db := setupDatabase()
@@ -217,14 +124,56 @@ func main() {
 	//Set Gin-gonic to debug or release mode
 	gin.SetMode(os.Getenv("GIN_MODE"))
 
+	//Setup the rate limiter, whose buckets are shared by REST and grpc
+	rateLimiter := router.SetupRateLimiter(db)
+
 	//Setup Routes
-	r := setupRouter(db, azClient)
+	r := router.SetupRouter(db, azureApi, k8s, authService, rateLimiter)
+
+	//Setup grpc, which is served on the port of the REST api or on its own port
+	grpcConfig := rpc.ConfigFromEnv()
+	var handler http.Handler = r
+	switch grpcConfig.Mode {
+	case rpc.Mode_Shared:
+		handler = rpc.Handler(router.SetupGrpcServer(db, azureApi, k8s, authService, grpcConfig, rateLimiter), r)
+	case rpc.Mode_Separate:
+		grpcServer := router.SetupGrpcServer(db, azureApi, k8s, authService, grpcConfig, rateLimiter)
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcConfig.Port))
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		go func() {
+			log.Fatal(grpcServer.Serve(listener))
+		}()
+	}
 
 	// Listen and Server in 0.0.0.0:8080
-	err := r.Run(fmt.Sprintf(":%s", os.Getenv("PORT")))
+	err := http.ListenAndServe(fmt.Sprintf(":%s", os.Getenv("PORT")), handler)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// setupDevMode replaces azure with a directory, kubernetes with an in-memory cluster and the Google sign-in with a demo user.
+// It starts the database, unless it is reachable already, and the games of the database are deployed into the cluster again.
+func setupDevMode() (apis.IAzureApi, client.Client, services.IAuthService) {
+	config := dev.ConfigFromEnv()
+	if os.Getenv("AZURE_CONTAINER_NAME") == "" {
+		os.Setenv("AZURE_CONTAINER_NAME", "games")
+	}
+	if os.Getenv("PORT") == "" {
+		os.Setenv("PORT", "8080")
+	}
+	dev.SeedDemoUser(config)
+	dev.StartDatabase(config)
+	dev.RestoreClusterOnStart()
+
+	scheme, err := createScheme()
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	log.Printf("Development mode: blobs are stored in %s", config.StorageDir)
+	return apis.FileStorageApi(config.StorageDir), dev.Cluster(scheme, config), dev.AuthService(config)
 }
 
 func setupAzureBlobClient() *azblob.Client {
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"api/dtos"
+	"api/models"
+	"api/services"
+	"api/shared"
+	"fmt"
+	"github.com/dranikpg/dto-mapper"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+)
+
+// batchAction is the custom method of the route /games:batch
+const batchAction = ":batch"
+
+type IBatchController interface {
+	BatchGames(c *gin.Context)
+	GetJob(c *gin.Context)
+}
+
+type batchController struct {
+	service services.IBatchService
+}
+
+// BatchGames runs an operation on many games.
+// Small batches return HTTP 200 with the result of every game,
+// larger batches return HTTP 202 and the location of the job.
+func (b batchController) BatchGames(c *gin.Context) {
+	//The route /games:action matches every path starting with /games
+	if c.Param("action") != batchAction {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	var body dtos.BatchGamesRequestBody
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if len(body.IDs) > b.service.MaxItems() {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("A batch can contain at most %d games", b.service.MaxItems())})
+		return
+	}
+
+	job, err := b.service.Submit(c.GetString("subject"), c.GetString("email"), body.Operation, body.IDs, body.Visibility)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	if job.Status == shared.Job_Done {
+		respondWithJob(c, http.StatusOK, job)
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/jobs/%s", job.ID.String()))
+	respondWithJob(c, http.StatusAccepted, job)
+}
+
+// GetJob returns the progress of a batch job of the logged-in user.
+func (b batchController) GetJob(c *gin.Context) {
+	_uuid, err := uuid.Parse(c.Param("id"))
+	if err != nil || _uuid == uuid.Nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid job ID"})
+		return
+	}
+
+	job, err := b.service.FindJob(_uuid)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if job == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "Job not found"})
+		return
+	}
+	if job.Owner != c.GetString("subject") {
+		log.Print(fmt.Sprintf("%s tried to access an resource of %s", c.GetString("subject"), job.Owner))
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "You are not allowed to access this job"})
+		return
+	}
+
+	respondWithJob(c, http.StatusOK, job)
+}
+
+func respondWithJob(c *gin.Context, status int, job *models.BatchJob) {
+	resultDto := dtos.BatchJobResponseBody{}
+	err := dto.Map(&resultDto, job)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.IndentedJSON(status, resultDto)
+}
+
+func BatchController(service services.IBatchService) IBatchController {
+	return &batchController{
+		service: service,
+	}
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"api/dtos"
+	"api/services"
+	"api/shared"
+	"fmt"
+	"github.com/dranikpg/dto-mapper"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"time"
+)
+
+type ICatalogController interface {
+	ExportCatalog(c *gin.Context)
+	ImportCatalog(c *gin.Context)
+}
+
+type catalogController struct {
+	service services.ICatalogService
+}
+
+// ExportCatalog streams the games with their versions and files as tar.gz archive.
+// Only the games of the owner of the query parameter "owner" are exported if it is set.
+func (cc catalogController) ExportCatalog(c *gin.Context) {
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"catalog-%s.tar.gz\"", time.Now().UTC().Format("20060102-150405")))
+	c.Status(http.StatusOK)
+
+	err := cc.service.Export(c.Writer, c.Query("owner"))
+	if err != nil {
+		if c.Writer.Written() {
+			//The archive has been sent partly, it is cut off, so the client fails to read it
+			log.Println(fmt.Sprintf("Exporting the catalog failed: %s", err))
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		abortWithServiceError(c, err)
+	}
+}
+
+// ImportCatalog imports an archive of ExportCatalog, which is sent as request body.
+// The games keep their ids with the query parameter "mode=preserve", which is the default, and get new ids with "mode=remap".
+func (cc catalogController) ImportCatalog(c *gin.Context) {
+	mode := shared.ImportMode(c.DefaultQuery("mode", string(shared.Import_Preserve)))
+	result, err := cc.service.Import(c.Request.Body, mode)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	//Map to dto
+	resultDto := dtos.CatalogImportResponseBody{}
+	err = dto.Map(&resultDto, result)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, resultDto)
+}
+
+func CatalogController(service services.ICatalogService) ICatalogController {
+	return &catalogController{
+		service: service,
+	}
+}
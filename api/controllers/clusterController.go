@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"api/dtos"
+	"api/models"
+	"api/services"
+	"api/shared"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type IClusterController interface {
+	GetAllClusters(c *gin.Context)
+	SaveCluster(c *gin.Context)
+	DeleteCluster(c *gin.Context)
+}
+
+type clusterController struct {
+	service services.IClusterService
+}
+
+// GetAllClusters returns the registered clusters with the number of their games.
+func (cc clusterController) GetAllClusters(c *gin.Context) {
+	clusters, err := cc.service.FindAll()
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	resultDto := make([]dtos.ClusterResponseBody, len(clusters))
+	for i := range clusters {
+		resultDto[i] = clusterDto(&clusters[i])
+	}
+	c.IndentedJSON(http.StatusOK, resultDto)
+}
+
+// SaveCluster registers the cluster of the path or updates it. Updating the default cluster only changes its placement settings.
+func (cc clusterController) SaveCluster(c *gin.Context) {
+	var body dtos.SaveClusterRequestBody
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	cluster := models.Cluster{
+		Name:           c.Param("name"),
+		Region:         body.Region,
+		Capacity:       body.Capacity,
+		Labels:         shared.NormalizeTags(body.Labels),
+		KubeconfigPath: body.KubeconfigPath,
+		KubeContext:    body.KubeContext,
+		Cordoned:       body.Cordoned,
+	}
+	err = cc.service.Save(&cluster)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	//The stored cluster is returned with its creation time and its games
+	clusters, err := cc.service.FindAll()
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+	for i := range clusters {
+		if clusters[i].Name == cluster.Name {
+			c.IndentedJSON(http.StatusOK, clusterDto(&clusters[i]))
+			return
+		}
+	}
+	c.IndentedJSON(http.StatusOK, clusterDto(&models.ClusterLoad{Cluster: cluster}))
+}
+
+// DeleteCluster removes a cluster without games from the registry.
+func (cc clusterController) DeleteCluster(c *gin.Context) {
+	err := cc.service.Delete(c.Param("name"))
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+func clusterDto(cluster *models.ClusterLoad) dtos.ClusterResponseBody {
+	return dtos.ClusterResponseBody{
+		Name:           cluster.Name,
+		Region:         cluster.Region,
+		Capacity:       cluster.Capacity,
+		Labels:         cluster.Labels,
+		KubeconfigPath: cluster.KubeconfigPath,
+		KubeContext:    cluster.KubeContext,
+		Cordoned:       cluster.Cordoned,
+		Games:          cluster.Games,
+		CreatedAt:      cluster.CreatedAt,
+	}
+}
+
+func ClusterController(service services.IClusterService) IClusterController {
+	return &clusterController{
+		service: service,
+	}
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"api/dtos"
+	"api/services"
+	"github.com/dranikpg/dto-mapper"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+type ICollaboratorController interface {
+	GetCollaborators(c *gin.Context)
+	GrantCollaborator(c *gin.Context)
+	RevokeCollaborator(c *gin.Context)
+}
+
+type collaboratorController struct {
+	service services.ICollaboratorService
+}
+
+// GetCollaborators returns the users with whom the game has been shared.
+func (g collaboratorController) GetCollaborators(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		collaborators, err := g.service.FindAllByGame(_uuid, c.GetString("subject"))
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		//Map to dto
+		resultDto := []dtos.CollaboratorResponseBody{}
+		err = dto.Map(&resultDto, collaborators)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		c.IndentedJSON(http.StatusOK, resultDto)
+	}
+}
+
+// GrantCollaborator shares the game with a user. If the game has already been shared with the user,
+// the permissions are replaced.
+func (g collaboratorController) GrantCollaborator(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		var body dtos.GrantCollaboratorRequestBody
+		err := c.ShouldBindJSON(&body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+
+		collaborator, err := g.service.Grant(_uuid, c.GetString("subject"), body.Subject, body.Email, body.Permissions)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		//Map to dto
+		resultDto := dtos.CollaboratorResponseBody{}
+		err = dto.Map(&resultDto, collaborator)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		c.IndentedJSON(http.StatusOK, resultDto)
+	}
+}
+
+func (g collaboratorController) RevokeCollaborator(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		collaboratorID, err := uuid.Parse(c.Param("collaboratorId"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid collaborator ID"})
+			return
+		}
+
+		err = g.service.Revoke(_uuid, c.GetString("subject"), collaboratorID)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+func CollaboratorController(service services.ICollaboratorService) ICollaboratorController {
+	return &collaboratorController{
+		service: service,
+	}
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"api/dtos"
+	"api/services"
+	"github.com/dranikpg/dto-mapper"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type IConsistencyController interface {
+	GetConsistencyReport(c *gin.Context)
+	RepairConsistency(c *gin.Context)
+}
+
+type consistencyController struct {
+	service services.IConsistencyService
+}
+
+// GetConsistencyReport checks the consistency of the database, the blob storage and kubernetes without repairing anything.
+func (cc consistencyController) GetConsistencyReport(c *gin.Context) {
+	cc.check(c, false)
+}
+
+// RepairConsistency checks the consistency and repairs the kinds of inconsistencies which are enabled by CONSISTENCY_REPAIR.
+func (cc consistencyController) RepairConsistency(c *gin.Context) {
+	cc.check(c, true)
+}
+
+func (cc consistencyController) check(c *gin.Context, repair bool) {
+	report, err := cc.service.Check(repair)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	//Map to dto
+	reportDto := dtos.ConsistencyReportResponseBody{}
+	err = dto.Map(&reportDto, report)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, reportDto)
+}
+
+func ConsistencyController(service services.IConsistencyService) IConsistencyController {
+	return &consistencyController{
+		service: service,
+	}
+}
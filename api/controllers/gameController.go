@@ -2,14 +2,19 @@ package controllers
 
 import (
 	"api/dtos"
+	"api/models"
 	"api/services"
+	"api/shared"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/dranikpg/dto-mapper"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type IGameController interface {
@@ -17,19 +22,43 @@ type IGameController interface {
 	GetGameById(c *gin.Context)
 	UploadGame(c *gin.Context)
 	DeleteGameById(c *gin.Context)
+	UpdateGameById(c *gin.Context)
+	GetTrash(c *gin.Context)
+	RestoreGame(c *gin.Context)
+	StopGame(c *gin.Context)
+	StartGame(c *gin.Context)
+	RestartGame(c *gin.Context)
 }
 
 type gameController struct {
-	service services.IGameService
+	service  services.IGameService
+	access   services.IAccessService
+	clusters services.IClusterService
+	//admins are the subjects of the administrators, only they can pin games to a cluster
+	admins map[string]bool
 }
 
 func (g gameController) GetAllGames(c *gin.Context) {
+	owners, ok := ownersFromRequest(c, g.access)
+	if !ok {
+		return
+	}
+
 	//Get Games
-	games, err := g.service.FindAllByOwner(c.GetString("subject"))
+	games, err := g.service.FindAllByOwners(owners)
 	if err != nil { //TODO handle different errors
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
 		return
 	}
+	//Without an owner, the games which have been shared with the user are listed too
+	if c.Query("owner") == "" {
+		shared, err := g.service.FindAllSharedWith(c.GetString("subject"), c.GetString("email"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		games = appendMissingGames(games, shared)
+	}
 
 	//Map to dto
 	resultDto := []dtos.GetGameByIdResponseBody{}
@@ -56,7 +85,16 @@ func (g gameController) GetGameById(c *gin.Context) {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "Game not found"})
 			return
 		}
-		if game.Owner != c.GetString("subject") {
+		//Public games can be read by everyone
+		authorized := game.Visibility == shared.Visibility_Public
+		if !authorized {
+			authorized, err = g.access.HasGamePermission(c.GetString("subject"), c.GetString("email"), game.ID, game.Owner, shared.Permission_View)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+				return
+			}
+		}
+		if !authorized {
 			log.Print(fmt.Printf("%s tried to access an resource of %s", c.GetString("subject"), game.Owner))
 			c.AbortWithStatusJSON(
 				http.StatusForbidden,
@@ -72,11 +110,86 @@ func (g gameController) GetGameById(c *gin.Context) {
 			return
 		}
 
+		c.Header("ETag", etag(game))
 		c.IndentedJSON(http.StatusOK, resultDto)
 		return
 	}
 }
 
+func (g gameController) UpdateGameById(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, g.service, g.access, shared.Permission_Update) {
+			return
+		}
+
+		revision, ok := getRevisionFromRequest(c)
+		if !ok {
+			return
+		}
+
+		var body dtos.UpdateGameRequestBody
+		err := c.ShouldBindJSON(&body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		if body.Title != nil && len(*body.Title) == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Title must not be empty"})
+			return
+		}
+		if body.Visibility != nil && !body.Visibility.IsValid() {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Visibility must be private or public"})
+			return
+		}
+		if body.Tags != nil {
+			*body.Tags = shared.NormalizeTags(*body.Tags)
+		}
+		if message := shared.ValidateMetadata(body.Description, body.Tags, body.Platform); message != "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": message})
+			return
+		}
+
+		game, err := g.service.FindByID(_uuid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		if game == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "Game not found"})
+			return
+		}
+		if revision == 0 {
+			revision = game.Revision
+		}
+
+		if body.Title != nil {
+			game.Title = *body.Title
+		}
+		if body.Visibility != nil {
+			game.Visibility = *body.Visibility
+		}
+		if body.Description != nil {
+			game.Description = *body.Description
+		}
+		if body.Tags != nil {
+			game.Tags = *body.Tags
+		}
+		if body.Platform != nil {
+			game.Platform = strings.TrimSpace(*body.Platform)
+		}
+
+		err = g.service.Update(game, revision)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		respondWithGame(c, game)
+		return
+	}
+}
+
 func (g gameController) UploadGame(c *gin.Context) {
 
 	//Try to read the title from body
@@ -97,16 +210,67 @@ func (g gameController) UploadGame(c *gin.Context) {
 		return
 	}
 
+	//The searchable metadata is optional, the tags are separated by commas.
+	//The game is placed in the region or on the cluster of the form values "region" and "cluster", if they are set.
+	metadata := models.GameMetadata{
+		Title:       title,
+		Description: c.Request.PostFormValue("description"),
+		Tags:        shared.NormalizeTags(strings.Split(c.Request.PostFormValue("tags"), ",")),
+		Platform:    strings.TrimSpace(c.Request.PostFormValue("platform")),
+		Region:      strings.TrimSpace(c.Request.PostFormValue("region")),
+		Cluster:     strings.TrimSpace(c.Request.PostFormValue("cluster")),
+	}
+	archive, ok := getArchiveOptionsFromRequest(c)
+	if !ok {
+		return
+	}
+	metadata.Archive = archive
+	if message := shared.ValidateMetadata(&metadata.Description, &metadata.Tags, &metadata.Platform); message != "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": message})
+		return
+	}
+
 	sub := c.GetString("subject")
 	if len(sub) == 0 {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "IdToken is invalid, sub is missing"})
 		return
 	}
 
+	//Games can be uploaded for an organization with the form value "owner" ("org:<id>")
+	owner := sub
+	if requested := c.Request.PostFormValue("owner"); requested != "" && requested != sub {
+		_, isOrg := shared.ParseOrgOwner(requested)
+		authorized := false
+		if isOrg {
+			authorized, err = g.access.HasPermission(sub, requested, shared.Permission_Update)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+				return
+			}
+		}
+		if !authorized {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "You are not allowed to upload games for this owner"})
+			return
+		}
+		owner = requested
+	}
+
+	//Only administrators can pin games, the pinned cluster is checked before the file is stored
+	if metadata.Cluster != "" {
+		if !g.admins[sub] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Only administrators can pin games to a cluster"})
+			return
+		}
+		if err = g.clusters.Pin(metadata.Cluster); err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+	}
+
 	//Save the game in the database and azure
-	game, err := g.service.Save(file, title, sub)
+	game, err := g.service.Save(file, metadata, owner)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		abortWithServiceError(c, err)
 		return
 	}
 
@@ -120,37 +284,109 @@ func (g gameController) DeleteGameById(c *gin.Context) {
 	if _uuid != uuid.Nil {
 
 		//Check if the user has access to the game
-		authorized, err := g.hasAccessToGame(c)
+		if !checkAccessToGame(c, g.service, g.access, shared.Permission_Delete) {
+			return
+		}
 
+		//Delete game from k8s/aks and move it to the trash
+		err := g.service.Delete(_uuid)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "Game not found"})
-				return
-			}
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			abortWithServiceError(c, err)
+			return
+		} else {
+			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
+	}
+}
 
-		if !authorized {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "You don't have permission to access this resource"})
+// GetTrash returns the games of the logged-in user which are in the trash.
+func (g gameController) GetTrash(c *gin.Context) {
+	owners, ok := ownersFromRequest(c, g.access)
+	if !ok {
+		return
+	}
+
+	games, err := g.service.FindAllDeletedByOwners(owners)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	//Map to dto
+	resultDto := []dtos.GetDeletedGameResponseBody{}
+	err = dto.Map(&resultDto, games)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, resultDto)
+	return
+}
+
+// RestoreGame moves a game out of the trash and deploys it again.
+func (g gameController) RestoreGame(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, g.service, g.access, shared.Permission_Delete) {
 			return
 		}
 
-		//Delete game from db, azure storage and k8s/aks
-		err = g.service.Delete(_uuid)
-		if err != nil { //TODO handle different errors
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		game, err := g.service.Restore(_uuid)
+		if err != nil {
+			abortWithServiceError(c, err)
 			return
-		} else {
-			c.AbortWithStatus(http.StatusNoContent)
+		}
+
+		respondWithGame(c, game)
+		return
+	}
+}
+
+// StopGame scales the game down until it is started again, its url is kept.
+func (g gameController) StopGame(c *gin.Context) {
+	g.controlGame(c, g.service.Stop)
+}
+
+func (g gameController) StartGame(c *gin.Context) {
+	g.controlGame(c, g.service.Start)
+}
+
+// RestartGame starts a stopped game or rolls out new pods of a running game.
+func (g gameController) RestartGame(c *gin.Context) {
+	g.controlGame(c, g.service.Restart)
+}
+
+// controlGame runs an action which controls the deployment of the game and responds with the game.
+func (g gameController) controlGame(c *gin.Context, action func(id uuid.UUID) (*models.Game, error)) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, g.service, g.access, shared.Permission_DeployControl) {
 			return
 		}
+
+		game, err := action(_uuid)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		respondWithGame(c, game)
+		return
 	}
 }
 
-func GameController(service services.IGameService) IGameController {
+func GameController(service services.IGameService, access services.IAccessService, clusters services.IClusterService, admins []string) IGameController {
+	adminSet := map[string]bool{}
+	for _, subject := range admins {
+		adminSet[subject] = true
+	}
 	return &gameController{
-		service: service,
+		service:  service,
+		access:   access,
+		clusters: clusters,
+		admins:   adminSet,
 	}
 }
 
@@ -168,13 +404,192 @@ func getUUIDFromRequest(c *gin.Context) uuid.UUID {
 	return _uuid
 }
 
-// Returns true if the owner the user who is logged-in has the same subject-id as the game owner.
+// checkAccessToGame aborts the request and returns false if the logged-in user does not have all permissions on the game.
+func checkAccessToGame(c *gin.Context, service services.IGameService, access services.IAccessService, permissions ...shared.Permission) bool {
+	authorized, err := hasAccessToGame(c, service, access, permissions...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "Game not found"})
+			return false
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+
+	if !authorized {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "You don't have permission to access this resource"})
+		return false
+	}
+	return true
+}
+
+// respondWithGame writes the game and its ETag as response.
+func respondWithGame(c *gin.Context, game *models.Game) {
+	resultDto := dtos.GetGameByIdResponseBody{}
+	err := dto.Map(&resultDto, game)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.Header("ETag", etag(game))
+	c.IndentedJSON(http.StatusOK, resultDto)
+}
+
+// abortWithServiceError maps the errors of the game service to http status codes.
+func abortWithServiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "Game not found"})
+	case errors.Is(err, shared.ErrPreconditionFailed):
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrVersionNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrNoPreviousVersion):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrInvalidChannel):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrForbidden):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrOrgNotFound), errors.Is(err, shared.ErrMemberNotFound), errors.Is(err, shared.ErrInvitationNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrAlreadyMember), errors.Is(err, shared.ErrLastOwner):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrCollaboratorNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrInvalidCollaborator):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrInvalidSearchQuery):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrInvalidBatchOperation):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrOperationNotSupported):
+		c.AbortWithStatusJSON(http.StatusNotImplemented, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrInvalidPlaySession), errors.Is(err, shared.ErrInvalidUsageRange):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrInvalidImportMode), errors.Is(err, shared.ErrInvalidArchive):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrRepairDisabled):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrClusterNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrInvalidCluster):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrClusterInUse), errors.Is(err, shared.ErrClusterCordoned):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrNoClusterAvailable):
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrContentRejected):
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrScannerUnavailable):
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrGameNotDeployed):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case errors.Is(err, shared.ErrInvalidSignature):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// etag returns the ETag of a game, which is its quoted revision.
+func etag(game *models.Game) string {
+	return fmt.Sprintf("\"%d\"", game.Revision)
+}
+
+// getRevisionFromRequest parses the revision from the If-Match header.
+// It returns 0 if the header is not set or "*".
+// It returns HTTP 412 and false if the header is not a valid ETag.
+func getRevisionFromRequest(c *gin.Context) (int, bool) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, true
+	}
+
+	revision, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), "\""))
+	if err != nil || revision <= 0 {
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"message": "Invalid If-Match header"})
+		return 0, false
+	}
+	return revision, true
+}
+
+// getArchiveOptionsFromRequest parses the form values "archive" and "entry" of an upload.
+// If "archive" is true, the uploaded zip archive is extracted and "entry" overrides the entry file of its manifest.
+// It returns HTTP 400 and false if "archive" is not a boolean.
+func getArchiveOptionsFromRequest(c *gin.Context) (models.ArchiveOptions, bool) {
+	options := models.ArchiveOptions{Entry: strings.TrimSpace(c.Request.PostFormValue("entry"))}
+	if value := c.Request.PostFormValue("archive"); value != "" {
+		extract, err := strconv.ParseBool(value)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Archive must be true or false"})
+			return options, false
+		}
+		options.Extract = extract
+	}
+	if options.Entry != "" && !options.Extract {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "The entry can only be set for archives"})
+		return options, false
+	}
+	return options, true
+}
+
+// Returns true if the user who is logged-in is the owner of the game,
+// a member of the organization owning the game with a role which allows the action
+// or a collaborator who has been granted the permissions.
 // Returns false and error if any other error occurred.
-func (g gameController) hasAccessToGame(c *gin.Context) (bool, error) {
-	owner, err := g.service.ReadOwner(getUUIDFromRequest(c))
+func hasAccessToGame(c *gin.Context, service services.IGameService, access services.IAccessService, permissions ...shared.Permission) (bool, error) {
+	_uuid := getUUIDFromRequest(c)
+	owner, err := service.ReadOwner(_uuid)
 	if err != nil {
 		return false, err
 	}
 
-	return owner == c.GetString("subject"), nil
+	for _, permission := range permissions {
+		authorized, err := access.HasGamePermission(c.GetString("subject"), c.GetString("email"), _uuid, owner, permission)
+		if err != nil || !authorized {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// ownersFromRequest returns the owners whose games are listed. The query parameter "owner" selects
+// the games of the user or of one organization of the user, by default the games of all of them are listed.
+// It returns HTTP 403 and false if the user can not see the games of the requested owner.
+func ownersFromRequest(c *gin.Context, access services.IAccessService) ([]string, bool) {
+	subject := c.GetString("subject")
+	if owner := c.Query("owner"); owner != "" {
+		authorized, err := access.HasPermission(subject, owner, shared.Permission_View)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return nil, false
+		}
+		if !authorized {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "You don't have permission to access this resource"})
+			return nil, false
+		}
+		return []string{owner}, true
+	}
+
+	owners, err := access.Owners(subject)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return nil, false
+	}
+	return owners, true
+}
+
+// appendMissingGames appends the games which are not contained in games yet
+func appendMissingGames(games []models.Game, more []models.Game) []models.Game {
+	contained := map[uuid.UUID]bool{}
+	for _, game := range games {
+		contained[game.ID] = true
+	}
+	for _, game := range more {
+		if !contained[game.ID] {
+			games = append(games, game)
+		}
+	}
+	return games
 }
@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	"api/dtos"
+	"api/models"
+	"api/services"
+	"api/shared"
+	"fmt"
+	"github.com/dranikpg/dto-mapper"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type IGameVersionController interface {
+	GetAllVersions(c *gin.Context)
+	UploadVersion(c *gin.Context)
+	ReplaceRom(c *gin.Context)
+	PromoteVersion(c *gin.Context)
+	Rollback(c *gin.Context)
+	RemoveBeta(c *gin.Context)
+	DownloadRom(c *gin.Context)
+}
+
+type gameVersionController struct {
+	games    services.IGameService
+	versions services.IGameVersionService
+	roms     services.IRomService
+	access   services.IAccessService
+}
+
+func (g gameVersionController) GetAllVersions(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, g.games, g.access, shared.Permission_View) {
+			return
+		}
+
+		versions, err := g.versions.FindAllByGame(_uuid)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		//Map to dto
+		resultDto := []dtos.GameVersionResponseBody{}
+		err = dto.Map(&resultDto, versions)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, resultDto)
+		return
+	}
+}
+
+// UploadVersion uploads a new version of the game.
+// The version is only deployed if the form value "channel" is "live" or "beta", which requires the permission deploy-control.
+func (g gameVersionController) UploadVersion(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, g.games, g.access, shared.Permission_Update) {
+			return
+		}
+
+		revision, ok := getRevisionFromRequest(c)
+		if !ok {
+			return
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+
+		archive, ok := getArchiveOptionsFromRequest(c)
+		if !ok {
+			return
+		}
+
+		channel := shared.Channel(c.Request.PostFormValue("channel"))
+		if channel == "" {
+			channel = shared.Channel_None
+		}
+		if !channel.IsValid() {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Channel must be live, beta or none"})
+			return
+		}
+		//Deploying the version changes which version is running
+		if channel != shared.Channel_None && !checkAccessToGame(c, g.games, g.access, shared.Permission_DeployControl) {
+			return
+		}
+
+		game, version, err := g.versions.Create(_uuid, file, archive, c.Request.PostFormValue("changelog"), c.GetString("subject"), channel, revision)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		//Map to dto
+		resultDto := dtos.CreateGameVersionResponseBody{}
+		err = dto.Map(&resultDto.Game, game)
+		if err == nil {
+			err = dto.Map(&resultDto.Version, version)
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		c.Header("ETag", etag(game))
+		c.IndentedJSON(http.StatusCreated, resultDto)
+		return
+	}
+}
+
+// ReplaceRom uploads a new version of the game and deploys it to the live channel.
+func (g gameVersionController) ReplaceRom(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, g.games, g.access, shared.Permission_Update, shared.Permission_DeployControl) {
+			return
+		}
+
+		revision, ok := getRevisionFromRequest(c)
+		if !ok {
+			return
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+
+		archive, ok := getArchiveOptionsFromRequest(c)
+		if !ok {
+			return
+		}
+
+		game, _, err := g.versions.Create(_uuid, file, archive, c.Request.PostFormValue("changelog"), c.GetString("subject"), shared.Channel_Live, revision)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		respondWithGame(c, game)
+		return
+	}
+}
+
+func (g gameVersionController) PromoteVersion(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, g.games, g.access, shared.Permission_DeployControl) {
+			return
+		}
+
+		revision, ok := getRevisionFromRequest(c)
+		if !ok {
+			return
+		}
+
+		version, err := strconv.Atoi(c.Param("version"))
+		if err != nil || version <= 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid version"})
+			return
+		}
+
+		var body dtos.PromoteGameVersionRequestBody
+		err = c.ShouldBindJSON(&body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+
+		game, err := g.versions.Promote(_uuid, version, body.Channel, revision)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		respondWithGame(c, game)
+		return
+	}
+}
+
+// Rollback deploys the version before the current live version to the live channel.
+func (g gameVersionController) Rollback(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, g.games, g.access, shared.Permission_DeployControl) {
+			return
+		}
+
+		revision, ok := getRevisionFromRequest(c)
+		if !ok {
+			return
+		}
+
+		game, err := g.versions.Rollback(_uuid, revision)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		respondWithGame(c, game)
+		return
+	}
+}
+
+func (g gameVersionController) RemoveBeta(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, g.games, g.access, shared.Permission_DeployControl) {
+			return
+		}
+
+		revision, ok := getRevisionFromRequest(c)
+		if !ok {
+			return
+		}
+
+		game, err := g.versions.RemoveBeta(_uuid, revision)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		respondWithGame(c, game)
+		return
+	}
+}
+
+// DownloadRom returns the game file of the live version or of the version in the query parameter "version".
+// Requests with a signed url of the api do not need to be authorized.
+func (g gameVersionController) DownloadRom(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		version := 0
+		if c.Query("version") != "" {
+			var err error
+			version, err = strconv.Atoi(c.Query("version"))
+			if err != nil || version <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid version"})
+				return
+			}
+		}
+
+		var rom *services.Rom
+		var err error
+		method := shared.Download_Stream
+		if signature := c.Query("signature"); signature != "" {
+			expires, errParse := strconv.ParseInt(c.Query("expires"), 10, 64)
+			if errParse != nil || version == 0 {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": shared.ErrInvalidSignature.Error()})
+				return
+			}
+			method = shared.Download_Signed
+			rom, err = g.roms.DownloadSigned(_uuid, version, time.Unix(expires, 0), signature)
+		} else {
+			if !checkAccessToGame(c, g.games, g.access, shared.Permission_View) {
+				return
+			}
+			game, errFind := g.games.FindByID(_uuid)
+			if errFind != nil {
+				abortWithServiceError(c, errFind)
+				return
+			}
+			//The owner of a game in the trash is still read, the game itself is not found
+			if game == nil {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "Game not found"})
+				return
+			}
+			rom, err = g.roms.Download(game, version)
+		}
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+		if rom.Content != nil {
+			defer rom.Content.Close()
+		} else {
+			method = shared.Download_Redirect
+		}
+
+		err = g.roms.LogDownload(&models.RomDownload{
+			GameID:    _uuid,
+			Version:   rom.Version,
+			Subject:   c.GetString("subject"),
+			ClientIP:  c.ClientIP(),
+			Method:    method,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		c.Header("Cache-Control", "private, no-store")
+		if rom.RedirectUrl != "" {
+			c.Redirect(http.StatusTemporaryRedirect, rom.RedirectUrl)
+			return
+		}
+
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": rom.FileName}))
+		if rom.Checksum != "" {
+			//Enables If-Range requests to resume downloads
+			c.Header("ETag", fmt.Sprintf("\"%s\"", rom.Checksum))
+		}
+		//Handles range requests
+		http.ServeContent(c.Writer, c.Request, rom.FileName, rom.LastModified, rom.Content)
+		return
+	}
+}
+
+func GameVersionController(games services.IGameService, versions services.IGameVersionService, roms services.IRomService, access services.IAccessService) IGameVersionController {
+	return &gameVersionController{
+		games:    games,
+		versions: versions,
+		roms:     roms,
+		access:   access,
+	}
+}
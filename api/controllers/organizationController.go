@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"api/dtos"
+	"api/models"
+	"api/services"
+	"api/shared"
+	"fmt"
+	"github.com/dranikpg/dto-mapper"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+type IOrganizationController interface {
+	CreateOrganization(c *gin.Context)
+	GetAllOrganizations(c *gin.Context)
+	GetOrganizationById(c *gin.Context)
+	GetMembers(c *gin.Context)
+	UpdateMember(c *gin.Context)
+	RemoveMember(c *gin.Context)
+	CreateInvitation(c *gin.Context)
+	GetInvitations(c *gin.Context)
+	RevokeInvitation(c *gin.Context)
+	AcceptInvitation(c *gin.Context)
+}
+
+type organizationController struct {
+	service services.IOrganizationService
+}
+
+// CreateOrganization creates an organization, the logged-in user becomes its owner.
+func (o organizationController) CreateOrganization(c *gin.Context) {
+	var body dtos.CreateOrganizationRequestBody
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	org, err := o.service.Create(body.Name, c.GetString("subject"))
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.Header("content-location", fmt.Sprintf("%s/orgs/%s", c.Request.Host, org.ID.String()))
+	c.IndentedJSON(http.StatusCreated, organizationDto(&models.Membership{Organization: *org, Role: shared.Role_Owner}))
+}
+
+// GetAllOrganizations returns the organizations of the logged-in user.
+func (o organizationController) GetAllOrganizations(c *gin.Context) {
+	memberships, err := o.service.FindAllBySubject(c.GetString("subject"))
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	resultDto := make([]dtos.OrganizationResponseBody, len(memberships))
+	for i := range memberships {
+		resultDto[i] = organizationDto(&memberships[i])
+	}
+	c.IndentedJSON(http.StatusOK, resultDto)
+}
+
+func (o organizationController) GetOrganizationById(c *gin.Context) {
+	id := getOrgIDFromRequest(c)
+	if id != uuid.Nil {
+		membership, err := o.service.FindByID(id, c.GetString("subject"))
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, organizationDto(membership))
+	}
+}
+
+func (o organizationController) GetMembers(c *gin.Context) {
+	id := getOrgIDFromRequest(c)
+	if id != uuid.Nil {
+		members, err := o.service.FindMembers(id, c.GetString("subject"))
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		//Map to dto
+		resultDto := []dtos.OrgMemberResponseBody{}
+		err = dto.Map(&resultDto, members)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		c.IndentedJSON(http.StatusOK, resultDto)
+	}
+}
+
+// UpdateMember changes the role of the member with the subject in the path.
+func (o organizationController) UpdateMember(c *gin.Context) {
+	id := getOrgIDFromRequest(c)
+	if id != uuid.Nil {
+		var body dtos.UpdateOrgMemberRequestBody
+		err := c.ShouldBindJSON(&body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		if !body.Role.IsValid() {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Role must be viewer, developer, admin or owner"})
+			return
+		}
+
+		err = o.service.UpdateRole(id, c.GetString("subject"), c.Param("subject"), body.Role)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// RemoveMember removes the member with the subject in the path, members can remove themselves to leave the organization.
+func (o organizationController) RemoveMember(c *gin.Context) {
+	id := getOrgIDFromRequest(c)
+	if id != uuid.Nil {
+		err := o.service.RemoveMember(id, c.GetString("subject"), c.Param("subject"))
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// CreateInvitation invites a user by email, the response contains the token which has to be sent to the user.
+func (o organizationController) CreateInvitation(c *gin.Context) {
+	id := getOrgIDFromRequest(c)
+	if id != uuid.Nil {
+		var body dtos.CreateOrgInvitationRequestBody
+		err := c.ShouldBindJSON(&body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		if !body.Role.IsValid() {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Role must be viewer, developer, admin or owner"})
+			return
+		}
+
+		invitation, token, err := o.service.Invite(id, c.GetString("subject"), body.Email, body.Role)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		//Map to dto
+		resultDto := dtos.CreateOrgInvitationResponseBody{Token: token}
+		err = dto.Map(&resultDto.Invitation, invitation)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		c.IndentedJSON(http.StatusCreated, resultDto)
+	}
+}
+
+// GetInvitations returns the pending invitations of an organization.
+func (o organizationController) GetInvitations(c *gin.Context) {
+	id := getOrgIDFromRequest(c)
+	if id != uuid.Nil {
+		invitations, err := o.service.FindInvitations(id, c.GetString("subject"))
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		//Map to dto
+		resultDto := []dtos.OrgInvitationResponseBody{}
+		err = dto.Map(&resultDto, invitations)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		c.IndentedJSON(http.StatusOK, resultDto)
+	}
+}
+
+func (o organizationController) RevokeInvitation(c *gin.Context) {
+	id := getOrgIDFromRequest(c)
+	if id != uuid.Nil {
+		invitationID, err := uuid.Parse(c.Param("invitationId"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid invitation ID"})
+			return
+		}
+
+		err = o.service.RevokeInvitation(id, c.GetString("subject"), invitationID)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// AcceptInvitation adds the logged-in user to the organization of the invitation.
+func (o organizationController) AcceptInvitation(c *gin.Context) {
+	var body dtos.AcceptOrgInvitationRequestBody
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	member, err := o.service.AcceptInvitation(body.Token, c.GetString("subject"), c.GetString("email"))
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	membership, err := o.service.FindByID(member.OrgID, member.Subject)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, organizationDto(membership))
+}
+
+// getOrgIDFromRequest parses the id of the organization from the request param "id".
+// It returns HTTP 400 and uuid.Nil if the id is invalid.
+func getOrgIDFromRequest(c *gin.Context) uuid.UUID {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid organization ID"})
+		return uuid.Nil
+	}
+	return id
+}
+
+func organizationDto(membership *models.Membership) dtos.OrganizationResponseBody {
+	return dtos.OrganizationResponseBody{
+		ID:        membership.Organization.ID,
+		Name:      membership.Organization.Name,
+		Owner:     shared.OrgOwner(membership.Organization.ID),
+		Role:      membership.Role,
+		CreatedAt: membership.Organization.CreatedAt,
+	}
+}
+
+func OrganizationController(service services.IOrganizationService) IOrganizationController {
+	return &organizationController{
+		service: service,
+	}
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"api/dtos"
+	"api/services"
+	"github.com/dranikpg/dto-mapper"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// maxSearchLimit is the maximum number of games which are returned by a search
+const maxSearchLimit = 100
+
+type ISearchController interface {
+	Search(c *gin.Context)
+}
+
+type searchController struct {
+	service services.ISearchService
+}
+
+// Search returns the games matching the query parameter "q", which are visible to the logged-in user.
+// The query parameters "limit" (default 20) and "offset" page through the results.
+func (s searchController) Search(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > maxSearchLimit {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Limit must be between 1 and 100"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid offset"})
+		return
+	}
+
+	hits, err := s.service.Search(c.Query("q"), c.GetString("subject"), c.GetString("email"), limit, offset)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	//Map to dto
+	resultDto := make([]dtos.SearchResultResponseBody, len(hits))
+	for i, hit := range hits {
+		err = dto.Map(&resultDto[i].Game, hit.Game)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		resultDto[i].Score = hit.Score
+		resultDto[i].Highlights = hit.Highlights
+	}
+
+	c.IndentedJSON(http.StatusOK, resultDto)
+}
+
+func SearchController(service services.ISearchService) ISearchController {
+	return &searchController{
+		service: service,
+	}
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"api/dtos"
+	"api/models"
+	"api/services"
+	"api/shared"
+	"encoding/csv"
+	"fmt"
+	"github.com/dranikpg/dto-mapper"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultUsageRange is used if a usage request has no "from"
+const defaultUsageRange = 30 * 24 * time.Hour
+
+type IUsageController interface {
+	RecordSession(c *gin.Context)
+	GetGameUsage(c *gin.Context)
+	GetUsageReport(c *gin.Context)
+}
+
+type usageController struct {
+	service services.IUsageService
+	games   services.IGameService
+	access  services.IAccessService
+}
+
+// RecordSession saves a play session reported by a coordinator or the operator.
+func (u usageController) RecordSession(c *gin.Context) {
+	var body dtos.PlaySessionRequestBody
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	session := &models.PlaySession{
+		SessionKey: body.SessionID,
+		GameID:     body.GameID,
+		Player:     body.Player,
+		StartedAt:  body.StartedAt,
+		LastSeenAt: body.StartedAt,
+		EndedAt:    body.EndedAt,
+	}
+	if body.LastSeenAt != nil {
+		session.LastSeenAt = *body.LastSeenAt
+	}
+	err = u.service.Record(session)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// GetGameUsage returns the play time of a game in total and per day.
+// The range is set by the query parameters "from" and "to", by default it contains the last 30 days.
+func (u usageController) GetGameUsage(c *gin.Context) {
+	_uuid := getUUIDFromRequest(c)
+	if _uuid != uuid.Nil {
+		if !checkAccessToGame(c, u.games, u.access, shared.Permission_View) {
+			return
+		}
+		from, to, ok := usageRangeFromRequest(c)
+		if !ok {
+			return
+		}
+
+		usage, err := u.service.GameUsage(_uuid, from, to)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		//Map to dto
+		resultDto := dtos.GameUsageResponseBody{}
+		err = dto.Map(&resultDto, usage)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		c.IndentedJSON(http.StatusOK, resultDto)
+	}
+}
+
+// GetUsageReport returns the play time of all games per game, owner and day.
+// The report is exported as csv if the query parameter "format" is "csv".
+func (u usageController) GetUsageReport(c *gin.Context) {
+	from, to, ok := usageRangeFromRequest(c)
+	if !ok {
+		return
+	}
+
+	entries, err := u.service.Report(from, to)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		writeUsageCsv(c, entries, from, to)
+		return
+	}
+
+	//Map to dto
+	resultDto := []dtos.UsageEntryResponseBody{}
+	err = dto.Map(&resultDto, entries)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, resultDto)
+}
+
+func writeUsageCsv(c *gin.Context, entries []models.UsageEntry, from time.Time, to time.Time) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"usage-%s-%s.csv\"", from.Format(time.DateOnly), to.Format(time.DateOnly)))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write([]string{"day", "owner", "game_id", "minutes", "sessions", "players"})
+	for _, entry := range entries {
+		_ = writer.Write([]string{entry.Day, entry.Owner, entry.GameID.String(),
+			strconv.FormatFloat(entry.Minutes, 'f', 2, 64), strconv.Itoa(entry.Sessions), strconv.Itoa(entry.Players)})
+	}
+	writer.Flush()
+}
+
+// usageRangeFromRequest reads the query parameters "from" and "to", which are either dates or RFC 3339 times.
+// A date as "to" includes the whole day. It returns HTTP 400 and false if a parameter is invalid.
+func usageRangeFromRequest(c *gin.Context) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		parsed, isDate, err := parseUsageTime(value)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid to, use a date like 2024-01-31 or a RFC 3339 time"})
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+		if isDate {
+			to = to.Add(24 * time.Hour)
+		}
+	}
+
+	from := to.Add(-defaultUsageRange)
+	if value := c.Query("from"); value != "" {
+		parsed, _, err := parseUsageTime(value)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid from, use a date like 2024-01-01 or a RFC 3339 time"})
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	return from, to, true
+}
+
+// parseUsageTime parses a date or a RFC 3339 time and returns whether it has been a date
+func parseUsageTime(value string) (time.Time, bool, error) {
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		return parsed, true, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	return parsed.UTC(), false, err
+}
+
+func UsageController(service services.IUsageService, games services.IGameService, access services.IAccessService) IUsageController {
+	return &usageController{
+		service: service,
+		games:   games,
+		access:  access,
+	}
+}
@@ -0,0 +1,110 @@
+package dev
+
+import (
+	"api/shared"
+	"context"
+	"fmt"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"time"
+)
+
+// operatorInterval is the interval in which the simulated operator updates the game resources
+const operatorInterval = time.Second
+
+// operator simulates the operator of the cluster by reporting the url and the phase of the game resources
+type operator struct {
+	k8s    client.Client
+	config Config
+	//When the resources have been seen first
+	created map[types.NamespacedName]time.Time
+}
+
+// reconcile reports the url of a new game after the deploy delay and moves suspended and resumed games
+// through the phases stopping and stopped or starting and running.
+func (o *operator) reconcile(ctx context.Context) error {
+	list := streamv1.GameList{}
+	err := o.k8s.List(ctx, &list)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	existing := map[types.NamespacedName]bool{}
+	for i := range list.Items {
+		game := &list.Items[i]
+		key := types.NamespacedName{Namespace: game.Namespace, Name: game.Name}
+		existing[key] = true
+		if _, ok := o.created[key]; !ok {
+			o.created[key] = now
+		}
+		if now.Sub(o.created[key]) < o.config.DeployDelay {
+			continue
+		}
+
+		status := game.Status
+		switch {
+		case game.Spec.Suspend && status.Phase == streamv1.GamePhaseStopping:
+			status.Phase = streamv1.GamePhaseStopped
+		case game.Spec.Suspend && status.Phase != streamv1.GamePhaseStopped:
+			status.Phase = streamv1.GamePhaseStopping
+		case !game.Spec.Suspend && status.URL == "":
+			status.URL = fmt.Sprintf(o.config.GameUrl, game.Name)
+			status.Phase = streamv1.GamePhaseRunning
+		case !game.Spec.Suspend && status.Phase == streamv1.GamePhaseStarting:
+			status.Phase = streamv1.GamePhaseRunning
+		case !game.Spec.Suspend && status.Phase != streamv1.GamePhaseRunning:
+			status.Phase = streamv1.GamePhaseStarting
+		}
+		if status.URL == game.Status.URL && status.Phase == game.Status.Phase {
+			continue
+		}
+		game.Status = status
+		err = o.k8s.Status().Update(ctx, game)
+		if err != nil {
+			return err
+		}
+	}
+
+	for key := range o.created {
+		if !existing[key] {
+			delete(o.created, key)
+		}
+	}
+	return nil
+}
+
+// RestoreClusterOnStart lets the consistency checker deploy the games of the database into the empty cluster
+// before the api is served, see CONSISTENCY_CHECK_ON_START.
+func RestoreClusterOnStart() {
+	setDefault("CONSISTENCY_CHECK_ON_START", "true")
+	repair := os.Getenv("CONSISTENCY_REPAIR")
+	if !strings.Contains(repair, string(shared.Inconsistency_RowWithoutResource)) {
+		os.Setenv("CONSISTENCY_REPAIR", strings.Trim(repair+","+string(shared.Inconsistency_RowWithoutResource), ","))
+	}
+}
+
+// Cluster returns an in-memory kubernetes client, whose game resources are updated by a simulated operator.
+func Cluster(scheme *runtime.Scheme, config Config) client.Client {
+	k8s := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&streamv1.Game{}).Build()
+	o := &operator{
+		k8s:     k8s,
+		config:  config,
+		created: map[types.NamespacedName]time.Time{},
+	}
+	go func() {
+		for range time.Tick(operatorInterval) {
+			err := o.reconcile(context.Background())
+			if err != nil {
+				log.Println(fmt.Sprintf("Simulating the operator failed: %s", err))
+			}
+		}
+	}()
+	return k8s
+}
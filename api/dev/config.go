@@ -0,0 +1,66 @@
+package dev
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// Config configures the development mode, which runs the api without azure and kubernetes.
+type Config struct {
+	//Directory of the blobs
+	StorageDir string
+	//Url of the deployed games, %s is replaced with the name of the game resource
+	GameUrl string
+	//Time after which the simulated operator reports the url of a new game
+	DeployDelay time.Duration
+	//The demo user, who makes all requests
+	Subject string
+	Email   string
+	//"docker" or "external", see StartDatabase
+	Database string
+	//Image and name of the database container
+	MysqlImage     string
+	MysqlContainer string
+}
+
+// ConfigFromEnv reads the config from the environment variables DEV_STORAGE_DIR, DEV_GAME_URL, DEV_DEPLOY_DELAY,
+// DEV_SUBJECT, DEV_EMAIL, DEV_DATABASE, DEV_MYSQL_IMAGE and DEV_MYSQL_CONTAINER.
+func ConfigFromEnv() Config {
+	config := Config{
+		StorageDir:     stringFromEnv("DEV_STORAGE_DIR", ".dev/storage"),
+		GameUrl:        stringFromEnv("DEV_GAME_URL", "http://%s.localhost"),
+		DeployDelay:    3 * time.Second,
+		Subject:        stringFromEnv("DEV_SUBJECT", "demo-user"),
+		Email:          stringFromEnv("DEV_EMAIL", "demo@localhost"),
+		Database:       stringFromEnv("DEV_DATABASE", Database_Docker),
+		MysqlImage:     stringFromEnv("DEV_MYSQL_IMAGE", "mysql:8.4.0"),
+		MysqlContainer: stringFromEnv("DEV_MYSQL_CONTAINER", "igs-dev-mysql"),
+	}
+	if config.Database != Database_Docker && config.Database != Database_External {
+		log.Fatalf("Unknown DEV_DATABASE %s", config.Database)
+	}
+	if value := os.Getenv("DEV_DEPLOY_DELAY"); value != "" {
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			log.Fatalf("Invalid DEV_DEPLOY_DELAY %s", value)
+		}
+		config.DeployDelay = delay
+	}
+	return config
+}
+
+func stringFromEnv(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// SeedDemoUser makes the demo user an administrator, unless the administrators are configured.
+func SeedDemoUser(config Config) {
+	if os.Getenv("ADMIN_SUBJECTS") == "" {
+		os.Setenv("ADMIN_SUBJECTS", config.Subject)
+	}
+	log.Printf("Development mode: all requests are made by %s (%s)", config.Subject, config.Email)
+}
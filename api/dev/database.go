@@ -0,0 +1,81 @@
+package dev
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// databaseTimeout is the time which a started database has to accept connections
+const databaseTimeout = 2 * time.Minute
+
+// Database backends of the development mode
+const (
+	//Database_Docker starts a MySQL container, unless the database is reachable already
+	Database_Docker = "docker"
+	//Database_External uses the database of the MYSQL_* variables
+	Database_External = "external"
+)
+
+// StartDatabase starts the database of the development mode with docker, the api migrates it as usual.
+// The database is not embedded, docker pulls the image on the first start, so the development mode needs docker and the network once.
+// The MYSQL_* variables which are not set get the defaults of a local container. The container is reused
+// and keeps its data in a volume, so the games are still there after a restart.
+func StartDatabase(config Config) {
+	setDefault("MYSQL_HOST", "127.0.0.1")
+	setDefault("MYSQL_PORT", "3306")
+	setDefault("MYSQL_DATABASE", "api")
+	setDefault("MYSQL_ROOT_USER", "root")
+	setDefault("MYSQL_ROOT_PASSWORD", "dev")
+	if config.Database == Database_External || databaseReachable() {
+		return
+	}
+
+	log.Printf("Development mode: starting the database container %s", config.MysqlContainer)
+	//Start the container of a previous run, otherwise create it
+	output, err := exec.Command("docker", "start", config.MysqlContainer).CombinedOutput()
+	if err != nil {
+		output, err = exec.Command("docker", "run", "--detach", "--name", config.MysqlContainer,
+			"--publish", fmt.Sprintf("%s:3306", os.Getenv("MYSQL_PORT")),
+			"--env", "MYSQL_ROOT_PASSWORD="+os.Getenv("MYSQL_ROOT_PASSWORD"),
+			"--volume", config.MysqlContainer+":/var/lib/mysql",
+			config.MysqlImage).CombinedOutput()
+	}
+	if err != nil {
+		log.Fatalf("Starting the database with docker failed, start it with \"docker compose up -d mysql\" and set DEV_DATABASE=%s: %s %s",
+			Database_External, err, strings.TrimSpace(string(output)))
+	}
+
+	//MySQL accepts connections over tcp after its initialization
+	deadline := time.Now().Add(databaseTimeout)
+	for !databaseReachable() {
+		if time.Now().After(deadline) {
+			log.Fatalf("The database container %s did not start within %s", config.MysqlContainer, databaseTimeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// databaseReachable returns true if the server of the MYSQL_* variables accepts the credentials
+func databaseReachable() bool {
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/",
+		os.Getenv("MYSQL_ROOT_USER"),
+		os.Getenv("MYSQL_ROOT_PASSWORD"),
+		os.Getenv("MYSQL_HOST"),
+		os.Getenv("MYSQL_PORT")))
+	if err != nil {
+		return false
+	}
+	defer db.Close()
+	return db.Ping() == nil
+}
+
+func setDefault(key string, value string) {
+	if os.Getenv(key) == "" {
+		os.Setenv(key, value)
+	}
+}
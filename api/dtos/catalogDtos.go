@@ -0,0 +1,17 @@
+package dtos
+
+import "github.com/google/uuid"
+
+type CatalogImportResponseBody struct {
+	Imported []CatalogImportItemResponseBody `json:"imported"`
+	Skipped  []CatalogImportItemResponseBody `json:"skipped"`
+	Failed   []CatalogImportItemResponseBody `json:"failed"`
+}
+
+// CatalogImportItemResponseBody is a game of an imported archive, sourceId is its id in the archive
+type CatalogImportItemResponseBody struct {
+	SourceID uuid.UUID `json:"sourceId"`
+	ID       uuid.UUID `json:"id"`
+	Title    string    `json:"title"`
+	Reason   string    `json:"reason,omitempty"`
+}
@@ -0,0 +1,26 @@
+package dtos
+
+import "time"
+
+// SaveClusterRequestBody registers or updates a cluster, the name is part of the path
+type SaveClusterRequestBody struct {
+	Region string `json:"region" binding:"max=63"`
+	//Capacity is the maximum number of games, 0 is unlimited
+	Capacity       int      `json:"capacity" binding:"min=0"`
+	Labels         []string `json:"labels"`
+	KubeconfigPath string   `json:"kubeconfigPath" binding:"max=512"`
+	KubeContext    string   `json:"kubeContext" binding:"max=255"`
+	Cordoned       bool     `json:"cordoned"`
+}
+
+type ClusterResponseBody struct {
+	Name           string    `json:"name"`
+	Region         string    `json:"region"`
+	Capacity       int       `json:"capacity"`
+	Labels         []string  `json:"labels"`
+	KubeconfigPath string    `json:"kubeconfigPath,omitempty"`
+	KubeContext    string    `json:"kubeContext,omitempty"`
+	Cordoned       bool      `json:"cordoned"`
+	Games          int       `json:"games"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
@@ -0,0 +1,23 @@
+package dtos
+
+import (
+	"api/shared"
+	"github.com/google/uuid"
+	"time"
+)
+
+// GrantCollaboratorRequestBody grants permissions on a game to a user, identified by either subject or email.
+type GrantCollaboratorRequestBody struct {
+	Subject     *string             `json:"subject" binding:"omitempty,max=255"`
+	Email       *string             `json:"email" binding:"omitempty,email,max=320"`
+	Permissions []shared.Permission `json:"permissions" binding:"required,min=1"`
+}
+
+type CollaboratorResponseBody struct {
+	ID          uuid.UUID           `json:"id"`
+	Subject     *string             `json:"subject"`
+	Email       *string             `json:"email"`
+	Permissions []shared.Permission `json:"permissions"`
+	GrantedBy   string              `json:"grantedBy"`
+	GrantedAt   time.Time           `json:"grantedAt"`
+}
@@ -0,0 +1,25 @@
+package dtos
+
+import (
+	"api/shared"
+	"time"
+)
+
+// ConsistencyReportResponseBody lists the inconsistencies, nothing has been repaired if dryRun is true
+type ConsistencyReportResponseBody struct {
+	CheckedAt time.Time                      `json:"checkedAt"`
+	DryRun    bool                           `json:"dryRun"`
+	Issues    []ConsistencyIssueResponseBody `json:"issues"`
+}
+
+type ConsistencyIssueResponseBody struct {
+	Kind      shared.InconsistencyKind `json:"kind"`
+	GameID    string                   `json:"gameId,omitempty"`
+	Channel   shared.Channel           `json:"channel,omitempty"`
+	Cluster   string                   `json:"cluster,omitempty"`
+	Namespace string                   `json:"namespace,omitempty"`
+	Name      string                   `json:"name"`
+	Repair    string                   `json:"repair"`
+	Repaired  bool                     `json:"repaired"`
+	Error     string                   `json:"error,omitempty"`
+}
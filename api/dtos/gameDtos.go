@@ -3,18 +3,106 @@ package dtos
 import (
 	"api/shared"
 	"github.com/google/uuid"
+	"time"
 )
 
 type GetAllGamesResponseBody struct {
 	ID     uuid.UUID         `json:"id"`
 	Title  string            `json:"title"`
 	Status shared.GameStatus `json:"status"`
-	Url    string            `json:"url"`
+	//StatusReason names why a game is quarantined or why its deployment has failed
+	StatusReason string            `json:"statusReason,omitempty"`
+	Url          string            `json:"url"`
+	LiveVersion  int               `json:"liveVersion"`
+	BetaVersion  int               `json:"betaVersion,omitempty"`
+	BetaUrl      string            `json:"betaUrl,omitempty"`
+	Checksum     string            `json:"checksum"`
+	Visibility   shared.Visibility `json:"visibility"`
+	Description  string            `json:"description"`
+	Tags         []string          `json:"tags"`
+	Platform     string            `json:"platform"`
 }
 
 type GetGameByIdResponseBody struct {
 	ID     uuid.UUID         `json:"id"`
 	Title  string            `json:"title"`
 	Status shared.GameStatus `json:"status"`
-	Url    string            `json:"url"`
+	//StatusReason names why a game is quarantined or why its deployment has failed
+	StatusReason string            `json:"statusReason,omitempty"`
+	Url          string            `json:"url"`
+	LiveVersion  int               `json:"liveVersion"`
+	BetaVersion  int               `json:"betaVersion,omitempty"`
+	BetaUrl      string            `json:"betaUrl,omitempty"`
+	Checksum     string            `json:"checksum"`
+	Visibility   shared.Visibility `json:"visibility"`
+	Description  string            `json:"description"`
+	Tags         []string          `json:"tags"`
+	Platform     string            `json:"platform"`
+	Cluster      string            `json:"cluster,omitempty"`
+	Region       string            `json:"region,omitempty"`
+}
+
+type GetDeletedGameResponseBody struct {
+	ID        uuid.UUID  `json:"id"`
+	Title     string     `json:"title"`
+	FileName  string     `json:"fileName"`
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+type UpdateGameRequestBody struct {
+	Title       *string            `json:"title"`
+	Visibility  *shared.Visibility `json:"visibility"`
+	Description *string            `json:"description"`
+	Tags        *[]string          `json:"tags"`
+	Platform    *string            `json:"platform"`
+}
+
+type GameVersionResponseBody struct {
+	Version  int    `json:"version"`
+	FileName string `json:"fileName"`
+	//Bios are the BIOS files of a version with several files
+	Bios      []string  `json:"bios,omitempty"`
+	Checksum  string    `json:"checksum"`
+	Changelog string    `json:"changelog"`
+	Uploader  string    `json:"uploader"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreateGameVersionResponseBody struct {
+	Game    GetGameByIdResponseBody `json:"game"`
+	Version GameVersionResponseBody `json:"version"`
+}
+
+type PromoteGameVersionRequestBody struct {
+	Channel shared.Channel `json:"channel" binding:"required"`
+}
+
+type BatchGamesRequestBody struct {
+	IDs        []uuid.UUID           `json:"ids" binding:"required,min=1"`
+	Operation  shared.BatchOperation `json:"operation" binding:"required"`
+	Visibility shared.Visibility     `json:"visibility"`
+}
+
+type BatchItemResponseBody struct {
+	ID      uuid.UUID           `json:"id"`
+	Outcome shared.BatchOutcome `json:"outcome"`
+	Error   string              `json:"error,omitempty"`
+}
+
+type BatchJobResponseBody struct {
+	ID         uuid.UUID               `json:"id"`
+	Operation  shared.BatchOperation   `json:"operation"`
+	Status     shared.JobStatus        `json:"status"`
+	Total      int                     `json:"total"`
+	Succeeded  int                     `json:"succeeded"`
+	Failed     int                     `json:"failed"`
+	Results    []BatchItemResponseBody `json:"results"`
+	CreatedAt  time.Time               `json:"createdAt"`
+	FinishedAt *time.Time              `json:"finishedAt"`
+}
+
+type SearchResultResponseBody struct {
+	Game       GetAllGamesResponseBody `json:"game"`
+	Score      float64                 `json:"score"`
+	Highlights map[string]string       `json:"highlights"`
 }
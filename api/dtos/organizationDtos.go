@@ -0,0 +1,54 @@
+package dtos
+
+import (
+	"api/shared"
+	"github.com/google/uuid"
+	"time"
+)
+
+type CreateOrganizationRequestBody struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type OrganizationResponseBody struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	//Owner is used as owner of the games of the organization, e.g. in the upload form or the owner filter
+	Owner     string         `json:"owner"`
+	Role      shared.OrgRole `json:"role"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+type OrgMemberResponseBody struct {
+	Subject  string         `json:"subject"`
+	Role     shared.OrgRole `json:"role"`
+	JoinedAt time.Time      `json:"joinedAt"`
+}
+
+type UpdateOrgMemberRequestBody struct {
+	Role shared.OrgRole `json:"role" binding:"required"`
+}
+
+type CreateOrgInvitationRequestBody struct {
+	Email string         `json:"email" binding:"required,email"`
+	Role  shared.OrgRole `json:"role" binding:"required"`
+}
+
+type OrgInvitationResponseBody struct {
+	ID        uuid.UUID      `json:"id"`
+	Email     string         `json:"email"`
+	Role      shared.OrgRole `json:"role"`
+	InvitedBy string         `json:"invitedBy"`
+	CreatedAt time.Time      `json:"createdAt"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+}
+
+type CreateOrgInvitationResponseBody struct {
+	Invitation OrgInvitationResponseBody `json:"invitation"`
+	//Token has to be sent to the invited user, it can not be read again
+	Token string `json:"token"`
+}
+
+type AcceptOrgInvitationRequestBody struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -0,0 +1,36 @@
+package dtos
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// PlaySessionRequestBody reports a play session. A session is reported when it starts, can be reported again
+// with a newer lastSeenAt while it is running, and is ended by a report with endedAt.
+type PlaySessionRequestBody struct {
+	GameID     uuid.UUID  `json:"gameId" binding:"required"`
+	SessionID  string     `json:"sessionId" binding:"required,max=128"`
+	Player     *string    `json:"player" binding:"omitempty,max=255"`
+	StartedAt  time.Time  `json:"startedAt" binding:"required"`
+	LastSeenAt *time.Time `json:"lastSeenAt"`
+	EndedAt    *time.Time `json:"endedAt"`
+}
+
+type UsageEntryResponseBody struct {
+	Day      string    `json:"day"`
+	GameID   uuid.UUID `json:"gameId"`
+	Owner    string    `json:"owner"`
+	Minutes  float64   `json:"minutes"`
+	Sessions int       `json:"sessions"`
+	Players  int       `json:"players"`
+}
+
+type GameUsageResponseBody struct {
+	GameID   uuid.UUID                `json:"gameId"`
+	From     time.Time                `json:"from"`
+	To       time.Time                `json:"to"`
+	Minutes  float64                  `json:"minutes"`
+	Sessions int                      `json:"sessions"`
+	Players  int                      `json:"players"`
+	Days     []UsageEntryResponseBody `json:"days"`
+}
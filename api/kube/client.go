@@ -0,0 +1,207 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"log"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sync"
+	"time"
+)
+
+// retryDelay is the time after which building the client is tried again if it has failed
+const retryDelay = 10 * time.Second
+
+// refreshingClient builds its client with new credentials once per refresh interval and whenever the cluster
+// rejects the credentials. If the client can not be built, the calls fail until it can be built again.
+type refreshingClient struct {
+	build    func(ctx context.Context) (client.Client, error)
+	scheme   *runtime.Scheme
+	interval time.Duration
+
+	mutex   sync.Mutex
+	client  client.Client
+	builtAt time.Time
+	//generation is increased whenever the client is built
+	generation int
+	//The last error of build, which is returned until retryAt
+	err     error
+	retryAt time.Time
+}
+
+// current returns the client, which is built if there is none or if it is older than the refresh interval.
+// The old client is kept if a refresh fails.
+func (r *refreshingClient) current(ctx context.Context) (client.Client, int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if r.client != nil && (r.interval == 0 || now.Sub(r.builtAt) < r.interval) {
+		return r.client, r.generation, nil
+	}
+	if now.Before(r.retryAt) {
+		if r.client != nil {
+			return r.client, r.generation, nil
+		}
+		return nil, 0, r.err
+	}
+
+	c, err := r.build(ctx)
+	if err != nil {
+		log.Println(fmt.Sprintf("Building the kubernetes client failed: %s", err))
+		r.err = fmt.Errorf("the kubernetes client is not available: %w", err)
+		r.retryAt = now.Add(retryDelay)
+		if r.client != nil {
+			return r.client, r.generation, nil
+		}
+		return nil, 0, r.err
+	}
+	r.client = c
+	r.generation++
+	r.builtAt = now
+	r.err = nil
+	r.retryAt = time.Time{}
+	return c, r.generation, nil
+}
+
+// invalidate drops the client, unless it has been replaced in the meantime
+func (r *refreshingClient) invalidate(generation int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.generation == generation {
+		r.client = nil
+		r.retryAt = time.Time{}
+	}
+}
+
+// call runs the request with the current client. It is run once more with a new client if the credentials have been rejected.
+func (r *refreshingClient) call(ctx context.Context, request func(c client.Client) error) error {
+	c, generation, err := r.current(ctx)
+	if err != nil {
+		return err
+	}
+	err = request(c)
+	if !k8serrors.IsUnauthorized(err) {
+		return err
+	}
+
+	log.Println("The cluster rejected the credentials, the kubernetes client is built again")
+	r.invalidate(generation)
+	c, _, errRefresh := r.current(ctx)
+	if errRefresh != nil {
+		return err
+	}
+	return request(c)
+}
+
+func (r *refreshingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return r.call(ctx, func(c client.Client) error { return c.Get(ctx, key, obj, opts...) })
+}
+
+func (r *refreshingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return r.call(ctx, func(c client.Client) error { return c.List(ctx, list, opts...) })
+}
+
+func (r *refreshingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return r.call(ctx, func(c client.Client) error { return c.Create(ctx, obj, opts...) })
+}
+
+func (r *refreshingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return r.call(ctx, func(c client.Client) error { return c.Delete(ctx, obj, opts...) })
+}
+
+func (r *refreshingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return r.call(ctx, func(c client.Client) error { return c.Update(ctx, obj, opts...) })
+}
+
+func (r *refreshingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return r.call(ctx, func(c client.Client) error { return c.Patch(ctx, obj, patch, opts...) })
+}
+
+func (r *refreshingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	return r.call(ctx, func(c client.Client) error { return c.DeleteAllOf(ctx, obj, opts...) })
+}
+
+func (r *refreshingClient) Status() client.SubResourceWriter {
+	return r.SubResource("status")
+}
+
+func (r *refreshingClient) SubResource(subResource string) client.SubResourceClient {
+	return &subResourceClient{client: r, name: subResource}
+}
+
+func (r *refreshingClient) Scheme() *runtime.Scheme {
+	return r.scheme
+}
+
+// RESTMapper returns nil if the client can not be built
+func (r *refreshingClient) RESTMapper() meta.RESTMapper {
+	c, _, err := r.current(context.Background())
+	if err != nil {
+		return nil
+	}
+	return c.RESTMapper()
+}
+
+func (r *refreshingClient) GroupVersionKindFor(obj runtime.Object) (schema.GroupVersionKind, error) {
+	return apiutil.GVKForObject(obj, r.scheme)
+}
+
+func (r *refreshingClient) IsObjectNamespaced(obj runtime.Object) (bool, error) {
+	c, _, err := r.current(context.Background())
+	if err != nil {
+		return false, err
+	}
+	return c.IsObjectNamespaced(obj)
+}
+
+// subResourceClient calls a sub resource with the current client
+type subResourceClient struct {
+	client *refreshingClient
+	name   string
+}
+
+func (s *subResourceClient) Get(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceGetOption) error {
+	return s.client.call(ctx, func(c client.Client) error { return c.SubResource(s.name).Get(ctx, obj, subResource, opts...) })
+}
+
+func (s *subResourceClient) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	return s.client.call(ctx, func(c client.Client) error { return c.SubResource(s.name).Create(ctx, obj, subResource, opts...) })
+}
+
+func (s *subResourceClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	return s.client.call(ctx, func(c client.Client) error { return c.SubResource(s.name).Update(ctx, obj, opts...) })
+}
+
+func (s *subResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	return s.client.call(ctx, func(c client.Client) error { return c.SubResource(s.name).Patch(ctx, obj, patch, opts...) })
+}
+
+// RefreshingClient returns a client which is built by build. It is built again once per refresh interval, 0 disables it,
+// and whenever the cluster rejects the credentials. The first client is built immediately, if this fails, the calls fail
+// and the client is built again by the first call after the retry delay.
+func RefreshingClient(scheme *runtime.Scheme, refreshInterval time.Duration, build func(ctx context.Context) (client.Client, error)) client.Client {
+	r := &refreshingClient{
+		build:    build,
+		scheme:   scheme,
+		interval: refreshInterval,
+	}
+	_, _, _ = r.current(context.Background())
+	return r
+}
+
+// Client returns a client of the cluster of the provider, which is built again with new credentials when they expire.
+func Client(provider IConfigProvider, scheme *runtime.Scheme, refreshInterval time.Duration) client.Client {
+	return RefreshingClient(scheme, refreshInterval, func(ctx context.Context) (client.Client, error) {
+		restConfig, err := provider.RestConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.New(restConfig, client.Options{Scheme: scheme})
+	})
+}
@@ -0,0 +1,94 @@
+package kube
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// ProviderType selects how the rest config of the cluster is obtained
+type ProviderType string
+
+const (
+	//The config of the environment (--kubeconfig, KUBECONFIG, in-cluster, ~/.kube/config), AKS if there is none
+	Provider_Auto ProviderType = "auto"
+	//The service account of the pod
+	Provider_InCluster ProviderType = "in-cluster"
+	//A kubeconfig file, e.g. a mounted secret, and optionally one of its contexts
+	Provider_Kubeconfig ProviderType = "kubeconfig"
+	//The user credentials of an AKS cluster, requested with the azure credentials of the api
+	Provider_AKS ProviderType = "aks"
+	//An exec credential plugin, e.g. "aws eks get-token" or "kubelogin get-token"
+	Provider_Exec ProviderType = "exec"
+)
+
+func (p ProviderType) IsValid() bool {
+	switch p {
+	case Provider_Auto, Provider_InCluster, Provider_Kubeconfig, Provider_AKS, Provider_Exec:
+		return true
+	}
+	return false
+}
+
+// Config selects and configures the provider of the kubeconfig.
+type Config struct {
+	Provider ProviderType
+	//The client is rebuilt with new credentials once per interval, 0 only rebuilds it if the cluster rejects the credentials
+	RefreshInterval time.Duration
+
+	//Provider_Kubeconfig, the default loading rules are used if the path is empty and the current context if the context is empty
+	KubeconfigPath string
+	Context        string
+
+	//Provider_AKS
+	SubscriptionID string
+	ResourceGroup  string
+	ClusterName    string
+
+	//Provider_Exec
+	Server      string
+	CAFile      string
+	Command     string
+	Args        []string
+	ExecVersion string
+}
+
+// ConfigFromEnv reads the config from the environment variables KUBE_PROVIDER, KUBE_REFRESH_INTERVAL, KUBECONFIG, KUBE_CONTEXT,
+// AZURERM_SUBSCRIPTION_ID, AZURERM_RESOURCE_GROUP_NAME, AZURE_AKS_CLUSTER_NAME, KUBE_SERVER, KUBE_CA_FILE,
+// KUBE_EXEC_COMMAND, KUBE_EXEC_ARGS (separated by spaces) and KUBE_EXEC_API_VERSION.
+func ConfigFromEnv() Config {
+	config := Config{
+		Provider:       ProviderType(os.Getenv("KUBE_PROVIDER")),
+		KubeconfigPath: os.Getenv("KUBECONFIG"),
+		Context:        os.Getenv("KUBE_CONTEXT"),
+		SubscriptionID: os.Getenv("AZURERM_SUBSCRIPTION_ID"),
+		ResourceGroup:  os.Getenv("AZURERM_RESOURCE_GROUP_NAME"),
+		ClusterName:    os.Getenv("AZURE_AKS_CLUSTER_NAME"),
+		Server:         os.Getenv("KUBE_SERVER"),
+		CAFile:         os.Getenv("KUBE_CA_FILE"),
+		Command:        os.Getenv("KUBE_EXEC_COMMAND"),
+		Args:           strings.Fields(os.Getenv("KUBE_EXEC_ARGS")),
+		ExecVersion:    os.Getenv("KUBE_EXEC_API_VERSION"),
+	}
+	if config.Provider == "" {
+		config.Provider = Provider_Auto
+	}
+	if !config.Provider.IsValid() {
+		log.Fatalf("Invalid KUBE_PROVIDER %s, valid providers are auto, in-cluster, kubeconfig, aks and exec", config.Provider)
+	}
+	if config.ExecVersion == "" {
+		config.ExecVersion = "client.authentication.k8s.io/v1"
+	}
+	if config.Provider == Provider_Exec && (config.Server == "" || config.Command == "") {
+		log.Fatalf("KUBE_PROVIDER exec needs KUBE_SERVER and KUBE_EXEC_COMMAND")
+	}
+	if value := os.Getenv("KUBE_REFRESH_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			log.Fatalf("Invalid KUBE_REFRESH_INTERVAL %s", value)
+		}
+		config.RefreshInterval = interval
+	}
+	return config
+}
@@ -0,0 +1,126 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v5"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"log"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// IConfigProvider returns the rest config of the cluster. It is asked again whenever the client is rebuilt,
+// so it must return fresh credentials.
+type IConfigProvider interface {
+	RestConfig(ctx context.Context) (*rest.Config, error)
+}
+
+type inClusterProvider struct{}
+
+func (inClusterProvider) RestConfig(_ context.Context) (*rest.Config, error) {
+	return rest.InClusterConfig()
+}
+
+type kubeconfigProvider struct {
+	path    string
+	context string
+}
+
+// RestConfig reads the file again, so a rotated secret is used after the next refresh
+func (k kubeconfigProvider) RestConfig(_ context.Context) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if k.path != "" {
+		rules = &clientcmd.ClientConfigLoadingRules{ExplicitPath: k.path}
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: k.context}).ClientConfig()
+}
+
+type aksProvider struct {
+	subscriptionID string
+	resourceGroup  string
+	clusterName    string
+}
+
+// RestConfig requests the user credentials of the cluster with the azure credentials of the api
+func (a aksProvider) RestConfig(ctx context.Context) (*rest.Config, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain a credential: %w", err)
+	}
+	clientFactory, err := armcontainerservice.NewClientFactory(a.subscriptionID, credential, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	response, err := clientFactory.NewManagedClustersClient().ListClusterUserCredentials(ctx, a.resourceGroup, a.clusterName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the cluster user credentials: %w", err)
+	}
+	if len(response.Kubeconfigs) == 0 {
+		return nil, errors.New("the kubeconfig request was successful but its response body is empty")
+	}
+	if len(response.Kubeconfigs) > 1 {
+		log.Println("WARNING: Multiple kube-config's have been found. The first one will be used.")
+	}
+
+	clientConfig, err := clientcmd.NewClientConfigFromBytes(response.Kubeconfigs[0].Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client config: %w", err)
+	}
+	return clientConfig.ClientConfig()
+}
+
+// execProvider authenticates with a credential plugin, client-go runs it again when the credential expires
+type execProvider struct {
+	server     string
+	caFile     string
+	command    string
+	args       []string
+	apiVersion string
+}
+
+func (e execProvider) RestConfig(_ context.Context) (*rest.Config, error) {
+	return &rest.Config{
+		Host:            e.server,
+		TLSClientConfig: rest.TLSClientConfig{CAFile: e.caFile},
+		ExecProvider: &clientcmdapi.ExecConfig{
+			Command:         e.command,
+			Args:            e.args,
+			APIVersion:      e.apiVersion,
+			InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+		},
+	}, nil
+}
+
+// autoProvider uses the config of the environment and falls back to AKS
+type autoProvider struct {
+	aks aksProvider
+}
+
+func (a autoProvider) RestConfig(ctx context.Context) (*rest.Config, error) {
+	restConfig, err := ctrlconfig.GetConfig()
+	if err == nil && restConfig != nil {
+		return restConfig, nil
+	}
+	return a.aks.RestConfig(ctx)
+}
+
+// Provider returns the provider which is selected by the config.
+func Provider(config Config) IConfigProvider {
+	aks := aksProvider{subscriptionID: config.SubscriptionID, resourceGroup: config.ResourceGroup, clusterName: config.ClusterName}
+	switch config.Provider {
+	case Provider_InCluster:
+		return inClusterProvider{}
+	case Provider_Kubeconfig:
+		return kubeconfigProvider{path: config.KubeconfigPath, context: config.Context}
+	case Provider_AKS:
+		return aks
+	case Provider_Exec:
+		return execProvider{server: config.Server, caFile: config.CAFile, command: config.Command, args: config.Args, apiVersion: config.ExecVersion}
+	default:
+		return autoProvider{aks: aks}
+	}
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// AdminSubjectsFromEnv reads the subjects of the administrators from the environment variable ADMIN_SUBJECTS,
+// separated by commas.
+func AdminSubjectsFromEnv() []string {
+	return splitList(os.Getenv("ADMIN_SUBJECTS"))
+}
+
+// AdminMiddleware only lets administrators pass. It must run after the authorization, which sets the subject.
+func AdminMiddleware(subjects []string) gin.HandlerFunc {
+	admins := map[string]bool{}
+	for _, subject := range subjects {
+		admins[subject] = true
+	}
+
+	return func(c *gin.Context) {
+		if !admins[c.GetString("subject")] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "You don't have permission to access this resource"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// TokenMiddleware only lets requests pass, which send the token as bearer token.
+// It is used by services like the coordinators, which don't have a user. An empty token rejects all requests.
+func TokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid token"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,240 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CORSConfig describes which cross-origin requests are allowed.
+type CORSConfig struct {
+	//Origins which are allowed to access the api, e.g. "https://example.com" or "https://*.example.com".
+	//A single "*" allows every origin.
+	AllowedOrigins []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	//Allow-Credentials must never be combined with a wildcard origin.
+	AllowCredentials bool
+	//How long (in seconds) the browser is allowed to cache the preflight response.
+	MaxAge int
+}
+
+// CORSConfigFromEnv reads the CORS policy from the environment variables
+// CORS_ALLOWED_ORIGINS, CORS_ALLOWED_HEADERS, CORS_EXPOSED_HEADERS, CORS_ALLOW_CREDENTIALS and CORS_MAX_AGE.
+func CORSConfigFromEnv() CORSConfig {
+	config := CORSConfig{
+		AllowedOrigins:   splitList(getEnvOrDefault("CORS_ALLOWED_ORIGINS", "*")),
+		AllowedHeaders:   splitList(getEnvOrDefault("CORS_ALLOWED_HEADERS", "Origin, Content-Type, Content-Length, Accept-Encoding, Authorization, If-Match, If-None-Match, Range, If-Range")),
+		ExposedHeaders:   splitList(getEnvOrDefault("CORS_EXPOSED_HEADERS", "Content-Length, Content-Location, ETag, Content-Disposition, Content-Range")),
+		AllowCredentials: getEnvOrDefault("CORS_ALLOW_CREDENTIALS", "false") == "true",
+		MaxAge:           86400,
+	}
+
+	if maxAge := os.Getenv("CORS_MAX_AGE"); maxAge != "" {
+		value, err := strconv.Atoi(maxAge)
+		if err != nil {
+			log.Printf("Invalid CORS_MAX_AGE %s, using default of %d seconds", maxAge, config.MaxAge)
+		} else {
+			config.MaxAge = value
+		}
+	}
+
+	return config
+}
+
+// CORSMiddleware answers preflight requests and adds the CORS headers to every response
+// whose origin is allowed by the config. The allowed methods are taken from the routes
+// registered on the engine for the requested path, so the routes have to be registered
+// before the first request is served.
+func CORSMiddleware(config CORSConfig, engine *gin.Engine) gin.HandlerFunc {
+	wildcard := false
+	for _, origin := range config.AllowedOrigins {
+		if origin == "*" {
+			wildcard = true
+		}
+	}
+	if wildcard && config.AllowCredentials {
+		log.Println("WARNING: CORS_ALLOW_CREDENTIALS can not be used together with a wildcard origin and has been disabled.")
+		config.AllowCredentials = false
+	}
+
+	routes := routeMethods{engine: engine}
+	allowedHeaders := strings.Join(config.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(config.MaxAge)
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		//Not a cross-origin request, nothing to do
+		if origin == "" {
+			if preflight {
+				c.AbortWithStatus(http.StatusNoContent)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+
+		if !isOriginAllowed(origin, config.AllowedOrigins) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			//The browser will block the response, because the CORS headers are missing
+			c.Next()
+			return
+		}
+
+		if wildcard {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			methods := routes.methodsFor(c.Request.URL.Path)
+			if len(methods) == 0 {
+				c.AbortWithStatus(http.StatusNotFound)
+				return
+			}
+			header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			header.Set("Access-Control-Allow-Headers", allowedHeaders)
+			header.Set("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposedHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", exposedHeaders)
+		}
+		c.Next()
+	}
+}
+
+// isOriginAllowed returns true if the origin matches one of the allowed origins.
+// Allowed origins may contain a wildcard subdomain like "https://*.example.com",
+// which matches every subdomain of example.com but not example.com itself.
+func isOriginAllowed(origin string, allowedOrigins []string) bool {
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return false
+	}
+
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		pattern, err := url.Parse(allowed)
+		if err != nil || !strings.HasPrefix(pattern.Host, "*.") {
+			continue
+		}
+		if !strings.EqualFold(pattern.Scheme, parsed.Scheme) {
+			continue
+		}
+		suffix := strings.ToLower(pattern.Host[1:])
+		host := strings.ToLower(parsed.Host)
+		if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeMethods caches the methods registered per route of a gin engine.
+type routeMethods struct {
+	engine *gin.Engine
+	once   sync.Once
+	routes map[string][]string
+}
+
+// methodsFor returns the methods which are registered for the path, including OPTIONS.
+func (r *routeMethods) methodsFor(path string) []string {
+	r.once.Do(func() {
+		r.routes = map[string][]string{}
+		for _, route := range r.engine.Routes() {
+			r.routes[route.Path] = append(r.routes[route.Path], route.Method)
+		}
+	})
+
+	methods := map[string]bool{}
+	for pattern, routeMethods := range r.routes {
+		if matchRoute(pattern, path) {
+			for _, method := range routeMethods {
+				methods[method] = true
+			}
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+	methods[http.MethodOptions] = true
+
+	var result []string
+	for method := range methods {
+		result = append(result, method)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// matchRoute checks if a request path matches a gin route pattern like /games/:id.
+func matchRoute(pattern string, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "*") {
+			return true
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if strings.HasPrefix(segment, ":") {
+			continue
+		}
+		//A parameter inside of a segment like /games:action matches everything after the prefix
+		if index := strings.Index(segment, ":"); index > 0 {
+			if !strings.HasPrefix(pathSegments[i], segment[:index]) {
+				return false
+			}
+			continue
+		}
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
+	return len(patternSegments) == len(pathSegments)
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func getEnvOrDefault(key string, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,183 @@
+package middlewares
+
+import (
+	"api/repositories"
+	"api/shared"
+	"database/sql"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IRateLimitStore holds the token buckets of the rate limiter.
+// The in-memory store is only correct for a single replica, use a shared store for multi-replica deployments.
+type IRateLimitStore interface {
+	Take(key string, policy shared.RateLimitPolicy, now time.Time) (shared.RateLimitResult, error)
+}
+
+// RateLimitConfig contains the policies for the different kinds of routes.
+type RateLimitConfig struct {
+	Enabled bool
+	//"memory" or "mysql"
+	Backend string
+	Reads   shared.RateLimitPolicy
+	Writes  shared.RateLimitPolicy
+	Uploads shared.RateLimitPolicy
+	Deletes shared.RateLimitPolicy
+	//Batches limits the batch operations, which change up to BATCH_MAX_ITEMS games at once
+	Batches shared.RateLimitPolicy
+}
+
+// RateLimitConfigFromEnv reads the rate limit config from the environment variables
+// RATE_LIMIT_ENABLED, RATE_LIMIT_BACKEND, RATE_LIMIT_READS, RATE_LIMIT_WRITES, RATE_LIMIT_UPLOADS, RATE_LIMIT_DELETES
+// and RATE_LIMIT_BATCHES.
+// The limits have the format "<limit>/<window>", e.g. "10/1h".
+func RateLimitConfigFromEnv() RateLimitConfig {
+	return RateLimitConfig{
+		Enabled: getEnvOrDefault("RATE_LIMIT_ENABLED", "true") == "true",
+		Backend: getEnvOrDefault("RATE_LIMIT_BACKEND", "memory"),
+		Reads:   policyFromEnv("reads", "RATE_LIMIT_READS", "120/1m"),
+		Writes:  policyFromEnv("writes", "RATE_LIMIT_WRITES", "30/1m"),
+		Uploads: policyFromEnv("uploads", "RATE_LIMIT_UPLOADS", "10/1h"),
+		Deletes: policyFromEnv("deletes", "RATE_LIMIT_DELETES", "30/1m"),
+		Batches: policyFromEnv("batches", "RATE_LIMIT_BATCHES", "10/1h"),
+	}
+}
+
+func policyFromEnv(name string, key string, defaultValue string) shared.RateLimitPolicy {
+	policy, err := shared.ParseRateLimitPolicy(name, getEnvOrDefault(key, defaultValue))
+	if err != nil {
+		log.Printf("%s: %s, using default %s", key, err, defaultValue)
+		policy, _ = shared.ParseRateLimitPolicy(name, defaultValue)
+	}
+	return policy
+}
+
+// RateLimitStore creates the store which has been configured as backend.
+func RateLimitStore(config RateLimitConfig, db *sql.DB) IRateLimitStore {
+	switch config.Backend {
+	case "mysql":
+		repository := repositories.RateLimitRepository(db)
+		//Buckets which have not been used for the longest window are full and can be removed
+		idle := maxWindow(config.Reads, config.Writes, config.Uploads, config.Deletes, config.Batches)
+		go func() {
+			for range time.Tick(time.Hour) {
+				err := repository.DeleteIdle(time.Now().Add(-idle))
+				if err != nil {
+					log.Println(fmt.Sprintf("Deleting idle rate limit buckets failed: %s", err))
+				}
+			}
+		}()
+		return repository
+	case "memory", "":
+		return MemoryRateLimitStore()
+	default:
+		log.Fatalf("Unknown RATE_LIMIT_BACKEND %s", config.Backend)
+		return nil
+	}
+}
+
+// RateLimitMiddleware limits the requests per authenticated subject, or per client ip if the request is not authenticated.
+// It must be registered after the authorization, otherwise all requests are limited per ip.
+// If the store fails, the request is let through.
+func RateLimitMiddleware(store IRateLimitStore, policy shared.RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := fmt.Sprintf("%s:ip:%s", policy.Name, c.ClientIP())
+		if sub := c.GetString("subject"); sub != "" {
+			key = fmt.Sprintf("%s:sub:%s", policy.Name, sub)
+		}
+
+		result, err := store.Take(key, policy, time.Now())
+		if err != nil {
+			log.Println(fmt.Sprintf("Rate limiter failed, request will be allowed: %s", err))
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds())))
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"message": "Too many requests, please try again later"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func maxWindow(policies ...shared.RateLimitPolicy) time.Duration {
+	var window time.Duration
+	for _, policy := range policies {
+		if policy.Window > window {
+			window = policy.Window
+		}
+	}
+	return window
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type bucket struct {
+	tokens     float64
+	lastUpdate time.Time
+	window     time.Duration
+}
+
+type memoryRateLimitStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+// MemoryRateLimitStore creates a store which keeps the buckets in memory.
+// Buckets which have been refilled completely are removed periodically.
+func MemoryRateLimitStore() IRateLimitStore {
+	store := &memoryRateLimitStore{
+		buckets: map[string]*bucket{},
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			store.deleteFullBuckets(time.Now())
+		}
+	}()
+	return store
+}
+
+func (s *memoryRateLimitStore) Take(key string, policy shared.RateLimitPolicy, now time.Time) (shared.RateLimitResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Limit), lastUpdate: now}
+		s.buckets[key] = b
+	}
+
+	tokens, result := shared.TakeToken(policy, b.tokens, b.lastUpdate, now)
+	b.tokens = tokens
+	b.lastUpdate = now
+	b.window = policy.Window
+	return result, nil
+}
+
+// deleteFullBuckets removes all buckets which have not been used for a whole window, they are full anyway.
+func (s *memoryRateLimitStore) deleteFullBuckets(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key, b := range s.buckets {
+		if now.Sub(b.lastUpdate) > b.window {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"os"
+	"strconv"
+)
+
+// SecurityHeadersConfig describes which security headers are added to every response.
+type SecurityHeadersConfig struct {
+	//Max-Age of the Strict-Transport-Security header in seconds. 0 disables the header.
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	//Sets X-Content-Type-Options: nosniff
+	NoSniff bool
+	//Value of the frame-ancestors directive of the Content-Security-Policy header. Empty disables the header.
+	FrameAncestors string
+}
+
+// SecurityHeadersConfigFromEnv reads the security headers config from the environment variables
+// SECURITY_HSTS_MAX_AGE, SECURITY_HSTS_INCLUDE_SUBDOMAINS, SECURITY_NOSNIFF and SECURITY_FRAME_ANCESTORS.
+func SecurityHeadersConfigFromEnv() SecurityHeadersConfig {
+	config := SecurityHeadersConfig{
+		HSTSIncludeSubdomains: getEnvOrDefault("SECURITY_HSTS_INCLUDE_SUBDOMAINS", "false") == "true",
+		NoSniff:               getEnvOrDefault("SECURITY_NOSNIFF", "true") == "true",
+		FrameAncestors:        getEnvOrDefault("SECURITY_FRAME_ANCESTORS", "'none'"),
+	}
+
+	if maxAge := os.Getenv("SECURITY_HSTS_MAX_AGE"); maxAge != "" {
+		value, err := strconv.Atoi(maxAge)
+		if err != nil {
+			log.Printf("Invalid SECURITY_HSTS_MAX_AGE %s, HSTS will be disabled", maxAge)
+		} else {
+			config.HSTSMaxAge = value
+		}
+	}
+
+	return config
+}
+
+// SecurityHeadersMiddleware adds the configured security headers to every response.
+func SecurityHeadersMiddleware(config SecurityHeadersConfig) gin.HandlerFunc {
+	hsts := ""
+	if config.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", config.HSTSMaxAge)
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		if hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if config.NoSniff {
+			header.Set("X-Content-Type-Options", "nosniff")
+		}
+		if config.FrameAncestors != "" {
+			header.Set("Content-Security-Policy", fmt.Sprintf("frame-ancestors %s", config.FrameAncestors))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,8 @@
+ALTER TABLE games ADD Description varchar(4000) NOT NULL DEFAULT '';
+ALTER TABLE games ADD Tags varchar(1024) NOT NULL DEFAULT '';
+ALTER TABLE games ADD Platform varchar(64) NOT NULL DEFAULT '';
+
+CREATE FULLTEXT INDEX games_search ON games (Title, Description, Tags, Platform);
+CREATE FULLTEXT INDEX games_title_search ON games (Title);
+
+INSERT INTO db_state VALUES (10);
\ No newline at end of file
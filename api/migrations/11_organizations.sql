@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    ID varchar(36) NOT NULL primary key,
+    Name varchar(255) NOT NULL,
+    CreatedBy varchar(255) NOT NULL,
+    CreatedAt datetime NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS org_members (
+    OrgID varchar(36) NOT NULL,
+    Subject varchar(255) NOT NULL,
+    Role varchar(16) NOT NULL,
+    JoinedAt datetime NOT NULL,
+    PRIMARY KEY (OrgID, Subject),
+    INDEX org_members_subject (Subject),
+    FOREIGN KEY (OrgID) REFERENCES organizations(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS org_invitations (
+    ID varchar(36) NOT NULL primary key,
+    OrgID varchar(36) NOT NULL,
+    Email varchar(320) NOT NULL,
+    Role varchar(16) NOT NULL,
+    TokenHash char(64) NOT NULL UNIQUE,
+    InvitedBy varchar(255) NOT NULL,
+    CreatedAt datetime NOT NULL,
+    ExpiresAt datetime NOT NULL,
+    AcceptedAt datetime NULL,
+    AcceptedBy varchar(255) NULL,
+    INDEX org_invitations_org (OrgID),
+    FOREIGN KEY (OrgID) REFERENCES organizations(ID) ON DELETE CASCADE
+);
+
+INSERT INTO db_state VALUES (11);
\ No newline at end of file
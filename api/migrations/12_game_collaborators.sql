@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS game_collaborators (
+    ID varchar(36) NOT NULL primary key,
+    GameID varchar(36) NOT NULL,
+    Subject varchar(255) NULL,
+    Email varchar(320) NULL,
+    Permissions varchar(64) NOT NULL,
+    GrantedBy varchar(255) NOT NULL,
+    GrantedAt datetime NOT NULL,
+    UNIQUE (GameID, Subject),
+    UNIQUE (GameID, Email),
+    INDEX game_collaborators_subject (Subject),
+    INDEX game_collaborators_email (Email),
+    FOREIGN KEY (GameID) REFERENCES games(ID) ON DELETE CASCADE
+);
+
+INSERT INTO db_state VALUES (12);
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS play_sessions (
+    ID varchar(36) NOT NULL primary key,
+    SessionKey varchar(128) NOT NULL,
+    GameID varchar(36) NOT NULL,
+    Owner varchar(255) NOT NULL,
+    Player varchar(255) NULL,
+    StartedAt datetime NOT NULL,
+    LastSeenAt datetime NOT NULL,
+    EndedAt datetime NULL,
+    UNIQUE (GameID, SessionKey),
+    INDEX play_sessions_game (GameID, StartedAt),
+    INDEX play_sessions_started (StartedAt)
+);
+
+INSERT INTO db_state VALUES (13);
\ No newline at end of file
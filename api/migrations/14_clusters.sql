@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS clusters (
+    Name varchar(63) NOT NULL primary key,
+    Region varchar(63) NOT NULL DEFAULT '',
+    Capacity int NOT NULL DEFAULT 0,
+    Labels varchar(1024) NOT NULL DEFAULT '',
+    KubeconfigPath varchar(512) NOT NULL DEFAULT '',
+    KubeContext varchar(255) NOT NULL DEFAULT '',
+    Cordoned boolean NOT NULL DEFAULT FALSE,
+    CreatedAt datetime NOT NULL
+);
+
+INSERT INTO clusters (Name, CreatedAt) VALUES ('default', NOW());
+
+ALTER TABLE games ADD Cluster varchar(63) NOT NULL DEFAULT 'default';
+ALTER TABLE games ADD Region varchar(63) NOT NULL DEFAULT '';
+CREATE INDEX games_cluster ON games (Cluster);
+
+INSERT INTO db_state VALUES (14);
\ No newline at end of file
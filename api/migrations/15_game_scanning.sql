@@ -0,0 +1,3 @@
+ALTER TABLE games ADD StatusReason varchar(1024) NOT NULL DEFAULT '';
+
+INSERT INTO db_state VALUES (15);
\ No newline at end of file
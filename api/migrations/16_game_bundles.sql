@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS blob_files (
+    BlobHash varchar(64) NOT NULL,
+    Path varchar(512) NOT NULL,
+    Hash varchar(64) NOT NULL,
+    Size bigint NOT NULL,
+    primary key (BlobHash, Path)
+);
+
+ALTER TABLE games ADD Bios varchar(2048) NOT NULL DEFAULT '';
+ALTER TABLE game_versions ADD Bios varchar(2048) NOT NULL DEFAULT '';
+
+INSERT INTO db_state VALUES (16);
\ No newline at end of file
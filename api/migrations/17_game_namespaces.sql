@@ -0,0 +1,3 @@
+ALTER TABLE games ADD Namespace varchar(63) NOT NULL DEFAULT '';
+
+INSERT INTO db_state VALUES (17);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE games ADD DeployRevision int NOT NULL DEFAULT 0;
+UPDATE games SET DeployRevision = Revision;
+
+INSERT INTO db_state VALUES (18);
\ No newline at end of file
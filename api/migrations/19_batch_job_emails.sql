@@ -0,0 +1,3 @@
+ALTER TABLE batch_jobs ADD Email varchar(255) NOT NULL DEFAULT '';
+
+INSERT INTO db_state VALUES (19);
\ No newline at end of file
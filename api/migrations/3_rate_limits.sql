@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    BucketKey varchar(255) NOT NULL primary key,
+    Tokens double NOT NULL,
+    UpdatedAt bigint NOT NULL
+);
+
+INSERT INTO db_state VALUES (3);
\ No newline at end of file
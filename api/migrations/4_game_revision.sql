@@ -0,0 +1,2 @@
+ALTER TABLE games ADD Revision int NOT NULL DEFAULT 1;
+INSERT INTO db_state VALUES (4);
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS game_versions (
+    ID varchar(36) NOT NULL primary key,
+    GameID varchar(36) NOT NULL,
+    Version int NOT NULL,
+    BlobName varchar(512) NOT NULL,
+    StorageLocation varchar(255),
+    FileName varchar(512),
+    Checksum varchar(64),
+    Changelog text,
+    Uploader varchar(255),
+    CreatedAt datetime NOT NULL,
+    UNIQUE (GameID, Version)
+);
+
+ALTER TABLE games ADD BlobName varchar(512) NOT NULL DEFAULT '';
+ALTER TABLE games ADD LiveVersion int NOT NULL DEFAULT 1;
+ALTER TABLE games ADD BetaVersion int NOT NULL DEFAULT 0;
+ALTER TABLE games ADD BetaUrl varchar(255) NOT NULL DEFAULT '';
+
+INSERT INTO game_versions (ID, GameID, Version, BlobName, StorageLocation, FileName, Checksum, Changelog, Uploader, CreatedAt)
+SELECT UUID(), ID, 1, ID, StorageLocation, FileName, '', '', Owner, NOW() FROM games;
+
+INSERT INTO db_state VALUES (5);
\ No newline at end of file
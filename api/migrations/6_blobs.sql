@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS blobs (
+    Hash varchar(64) NOT NULL primary key,
+    BlobName varchar(512) NOT NULL,
+    StorageLocation varchar(255) NOT NULL,
+    Size bigint NOT NULL,
+    RefCount int NOT NULL,
+    Status varchar(32) NOT NULL,
+    VerifiedAt datetime NULL,
+    CreatedAt datetime NOT NULL
+);
+
+ALTER TABLE games ADD Checksum varchar(64) NOT NULL DEFAULT '';
+
+INSERT INTO db_state VALUES (6);
\ No newline at end of file
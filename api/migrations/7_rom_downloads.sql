@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS rom_downloads (
+    ID varchar(36) NOT NULL primary key,
+    GameID varchar(36) NOT NULL,
+    Version int NOT NULL,
+    Subject varchar(255) NOT NULL,
+    ClientIP varchar(45) NOT NULL,
+    Method varchar(16) NOT NULL,
+    CreatedAt datetime NOT NULL,
+    INDEX (GameID)
+);
+
+INSERT INTO db_state VALUES (7);
\ No newline at end of file
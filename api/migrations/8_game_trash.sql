@@ -0,0 +1,4 @@
+ALTER TABLE games ADD DeletedAt datetime NULL;
+CREATE INDEX games_deleted_at ON games (DeletedAt);
+
+INSERT INTO db_state VALUES (8);
\ No newline at end of file
@@ -0,0 +1,16 @@
+ALTER TABLE games ADD Visibility varchar(16) NOT NULL DEFAULT 'private';
+
+CREATE TABLE IF NOT EXISTS batch_jobs (
+    ID varchar(36) NOT NULL primary key,
+    Owner varchar(255) NOT NULL,
+    Operation varchar(32) NOT NULL,
+    Status varchar(16) NOT NULL,
+    Total int NOT NULL,
+    Succeeded int NOT NULL,
+    Failed int NOT NULL,
+    Results mediumtext NOT NULL,
+    CreatedAt datetime NOT NULL,
+    FinishedAt datetime NULL
+);
+
+INSERT INTO db_state VALUES (9);
\ No newline at end of file
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"api/shared"
+	"github.com/google/uuid"
+	"time"
+)
+
+// BatchJob is an operation on many games, which runs in the background.
+type BatchJob struct {
+	ID         uuid.UUID             `json:"id"`
+	Owner      string                `json:"owner"`
+	Operation  shared.BatchOperation `json:"operation"`
+	Status     shared.JobStatus      `json:"status"`
+	Total      int                   `json:"total"`
+	Succeeded  int                   `json:"succeeded"`
+	Failed     int                   `json:"failed"`
+	Results    []BatchItemResult     `json:"results"`
+	CreatedAt  time.Time             `json:"createdAt"`
+	FinishedAt *time.Time            `json:"finishedAt"`
+	//Email is the verified email of the owner, so the games which have been shared with the email can be changed
+	Email string `json:"-"`
+}
+
+// BatchItemResult is the result of the operation on one game of a batch.
+type BatchItemResult struct {
+	ID      uuid.UUID           `json:"id"`
+	Outcome shared.BatchOutcome `json:"outcome"`
+	Error   string              `json:"error,omitempty"`
+}
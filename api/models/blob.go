@@ -0,0 +1,22 @@
+package models
+
+import (
+	"api/shared"
+	"time"
+)
+
+// Blob is a game file in the blob storage, which is addressed by the SHA-256 hash of its content.
+// It is shared by all game versions with the same content. A bundle of several files is a blob as well,
+// its files are stored below its name and its hash is computed from the paths and the hashes of its files.
+type Blob struct {
+	Hash            string `json:"hash"`
+	BlobName        string `json:"blobName"`
+	StorageLocation string `json:"storageLocation"`
+	Size            int64  `json:"size"`
+	//RefCount is the number of game versions which are using the blob
+	RefCount int               `json:"refCount"`
+	Status   shared.BlobStatus `json:"status"`
+	//VerifiedAt is nil if the blob has never been verified
+	VerifiedAt *time.Time `json:"verifiedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
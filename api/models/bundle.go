@@ -0,0 +1,27 @@
+package models
+
+// Bundle describes the files of a game which has been uploaded as archive, e.g. the cue sheet and the bin tracks
+// of a disc image. It is read from the optional file "manifest.json" of the archive.
+type Bundle struct {
+	//Entry is the path of the file which is loaded by the emulator, e.g. game.cue
+	Entry string `json:"entry"`
+	//Bios are the paths of the files which are mounted into the system directory of the emulator
+	Bios []string `json:"bios"`
+}
+
+// BundleFile is a file of a bundle. It is stored below the blob of the bundle under its path.
+type BundleFile struct {
+	Path string `json:"path"`
+	//Hash is the SHA-256 hash of the content of the file
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// ArchiveOptions control how an uploaded file is stored.
+type ArchiveOptions struct {
+	//Extract stores the files of a zip archive as bundle. Otherwise the archive is stored as it is,
+	//e.g. the zipped roms of arcade games.
+	Extract bool
+	//Entry overrides the entry of the manifest of the archive
+	Entry string
+}
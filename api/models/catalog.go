@@ -0,0 +1,64 @@
+package models
+
+import (
+	"api/shared"
+	"github.com/google/uuid"
+	"time"
+)
+
+// CatalogManifest describes the games of an exported catalog. It is the file "manifest.json" of the archive,
+// the game files are stored next to it under the paths of their versions.
+type CatalogManifest struct {
+	//FormatVersion is increased on incompatible changes of the archive
+	FormatVersion int           `json:"formatVersion"`
+	ExportedAt    time.Time     `json:"exportedAt"`
+	Games         []CatalogGame `json:"games"`
+}
+
+// CatalogGame is an exported game with all its versions.
+type CatalogGame struct {
+	ID          uuid.UUID         `json:"id"`
+	Title       string            `json:"title"`
+	Owner       string            `json:"owner"`
+	Visibility  shared.Visibility `json:"visibility"`
+	Description string            `json:"description"`
+	Tags        []string          `json:"tags"`
+	Platform    string            `json:"platform"`
+	LiveVersion int               `json:"liveVersion"`
+	//BetaVersion is 0 if the game has no beta channel
+	BetaVersion int              `json:"betaVersion"`
+	Versions    []CatalogVersion `json:"versions"`
+}
+
+// CatalogVersion is an exported version of a game.
+type CatalogVersion struct {
+	Version  int    `json:"version"`
+	FileName string `json:"fileName"`
+	//Checksum is empty for versions which have been uploaded before content addressing
+	Checksum  string    `json:"checksum"`
+	Changelog string    `json:"changelog"`
+	Uploader  string    `json:"uploader"`
+	CreatedAt time.Time `json:"createdAt"`
+	//File is the path of the game file in the archive, versions with the same content share it.
+	//The files of a bundle are stored below this path.
+	File string `json:"file"`
+	//Files are the paths of the files of a bundle relative to File, it is empty for a single file
+	Files []string `json:"files,omitempty"`
+	Bios  []string `json:"bios,omitempty"`
+}
+
+// CatalogImportResult lists what happened to the games of an imported catalog.
+type CatalogImportResult struct {
+	Imported []CatalogImportItem `json:"imported"`
+	Skipped  []CatalogImportItem `json:"skipped"`
+	Failed   []CatalogImportItem `json:"failed"`
+}
+
+// CatalogImportItem is a game of an imported catalog. ID is the id of the imported game,
+// SourceID the id in the exported catalog.
+type CatalogImportItem struct {
+	SourceID uuid.UUID `json:"sourceId"`
+	ID       uuid.UUID `json:"id"`
+	Title    string    `json:"title"`
+	Reason   string    `json:"reason,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Cluster is a kubernetes cluster on which games are deployed.
+// The cluster shared.DefaultCluster is the cluster of the kubernetes provider of the api.
+type Cluster struct {
+	Name   string `json:"name"`
+	Region string `json:"region"`
+	//Capacity is the maximum number of games, 0 is unlimited
+	Capacity int      `json:"capacity"`
+	Labels   []string `json:"labels"`
+	//The api connects to the cluster with the kubeconfig file and its context, the default cluster has no kubeconfig
+	KubeconfigPath string `json:"kubeconfigPath"`
+	KubeContext    string `json:"kubeContext"`
+	//No games are placed on a cordoned cluster, its games keep running
+	Cordoned  bool      `json:"cordoned"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ClusterLoad is a cluster with the number of its games
+type ClusterLoad struct {
+	Cluster
+	Games int `json:"games"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"api/shared"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Collaborator is a user who has been granted permissions on a single game.
+// The user is identified either by the subject or by the verified email address.
+type Collaborator struct {
+	ID          uuid.UUID           `json:"id"`
+	GameID      uuid.UUID           `json:"gameId"`
+	Subject     *string             `json:"subject"`
+	Email       *string             `json:"email"`
+	Permissions []shared.Permission `json:"permissions"`
+	GrantedBy   string              `json:"grantedBy"`
+	GrantedAt   time.Time           `json:"grantedAt"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"api/shared"
+	"time"
+)
+
+// ConsistencyReport lists the mismatches between the database, the blob storage and kubernetes.
+// Nothing has been repaired if DryRun is true.
+type ConsistencyReport struct {
+	CheckedAt time.Time
+	DryRun    bool
+	Issues    []ConsistencyIssue
+}
+
+// ConsistencyIssue is a single mismatch, GameID is only set if the issue belongs to a known game
+type ConsistencyIssue struct {
+	Kind    shared.InconsistencyKind
+	GameID  string
+	Channel shared.Channel
+	//Cluster, namespace and name of the game resource or name of the blob
+	Cluster   string
+	Namespace string
+	Name      string
+	//Repair is the action which repairs the issue, Repaired is true if it has been done
+	Repair   string
+	Repaired bool
+	Error    string
+}
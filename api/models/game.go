@@ -3,6 +3,7 @@ package models
 import (
 	"api/shared"
 	"github.com/google/uuid"
+	"time"
 )
 
 type Game struct {
@@ -13,4 +14,46 @@ type Game struct {
 	Url             string            `json:"url"`
 	Owner           string            `json:"owner"`
 	FileName        string            `json:"fileName"`
+	//Revision is increased on every change of the metadata or the game file, it is used as ETag
+	Revision int `json:"revision"`
+	//Name of the blob of the live version, the blob is named after the game id if it is empty
+	BlobName    string `json:"blobName"`
+	LiveVersion int    `json:"liveVersion"`
+	//BetaVersion is 0 if the game has no beta channel
+	BetaVersion int    `json:"betaVersion"`
+	BetaUrl     string `json:"betaUrl"`
+	//Checksum is the SHA-256 hash of the game file of the live version
+	Checksum string `json:"checksum"`
+	//DeletedAt is set if the game is in the trash
+	DeletedAt  *time.Time        `json:"deletedAt"`
+	Visibility shared.Visibility `json:"visibility"`
+	//Description, Tags and Platform are searchable
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Platform    string   `json:"platform"`
+	//Cluster on which the game is deployed, Region is the region which has been requested by the uploader
+	Cluster string `json:"cluster"`
+	Region  string `json:"region"`
+	//StatusReason explains the status quarantined or error, e.g. the signature which has been found by the scanner
+	StatusReason string `json:"statusReason"`
+	//Bios are the BIOS files of the live version, if it is a bundle of several files
+	Bios []string `json:"bios"`
+	//Namespace in which the game is deployed, it is kept if the namespace strategy is changed
+	Namespace string `json:"namespace"`
+	//DeployRevision is increased when the live file is replaced or the game is redeployed, it rolls out new pods.
+	//Changes of the metadata only increase the Revision.
+	DeployRevision int `json:"deployRevision"`
+}
+
+// GameMetadata is the metadata of a game which is uploaded.
+type GameMetadata struct {
+	Title       string
+	Description string
+	Tags        []string
+	Platform    string
+	//Region and Cluster request the placement of the game, see services.IClusterService
+	Region  string
+	Cluster string
+	//Archive requests the extraction of an uploaded archive
+	Archive ArchiveOptions
 }
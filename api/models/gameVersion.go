@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// GameVersion is an immutable upload of a game file.
+type GameVersion struct {
+	ID              uuid.UUID `json:"id"`
+	GameID          uuid.UUID `json:"gameId"`
+	Version         int       `json:"version"`
+	BlobName        string    `json:"blobName"`
+	StorageLocation string    `json:"storageLocation"`
+	FileName        string    `json:"fileName"`
+	Checksum        string    `json:"checksum"`
+	Changelog       string    `json:"changelog"`
+	Uploader        string    `json:"uploader"`
+	CreatedAt       time.Time `json:"createdAt"`
+	//Bios are the BIOS files of a bundle, FileName is the entry of the bundle then
+	Bios []string `json:"bios"`
+}
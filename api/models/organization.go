@@ -0,0 +1,45 @@
+package models
+
+import (
+	"api/shared"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Organization owns games, which can be accessed by its members according to their role.
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// OrgMember is a user who is a member of an organization.
+type OrgMember struct {
+	OrgID    uuid.UUID      `json:"orgId"`
+	Subject  string         `json:"subject"`
+	Role     shared.OrgRole `json:"role"`
+	JoinedAt time.Time      `json:"joinedAt"`
+}
+
+// Membership is an organization together with the role of a user in it.
+type Membership struct {
+	Organization Organization   `json:"organization"`
+	Role         shared.OrgRole `json:"role"`
+}
+
+// OrgInvitation invites a user to join an organization.
+// Only the hash of the token is stored, the token itself is only returned when the invitation is created.
+type OrgInvitation struct {
+	ID        uuid.UUID      `json:"id"`
+	OrgID     uuid.UUID      `json:"orgId"`
+	Email     string         `json:"email"`
+	Role      shared.OrgRole `json:"role"`
+	TokenHash string         `json:"tokenHash"`
+	InvitedBy string         `json:"invitedBy"`
+	CreatedAt time.Time      `json:"createdAt"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+	//AcceptedAt is set once the invitation has been accepted
+	AcceptedAt *time.Time `json:"acceptedAt"`
+	AcceptedBy *string    `json:"acceptedBy"`
+}
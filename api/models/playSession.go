@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// PlaySession is a session of a player, which has been reported by a coordinator or the operator.
+type PlaySession struct {
+	ID uuid.UUID `json:"id"`
+	//SessionKey identifies the session at the reporter, it is unique per game
+	SessionKey string    `json:"sessionKey"`
+	GameID     uuid.UUID `json:"gameId"`
+	//Owner of the game when the session has been started
+	Owner string `json:"owner"`
+	//Player is nil if the player is not known
+	Player    *string   `json:"player"`
+	StartedAt time.Time `json:"startedAt"`
+	//LastSeenAt is the end of the session as long as it has not been ended
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	EndedAt    *time.Time `json:"endedAt"`
+}
+
+// UsageEntry is the play time of a game on one day (UTC).
+type UsageEntry struct {
+	Day      string    `json:"day"`
+	GameID   uuid.UUID `json:"gameId"`
+	Owner    string    `json:"owner"`
+	Minutes  float64   `json:"minutes"`
+	Sessions int       `json:"sessions"`
+	//Players is the number of known players
+	Players int `json:"players"`
+}
+
+// GameUsage is the play time of a game in a range of time.
+type GameUsage struct {
+	GameID   uuid.UUID    `json:"gameId"`
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	Minutes  float64      `json:"minutes"`
+	Sessions int          `json:"sessions"`
+	Players  int          `json:"players"`
+	Days     []UsageEntry `json:"days"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"api/shared"
+	"github.com/google/uuid"
+	"time"
+)
+
+// RomDownload is an entry of the download log of the game files.
+type RomDownload struct {
+	ID      uuid.UUID `json:"id"`
+	GameID  uuid.UUID `json:"gameId"`
+	Version int       `json:"version"`
+	//Subject is empty if the file has been downloaded with a signed url
+	Subject   string                `json:"subject"`
+	ClientIP  string                `json:"clientIp"`
+	Method    shared.DownloadMethod `json:"method"`
+	CreatedAt time.Time             `json:"createdAt"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// SearchHit is a game which matches a search, with its relevance.
+type SearchHit struct {
+	Game  Game
+	Score float64
+	//Highlights contains the matching fields, where the matches are wrapped in <mark> tags.
+	//Search engines which do not highlight the matches leave it empty.
+	Highlights map[string]string
+}
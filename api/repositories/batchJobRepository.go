@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"api/models"
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+)
+
+type IBatchJobRepository interface {
+	Create(job *models.BatchJob) error
+	Update(job *models.BatchJob) error
+	FindByID(id uuid.UUID) (*models.BatchJob, error)
+}
+
+type batchJobRepository struct {
+	db *sql.DB
+}
+
+func BatchJobRepository(db *sql.DB) IBatchJobRepository {
+	return &batchJobRepository{
+		db: db,
+	}
+}
+
+// Create saves a new job and sets its id.
+func (b batchJobRepository) Create(job *models.BatchJob) error {
+	job.ID = uuid.New()
+	results, err := json.Marshal(job.Results)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.db.Exec("INSERT INTO batch_jobs (ID, Owner, Operation, Status, Total, Succeeded, Failed, Results, CreatedAt, FinishedAt, Email) VALUES (?,?,?,?,?,?,?,?,?,?,?)",
+		job.ID, job.Owner, job.Operation, job.Status, job.Total, job.Succeeded, job.Failed, string(results), job.CreatedAt, job.FinishedAt, job.Email)
+	return err
+}
+
+// Update saves the progress of a job.
+func (b batchJobRepository) Update(job *models.BatchJob) error {
+	results, err := json.Marshal(job.Results)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.db.Exec("UPDATE batch_jobs SET Status=?, Succeeded=?, Failed=?, Results=?, FinishedAt=? WHERE ID = ?",
+		job.Status, job.Succeeded, job.Failed, string(results), job.FinishedAt, job.ID)
+	return err
+}
+
+// FindByID finds a job with a specific id or nil if the job has not been found.
+func (b batchJobRepository) FindByID(id uuid.UUID) (*models.BatchJob, error) {
+	var job models.BatchJob
+	var results string
+	err := b.db.QueryRow("SELECT * FROM batch_jobs WHERE ID = ?", id).
+		Scan(&job.ID, &job.Owner, &job.Operation, &job.Status, &job.Total, &job.Succeeded, &job.Failed, &results,
+			&job.CreatedAt, &job.FinishedAt, &job.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(results), &job.Results)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
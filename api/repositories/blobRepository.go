@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"api/models"
+	"api/shared"
+	"database/sql"
+	"time"
+)
+
+type IBlobRepository interface {
+	Reference(blob *models.Blob, upload func(blob *models.Blob) error) error
+	Release(hash string, remove func(blob *models.Blob) error) error
+	FindByHash(hash string) (*models.Blob, error)
+	FindNotVerifiedSince(before time.Time, limit int) ([]models.Blob, error)
+	UpdateStatus(hash string, status shared.BlobStatus, verifiedAt time.Time) error
+	FindAllBlobNames() ([]string, error)
+	SaveFiles(hash string, files []models.BundleFile) error
+	FindFiles(hash string) ([]models.BundleFile, error)
+	DeleteFiles(hash string) error
+}
+
+type blobRepository struct {
+	db *sql.DB
+}
+
+func BlobRepository(db *sql.DB) IBlobRepository {
+	return &blobRepository{
+		db: db,
+	}
+}
+
+// Reference increases the reference count of the blob with the hash of the given blob.
+// If the blob is not existing yet, it is created and upload is called before the transaction is committed,
+// so concurrent uploads of the same content wait until the blob has been uploaded.
+// The blob is filled with the stored values.
+func (b blobRepository) Reference(blob *models.Blob, upload func(blob *models.Blob) error) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	//MySQL returns 1 affected row for an insert and 2 for an update
+	result, err := tx.Exec("INSERT INTO blobs (Hash, BlobName, StorageLocation, Size, RefCount, Status, CreatedAt) VALUES (?,?,?,?,1,?,?) "+
+		"ON DUPLICATE KEY UPDATE RefCount=RefCount+1",
+		blob.Hash, blob.BlobName, blob.StorageLocation, blob.Size, shared.Blob_Unverified, blob.CreatedAt)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 1 {
+		err = upload(blob)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE blobs SET StorageLocation=? WHERE Hash = ?", blob.StorageLocation, blob.Hash)
+		if err != nil {
+			return err
+		}
+		blob.RefCount = 1
+		blob.Status = shared.Blob_Unverified
+	} else {
+		err = scanBlob(tx.QueryRow("SELECT * FROM blobs WHERE Hash = ?", blob.Hash), blob)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Release decreases the reference count of the blob with the given hash.
+// If the blob is not referenced anymore, remove is called and the blob is deleted.
+// Returns sql.ErrNoRows if there is no blob with this hash.
+func (b blobRepository) Release(hash string, remove func(blob *models.Blob) error) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var blob models.Blob
+	err = scanBlob(tx.QueryRow("SELECT * FROM blobs WHERE Hash = ? FOR UPDATE", hash), &blob)
+	if err != nil {
+		return err
+	}
+
+	if blob.RefCount > 1 {
+		_, err = tx.Exec("UPDATE blobs SET RefCount=RefCount-1 WHERE Hash = ?", hash)
+	} else {
+		err = remove(&blob)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM blobs WHERE Hash = ?", hash)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindByHash finds the blob with a specific hash or nil if the blob has not been found.
+func (b blobRepository) FindByHash(hash string) (*models.Blob, error) {
+	var blob models.Blob
+	err := scanBlob(b.db.QueryRow("SELECT * FROM blobs WHERE Hash = ?", hash), &blob)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// FindNotVerifiedSince returns blobs which have not been verified since before, the blobs which have never been verified first.
+func (b blobRepository) FindNotVerifiedSince(before time.Time, limit int) ([]models.Blob, error) {
+	query, err := b.db.Query("SELECT * FROM blobs WHERE VerifiedAt IS NULL OR VerifiedAt < ? ORDER BY VerifiedAt LIMIT ?", before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+
+	var blobs = []models.Blob{}
+	for query.Next() {
+		var blob models.Blob
+		err := scanBlob(query, &blob)
+		if err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, blob)
+	}
+
+	return blobs, query.Err()
+}
+
+// UpdateStatus saves the result of a verification of a blob.
+func (b blobRepository) UpdateStatus(hash string, status shared.BlobStatus, verifiedAt time.Time) error {
+	_, err := b.db.Exec("UPDATE blobs SET Status=?, VerifiedAt=? WHERE Hash = ?", status, verifiedAt, hash)
+	return err
+}
+
+// FindAllBlobNames returns the names of all stored blobs.
+func (b blobRepository) FindAllBlobNames() ([]string, error) {
+	return queryStrings(b.db, "SELECT BlobName FROM blobs")
+}
+
+// SaveFiles saves the files of the bundle with the given hash.
+func (b blobRepository) SaveFiles(hash string, files []models.BundleFile) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, file := range files {
+		_, err = tx.Exec("INSERT INTO blob_files (BlobHash, Path, Hash, Size) VALUES (?,?,?,?)", hash, file.Path, file.Hash, file.Size)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// FindFiles returns the files of the bundle with the given hash ordered by their path, it is empty for other blobs.
+func (b blobRepository) FindFiles(hash string) ([]models.BundleFile, error) {
+	query, err := b.db.Query("SELECT Path, Hash, Size FROM blob_files WHERE BlobHash = ? ORDER BY Path", hash)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+
+	var files = []models.BundleFile{}
+	for query.Next() {
+		var file models.BundleFile
+		err := query.Scan(&file.Path, &file.Hash, &file.Size)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, query.Err()
+}
+
+// DeleteFiles removes the files of the bundle with the given hash.
+func (b blobRepository) DeleteFiles(hash string) error {
+	_, err := b.db.Exec("DELETE FROM blob_files WHERE BlobHash = ?", hash)
+	return err
+}
+
+// scanBlob reads a row of "SELECT * FROM blobs" into the blob
+func scanBlob(row scanner, blob *models.Blob) error {
+	return row.Scan(&blob.Hash, &blob.BlobName, &blob.StorageLocation, &blob.Size, &blob.RefCount, &blob.Status,
+		&blob.VerifiedAt, &blob.CreatedAt)
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"api/models"
+	"database/sql"
+)
+
+type IClusterRepository interface {
+	FindAll() ([]models.Cluster, error)
+	FindByName(name string) (*models.Cluster, error)
+	Save(cluster *models.Cluster) error
+	Delete(name string) error
+}
+
+type clusterRepository struct {
+	db *sql.DB
+}
+
+func ClusterRepository(db *sql.DB) IClusterRepository {
+	return &clusterRepository{
+		db: db,
+	}
+}
+
+// FindAll returns all registered clusters ordered by their name.
+func (c clusterRepository) FindAll() ([]models.Cluster, error) {
+	query, err := c.db.Query("SELECT * FROM clusters ORDER BY Name")
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+
+	var clusters = []models.Cluster{}
+	for query.Next() {
+		var cluster models.Cluster
+		err := scanCluster(query, &cluster)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, query.Err()
+}
+
+// FindByName finds the cluster with a specific name or nil if the cluster has not been found.
+func (c clusterRepository) FindByName(name string) (*models.Cluster, error) {
+	var cluster models.Cluster
+	err := scanCluster(c.db.QueryRow("SELECT * FROM clusters WHERE Name = ?", name), &cluster)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+// Save registers the cluster or updates the cluster with the same name, the creation time of an existing cluster is kept.
+func (c clusterRepository) Save(cluster *models.Cluster) error {
+	_, err := c.db.Exec("INSERT INTO clusters (Name, Region, Capacity, Labels, KubeconfigPath, KubeContext, Cordoned, CreatedAt) VALUES (?,?,?,?,?,?,?,?) "+
+		"ON DUPLICATE KEY UPDATE Region=VALUES(Region), Capacity=VALUES(Capacity), Labels=VALUES(Labels), "+
+		"KubeconfigPath=VALUES(KubeconfigPath), KubeContext=VALUES(KubeContext), Cordoned=VALUES(Cordoned)",
+		cluster.Name, cluster.Region, cluster.Capacity, joinTags(cluster.Labels), cluster.KubeconfigPath, cluster.KubeContext,
+		cluster.Cordoned, cluster.CreatedAt)
+	return err
+}
+
+// Delete removes the cluster with the given name.
+func (c clusterRepository) Delete(name string) error {
+	_, err := c.db.Exec("DELETE FROM clusters WHERE Name = ?", name)
+	return err
+}
+
+// scanCluster reads a row of "SELECT * FROM clusters" into the cluster
+func scanCluster(row scanner, cluster *models.Cluster) error {
+	var labels string
+	err := row.Scan(&cluster.Name, &cluster.Region, &cluster.Capacity, &labels, &cluster.KubeconfigPath, &cluster.KubeContext,
+		&cluster.Cordoned, &cluster.CreatedAt)
+	if err != nil {
+		return err
+	}
+	cluster.Labels = splitTags(labels)
+	return nil
+}
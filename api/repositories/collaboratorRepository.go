@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"api/models"
+	"api/shared"
+	"database/sql"
+	"github.com/google/uuid"
+	"strings"
+)
+
+type ICollaboratorRepository interface {
+	Save(collaborator *models.Collaborator) error
+	FindAllByGame(gameID uuid.UUID) ([]models.Collaborator, error)
+	FindByGrantee(gameID uuid.UUID, subject *string, email *string) (*models.Collaborator, error)
+	FindPermissions(gameID uuid.UUID, subject string, email string) ([]shared.Permission, error)
+	Delete(gameID uuid.UUID, id uuid.UUID) error
+}
+
+type collaboratorRepository struct {
+	db *sql.DB
+}
+
+func CollaboratorRepository(db *sql.DB) ICollaboratorRepository {
+	return &collaboratorRepository{
+		db: db,
+	}
+}
+
+// Save grants the permissions to the collaborator.
+// If the subject or email already is a collaborator of the game, its permissions are replaced.
+func (c collaboratorRepository) Save(collaborator *models.Collaborator) error {
+	collaborator.ID = uuid.New()
+	_, err := c.db.Exec("INSERT INTO game_collaborators (ID, GameID, Subject, Email, Permissions, GrantedBy, GrantedAt) VALUES (?,?,?,?,?,?,?) AS new "+
+		"ON DUPLICATE KEY UPDATE Permissions=new.Permissions, GrantedBy=new.GrantedBy, GrantedAt=new.GrantedAt",
+		collaborator.ID, collaborator.GameID, collaborator.Subject, collaborator.Email, joinPermissions(collaborator.Permissions),
+		collaborator.GrantedBy, collaborator.GrantedAt)
+	return err
+}
+
+// FindAllByGame returns the collaborators of a game, the longest collaborator first.
+func (c collaboratorRepository) FindAllByGame(gameID uuid.UUID) ([]models.Collaborator, error) {
+	rows, err := c.db.Query("SELECT * FROM game_collaborators WHERE GameID = ? ORDER BY GrantedAt", gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collaborators := []models.Collaborator{}
+	for rows.Next() {
+		var collaborator models.Collaborator
+		err = scanCollaborator(rows, &collaborator)
+		if err != nil {
+			return nil, err
+		}
+		collaborators = append(collaborators, collaborator)
+	}
+	return collaborators, rows.Err()
+}
+
+// FindByGrantee finds the collaborator of a game with the subject or the email, one of them must be nil.
+// Returns nil if it has not been found.
+func (c collaboratorRepository) FindByGrantee(gameID uuid.UUID, subject *string, email *string) (*models.Collaborator, error) {
+	var row *sql.Row
+	if subject != nil {
+		row = c.db.QueryRow("SELECT * FROM game_collaborators WHERE GameID = ? AND Subject = ?", gameID, *subject)
+	} else {
+		row = c.db.QueryRow("SELECT * FROM game_collaborators WHERE GameID = ? AND Email = ?", gameID, *email)
+	}
+
+	var collaborator models.Collaborator
+	err := scanCollaborator(row, &collaborator)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+// FindPermissions returns the permissions which have been granted on the game to the subject or to the email.
+// An empty email never matches.
+func (c collaboratorRepository) FindPermissions(gameID uuid.UUID, subject string, email string) ([]shared.Permission, error) {
+	rows, err := c.db.Query("SELECT Permissions FROM game_collaborators WHERE GameID = ? AND (Subject = ? OR Email = ?)",
+		gameID, subject, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []shared.Permission{}
+	for rows.Next() {
+		var joined string
+		err = rows.Scan(&joined)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, splitPermissions(joined)...)
+	}
+	return permissions, rows.Err()
+}
+
+// Delete revokes all permissions of a collaborator of a game.
+// Returns sql.ErrNoRows if the game has no such collaborator.
+func (c collaboratorRepository) Delete(gameID uuid.UUID, id uuid.UUID) error {
+	result, err := c.db.Exec("DELETE FROM game_collaborators WHERE ID = ? AND GameID = ?", id, gameID)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(result)
+}
+
+// scanCollaborator reads a row of "SELECT * FROM game_collaborators" into the collaborator
+func scanCollaborator(row scanner, collaborator *models.Collaborator) error {
+	var permissions string
+	err := row.Scan(&collaborator.ID, &collaborator.GameID, &collaborator.Subject, &collaborator.Email, &permissions,
+		&collaborator.GrantedBy, &collaborator.GrantedAt)
+	if err != nil {
+		return err
+	}
+	collaborator.Permissions = splitPermissions(permissions)
+	return nil
+}
+
+// joinPermissions converts the permissions into the comma separated column
+func joinPermissions(permissions []shared.Permission) string {
+	parts := make([]string, len(permissions))
+	for i, permission := range permissions {
+		parts[i] = string(permission)
+	}
+	return strings.Join(parts, ",")
+}
+
+// splitPermissions converts the comma separated column into the permissions
+func splitPermissions(permissions string) []shared.Permission {
+	result := []shared.Permission{}
+	for _, part := range splitTags(permissions) {
+		result = append(result, shared.Permission(part))
+	}
+	return result
+}
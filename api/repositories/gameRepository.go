@@ -2,9 +2,11 @@ package repositories
 
 import (
 	"api/models"
+	"api/shared"
 	"database/sql"
-
 	"github.com/google/uuid"
+	"strings"
+	"time"
 )
 
 type IGameRepository interface {
@@ -12,7 +14,25 @@ type IGameRepository interface {
 	Save(game *models.Game) error
 	Delete(id uuid.UUID) error
 	FindAllByOwner(owner string) ([]models.Game, error)
+	FindAllByOwners(owners []string) ([]models.Game, error)
+	FindAllSharedWith(subject string, email string) ([]models.Game, error)
 	ReadOwner(id uuid.UUID) (string, error)
+	Update(game *models.Game, revision int) error
+	UpdateBetaUrl(id uuid.UUID, url string) error
+	UpdateVisibility(id uuid.UUID, visibility shared.Visibility) error
+	UpdateStatus(id uuid.UUID, status shared.GameStatus) error
+	SoftDelete(id uuid.UUID, deletedAt time.Time) error
+	Restore(id uuid.UUID) error
+	FindDeletedByID(id uuid.UUID) (*models.Game, error)
+	FindAllDeletedByOwners(owners []string) ([]models.Game, error)
+	FindAllDeletedBefore(before time.Time) ([]models.Game, error)
+	FindAllByIDs(ids []uuid.UUID) ([]models.Game, error)
+	FindAll() ([]models.Game, error)
+	CountByCluster() (map[string]int, error)
+	CountInCluster(cluster string) (int, error)
+	UpdateStatusFrom(id uuid.UUID, from shared.GameStatus, to shared.GameStatus, reason string) (bool, error)
+	UpdateDeployment(game *models.Game) error
+	FindAllByStatus(status shared.GameStatus) ([]models.Game, error)
 }
 
 type gameRepository struct {
@@ -25,7 +45,8 @@ func GameRepository(db *sql.DB) IGameRepository {
 	}
 }
 
-// Read the owner of a specific game or empty if the game has not been found
+// Read the owner of a specific game or empty if the game has not been found.
+// Games in the trash are included, so their owner can restore them and manage their collaborators, whose grants are kept until the game is purged.
 func (g gameRepository) ReadOwner(id uuid.UUID) (string, error) {
 	var owner string
 	err := g.db.QueryRow("SELECT Owner FROM games WHERE ID = ?", id).Scan(&owner)
@@ -37,7 +58,7 @@ func (g gameRepository) ReadOwner(id uuid.UUID) (string, error) {
 
 // FindAll returns all games of a specific owner from the database or (nil, err) if an error occurred.
 func (g gameRepository) FindAllByOwner(owner string) ([]models.Game, error) {
-	stmt, err := g.db.Prepare("SELECT * FROM games WHERE owner = ?")
+	stmt, err := g.db.Prepare("SELECT * FROM games WHERE owner = ? AND DeletedAt IS NULL")
 	if err != nil {
 		return nil, err
 	}
@@ -49,11 +70,76 @@ func (g gameRepository) FindAllByOwner(owner string) ([]models.Game, error) {
 	return readGamesFromRows(query)
 }
 
-// FindByID finds a game with a specific id or nil if the game has not been found.
+// FindAll returns the games of all owners, which are not in the trash.
+func (g gameRepository) FindAll() ([]models.Game, error) {
+	query, err := g.db.Query("SELECT * FROM games WHERE DeletedAt IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+	return readGamesFromRows(query)
+}
+
+// CountByCluster returns the number of games per cluster, the games in the trash are not deployed and not counted.
+func (g gameRepository) CountByCluster() (map[string]int, error) {
+	rows, err := g.db.Query("SELECT Cluster, COUNT(*) FROM games WHERE DeletedAt IS NULL GROUP BY Cluster")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var cluster string
+		var count int
+		if err = rows.Scan(&cluster, &count); err != nil {
+			return nil, err
+		}
+		counts[cluster] = count
+	}
+	return counts, rows.Err()
+}
+
+// CountInCluster returns the number of games of a cluster, including the games in the trash, which are deployed on it when they are restored.
+func (g gameRepository) CountInCluster(cluster string) (int, error) {
+	var count int
+	err := g.db.QueryRow("SELECT COUNT(*) FROM games WHERE Cluster = ?", cluster).Scan(&count)
+	return count, err
+}
+
+// FindAllSharedWith returns the games which have been shared with the subject or the email, which are not in the trash.
+func (g gameRepository) FindAllSharedWith(subject string, email string) ([]models.Game, error) {
+	query, err := g.db.Query("SELECT * FROM games WHERE ID IN (SELECT GameID FROM game_collaborators WHERE Subject = ? OR Email = ?) AND DeletedAt IS NULL",
+		subject, email)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+	return readGamesFromRows(query)
+}
+
+// FindAllByOwners returns all games of the given owners, which are not in the trash.
+func (g gameRepository) FindAllByOwners(owners []string) ([]models.Game, error) {
+	if len(owners) == 1 {
+		return g.FindAllByOwner(owners[0])
+	}
+	if len(owners) == 0 {
+		return []models.Game{}, nil
+	}
+
+	placeholders, args := inPlaceholders(owners)
+	query, err := g.db.Query("SELECT * FROM games WHERE Owner IN ("+placeholders+") AND DeletedAt IS NULL", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+	return readGamesFromRows(query)
+}
+
+// FindByID finds a game with a specific id or nil if the game has not been found or is in the trash.
 func (g gameRepository) FindByID(id uuid.UUID) (*models.Game, error) {
 	var game models.Game
-	err := g.db.QueryRow("SELECT * FROM games WHERE ID = ?", id).
-		Scan(&game.ID, &game.Title, &game.StorageLocation, &game.Status, &game.Url, &game.Owner, &game.FileName)
+	err := scanGame(g.db.QueryRow("SELECT * FROM games WHERE ID = ? AND DeletedAt IS NULL", id), &game)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -63,6 +149,22 @@ func (g gameRepository) FindByID(id uuid.UUID) (*models.Game, error) {
 	return &game, nil
 }
 
+// FindAllByIDs returns the games with the given ids, which are not in the trash.
+// Ids of games which have not been found are ignored.
+func (g gameRepository) FindAllByIDs(ids []uuid.UUID) ([]models.Game, error) {
+	if len(ids) == 0 {
+		return []models.Game{}, nil
+	}
+
+	placeholders, args := inPlaceholders(ids)
+	query, err := g.db.Query("SELECT * FROM games WHERE ID IN ("+placeholders+") AND DeletedAt IS NULL", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+	return readGamesFromRows(query)
+}
+
 // Save will update the database entry if the game is already in the database.
 // If not it will create an uuid and save it in the database.
 func (g gameRepository) Save(game *models.Game) error {
@@ -88,15 +190,168 @@ func (g gameRepository) Save(game *models.Game) error {
 	}
 
 	//If not create a new one
-	stmt, err := g.db.Prepare("INSERT INTO games (ID, Title, StorageLocation, Status, Url, Owner, FileName) VALUES (?,?,?,?,?,?,?)")
+	stmt, err := g.db.Prepare("INSERT INTO games (ID, Title, StorageLocation, Status, Url, Owner, FileName, BlobName, Checksum, Visibility, Description, Tags, Platform, Cluster, Region, Bios) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")
 	if err != nil {
 		return err
 	}
 
-	_, err = stmt.Exec(game.ID, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName)
+	_, err = stmt.Exec(game.ID, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName,
+		game.BlobName, game.Checksum, game.Visibility, game.Description, joinTags(game.Tags), game.Platform, game.Cluster, game.Region, joinPaths(game.Bios))
+	if err == nil {
+		game.Revision = 1
+	}
 	return err
 }
 
+// Update saves the metadata, the file and the deploy revision of an existing game and increases its revision,
+// but only if the revision in the database is still the given one.
+// Returns shared.ErrPreconditionFailed if the game has been changed in the meantime
+// and sql.ErrNoRows if the game is not existing.
+func (g gameRepository) Update(game *models.Game, revision int) error {
+	stmt, err := g.db.Prepare("UPDATE games SET Title=?, StorageLocation=?, FileName=?, BlobName=?, Checksum=?, LiveVersion=?, BetaVersion=?, Visibility=?, Description=?, Tags=?, Platform=?, Bios=?, DeployRevision=?, Revision=Revision+1 WHERE ID = ? AND Revision = ? AND DeletedAt IS NULL")
+	if err != nil {
+		return err
+	}
+
+	result, err := stmt.Exec(game.Title, game.StorageLocation, game.FileName, game.BlobName, game.Checksum,
+		game.LiveVersion, game.BetaVersion, game.Visibility, game.Description, joinTags(game.Tags), game.Platform, joinPaths(game.Bios), game.DeployRevision, game.ID, revision)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		existing, err := g.FindByID(game.ID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return sql.ErrNoRows
+		}
+		return shared.ErrPreconditionFailed
+	}
+
+	game.Revision = revision + 1
+	return nil
+}
+
+// UpdateBetaUrl saves the url of the beta channel of a game.
+func (g gameRepository) UpdateBetaUrl(id uuid.UUID, url string) error {
+	_, err := g.db.Exec("UPDATE games SET BetaUrl=? WHERE ID = ?", url, id)
+	return err
+}
+
+// UpdateStatusFrom changes the status of a game and its reason, but only if the game still has the status from.
+// Returns false if the game has another status, e.g. because its scan has been finished by another instance.
+func (g gameRepository) UpdateStatusFrom(id uuid.UUID, from shared.GameStatus, to shared.GameStatus, reason string) (bool, error) {
+	result, err := g.db.Exec("UPDATE games SET Status=?, StatusReason=? WHERE ID = ? AND Status = ? AND DeletedAt IS NULL", to, reason, id, from)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// UpdateDeployment saves the status, the url and the cluster of a game which has been deployed.
+func (g gameRepository) UpdateDeployment(game *models.Game) error {
+	_, err := g.db.Exec("UPDATE games SET Status=?, StatusReason=?, Url=?, Cluster=?, Namespace=? WHERE ID = ? AND DeletedAt IS NULL",
+		game.Status, game.StatusReason, game.Url, game.Cluster, game.Namespace, game.ID)
+	return err
+}
+
+// FindAllByStatus returns all games with the given status, which are not in the trash.
+func (g gameRepository) FindAllByStatus(status shared.GameStatus) ([]models.Game, error) {
+	query, err := g.db.Query("SELECT * FROM games WHERE Status = ? AND DeletedAt IS NULL", status)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+	return readGamesFromRows(query)
+}
+
+// UpdateStatus saves the status of a game, which is not in the trash.
+func (g gameRepository) UpdateStatus(id uuid.UUID, status shared.GameStatus) error {
+	_, err := g.db.Exec("UPDATE games SET Status=? WHERE ID = ? AND DeletedAt IS NULL", status, id)
+	return err
+}
+
+// SoftDelete moves a game to the trash. The game is not deployed anymore, so its urls are removed.
+// Returns sql.ErrNoRows if the game is not existing or already in the trash.
+func (g gameRepository) SoftDelete(id uuid.UUID, deletedAt time.Time) error {
+	result, err := g.db.Exec("UPDATE games SET DeletedAt=?, Status=?, Url='', BetaVersion=0, BetaUrl='', Revision=Revision+1 WHERE ID = ? AND DeletedAt IS NULL",
+		deletedAt, shared.Status_Deleted, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(result)
+}
+
+// UpdateVisibility saves the visibility of a game and increases its revision. The deployment of the game is not affected.
+// Returns sql.ErrNoRows if the game is not existing or in the trash.
+func (g gameRepository) UpdateVisibility(id uuid.UUID, visibility shared.Visibility) error {
+	result, err := g.db.Exec("UPDATE games SET Visibility=?, Revision=Revision+1 WHERE ID = ? AND DeletedAt IS NULL", visibility, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(result)
+}
+
+// Restore moves a game out of the trash, the game is scanned again before it is deployed.
+// Returns sql.ErrNoRows if the game is not in the trash.
+func (g gameRepository) Restore(id uuid.UUID) error {
+	result, err := g.db.Exec("UPDATE games SET DeletedAt=NULL, Status=?, StatusReason='', Revision=Revision+1 WHERE ID = ? AND DeletedAt IS NOT NULL",
+		shared.Status_Scanning, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(result)
+}
+
+// FindDeletedByID finds a game in the trash or nil if there is no such game in the trash.
+func (g gameRepository) FindDeletedByID(id uuid.UUID) (*models.Game, error) {
+	var game models.Game
+	err := scanGame(g.db.QueryRow("SELECT * FROM games WHERE ID = ? AND DeletedAt IS NOT NULL", id), &game)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &game, nil
+}
+
+// FindAllDeletedByOwners returns the games of the owners which are in the trash, the latest deleted game first.
+func (g gameRepository) FindAllDeletedByOwners(owners []string) ([]models.Game, error) {
+	if len(owners) == 0 {
+		return []models.Game{}, nil
+	}
+
+	placeholders, args := inPlaceholders(owners)
+	query, err := g.db.Query("SELECT * FROM games WHERE Owner IN ("+placeholders+") AND DeletedAt IS NOT NULL ORDER BY DeletedAt DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+	return readGamesFromRows(query)
+}
+
+// FindAllDeletedBefore returns all games which have been moved to the trash before the given time.
+func (g gameRepository) FindAllDeletedBefore(before time.Time) ([]models.Game, error) {
+	query, err := g.db.Query("SELECT * FROM games WHERE DeletedAt < ?", before)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+	return readGamesFromRows(query)
+}
+
 // Delete removes the entry with a specific id from the games database.
 // Or returns sql.ErrNoRows if the game is not existing.
 func (g gameRepository) Delete(id uuid.UUID) error {
@@ -110,6 +365,39 @@ func (g gameRepository) Delete(id uuid.UUID) error {
 		return err
 	}
 
+	return expectRowsAffected(result)
+}
+
+// inPlaceholders returns the placeholders and the arguments of an IN clause
+func inPlaceholders[T any](values []T) (string, []any) {
+	args := make([]any, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(values)), ","), args
+}
+
+// queryStrings returns the values of a query which selects a single string column
+func queryStrings(db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// expectRowsAffected returns sql.ErrNoRows if no row has been changed.
+func expectRowsAffected(result sql.Result) error {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
@@ -119,14 +407,14 @@ func (g gameRepository) Delete(id uuid.UUID) error {
 		return sql.ErrNoRows
 	}
 
-	return err
+	return nil
 }
 
 func readGamesFromRows(query *sql.Rows) ([]models.Game, error) {
 	var games = []models.Game{}
 	for query.Next() {
 		var game models.Game
-		err := query.Scan(&game.ID, &game.Title, &game.StorageLocation, &game.Status, &game.Url, &game.Owner, &game.FileName)
+		err := scanGame(query, &game)
 		if err != nil {
 			return nil, err
 		}
@@ -140,3 +428,50 @@ func readGamesFromRows(query *sql.Rows) ([]models.Game, error) {
 
 	return games, nil
 }
+
+// scanner is implemented by sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanGame reads a row of "SELECT * FROM games" into the game.
+// Columns which are selected after the columns of the game are read into extra.
+func scanGame(row scanner, game *models.Game, extra ...any) error {
+	var tags, bios string
+	dest := []any{&game.ID, &game.Title, &game.StorageLocation, &game.Status, &game.Url, &game.Owner, &game.FileName,
+		&game.Revision, &game.BlobName, &game.LiveVersion, &game.BetaVersion, &game.BetaUrl, &game.Checksum, &game.DeletedAt, &game.Visibility,
+		&game.Description, &tags, &game.Platform, &game.Cluster, &game.Region, &game.StatusReason, &bios, &game.Namespace, &game.DeployRevision}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return err
+	}
+	game.Tags = splitTags(tags)
+	game.Bios = splitPaths(bios)
+	return nil
+}
+
+// joinTags converts the tags into the comma separated column
+func joinTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+// joinPaths converts the paths of files into a column, one path per line
+func joinPaths(paths []string) string {
+	return strings.Join(paths, "\n")
+}
+
+// splitPaths converts the column into the paths of files
+func splitPaths(paths string) []string {
+	if paths == "" {
+		return []string{}
+	}
+	return strings.Split(paths, "\n")
+}
+
+// splitTags converts the comma separated column into the tags
+func splitTags(tags string) []string {
+	if tags == "" {
+		return []string{}
+	}
+	return strings.Split(tags, ",")
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"api/models"
+	"database/sql"
+	"github.com/google/uuid"
+)
+
+type IGameVersionRepository interface {
+	Create(version *models.GameVersion) error
+	FindAllByGame(gameID uuid.UUID) ([]models.GameVersion, error)
+	FindByGameAndVersion(gameID uuid.UUID, version int) (*models.GameVersion, error)
+	DeleteAllByGame(gameID uuid.UUID) error
+	FindAllBlobNames() ([]string, error)
+}
+
+type gameVersionRepository struct {
+	db *sql.DB
+}
+
+func GameVersionRepository(db *sql.DB) IGameVersionRepository {
+	return &gameVersionRepository{
+		db: db,
+	}
+}
+
+// Create saves a new version of a game. It sets the id and the next version number of the game,
+// if they are not set already.
+func (g gameVersionRepository) Create(version *models.GameVersion) error {
+	if version.ID == uuid.Nil {
+		version.ID = uuid.New()
+	}
+
+	tx, err := g.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if version.Version == 0 {
+		err = tx.QueryRow("SELECT COALESCE(MAX(Version), 0) + 1 FROM game_versions WHERE GameID = ? FOR UPDATE", version.GameID).
+			Scan(&version.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO game_versions (ID, GameID, Version, BlobName, StorageLocation, FileName, Checksum, Changelog, Uploader, CreatedAt, Bios) VALUES (?,?,?,?,?,?,?,?,?,?,?)",
+		version.ID, version.GameID, version.Version, version.BlobName, version.StorageLocation, version.FileName,
+		version.Checksum, version.Changelog, version.Uploader, version.CreatedAt, joinPaths(version.Bios))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindAllByGame returns all versions of a game, the newest version first.
+func (g gameVersionRepository) FindAllByGame(gameID uuid.UUID) ([]models.GameVersion, error) {
+	query, err := g.db.Query("SELECT * FROM game_versions WHERE GameID = ? ORDER BY Version DESC", gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+
+	var versions = []models.GameVersion{}
+	for query.Next() {
+		var version models.GameVersion
+		err := scanGameVersion(query, &version)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, query.Err()
+}
+
+// FindByGameAndVersion finds a specific version of a game or nil if the version has not been found.
+func (g gameVersionRepository) FindByGameAndVersion(gameID uuid.UUID, version int) (*models.GameVersion, error) {
+	var gameVersion models.GameVersion
+	err := scanGameVersion(g.db.QueryRow("SELECT * FROM game_versions WHERE GameID = ? AND Version = ?", gameID, version), &gameVersion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &gameVersion, nil
+}
+
+// FindAllBlobNames returns the names of the blobs of all versions, including the versions of games in the trash.
+func (g gameVersionRepository) FindAllBlobNames() ([]string, error) {
+	return queryStrings(g.db, "SELECT DISTINCT BlobName FROM game_versions")
+}
+
+// DeleteAllByGame removes all versions of a game.
+func (g gameVersionRepository) DeleteAllByGame(gameID uuid.UUID) error {
+	_, err := g.db.Exec("DELETE FROM game_versions WHERE GameID = ?", gameID)
+	return err
+}
+
+// scanGameVersion reads a row of "SELECT * FROM game_versions" into the version
+func scanGameVersion(row scanner, version *models.GameVersion) error {
+	var bios string
+	err := row.Scan(&version.ID, &version.GameID, &version.Version, &version.BlobName, &version.StorageLocation,
+		&version.FileName, &version.Checksum, &version.Changelog, &version.Uploader, &version.CreatedAt, &bios)
+	if err != nil {
+		return err
+	}
+	version.Bios = splitPaths(bios)
+	return nil
+}
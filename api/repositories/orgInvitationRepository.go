@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"api/models"
+	"database/sql"
+	"github.com/google/uuid"
+	"time"
+)
+
+type IOrgInvitationRepository interface {
+	Create(invitation *models.OrgInvitation) error
+	FindByTokenHash(tokenHash string) (*models.OrgInvitation, error)
+	FindPendingByOrg(orgID uuid.UUID, now time.Time) ([]models.OrgInvitation, error)
+	Delete(orgID uuid.UUID, id uuid.UUID) error
+	Accept(invitation *models.OrgInvitation, member *models.OrgMember) error
+}
+
+type orgInvitationRepository struct {
+	db *sql.DB
+}
+
+func OrgInvitationRepository(db *sql.DB) IOrgInvitationRepository {
+	return &orgInvitationRepository{
+		db: db,
+	}
+}
+
+// Create saves a new invitation and sets its id.
+func (o orgInvitationRepository) Create(invitation *models.OrgInvitation) error {
+	invitation.ID = uuid.New()
+	_, err := o.db.Exec("INSERT INTO org_invitations (ID, OrgID, Email, Role, TokenHash, InvitedBy, CreatedAt, ExpiresAt) VALUES (?,?,?,?,?,?,?,?)",
+		invitation.ID, invitation.OrgID, invitation.Email, invitation.Role, invitation.TokenHash, invitation.InvitedBy,
+		invitation.CreatedAt, invitation.ExpiresAt)
+	return err
+}
+
+// FindByTokenHash finds an invitation by the hash of its token or nil if it has not been found.
+func (o orgInvitationRepository) FindByTokenHash(tokenHash string) (*models.OrgInvitation, error) {
+	var invitation models.OrgInvitation
+	err := scanInvitation(o.db.QueryRow("SELECT * FROM org_invitations WHERE TokenHash = ?", tokenHash), &invitation)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindPendingByOrg returns the invitations of an organization, which have neither been accepted nor expired.
+func (o orgInvitationRepository) FindPendingByOrg(orgID uuid.UUID, now time.Time) ([]models.OrgInvitation, error) {
+	rows, err := o.db.Query("SELECT * FROM org_invitations WHERE OrgID = ? AND AcceptedAt IS NULL AND ExpiresAt > ? ORDER BY CreatedAt",
+		orgID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.OrgInvitation{}
+	for rows.Next() {
+		var invitation models.OrgInvitation
+		err = scanInvitation(rows, &invitation)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// Delete revokes an invitation of an organization.
+// Returns sql.ErrNoRows if the organization has no such invitation.
+func (o orgInvitationRepository) Delete(orgID uuid.UUID, id uuid.UUID) error {
+	result, err := o.db.Exec("DELETE FROM org_invitations WHERE ID = ? AND OrgID = ?", id, orgID)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(result)
+}
+
+// Accept marks the invitation as accepted and adds the member to the organization.
+// Returns sql.ErrNoRows if the invitation has been accepted in the meantime.
+func (o orgInvitationRepository) Accept(invitation *models.OrgInvitation, member *models.OrgMember) error {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE org_invitations SET AcceptedAt=?, AcceptedBy=? WHERE ID = ? AND AcceptedAt IS NULL",
+		invitation.AcceptedAt, invitation.AcceptedBy, invitation.ID)
+	if err != nil {
+		return err
+	}
+	err = expectRowsAffected(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO org_members (OrgID, Subject, Role, JoinedAt) VALUES (?,?,?,?)",
+		member.OrgID, member.Subject, member.Role, member.JoinedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// scanInvitation reads a row of "SELECT * FROM org_invitations" into the invitation
+func scanInvitation(row scanner, invitation *models.OrgInvitation) error {
+	return row.Scan(&invitation.ID, &invitation.OrgID, &invitation.Email, &invitation.Role, &invitation.TokenHash,
+		&invitation.InvitedBy, &invitation.CreatedAt, &invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.AcceptedBy)
+}
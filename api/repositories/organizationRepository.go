@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"api/models"
+	"api/shared"
+	"database/sql"
+	"github.com/google/uuid"
+)
+
+type IOrganizationRepository interface {
+	Create(org *models.Organization, owner *models.OrgMember) error
+	FindByID(id uuid.UUID) (*models.Organization, error)
+	FindAllBySubject(subject string) ([]models.Membership, error)
+	FindRole(orgID uuid.UUID, subject string) (shared.OrgRole, error)
+	FindMembers(orgID uuid.UUID) ([]models.OrgMember, error)
+	UpdateRole(orgID uuid.UUID, subject string, role shared.OrgRole) error
+	DeleteMember(orgID uuid.UUID, subject string) error
+}
+
+type organizationRepository struct {
+	db *sql.DB
+}
+
+func OrganizationRepository(db *sql.DB) IOrganizationRepository {
+	return &organizationRepository{
+		db: db,
+	}
+}
+
+// Create saves a new organization together with its first owner and sets the id of the organization.
+func (o organizationRepository) Create(org *models.Organization, owner *models.OrgMember) error {
+	org.ID = uuid.New()
+	owner.OrgID = org.ID
+
+	tx, err := o.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO organizations (ID, Name, CreatedBy, CreatedAt) VALUES (?,?,?,?)",
+		org.ID, org.Name, org.CreatedBy, org.CreatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO org_members (OrgID, Subject, Role, JoinedAt) VALUES (?,?,?,?)",
+		owner.OrgID, owner.Subject, owner.Role, owner.JoinedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindByID finds an organization with a specific id or nil if it has not been found.
+func (o organizationRepository) FindByID(id uuid.UUID) (*models.Organization, error) {
+	var org models.Organization
+	err := o.db.QueryRow("SELECT ID, Name, CreatedBy, CreatedAt FROM organizations WHERE ID = ?", id).
+		Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// FindAllBySubject returns the organizations of a user together with the role of the user.
+func (o organizationRepository) FindAllBySubject(subject string) ([]models.Membership, error) {
+	rows, err := o.db.Query("SELECT organizations.ID, organizations.Name, organizations.CreatedBy, organizations.CreatedAt, org_members.Role "+
+		"FROM organizations JOIN org_members ON org_members.OrgID = organizations.ID WHERE org_members.Subject = ? ORDER BY organizations.Name",
+		subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []models.Membership{}
+	for rows.Next() {
+		var membership models.Membership
+		org := &membership.Organization
+		err = rows.Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &membership.Role)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+	return memberships, rows.Err()
+}
+
+// FindRole returns the role of a user in an organization or an empty role if the user is not a member.
+func (o organizationRepository) FindRole(orgID uuid.UUID, subject string) (shared.OrgRole, error) {
+	var role shared.OrgRole
+	err := o.db.QueryRow("SELECT Role FROM org_members WHERE OrgID = ? AND Subject = ?", orgID, subject).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// FindMembers returns the members of an organization, the longest member first.
+func (o organizationRepository) FindMembers(orgID uuid.UUID) ([]models.OrgMember, error) {
+	rows, err := o.db.Query("SELECT OrgID, Subject, Role, JoinedAt FROM org_members WHERE OrgID = ? ORDER BY JoinedAt", orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.OrgMember{}
+	for rows.Next() {
+		var member models.OrgMember
+		err = rows.Scan(&member.OrgID, &member.Subject, &member.Role, &member.JoinedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// UpdateRole changes the role of a member.
+// Returns shared.ErrMemberNotFound if the user is not a member and shared.ErrLastOwner if the last owner would be demoted.
+func (o organizationRepository) UpdateRole(orgID uuid.UUID, subject string, role shared.OrgRole) error {
+	return o.changeMember(orgID, subject, role == shared.Role_Owner, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.Exec("UPDATE org_members SET Role=? WHERE OrgID = ? AND Subject = ?", role, orgID, subject)
+	})
+}
+
+// DeleteMember removes a user from an organization.
+// Returns shared.ErrMemberNotFound if the user is not a member and shared.ErrLastOwner if the user is the last owner.
+func (o organizationRepository) DeleteMember(orgID uuid.UUID, subject string) error {
+	return o.changeMember(orgID, subject, false, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.Exec("DELETE FROM org_members WHERE OrgID = ? AND Subject = ?", orgID, subject)
+	})
+}
+
+// changeMember runs the change of a member in a transaction, which locks the owners of the organization,
+// so concurrent changes can not remove the last owner.
+func (o organizationRepository) changeMember(orgID uuid.UUID, subject string, staysOwner bool, change func(tx *sql.Tx) (sql.Result, error)) error {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT Subject FROM org_members WHERE OrgID = ? AND Role = ? FOR UPDATE", orgID, shared.Role_Owner)
+	if err != nil {
+		return err
+	}
+	var owners []string
+	for rows.Next() {
+		var owner string
+		err = rows.Scan(&owner)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		owners = append(owners, owner)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	isOwner := false
+	for _, owner := range owners {
+		if owner == subject {
+			isOwner = true
+		}
+	}
+	if isOwner && !staysOwner && len(owners) == 1 {
+		return shared.ErrLastOwner
+	}
+
+	result, err := change(tx)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		//MySQL does not count rows, which have been updated with the same values
+		role, err := o.findRoleInTx(tx, orgID, subject)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			return shared.ErrMemberNotFound
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (o organizationRepository) findRoleInTx(tx *sql.Tx, orgID uuid.UUID, subject string) (shared.OrgRole, error) {
+	var role shared.OrgRole
+	err := tx.QueryRow("SELECT Role FROM org_members WHERE OrgID = ? AND Subject = ?", orgID, subject).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
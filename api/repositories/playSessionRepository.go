@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"api/models"
+	"database/sql"
+	"github.com/google/uuid"
+	"time"
+)
+
+type IPlaySessionRepository interface {
+	Save(session *models.PlaySession) error
+	FindAllBetween(from time.Time, to time.Time) ([]models.PlaySession, error)
+	FindAllOfGameBetween(gameID uuid.UUID, from time.Time, to time.Time) ([]models.PlaySession, error)
+}
+
+type playSessionRepository struct {
+	db *sql.DB
+}
+
+func PlaySessionRepository(db *sql.DB) IPlaySessionRepository {
+	return &playSessionRepository{
+		db: db,
+	}
+}
+
+// Save records a play session. If the session has already been reported, it is extended to the new
+// last seen time and ended if the report contains its end. The start and the owner of a session are never changed.
+func (p playSessionRepository) Save(session *models.PlaySession) error {
+	session.ID = uuid.New()
+	_, err := p.db.Exec("INSERT INTO play_sessions (ID, SessionKey, GameID, Owner, Player, StartedAt, LastSeenAt, EndedAt) VALUES (?,?,?,?,?,?,?,?) AS new "+
+		"ON DUPLICATE KEY UPDATE Player=COALESCE(new.Player, play_sessions.Player), LastSeenAt=GREATEST(new.LastSeenAt, play_sessions.LastSeenAt), "+
+		"EndedAt=COALESCE(new.EndedAt, play_sessions.EndedAt)",
+		session.ID, session.SessionKey, session.GameID, session.Owner, session.Player, session.StartedAt, session.LastSeenAt, session.EndedAt)
+	return err
+}
+
+// FindAllBetween returns the sessions of all games, which have been running between from and to.
+func (p playSessionRepository) FindAllBetween(from time.Time, to time.Time) ([]models.PlaySession, error) {
+	rows, err := p.db.Query("SELECT * FROM play_sessions WHERE StartedAt < ? AND COALESCE(EndedAt, LastSeenAt) > ?", to, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return readPlaySessionsFromRows(rows)
+}
+
+// FindAllOfGameBetween returns the sessions of a game, which have been running between from and to.
+func (p playSessionRepository) FindAllOfGameBetween(gameID uuid.UUID, from time.Time, to time.Time) ([]models.PlaySession, error) {
+	rows, err := p.db.Query("SELECT * FROM play_sessions WHERE GameID = ? AND StartedAt < ? AND COALESCE(EndedAt, LastSeenAt) > ?", gameID, to, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return readPlaySessionsFromRows(rows)
+}
+
+func readPlaySessionsFromRows(rows *sql.Rows) ([]models.PlaySession, error) {
+	sessions := []models.PlaySession{}
+	for rows.Next() {
+		var session models.PlaySession
+		err := rows.Scan(&session.ID, &session.SessionKey, &session.GameID, &session.Owner, &session.Player,
+			&session.StartedAt, &session.LastSeenAt, &session.EndedAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
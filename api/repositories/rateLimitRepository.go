@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"api/shared"
+	"database/sql"
+	"time"
+)
+
+type IRateLimitRepository interface {
+	Take(key string, policy shared.RateLimitPolicy, now time.Time) (shared.RateLimitResult, error)
+	DeleteIdle(before time.Time) error
+}
+
+type rateLimitRepository struct {
+	db *sql.DB
+}
+
+func RateLimitRepository(db *sql.DB) IRateLimitRepository {
+	return &rateLimitRepository{
+		db: db,
+	}
+}
+
+// Take takes a token from the bucket with the given key.
+// The bucket is locked during the transaction, so it can be shared between multiple replicas of the api.
+func (r rateLimitRepository) Take(key string, policy shared.RateLimitPolicy, now time.Time) (shared.RateLimitResult, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return shared.RateLimitResult{}, err
+	}
+	defer tx.Rollback()
+
+	//A new bucket is full
+	tokens := float64(policy.Limit)
+	lastUpdate := now
+	var updatedAt int64
+	err = tx.QueryRow("SELECT Tokens, UpdatedAt FROM rate_limits WHERE BucketKey = ? FOR UPDATE", key).
+		Scan(&tokens, &updatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return shared.RateLimitResult{}, err
+	}
+	if err == nil {
+		lastUpdate = time.UnixMilli(updatedAt)
+	}
+
+	tokens, result := shared.TakeToken(policy, tokens, lastUpdate, now)
+
+	_, err = tx.Exec("INSERT INTO rate_limits (BucketKey, Tokens, UpdatedAt) VALUES (?,?,?) "+
+		"ON DUPLICATE KEY UPDATE Tokens=VALUES(Tokens), UpdatedAt=VALUES(UpdatedAt)",
+		key, tokens, now.UnixMilli())
+	if err != nil {
+		return shared.RateLimitResult{}, err
+	}
+
+	return result, tx.Commit()
+}
+
+// DeleteIdle removes all buckets which have not been used since before.
+func (r rateLimitRepository) DeleteIdle(before time.Time) error {
+	_, err := r.db.Exec("DELETE FROM rate_limits WHERE UpdatedAt < ?", before.UnixMilli())
+	return err
+}
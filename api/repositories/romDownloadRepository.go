@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"api/models"
+	"database/sql"
+	"github.com/google/uuid"
+)
+
+type IRomDownloadRepository interface {
+	Create(download *models.RomDownload) error
+}
+
+type romDownloadRepository struct {
+	db *sql.DB
+}
+
+func RomDownloadRepository(db *sql.DB) IRomDownloadRepository {
+	return &romDownloadRepository{
+		db: db,
+	}
+}
+
+// Create adds a download to the log and sets its id.
+func (r romDownloadRepository) Create(download *models.RomDownload) error {
+	download.ID = uuid.New()
+	_, err := r.db.Exec("INSERT INTO rom_downloads (ID, GameID, Version, Subject, ClientIP, Method, CreatedAt) VALUES (?,?,?,?,?,?,?)",
+		download.ID, download.GameID, download.Version, download.Subject, download.ClientIP, download.Method, download.CreatedAt)
+	return err
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"api/models"
+	"api/shared"
+	"database/sql"
+	"strings"
+)
+
+// SearchQuery describes a search over the game catalog.
+type SearchQuery struct {
+	//Terms are matched as prefixes, a game must match all terms
+	Terms []string
+	//Owners whose private games can be found, besides all public games
+	Owners []string
+	//Subject and Email of a collaborator, the games which have been shared with them can be found too
+	Subject string
+	Email   string
+	Limit   int
+	Offset  int
+}
+
+// ISearchRepository searches the game catalog.
+// It is implemented by the database, but could be implemented by an external search engine too.
+type ISearchRepository interface {
+	Search(query SearchQuery) ([]models.SearchHit, error)
+}
+
+type mysqlSearchRepository struct {
+	db *sql.DB
+}
+
+// MySQLSearchRepository searches the games with the FULLTEXT indexes of the games table.
+// Matches in the title are weighted higher than matches in the other columns.
+func MySQLSearchRepository(db *sql.DB) ISearchRepository {
+	return &mysqlSearchRepository{
+		db: db,
+	}
+}
+
+func (m mysqlSearchRepository) Search(query SearchQuery) ([]models.SearchHit, error) {
+	if len(query.Terms) == 0 {
+		return []models.SearchHit{}, nil
+	}
+	against := booleanQuery(query.Terms)
+
+	visible := "Visibility = ?"
+	args := []any{against, against, against, shared.Visibility_Public}
+	if len(query.Owners) > 0 {
+		placeholders, owners := inPlaceholders(query.Owners)
+		visible += " OR Owner IN (" + placeholders + ")"
+		args = append(args, owners...)
+	}
+	if query.Subject != "" {
+		visible += " OR ID IN (SELECT GameID FROM game_collaborators WHERE Subject = ? OR Email = ?)"
+		args = append(args, query.Subject, query.Email)
+	}
+	args = append(args, query.Limit, query.Offset)
+
+	rows, err := m.db.Query("SELECT games.*, "+
+		"MATCH(Title) AGAINST (? IN BOOLEAN MODE) * 2 + MATCH(Title, Description, Tags, Platform) AGAINST (? IN BOOLEAN MODE) AS Score "+
+		"FROM games WHERE MATCH(Title, Description, Tags, Platform) AGAINST (? IN BOOLEAN MODE) "+
+		"AND DeletedAt IS NULL AND ("+visible+") "+
+		"ORDER BY Score DESC, Title LIMIT ? OFFSET ?",
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []models.SearchHit{}
+	for rows.Next() {
+		var hit models.SearchHit
+		err = scanGame(rows, &hit.Game, &hit.Score)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// booleanQuery requires every term and matches it as prefix, e.g. "+mario* +kart*".
+// The terms must not contain operators of the boolean mode.
+func booleanQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = "+" + term + "*"
+	}
+	return strings.Join(parts, " ")
+}
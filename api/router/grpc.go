@@ -0,0 +1,44 @@
+package router
+
+import (
+	"api/apis"
+	gamesv1 "api/proto/games/v1"
+	"api/repositories"
+	"api/rpc"
+	"api/services"
+	"api/shared"
+	"database/sql"
+	"google.golang.org/grpc"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SetupGrpcServer creates the grpc server of the api, which uses the same services and rate limits as the REST api.
+func SetupGrpcServer(db *sql.DB, azureApi apis.IAzureApi, k8s client.Client, authService services.IAuthService, config rpc.Config, rateLimiter RateLimiter) *grpc.Server {
+	//Repositories
+	gamesRepository := repositories.GameRepository(db)
+	gameVersionsRepository := repositories.GameVersionRepository(db)
+	blobsRepository := repositories.BlobRepository(db)
+	organizationsRepository := repositories.OrganizationRepository(db)
+	collaboratorsRepository := repositories.CollaboratorRepository(db)
+	clustersRepository := repositories.ClusterRepository(db)
+
+	//Apis, the game resources are created on the registered clusters
+	k8sApi := apis.ClusterK8sService(clusterService(clustersRepository, gamesRepository, k8s))
+
+	//Services
+	accessService := services.AccessService(organizationsRepository, collaboratorsRepository)
+	blobsService := services.BlobService(blobsRepository, azureApi, services.ArchiveConfigFromEnv())
+	scansService := services.ScanService(services.Scanner(services.ScanConfigFromEnv()), azureApi, blobsRepository)
+	gamesService := services.GameService(gamesRepository, gameVersionsRepository, blobsService, k8sApi, scansService)
+
+	//The methods are limited like the routes of the REST api which do the same
+	rateLimits := map[string]shared.RateLimitPolicy{
+		gamesv1.GameService_List_FullMethodName:        rateLimiter.Config.Reads,
+		gamesv1.GameService_Get_FullMethodName:         rateLimiter.Config.Reads,
+		gamesv1.GameService_WatchStatus_FullMethodName: rateLimiter.Config.Reads,
+		gamesv1.GameService_Upload_FullMethodName:      rateLimiter.Config.Uploads,
+		gamesv1.GameService_Delete_FullMethodName:      rateLimiter.Config.Deletes,
+	}
+
+	return rpc.Server(rpc.GameServer(gamesService, accessService, config.WatchInterval), authService, rateLimiter.Store, rateLimits)
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"api/middlewares"
+	"database/sql"
+)
+
+// RateLimiter contains the buckets which are shared by the REST api and the grpc server, so a user can not
+// bypass the limits of one by calling the other. Rate limiting is disabled if the store is nil.
+type RateLimiter struct {
+	Config middlewares.RateLimitConfig
+	Store  middlewares.IRateLimitStore
+}
+
+// SetupRateLimiter creates the rate limiter which has been configured by the RATE_LIMIT_* environment variables.
+func SetupRateLimiter(db *sql.DB) RateLimiter {
+	config := middlewares.RateLimitConfigFromEnv()
+	if !config.Enabled {
+		return RateLimiter{Config: config}
+	}
+	return RateLimiter{Config: config, Store: middlewares.RateLimitStore(config, db)}
+}
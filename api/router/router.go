@@ -0,0 +1,268 @@
+package router
+
+import (
+	"api/apis"
+	"api/controllers"
+	"api/kube"
+	"api/middlewares"
+	"api/repositories"
+	"api/services"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log"
+	"net/http"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// SetupRouter registers all routes of the api. The clients of azure and k8s, the authorization and the rate limiter are passed in,
+// so the router can also run with fakes.
+func SetupRouter(db *sql.DB, azureApi apis.IAzureApi, k8s client.Client, authService services.IAuthService, rateLimiter RateLimiter) *gin.Engine {
+	//Setup Gin
+	r := gin.Default()
+	//Cors and security headers
+	r.Use(middlewares.SecurityHeadersMiddleware(middlewares.SecurityHeadersConfigFromEnv()))
+	r.Use(middlewares.CORSMiddleware(middlewares.CORSConfigFromEnv(), r))
+
+	//Repositories
+	gamesRepository := repositories.GameRepository(db)
+	gameVersionsRepository := repositories.GameVersionRepository(db)
+	blobsRepository := repositories.BlobRepository(db)
+	romDownloadsRepository := repositories.RomDownloadRepository(db)
+	batchJobsRepository := repositories.BatchJobRepository(db)
+	searchRepository := repositories.MySQLSearchRepository(db)
+	organizationsRepository := repositories.OrganizationRepository(db)
+	orgInvitationsRepository := repositories.OrgInvitationRepository(db)
+	collaboratorsRepository := repositories.CollaboratorRepository(db)
+	playSessionsRepository := repositories.PlaySessionRepository(db)
+	clustersRepository := repositories.ClusterRepository(db)
+
+	//Apis, the game resources are created on the registered clusters
+	clustersService := clusterService(clustersRepository, gamesRepository, k8s)
+	k8sApi := apis.ClusterK8sService(clustersService)
+
+	//Services
+	accessService := services.AccessService(organizationsRepository, collaboratorsRepository)
+	organizationsService := services.OrganizationService(organizationsRepository, orgInvitationsRepository, orgInvitationTTL())
+	blobsService := services.BlobService(blobsRepository, azureApi, services.ArchiveConfigFromEnv())
+	scanConfig := services.ScanConfigFromEnv()
+	scansService := services.ScanService(services.Scanner(scanConfig), azureApi, blobsRepository)
+	gamesService := services.GameService(gamesRepository, gameVersionsRepository, blobsService, k8sApi, scansService)
+	gameVersionsService := services.GameVersionService(gamesRepository, gameVersionsRepository, blobsService, k8sApi, scansService)
+	romsService := services.RomService(gameVersionsRepository, romDownloadsRepository, azureApi, services.RomDownloadConfigFromEnv())
+	collaboratorsService := services.CollaboratorService(gamesService, collaboratorsRepository, accessService)
+	batchService := services.BatchService(gamesService, gamesRepository, batchJobsRepository, accessService, services.BatchConfigFromEnv())
+	searchService := services.SearchService(searchRepository, accessService)
+	usageService := services.UsageService(gamesRepository, playSessionsRepository)
+	catalogService := services.CatalogService(gamesRepository, gameVersionsRepository, blobsService, azureApi, k8sApi, scansService)
+	consistencyConfig := services.ConsistencyConfigFromEnv()
+	consistencyService := services.ConsistencyService(gamesRepository, gameVersionsRepository, blobsRepository, azureApi, k8sApi, consistencyConfig)
+
+	//Background jobs
+	startBlobVerifyJob(blobsService)
+	startTrashPurger(gamesService)
+	//Scans the games again whose scan has failed, e.g. because the scanner was not available
+	services.StartScanRetrier(gamesService, scanConfig.RetryInterval)
+	//Checks the consistency once per CONSISTENCY_CHECK_INTERVAL, the checker is disabled if it is 0
+	if consistencyConfig.Interval > 0 {
+		services.StartConsistencyChecker(consistencyService, consistencyConfig.Interval)
+	}
+	//Checks the consistency before the api is served if CONSISTENCY_CHECK_ON_START is set, e.g. to deploy the games
+	//into the in-memory cluster of the development mode again
+	if consistencyConfig.CheckOnStart {
+		services.CheckConsistency(consistencyService)
+	}
+
+	//Controllers
+	gamesController := controllers.GameController(gamesService, accessService, clustersService, middlewares.AdminSubjectsFromEnv())
+	gameVersionsController := controllers.GameVersionController(gamesService, gameVersionsService, romsService, accessService)
+	batchController := controllers.BatchController(batchService)
+	searchController := controllers.SearchController(searchService)
+	organizationsController := controllers.OrganizationController(organizationsService)
+	collaboratorsController := controllers.CollaboratorController(collaboratorsService)
+	usageController := controllers.UsageController(usageService, gamesService, accessService)
+	catalogController := controllers.CatalogController(catalogService)
+	consistencyController := controllers.ConsistencyController(consistencyService)
+	clustersController := controllers.ClusterController(clustersService)
+
+	//Rate limits
+	readLimit, writeLimit, uploadLimit, deleteLimit, batchLimit := noLimit, noLimit, noLimit, noLimit, noLimit
+	if rateLimiter.Store != nil {
+		readLimit = middlewares.RateLimitMiddleware(rateLimiter.Store, rateLimiter.Config.Reads)
+		writeLimit = middlewares.RateLimitMiddleware(rateLimiter.Store, rateLimiter.Config.Writes)
+		uploadLimit = middlewares.RateLimitMiddleware(rateLimiter.Store, rateLimiter.Config.Uploads)
+		deleteLimit = middlewares.RateLimitMiddleware(rateLimiter.Store, rateLimiter.Config.Deletes)
+		//A batch changes many games at once, so it has its own bucket instead of taking one token of a single game route
+		batchLimit = middlewares.RateLimitMiddleware(rateLimiter.Store, rateLimiter.Config.Batches)
+	}
+
+	//Only administrators can call the admin routes
+	adminOnly := middlewares.AdminMiddleware(middlewares.AdminSubjectsFromEnv())
+
+	// Ping test
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	//Upload a game
+	r.POST("/games", authService.Authorize, uploadLimit, gamesController.UploadGame)
+	//Get all uploaded games
+	r.GET("/games", authService.Authorize, readLimit, gamesController.GetAllGames)
+	//Run an operation on many games, the handler only accepts the action ":batch" (/games:batch)
+	r.POST("/games:action", authService.Authorize, batchLimit, batchController.BatchGames)
+	//Get the progress of a batch job
+	r.GET("/jobs/:id", authService.Authorize, readLimit, batchController.GetJob)
+	//Get a specific game by its id
+	r.GET("/games/:id", authService.Authorize, readLimit, gamesController.GetGameById)
+	//Move a specific game to the trash, identified by its id
+	r.DELETE("/games/:id", authService.Authorize, deleteLimit, gamesController.DeleteGameById)
+	//Search the own and the public games by title, description, tags and platform
+	r.GET("/search", authService.Authorize, readLimit, searchController.Search)
+	//Get all games of the user which are in the trash
+	r.GET("/trash", authService.Authorize, readLimit, gamesController.GetTrash)
+	//Move a game out of the trash and deploy it again
+	r.POST("/games/:id/restore", authService.Authorize, uploadLimit, gamesController.RestoreGame)
+	//Scale a game down, it keeps its url
+	r.POST("/games/:id/stop", authService.Authorize, writeLimit, gamesController.StopGame)
+	//Scale a stopped game up again
+	r.POST("/games/:id/start", authService.Authorize, writeLimit, gamesController.StartGame)
+	//Start a stopped game or roll out new pods of a running game
+	r.POST("/games/:id/restart", authService.Authorize, writeLimit, gamesController.RestartGame)
+	//Update the metadata of a game, supports If-Match
+	r.PATCH("/games/:id", authService.Authorize, writeLimit, gamesController.UpdateGameById)
+	//Replace the game file and roll out the new version, supports If-Match
+	r.PUT("/games/:id/rom", authService.Authorize, uploadLimit, gameVersionsController.ReplaceRom)
+	//Download the game file, either streamed or as redirect to a signed url
+	r.GET("/games/:id/rom", authorizeUnlessSigned(authService.Authorize), readLimit, gameVersionsController.DownloadRom)
+	//Get all versions of a game, the newest version first
+	r.GET("/games/:id/versions", authService.Authorize, readLimit, gameVersionsController.GetAllVersions)
+	//Upload a new version and optionally deploy it to the live or beta channel
+	r.POST("/games/:id/versions", authService.Authorize, uploadLimit, gameVersionsController.UploadVersion)
+	//Deploy an existing version to the live or beta channel
+	r.POST("/games/:id/versions/:version/promote", authService.Authorize, writeLimit, gameVersionsController.PromoteVersion)
+	//Deploy the version before the live version
+	r.POST("/games/:id/rollback", authService.Authorize, writeLimit, gameVersionsController.Rollback)
+	//Remove the beta deployment of a game
+	r.DELETE("/games/:id/channels/beta", authService.Authorize, deleteLimit, gameVersionsController.RemoveBeta)
+	//Get the users with whom a game has been shared
+	r.GET("/games/:id/collaborators", authService.Authorize, readLimit, collaboratorsController.GetCollaborators)
+	//Share a game with a user by subject or email, replaces the permissions of an existing collaborator
+	r.POST("/games/:id/collaborators", authService.Authorize, writeLimit, collaboratorsController.GrantCollaborator)
+	//Revoke all permissions of a collaborator
+	r.DELETE("/games/:id/collaborators/:collaboratorId", authService.Authorize, deleteLimit, collaboratorsController.RevokeCollaborator)
+	//Get the play time of a game in total and per day
+	r.GET("/games/:id/usage", authService.Authorize, readLimit, usageController.GetGameUsage)
+
+	//Report a play session, used by the coordinators and the operator with the token USAGE_REPORT_TOKEN
+	r.POST("/usage/sessions", middlewares.TokenMiddleware(os.Getenv("USAGE_REPORT_TOKEN")), usageController.RecordSession)
+	//Get the play time of all games per game, owner and day, as json or csv (?format=csv)
+	r.GET("/admin/usage", authService.Authorize, adminOnly, readLimit, usageController.GetUsageReport)
+	//Export the games with their versions and files as tar.gz archive, e.g. to move them to another environment
+	r.GET("/admin/export", authService.Authorize, adminOnly, readLimit, catalogController.ExportCatalog)
+	//Import an exported archive, the games keep their ids (?mode=preserve) or get new ones (?mode=remap)
+	r.POST("/admin/import", authService.Authorize, adminOnly, uploadLimit, catalogController.ImportCatalog)
+	//Report games without game resources, game resources without games and blobs without game versions
+	r.GET("/admin/consistency", authService.Authorize, adminOnly, readLimit, consistencyController.GetConsistencyReport)
+	//Check the consistency and repair the kinds of inconsistencies which are enabled by CONSISTENCY_REPAIR
+	r.POST("/admin/consistency/repair", authService.Authorize, adminOnly, uploadLimit, consistencyController.RepairConsistency)
+	//Get the clusters on which the games are placed with the number of their games
+	r.GET("/admin/clusters", authService.Authorize, adminOnly, readLimit, clustersController.GetAllClusters)
+	//Register a cluster or change its region, capacity, labels, kubeconfig or cordon
+	r.PUT("/admin/clusters/:name", authService.Authorize, adminOnly, uploadLimit, clustersController.SaveCluster)
+	//Remove a cluster without games
+	r.DELETE("/admin/clusters/:name", authService.Authorize, adminOnly, deleteLimit, clustersController.DeleteCluster)
+
+	//Prometheus metrics, scraped with the token METRICS_TOKEN
+	r.GET("/metrics", middlewares.TokenMiddleware(os.Getenv("METRICS_TOKEN")), gin.WrapH(promhttp.Handler()))
+
+	//Create an organization, the user becomes its owner
+	r.POST("/orgs", authService.Authorize, writeLimit, organizationsController.CreateOrganization)
+	//Get all organizations of the user
+	r.GET("/orgs", authService.Authorize, readLimit, organizationsController.GetAllOrganizations)
+	//Get an organization of the user
+	r.GET("/orgs/:id", authService.Authorize, readLimit, organizationsController.GetOrganizationById)
+	//Get the members of an organization
+	r.GET("/orgs/:id/members", authService.Authorize, readLimit, organizationsController.GetMembers)
+	//Change the role of a member
+	r.PUT("/orgs/:id/members/:subject", authService.Authorize, writeLimit, organizationsController.UpdateMember)
+	//Remove a member or leave the organization
+	r.DELETE("/orgs/:id/members/:subject", authService.Authorize, deleteLimit, organizationsController.RemoveMember)
+	//Invite a user by email
+	r.POST("/orgs/:id/invitations", authService.Authorize, writeLimit, organizationsController.CreateInvitation)
+	//Get the pending invitations of an organization
+	r.GET("/orgs/:id/invitations", authService.Authorize, readLimit, organizationsController.GetInvitations)
+	//Revoke an invitation
+	r.DELETE("/orgs/:id/invitations/:invitationId", authService.Authorize, deleteLimit, organizationsController.RevokeInvitation)
+	//Join the organization of an invitation
+	r.POST("/invitations/accept", authService.Authorize, writeLimit, organizationsController.AcceptInvitation)
+
+	return r
+}
+
+// startBlobVerifyJob re-hashes all blobs once per BLOB_VERIFY_INTERVAL, the job is disabled if the interval is 0
+func startBlobVerifyJob(blobsService services.IBlobService) {
+	interval := os.Getenv("BLOB_VERIFY_INTERVAL")
+	if interval == "" {
+		interval = "24h"
+	}
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		log.Fatalf("Invalid BLOB_VERIFY_INTERVAL %s: %s", interval, err)
+	}
+	if duration > 0 {
+		services.StartBlobVerifyJob(blobsService, duration)
+	}
+}
+
+// startTrashPurger removes games permanently after they have been in the trash for TRASH_RETENTION, the purger is disabled if it is 0
+func startTrashPurger(gamesService services.IGameService) {
+	retention := os.Getenv("TRASH_RETENTION")
+	if retention == "" {
+		retention = "720h"
+	}
+	duration, err := time.ParseDuration(retention)
+	if err != nil {
+		log.Fatalf("Invalid TRASH_RETENTION %s: %s", retention, err)
+	}
+	if duration > 0 {
+		services.StartTrashPurger(gamesService, duration)
+	}
+}
+
+// clusterService places the games on the registered clusters, the default cluster is the cluster of k8s and
+// the other clusters are connected with their kubeconfig
+func clusterService(clusters repositories.IClusterRepository, games repositories.IGameRepository, k8s client.Client) services.IClusterService {
+	return services.ClusterService(clusters, games, k8s, apis.NamespaceConfigFromEnv(),
+		services.KubeconfigConnector(k8s.Scheme(), kube.ConfigFromEnv().RefreshInterval))
+}
+
+// orgInvitationTTL returns how long invitations to organizations are valid, ORG_INVITATION_TTL defaults to 7 days
+func orgInvitationTTL() time.Duration {
+	ttl := os.Getenv("ORG_INVITATION_TTL")
+	if ttl == "" {
+		ttl = "168h"
+	}
+	duration, err := time.ParseDuration(ttl)
+	if err != nil || duration <= 0 {
+		log.Fatalf("Invalid ORG_INVITATION_TTL %s", ttl)
+	}
+	return duration
+}
+
+// authorizeUnlessSigned lets requests with a signed url through without a token, the controller verifies their signature
+func authorizeUnlessSigned(authorize gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("signature") != "" {
+			c.Next()
+			return
+		}
+		authorize(c)
+	}
+}
+
+// noLimit is used instead of a rate limiter if rate limiting is disabled
+func noLimit(c *gin.Context) {
+	c.Next()
+}
@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"api/services"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log"
+	"strings"
+)
+
+// publicServices can be called without a token, e.g. by the health checks of kubernetes
+var publicServices = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+}
+
+type identityKey struct{}
+
+// identityFromContext returns the user which has been verified by the interceptors
+func identityFromContext(ctx context.Context) *services.Identity {
+	identity, _ := ctx.Value(identityKey{}).(*services.Identity)
+	if identity == nil {
+		return &services.Identity{}
+	}
+	return identity
+}
+
+// AuthUnaryInterceptor verifies the bearer token in the metadata "authorization" like the REST api does.
+func AuthUnaryInterceptor(auth services.IAuthService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, auth, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor verifies the bearer token of streaming calls like AuthUnaryInterceptor.
+func AuthStreamInterceptor(auth services.IAuthService) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(stream.Context(), auth, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authorize returns a context with the user of the token
+func authorize(ctx context.Context, auth services.IAuthService, method string) (context.Context, error) {
+	for _, service := range publicServices {
+		if strings.HasPrefix(method, service) {
+			return ctx, nil
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "Missing token")
+	}
+	identity, err := auth.Verify(ctx, strings.TrimPrefix(values[0], "Bearer "))
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+// authorizedStream is a stream with the context of the user
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authorizedStream) Context() context.Context {
+	return a.ctx
+}
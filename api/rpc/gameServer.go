@@ -0,0 +1,309 @@
+package rpc
+
+import (
+	"api/models"
+	gamesv1 "api/proto/games/v1"
+	"api/services"
+	"api/shared"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxUploadMemory is the size of an upload which is kept in memory, larger uploads are buffered on disk like REST uploads
+const maxUploadMemory = 32 << 20
+
+type gameServer struct {
+	gamesv1.UnimplementedGameServiceServer
+	games         services.IGameService
+	access        services.IAccessService
+	watchInterval time.Duration
+}
+
+func (g gameServer) List(ctx context.Context, request *gamesv1.ListRequest) (*gamesv1.ListResponse, error) {
+	identity := identityFromContext(ctx)
+	owners := []string{request.GetOwner()}
+	if request.GetOwner() != "" {
+		authorized, err := g.access.HasPermission(identity.Subject, request.GetOwner(), shared.Permission_View)
+		if err != nil {
+			return nil, statusFromError(err)
+		}
+		if !authorized {
+			return nil, status.Error(codes.PermissionDenied, "You don't have permission to access this resource")
+		}
+	} else {
+		var err error
+		owners, err = g.access.Owners(identity.Subject)
+		if err != nil {
+			return nil, statusFromError(err)
+		}
+	}
+
+	games, err := g.games.FindAllByOwners(owners)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	response := &gamesv1.ListResponse{Games: make([]*gamesv1.Game, len(games))}
+	for i := range games {
+		response.Games[i] = gameMessage(&games[i])
+	}
+	return response, nil
+}
+
+func (g gameServer) Get(ctx context.Context, request *gamesv1.GetRequest) (*gamesv1.Game, error) {
+	game, err := g.readGame(ctx, request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return gameMessage(game), nil
+}
+
+// Upload receives the metadata and then the file. The file is passed to the game service as multipart file,
+// so it is stored and deployed like a file of the REST api.
+func (g gameServer) Upload(stream gamesv1.GameService_UploadServer) error {
+	identity := identityFromContext(stream.Context())
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	upload := first.GetMetadata()
+	if upload == nil || upload.GetFileName() == "" {
+		return status.Error(codes.InvalidArgument, "The first message must contain the metadata with the file name")
+	}
+
+	title := upload.GetTitle()
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(upload.GetFileName()), filepath.Ext(upload.GetFileName()))
+	}
+	metadata := models.GameMetadata{
+		Title:       title,
+		Description: upload.GetDescription(),
+		Tags:        shared.NormalizeTags(upload.GetTags()),
+		Platform:    strings.TrimSpace(upload.GetPlatform()),
+	}
+	if message := shared.ValidateMetadata(&metadata.Description, &metadata.Tags, &metadata.Platform); message != "" {
+		return status.Error(codes.InvalidArgument, message)
+	}
+
+	//Games can be uploaded for an organization ("org:<id>")
+	owner := identity.Subject
+	if requested := upload.GetOwner(); requested != "" && requested != identity.Subject {
+		_, isOrg := shared.ParseOrgOwner(requested)
+		authorized := false
+		if isOrg {
+			authorized, err = g.access.HasPermission(identity.Subject, requested, shared.Permission_Update)
+			if err != nil {
+				return statusFromError(err)
+			}
+		}
+		if !authorized {
+			return status.Error(codes.PermissionDenied, "You are not allowed to upload games for this owner")
+		}
+		owner = requested
+	}
+
+	form, file, err := receiveFile(stream, filepath.Base(upload.GetFileName()))
+	if err != nil {
+		return err
+	}
+	defer form.RemoveAll()
+
+	game, err := g.games.Save(file, metadata, owner)
+	if err != nil {
+		return statusFromError(err)
+	}
+	return stream.SendAndClose(gameMessage(game))
+}
+
+func (g gameServer) Delete(ctx context.Context, request *gamesv1.DeleteRequest) (*gamesv1.DeleteResponse, error) {
+	id, err := parseID(request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	identity := identityFromContext(ctx)
+	owner, err := g.games.ReadOwner(id)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	authorized, err := g.access.HasGamePermission(identity.Subject, identity.Email, id, owner, shared.Permission_Delete)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	if !authorized {
+		return nil, status.Error(codes.PermissionDenied, "You don't have permission to access this resource")
+	}
+
+	if err = g.games.Delete(id); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &gamesv1.DeleteResponse{}, nil
+}
+
+// WatchStatus reads the game once per watch interval and sends it whenever its status has changed.
+func (g gameServer) WatchStatus(request *gamesv1.WatchStatusRequest, stream gamesv1.GameService_WatchStatusServer) error {
+	game, err := g.readGame(stream.Context(), request.GetId())
+	if err != nil {
+		return err
+	}
+	if err = stream.Send(gameMessage(game)); err != nil {
+		return err
+	}
+
+	lastStatus := game.Status
+	ticker := time.NewTicker(g.watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		game, err = g.games.FindByID(game.ID)
+		if err != nil {
+			return statusFromError(err)
+		}
+		if game == nil {
+			return status.Error(codes.NotFound, "Game not found")
+		}
+		if game.Status != lastStatus {
+			lastStatus = game.Status
+			if err = stream.Send(gameMessage(game)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readGame returns a game which can be viewed by the user, public games can be viewed by everyone
+func (g gameServer) readGame(ctx context.Context, rawID string) (*models.Game, error) {
+	id, err := parseID(rawID)
+	if err != nil {
+		return nil, err
+	}
+	game, err := g.games.FindByID(id)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	if game == nil {
+		return nil, status.Error(codes.NotFound, "Game not found")
+	}
+
+	if game.Visibility != shared.Visibility_Public {
+		identity := identityFromContext(ctx)
+		authorized, err := g.access.HasGamePermission(identity.Subject, identity.Email, game.ID, game.Owner, shared.Permission_View)
+		if err != nil {
+			return nil, statusFromError(err)
+		}
+		if !authorized {
+			log.Print(fmt.Sprintf("%s tried to access an resource of %s", identity.Subject, game.Owner))
+			return nil, status.Error(codes.PermissionDenied, "You don't have permission to access this resource")
+		}
+	}
+	return game, nil
+}
+
+// receiveFile reads the chunks of the stream into a multipart file like the file of a REST upload.
+// The form has to be removed after the upload, because large files are buffered on disk.
+func receiveFile(stream gamesv1.GameService_UploadServer, fileName string) (*multipart.Form, *multipart.FileHeader, error) {
+	form, file, err := shared.FormFile(fileName, &chunkReader{stream: stream}, maxUploadMemory)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, nil, err
+		}
+		return nil, nil, statusFromError(err)
+	}
+	if file.Size == 0 {
+		form.RemoveAll()
+		return nil, nil, status.Error(codes.InvalidArgument, "The file is empty")
+	}
+	return form, file, nil
+}
+
+// chunkReader reads the chunks of an upload stream
+type chunkReader struct {
+	stream gamesv1.GameService_UploadServer
+	chunk  []byte
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.chunk) == 0 {
+		request, err := c.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if request.GetMetadata() != nil {
+			return 0, status.Error(codes.InvalidArgument, "Only the first message may contain the metadata")
+		}
+		c.chunk = request.GetChunk()
+	}
+	n := copy(p, c.chunk)
+	c.chunk = c.chunk[n:]
+	return n, nil
+}
+
+func parseID(rawID string) (uuid.UUID, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil || id == uuid.Nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "Invalid game ID")
+	}
+	return id, nil
+}
+
+// gameMessage maps a game to its message
+func gameMessage(game *models.Game) *gamesv1.Game {
+	return &gamesv1.Game{
+		Id:          game.ID.String(),
+		Title:       game.Title,
+		Status:      string(game.Status),
+		Url:         game.Url,
+		LiveVersion: int32(game.LiveVersion),
+		BetaVersion: int32(game.BetaVersion),
+		BetaUrl:     game.BetaUrl,
+		Checksum:    game.Checksum,
+		Visibility:  string(game.Visibility),
+		Description: game.Description,
+		Tags:        game.Tags,
+		Platform:    game.Platform,
+		Revision:    int32(game.Revision),
+	}
+}
+
+// statusFromError maps the errors of the services to grpc status codes like the REST api maps them to http status codes.
+func statusFromError(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, "Game not found")
+	case errors.Is(err, shared.ErrPreconditionFailed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, shared.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, shared.ErrOrgNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, shared.ErrContentRejected):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, shared.ErrScannerUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, shared.ErrGameNotDeployed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func GameServer(games services.IGameService, access services.IAccessService, watchInterval time.Duration) gamesv1.GameServiceServer {
+	return &gameServer{
+		games:         games,
+		access:        access,
+		watchInterval: watchInterval,
+	}
+}
@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"api/middlewares"
+	"api/shared"
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log"
+	"math"
+	"strconv"
+	"time"
+)
+
+// RateLimitUnaryInterceptor limits the calls per subject with the policy of their method, methods without policy are not limited.
+// The keys are the keys of the REST api, so both share their buckets if they use the same store.
+// It must be chained after the authorization. If the store fails, the call is let through.
+func RateLimitUnaryInterceptor(store middlewares.IRateLimitStore, policies map[string]shared.RateLimitPolicy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		retryAfter, err := takeToken(ctx, store, policies, info.FullMethod)
+		if err != nil {
+			_ = grpc.SetHeader(ctx, retryAfter)
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor limits streaming calls like RateLimitUnaryInterceptor, a stream takes one token when it is opened.
+func RateLimitStreamInterceptor(store middlewares.IRateLimitStore, policies map[string]shared.RateLimitPolicy) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		retryAfter, err := takeToken(stream.Context(), store, policies, info.FullMethod)
+		if err != nil {
+			_ = stream.SetHeader(retryAfter)
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// takeToken takes a token from the bucket of the subject, if there is none left it returns ResourceExhausted and
+// the metadata "retry-after" with the seconds until the next token is available
+func takeToken(ctx context.Context, store middlewares.IRateLimitStore, policies map[string]shared.RateLimitPolicy, method string) (metadata.MD, error) {
+	policy, ok := policies[method]
+	if !ok {
+		return nil, nil
+	}
+
+	key := fmt.Sprintf("%s:sub:%s", policy.Name, identityFromContext(ctx).Subject)
+	result, err := store.Take(key, policy, time.Now())
+	if err != nil {
+		log.Println(fmt.Sprintf("Rate limiter failed, call will be allowed: %s", err))
+		return nil, nil
+	}
+	if !result.Allowed {
+		retryAfter := metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		return retryAfter, status.Error(codes.ResourceExhausted, "Too many requests, please try again later")
+	}
+	return nil, nil
+}
@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"api/middlewares"
+	gamesv1 "api/proto/games/v1"
+	"api/services"
+	"api/shared"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modes of the grpc server
+const (
+	//Mode_Shared serves grpc on the port of the REST api
+	Mode_Shared = "shared"
+	//Mode_Separate serves grpc on its own port
+	Mode_Separate = "separate"
+	//Mode_Disabled does not serve grpc
+	Mode_Disabled = "disabled"
+)
+
+type Config struct {
+	Mode string
+	//Port of the grpc server in the separate mode
+	Port int
+	//Interval in which WatchStatus reads the status of a game
+	WatchInterval time.Duration
+}
+
+// ConfigFromEnv reads the config from the environment variables GRPC_MODE, GRPC_PORT and GRPC_WATCH_INTERVAL.
+func ConfigFromEnv() Config {
+	config := Config{
+		Mode:          os.Getenv("GRPC_MODE"),
+		Port:          9090,
+		WatchInterval: 5 * time.Second,
+	}
+	if config.Mode == "" {
+		config.Mode = Mode_Shared
+	}
+	if config.Mode != Mode_Shared && config.Mode != Mode_Separate && config.Mode != Mode_Disabled {
+		log.Fatalf("Unknown GRPC_MODE %s", config.Mode)
+	}
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		parsed, err := strconv.Atoi(port)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("Invalid GRPC_PORT %s", port)
+		}
+		config.Port = parsed
+	}
+	if interval := os.Getenv("GRPC_WATCH_INTERVAL"); interval != "" {
+		duration, err := time.ParseDuration(interval)
+		if err != nil || duration <= 0 {
+			log.Fatalf("Invalid GRPC_WATCH_INTERVAL %s", interval)
+		}
+		config.WatchInterval = duration
+	}
+	return config
+}
+
+// Server creates a grpc server with the game service, the health service and reflection.
+// All calls except health checks and reflection need a token. The methods of the game service are limited by the given
+// policies, they are not limited if the store is nil.
+func Server(gameServer gamesv1.GameServiceServer, auth services.IAuthService, rateLimitStore middlewares.IRateLimitStore, rateLimits map[string]shared.RateLimitPolicy) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{AuthUnaryInterceptor(auth)}
+	stream := []grpc.StreamServerInterceptor{AuthStreamInterceptor(auth)}
+	if rateLimitStore != nil {
+		unary = append(unary, RateLimitUnaryInterceptor(rateLimitStore, rateLimits))
+		stream = append(stream, RateLimitStreamInterceptor(rateLimitStore, rateLimits))
+	}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	gamesv1.RegisterGameServiceServer(server, gameServer)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(gamesv1.GameService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+	return server
+}
+
+// Handler serves grpc and the REST api on the same port. Grpc requests are recognized by their content type,
+// cleartext HTTP/2 (h2c) is accepted, so grpc clients don't need TLS when TLS is terminated by the ingress.
+func Handler(grpcServer *grpc.Server, rest http.Handler) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		rest.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
@@ -14,7 +14,7 @@ import (
 
 func ConnectToDatabase() *sql.DB {
 	// connect to db using standard Go database/sql API
-	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
 		os.Getenv("MYSQL_ROOT_USER"),
 		os.Getenv("MYSQL_ROOT_PASSWORD"),
 		os.Getenv("MYSQL_HOST"),
@@ -45,12 +45,14 @@ func MigrateDatabase(db *sql.DB) {
 		log.Fatal(err)
 	}
 
-	//Sort the list of fileNames
+	//Sort the list of fileNames by their id, so 10_x is applied after 9_x
 	var fileNames []string
 	for _, file := range files {
 		fileNames = append(fileNames, file.Name())
 	}
-	sort.Strings(fileNames)
+	sort.SliceStable(fileNames, func(i, j int) bool {
+		return migrationOrder(fileNames[i]) < migrationOrder(fileNames[j])
+	})
 
 	//For each migration script
 	for _, fileName := range fileNames {
@@ -86,7 +88,7 @@ func MigrateDatabase(db *sql.DB) {
 			log.Println("Executing migration: " + fileName)
 			requests := strings.Split(string(content), ";")
 			for _, request := range requests {
-				if len(request) == 0 {
+				if len(strings.TrimSpace(request)) == 0 {
 					continue
 				}
 
@@ -101,6 +103,15 @@ func MigrateDatabase(db *sql.DB) {
 	log.Println("Finished migrations")
 }
 
+// migrationOrder returns the id of a migration script, files without an id are sorted first and ignored later
+func migrationOrder(fileName string) int {
+	id, err := strconv.Atoi(strings.Split(fileName, "_")[0])
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
 // getMigrationIds returns the Ids of migrations which have been applied to the database
 func getMigrationIds(db *sql.DB) []int {
 	var migrations []int
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"api/repositories"
+	"api/shared"
+	"github.com/google/uuid"
+)
+
+// IAccessService decides which games a user can access.
+// Games are owned either by a user or by an organization, whose members can access them according to their role.
+// Besides, single games can be shared with collaborators.
+type IAccessService interface {
+	HasPermission(subject string, owner string, permission shared.Permission) (bool, error)
+	HasGamePermission(subject string, email string, gameID uuid.UUID, owner string, permission shared.Permission) (bool, error)
+	Owners(subject string) ([]string, error)
+}
+
+type accessService struct {
+	orgs          repositories.IOrganizationRepository
+	collaborators repositories.ICollaboratorRepository
+}
+
+// HasPermission returns true if the user has the permission on the games of the owner.
+func (a accessService) HasPermission(subject string, owner string, permission shared.Permission) (bool, error) {
+	if subject == "" {
+		return false, nil
+	}
+	if owner == subject {
+		return true, nil
+	}
+
+	orgID, ok := shared.ParseOrgOwner(owner)
+	if !ok {
+		return false, nil
+	}
+	role, err := a.orgs.FindRole(orgID, subject)
+	if err != nil {
+		return false, err
+	}
+	return role.Allows(permission), nil
+}
+
+// HasGamePermission returns true if the user has the permission on the games of the owner
+// or if the permission on the game has been granted to the subject or the verified email of the user.
+// Collaborators with any permission can view the game.
+func (a accessService) HasGamePermission(subject string, email string, gameID uuid.UUID, owner string, permission shared.Permission) (bool, error) {
+	authorized, err := a.HasPermission(subject, owner, permission)
+	if err != nil || authorized || subject == "" {
+		return authorized, err
+	}
+
+	granted, err := a.collaborators.FindPermissions(gameID, subject, email)
+	if err != nil {
+		return false, err
+	}
+	for _, grant := range granted {
+		if grant == permission || permission == shared.Permission_View {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Owners returns the owners whose games the user can see, which are the user and the organizations of the user.
+func (a accessService) Owners(subject string) ([]string, error) {
+	memberships, err := a.orgs.FindAllBySubject(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := []string{subject}
+	for _, membership := range memberships {
+		owners = append(owners, shared.OrgOwner(membership.Organization.ID))
+	}
+	return owners, nil
+}
+
+func AccessService(orgs repositories.IOrganizationRepository, collaborators repositories.ICollaboratorRepository) IAccessService {
+	return &accessService{
+		orgs:          orgs,
+		collaborators: collaborators,
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"api/models"
+	"api/shared"
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"strings"
+)
+
+// ArchiveConfig limits the archives which are extracted on upload.
+type ArchiveConfig struct {
+	//MaxFiles is the maximum number of files of an archive
+	MaxFiles int
+	//MaxSize is the maximum size of the extracted files in bytes
+	MaxSize int64
+}
+
+// ArchiveConfigFromEnv reads the config from the environment variables ARCHIVE_MAX_FILES and ARCHIVE_MAX_SIZE_MB.
+func ArchiveConfigFromEnv() ArchiveConfig {
+	return ArchiveConfig{
+		MaxFiles: intFromEnv("ARCHIVE_MAX_FILES", 256),
+		MaxSize:  int64(intFromEnv("ARCHIVE_MAX_SIZE_MB", 4096)) << 20,
+	}
+}
+
+// bundleManifest is the optional file of an archive which names its entry and BIOS files
+const bundleManifest = "manifest.json"
+
+// entryExtensions are the extensions of files which are loaded by the emulator and reference the other files of a game,
+// the first extension which matches exactly one file of an archive without manifest selects its entry
+var entryExtensions = []string{".m3u", ".cue", ".gdi", ".ccd"}
+
+// archive is an extracted archive. Its files are buffered in forms, which have to be removed.
+type archive struct {
+	bundle models.Bundle
+	files  map[string]*multipart.FileHeader
+	forms  []*multipart.Form
+}
+
+func (a *archive) remove() {
+	for _, form := range a.forms {
+		_ = form.RemoveAll()
+	}
+}
+
+// readArchive extracts a zip archive and validates its files against its manifest. The entry overrides the entry of the manifest,
+// if neither is set, the entry is the only file of the archive or the only cue sheet or playlist.
+func readArchive(fileHeader *multipart.FileHeader, entry string, config ArchiveConfig) (*archive, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := zip.NewReader(file, fileHeader.Size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", shared.ErrInvalidArchive, err)
+	}
+
+	result := &archive{files: map[string]*multipart.FileHeader{}}
+	err = result.extract(reader, config)
+	if err == nil {
+		if entry != "" {
+			result.bundle.Entry = entry
+		}
+		err = result.validate()
+	}
+	if err != nil {
+		result.remove()
+		return nil, err
+	}
+	return result, nil
+}
+
+// extract reads the manifest and buffers all other files of the archive
+func (a *archive) extract(reader *zip.Reader, config ArchiveConfig) error {
+	var size int64
+	for _, entry := range reader.File {
+		name := entry.Name
+		if strings.HasSuffix(name, "/") || strings.HasPrefix(name, "__MACOSX/") {
+			continue
+		}
+		if !entry.Mode().IsRegular() {
+			return fmt.Errorf("%w: %s is not a regular file", shared.ErrInvalidArchive, name)
+		}
+		if !isBundlePath(name) {
+			return fmt.Errorf("%w: the path %s is not allowed", shared.ErrInvalidArchive, name)
+		}
+		if _, ok := a.files[name]; ok {
+			return fmt.Errorf("%w: the file %s is contained twice", shared.ErrInvalidArchive, name)
+		}
+		if len(a.files) == config.MaxFiles {
+			return fmt.Errorf("%w: the archive has more than %d files", shared.ErrInvalidArchive, config.MaxFiles)
+		}
+
+		content, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %s", shared.ErrInvalidArchive, err)
+		}
+		if name == bundleManifest {
+			err = json.NewDecoder(io.LimitReader(content, 1<<20)).Decode(&a.bundle)
+			content.Close()
+			if err != nil {
+				return fmt.Errorf("%w: the manifest is invalid: %s", shared.ErrInvalidArchive, err)
+			}
+			continue
+		}
+		//The sizes in the archive are not trusted, the content is limited to the remaining size
+		form, header, err := shared.FormFile(path.Base(name), io.LimitReader(content, config.MaxSize-size+1), 0)
+		content.Close()
+		if err != nil {
+			return fmt.Errorf("%w: %s", shared.ErrInvalidArchive, err)
+		}
+		a.forms = append(a.forms, form)
+		size += header.Size
+		if size > config.MaxSize {
+			return fmt.Errorf("%w: the extracted files exceed %d MB", shared.ErrInvalidArchive, config.MaxSize>>20)
+		}
+		a.files[name] = header
+	}
+	return nil
+}
+
+// validate checks that the entry and the BIOS files are part of the archive, that the entry is the only file with its extension
+// and that the files which are referenced by cue sheets and playlists are complete
+func (a *archive) validate() error {
+	if a.bundle.Bios == nil {
+		a.bundle.Bios = []string{}
+	}
+	bios := map[string]bool{}
+	for _, name := range a.bundle.Bios {
+		if _, ok := a.files[name]; !ok {
+			return fmt.Errorf("%w: the BIOS file %s is missing", shared.ErrInvalidArchive, name)
+		}
+		bios[name] = true
+	}
+
+	if a.bundle.Entry == "" {
+		a.bundle.Entry = a.findEntry(bios)
+		if a.bundle.Entry == "" {
+			return fmt.Errorf("%w: the entry file is ambiguous, name it in the %s of the archive", shared.ErrInvalidArchive, bundleManifest)
+		}
+	}
+	if _, ok := a.files[a.bundle.Entry]; !ok || bios[a.bundle.Entry] {
+		return fmt.Errorf("%w: the entry file %s is missing", shared.ErrInvalidArchive, a.bundle.Entry)
+	}
+	//The emulator lists the files with the extension of the entry as games, see gameEnv of the operator
+	for name := range a.files {
+		if name != a.bundle.Entry && strings.EqualFold(path.Ext(name), path.Ext(a.bundle.Entry)) {
+			return fmt.Errorf("%w: %s has the extension of the entry file %s, use a playlist as entry", shared.ErrInvalidArchive, name, a.bundle.Entry)
+		}
+	}
+
+	checked := map[string]bool{}
+	pending := []string{a.bundle.Entry}
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if checked[name] {
+			continue
+		}
+		checked[name] = true
+
+		references, err := a.references(name)
+		if err != nil {
+			return err
+		}
+		for _, reference := range references {
+			if _, ok := a.files[reference]; !ok {
+				return fmt.Errorf("%w: the file %s which is referenced by %s is missing", shared.ErrInvalidArchive, reference, name)
+			}
+		}
+		pending = append(pending, references...)
+	}
+	return nil
+}
+
+// findEntry returns the only file of the archive, which is not a BIOS file, or the only file with an entry extension
+func (a *archive) findEntry(bios map[string]bool) string {
+	candidates := []string{}
+	for name := range a.files {
+		if !bios[name] {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	for _, extension := range entryExtensions {
+		entries := []string{}
+		for _, name := range candidates {
+			if strings.EqualFold(path.Ext(name), extension) {
+				entries = append(entries, name)
+			}
+		}
+		if len(entries) == 1 {
+			return entries[0]
+		}
+		if len(entries) > 1 {
+			return ""
+		}
+	}
+	return ""
+}
+
+// references returns the paths of the files which are referenced by a cue sheet or a playlist, other files reference no files
+func (a *archive) references(name string) ([]string, error) {
+	extension := strings.ToLower(path.Ext(name))
+	if extension != ".cue" && extension != ".m3u" {
+		return nil, nil
+	}
+	file, err := a.files[name].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	references := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		reference := ""
+		switch {
+		case extension == ".cue" && len(line) > 5 && strings.EqualFold(line[:5], "FILE "):
+			reference = cueFileName(strings.TrimSpace(line[5:]))
+		case extension == ".m3u" && line != "" && !strings.HasPrefix(line, "#"):
+			reference = line
+		}
+		if reference != "" {
+			//Cue sheets which have been created on Windows use backslashes
+			references = append(references, path.Join(path.Dir(name), strings.ReplaceAll(reference, "\\", "/")))
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s is not readable: %s", shared.ErrInvalidArchive, name, err)
+	}
+	return references, nil
+}
+
+// cueFileName returns the file name of a FILE command of a cue sheet, e.g. "Track 01.bin" of `"Track 01.bin" BINARY`
+func cueFileName(arguments string) string {
+	if strings.HasPrefix(arguments, "\"") {
+		name, _, _ := strings.Cut(arguments[1:], "\"")
+		return name
+	}
+	name, _, _ := strings.Cut(arguments, " ")
+	return name
+}
+
+// isBundlePath returns true if the path is relative and stays inside the bundle
+func isBundlePath(name string) bool {
+	return name != "" && name == path.Clean(name) && !path.IsAbs(name) && name != ".." && !strings.HasPrefix(name, "../") &&
+		!strings.ContainsAny(name, "\\\n\r\x00")
+}
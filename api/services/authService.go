@@ -2,38 +2,88 @@ package services
 
 import (
 	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"google.golang.org/api/idtoken"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 )
 
+// webClientID is the OAuth client of the frontend
+const webClientID = "516825360638-ai7mibm97c1i5o66l18iqlfuqffl1dba.apps.googleusercontent.com"
+
+// AuthAudiencesFromEnv reads the OAuth clients whose tokens are accepted from the environment variable AUTH_AUDIENCES,
+// separated by commas. The client of the frontend is always accepted, further clients are e.g. used by the igs cli.
+func AuthAudiencesFromEnv() []string {
+	audiences := []string{webClientID}
+	for _, audience := range strings.Split(os.Getenv("AUTH_AUDIENCES"), ",") {
+		if audience = strings.TrimSpace(audience); audience != "" && audience != webClientID {
+			audiences = append(audiences, audience)
+		}
+	}
+	return audiences
+}
+
+// Identity is the user of a verified token
+type Identity struct {
+	Subject string
+	//Email is only set if it has been verified by Google
+	Email string
+}
+
 type IAuthService interface {
 	Authorize(_ *gin.Context)
+	Verify(ctx context.Context, token string) (*Identity, error)
 }
 
 type authService struct {
+	audiences map[string]bool
 }
 
-func (_ authService) Authorize(c *gin.Context) {
-	tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
-	payload, err := idtoken.Validate(context.Background(), tokenString, "516825360638-ai7mibm97c1i5o66l18iqlfuqffl1dba.apps.googleusercontent.com")
-
+func (a authService) Authorize(c *gin.Context) {
+	identity, err := a.Verify(c.Request.Context(), strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
 	if err != nil {
 		log.Println(err.Error())
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid token"})
 		return
 	}
-	if payload == nil {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+
+	c.Set("subject", identity.Subject)
+	if identity.Email != "" {
+		c.Set("email", identity.Email)
 	}
+}
 
-	c.Set("subject", payload.Subject)
+// Verify validates a Google ID token of an accepted client and returns its user. It is used by the REST and the gRPC api.
+func (a authService) Verify(ctx context.Context, token string) (*Identity, error) {
+	//The audience is checked below, because tokens of more than one client are accepted
+	payload, err := idtoken.Validate(ctx, token, "")
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil || payload.Subject == "" {
+		return nil, errors.New("idtoken: the JWT has no subject")
+	}
+	if !a.audiences[payload.Audience] {
+		return nil, errors.New("idtoken: the aud claim of the JWT is not accepted")
+	}
 
+	identity := &Identity{Subject: payload.Subject}
+	//The email is used to accept invitations, so it must have been verified by Google
+	if verified, _ := payload.Claims["email_verified"].(bool); verified {
+		identity.Email, _ = payload.Claims["email"].(string)
+	}
+	return identity, nil
 }
 
-func AuthService() IAuthService {
-	return &authService{}
+func AuthService(audiences []string) IAuthService {
+	accepted := map[string]bool{}
+	for _, audience := range audiences {
+		accepted[audience] = true
+	}
+	return &authService{
+		audiences: accepted,
+	}
 }
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// batchProgressInterval is the number of processed games after which the progress of a job is saved
+const batchProgressInterval = 10
+
+// BatchConfig limits the batch operations.
+type BatchConfig struct {
+	//Number of games which are processed in parallel
+	Concurrency int
+	//Batches with more games run in the background
+	SyncLimit int
+	//Maximum number of games of a batch
+	MaxItems int
+}
+
+// BatchConfigFromEnv reads the config from the environment variables BATCH_CONCURRENCY, BATCH_SYNC_LIMIT and BATCH_MAX_ITEMS.
+func BatchConfigFromEnv() BatchConfig {
+	return BatchConfig{
+		Concurrency: intFromEnv("BATCH_CONCURRENCY", 4),
+		SyncLimit:   intFromEnv("BATCH_SYNC_LIMIT", 20),
+		MaxItems:    intFromEnv("BATCH_MAX_ITEMS", 500),
+	}
+}
+
+func intFromEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil || result <= 0 {
+		log.Fatalf("Invalid %s %s", key, value)
+	}
+	return result
+}
+
+type IBatchService interface {
+	Submit(owner string, email string, operation shared.BatchOperation, ids []uuid.UUID, visibility shared.Visibility) (*models.BatchJob, error)
+	FindJob(id uuid.UUID) (*models.BatchJob, error)
+	MaxItems() int
+}
+
+type batchService struct {
+	games  IGameService
+	repo   repositories.IGameRepository
+	jobs   repositories.IBatchJobRepository
+	access IAccessService
+	config BatchConfig
+}
+
+// Submit runs an operation on the games with the given ids. The email is the verified email of the owner or empty.
+// Small batches run immediately and the finished job is returned,
+// larger batches run in the background and the queued job is returned.
+func (b batchService) Submit(owner string, email string, operation shared.BatchOperation, ids []uuid.UUID, visibility shared.Visibility) (*models.BatchJob, error) {
+	switch operation {
+	case shared.Batch_Delete, shared.Batch_Redeploy, shared.Batch_Stop:
+	case shared.Batch_SetVisibility:
+		if !visibility.IsValid() {
+			return nil, shared.ErrInvalidBatchOperation
+		}
+	default:
+		return nil, shared.ErrInvalidBatchOperation
+	}
+
+	ids = uniqueIDs(ids)
+	job := &models.BatchJob{
+		Owner:     owner,
+		Email:     email,
+		Operation: operation,
+		Status:    shared.Job_Queued,
+		Total:     len(ids),
+		Results:   []models.BatchItemResult{},
+		CreatedAt: time.Now(),
+	}
+	err := b.jobs.Create(job)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) <= b.config.SyncLimit {
+		b.run(job, ids, visibility)
+		return job, nil
+	}
+
+	//The job is modified by the background run, so a copy is returned
+	queued := *job
+	go b.run(job, ids, visibility)
+	return &queued, nil
+}
+
+func (b batchService) FindJob(id uuid.UUID) (*models.BatchJob, error) {
+	return b.jobs.FindByID(id)
+}
+
+func (b batchService) MaxItems() int {
+	return b.config.MaxItems
+}
+
+// run processes the games of the job with bounded concurrency and saves the results.
+func (b batchService) run(job *models.BatchJob, ids []uuid.UUID, visibility shared.Visibility) {
+	var mutex sync.Mutex
+	results := make([]models.BatchItemResult, len(ids))
+
+	job.Status = shared.Job_Running
+	b.saveJob(job)
+
+	//All games are loaded at once, instead of reading the owner of every game
+	games, err := b.repo.FindAllByIDs(ids)
+	if err != nil {
+		for i, id := range ids {
+			results[i] = models.BatchItemResult{ID: id, Outcome: shared.Outcome_Failed, Error: err.Error()}
+		}
+		b.finish(job, results)
+		return
+	}
+	gamesByID := map[uuid.UUID]*models.Game{}
+	for i := range games {
+		gamesByID[games[i].ID] = &games[i]
+	}
+
+	semaphore := make(chan struct{}, b.config.Concurrency)
+	var wg sync.WaitGroup
+	processed := 0
+	for i, id := range ids {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, id uuid.UUID) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			result := b.process(job, gamesByID[id], id, visibility)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			results[i] = result
+			countResult(job, result)
+			processed++
+			if processed%batchProgressInterval == 0 {
+				b.saveJob(job)
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	b.finish(job, results)
+}
+
+// process runs the operation on one game, after checking that the owner of the job is allowed to modify it.
+func (b batchService) process(job *models.BatchJob, game *models.Game, id uuid.UUID, visibility shared.Visibility) models.BatchItemResult {
+	result := models.BatchItemResult{ID: id, Outcome: shared.Outcome_Succeeded}
+	if game == nil {
+		result.Outcome = shared.Outcome_NotFound
+		return result
+	}
+
+	permission := shared.Permission_Update
+	switch job.Operation {
+	case shared.Batch_Delete:
+		permission = shared.Permission_Delete
+	case shared.Batch_Redeploy, shared.Batch_Stop:
+		permission = shared.Permission_DeployControl
+	}
+	authorized, err := b.access.HasGamePermission(job.Owner, job.Email, game.ID, game.Owner, permission)
+	if err != nil {
+		result.Outcome = shared.Outcome_Failed
+		result.Error = err.Error()
+		return result
+	}
+	if !authorized {
+		log.Print(fmt.Sprintf("%s tried to access an resource of %s", job.Owner, game.Owner))
+		result.Outcome = shared.Outcome_Forbidden
+		return result
+	}
+
+	switch job.Operation {
+	case shared.Batch_Delete:
+		err = b.games.Delete(id)
+	case shared.Batch_Redeploy:
+		_, err = b.games.Redeploy(id)
+	case shared.Batch_Stop:
+		_, err = b.games.Stop(id)
+	case shared.Batch_SetVisibility:
+		//The visibility is only used by the api, so the game resource is not updated
+		err = b.repo.UpdateVisibility(id, visibility)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		result.Outcome = shared.Outcome_NotFound
+	} else if err != nil {
+		result.Outcome = shared.Outcome_Failed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (b batchService) finish(job *models.BatchJob, results []models.BatchItemResult) {
+	finishedAt := time.Now()
+	job.Results = results
+	job.Succeeded, job.Failed = 0, 0
+	for _, result := range results {
+		countResult(job, result)
+	}
+	job.Status = shared.Job_Done
+	job.FinishedAt = &finishedAt
+	b.saveJob(job)
+}
+
+func (b batchService) saveJob(job *models.BatchJob) {
+	err := b.jobs.Update(job)
+	if err != nil {
+		log.Println(fmt.Sprintf("Saving batch job %s failed: %s", job.ID.String(), err))
+	}
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func countResult(job *models.BatchJob, result models.BatchItemResult) {
+	if result.Outcome == shared.Outcome_Succeeded {
+		job.Succeeded++
+	} else {
+		job.Failed++
+	}
+}
+
+func BatchService(games IGameService, repository repositories.IGameRepository, jobs repositories.IBatchJobRepository, access IAccessService, config BatchConfig) IBatchService {
+	return &batchService{
+		games:  games,
+		repo:   repository,
+		jobs:   jobs,
+		access: access,
+		config: config,
+	}
+}
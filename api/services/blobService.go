@@ -0,0 +1,335 @@
+package services
+
+import (
+	"api/apis"
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// verifyBatchSize is the number of blobs which are loaded at once by the verify job
+const verifyBatchSize = 100
+
+type IBlobService interface {
+	Store(file *multipart.FileHeader) (*models.Blob, error)
+	StoreArchive(file *multipart.FileHeader, entry string) (*models.Blob, *models.Bundle, error)
+	StoreBundle(files map[string]*multipart.FileHeader) (*models.Blob, error)
+	Files(hash string) ([]models.BundleFile, error)
+	Release(blobName string, checksum string) error
+	Verify(before time.Time) (int, error)
+}
+
+type blobService struct {
+	repository repositories.IBlobRepository
+	azure      apis.IAzureApi
+	archives   ArchiveConfig
+}
+
+// Store saves a game file in the blob storage under the SHA-256 hash of its content.
+// The file is only uploaded if there is no blob with the same content yet.
+func (b blobService) Store(fileHeader *multipart.FileHeader) (*models.Blob, error) {
+	hash, err := shared.Checksum(fileHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	blob := models.Blob{
+		Hash:      hash,
+		BlobName:  BlobName(hash),
+		Size:      fileHeader.Size,
+		CreatedAt: time.Now(),
+	}
+
+	err = b.repository.Reference(&blob, func(blob *models.Blob) error {
+		storageLocation, err := b.azure.UploadGame(os.Getenv("AZURE_CONTAINER_NAME"), blob.BlobName, fileHeader)
+		blob.StorageLocation = storageLocation
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// StoreArchive extracts a zip archive and saves its files as a bundle.
+// Returns the bundle blob and the entry and BIOS files of the archive.
+func (b blobService) StoreArchive(fileHeader *multipart.FileHeader, entry string) (*models.Blob, *models.Bundle, error) {
+	archive, err := readArchive(fileHeader, entry, b.archives)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer archive.remove()
+
+	blob, err := b.StoreBundle(archive.files)
+	if err != nil {
+		return nil, nil, err
+	}
+	return blob, &archive.bundle, nil
+}
+
+// StoreBundle saves several files under the hash of their paths and contents, the files are stored below the blob name.
+// The files are only uploaded if there is no bundle with the same files yet.
+func (b blobService) StoreBundle(fileHeaders map[string]*multipart.FileHeader) (*models.Blob, error) {
+	if len(fileHeaders) == 0 {
+		return nil, fmt.Errorf("%w: the bundle has no files", shared.ErrInvalidArchive)
+	}
+
+	files := []models.BundleFile{}
+	var size int64
+	for path, fileHeader := range fileHeaders {
+		if !isBundlePath(path) {
+			return nil, fmt.Errorf("%w: the path %s is not allowed", shared.ErrInvalidArchive, path)
+		}
+		hash, err := shared.Checksum(fileHeader)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, models.BundleFile{Path: path, Hash: hash, Size: fileHeader.Size})
+		size += fileHeader.Size
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	hash := bundleHash(files)
+	blob := models.Blob{
+		Hash:      hash,
+		BlobName:  BundleName(hash),
+		Size:      size,
+		CreatedAt: time.Now(),
+	}
+
+	err := b.repository.Reference(&blob, func(blob *models.Blob) error {
+		uploaded := []string{}
+		err := b.uploadBundle(blob, files, fileHeaders, &uploaded)
+		if err == nil {
+			err = b.repository.SaveFiles(blob.Hash, files)
+		}
+		if err != nil {
+			//Remove the files of the incomplete bundle, the blob is not created
+			for _, name := range uploaded {
+				if errDel := b.deleteBlob(name); errDel != nil {
+					log.Println(fmt.Sprintf("Delete blob %s failed", name))
+				}
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// Files returns the files of the bundle with the given hash, it is empty for blobs with a single file.
+func (b blobService) Files(hash string) ([]models.BundleFile, error) {
+	return b.repository.FindFiles(hash)
+}
+
+// uploadBundle uploads the files of a bundle and appends the names of the uploaded blobs to uploaded
+func (b blobService) uploadBundle(blob *models.Blob, files []models.BundleFile, fileHeaders map[string]*multipart.FileHeader, uploaded *[]string) error {
+	for _, file := range files {
+		name := BundleFileName(blob.BlobName, file.Path)
+		storageLocation, err := b.azure.UploadGame(os.Getenv("AZURE_CONTAINER_NAME"), name, fileHeaders[file.Path])
+		if err != nil {
+			return err
+		}
+		*uploaded = append(*uploaded, name)
+		if blob.StorageLocation == "" {
+			//The bundle is located where its files are located
+			blob.StorageLocation = strings.TrimSuffix(storageLocation, "/"+file.Path)
+		}
+	}
+	return nil
+}
+
+// Release removes a reference to a blob and deletes the blob if it is not referenced anymore.
+// Blobs which have been uploaded before content addressing have no checksum, they are deleted immediately.
+func (b blobService) Release(blobName string, checksum string) error {
+	if checksum != "" {
+		err := b.repository.Release(checksum, func(blob *models.Blob) error {
+			if shared.IsBundle(blob.BlobName) {
+				return b.deleteBundle(blob)
+			}
+			return b.deleteBlob(blob.BlobName)
+		})
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+	return b.deleteBlob(blobName)
+}
+
+// Verify hashes all blobs again, which have not been verified since before, and marks blobs
+// whose content does not match their hash as corrupted.
+// Returns the number of corrupted blobs.
+func (b blobService) Verify(before time.Time) (int, error) {
+	corrupted := 0
+	for {
+		blobs, err := b.repository.FindNotVerifiedSince(before, verifyBatchSize)
+		if err != nil {
+			return corrupted, err
+		}
+
+		for _, blob := range blobs {
+			status, err := b.verifyBlob(&blob)
+			if err != nil {
+				return corrupted, err
+			}
+			if status == shared.Blob_Corrupted {
+				corrupted++
+				log.Println(fmt.Sprintf("Blob %s is corrupted", blob.BlobName))
+			}
+			err = b.repository.UpdateStatus(blob.Hash, status, time.Now())
+			if err != nil {
+				return corrupted, err
+			}
+		}
+
+		if len(blobs) < verifyBatchSize {
+			return corrupted, nil
+		}
+	}
+}
+
+// verifyBlob downloads the blob and compares the hash of its content with the stored hash.
+// The files of a bundle are verified one by one, a bundle is corrupted if one of its files is corrupted.
+func (b blobService) verifyBlob(blob *models.Blob) (shared.BlobStatus, error) {
+	if !shared.IsBundle(blob.BlobName) {
+		return b.verifyContent(blob.BlobName, blob.Hash)
+	}
+
+	files, err := b.repository.FindFiles(blob.Hash)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 || bundleHash(files) != blob.Hash {
+		return shared.Blob_Corrupted, nil
+	}
+	for _, file := range files {
+		status, err := b.verifyContent(BundleFileName(blob.BlobName, file.Path), file.Hash)
+		if err != nil || status != shared.Blob_Ok {
+			return status, err
+		}
+	}
+	return shared.Blob_Ok, nil
+}
+
+// verifyContent downloads the blob with the given name and compares the hash of its content with the expected hash.
+func (b blobService) verifyContent(blobName string, expected string) (shared.BlobStatus, error) {
+	content, err := b.azure.DownloadGame(os.Getenv("AZURE_CONTAINER_NAME"), blobName)
+	if err != nil {
+		if isNotFound(err) {
+			return shared.Blob_Corrupted, nil
+		}
+		return "", err
+	}
+	defer content.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, content)
+	if err != nil {
+		return "", err
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != expected {
+		return shared.Blob_Corrupted, nil
+	}
+	return shared.Blob_Ok, nil
+}
+
+// deleteBundle deletes the files of a bundle
+func (b blobService) deleteBundle(blob *models.Blob) error {
+	files, err := b.repository.FindFiles(blob.Hash)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		err = b.deleteBlob(BundleFileName(blob.BlobName, file.Path))
+		if err != nil {
+			return err
+		}
+	}
+	return b.repository.DeleteFiles(blob.Hash)
+}
+
+func (b blobService) deleteBlob(blobName string) error {
+	err := b.azure.DeleteGame(os.Getenv("AZURE_CONTAINER_NAME"), blobName)
+	if err != nil {
+		if isNotFound(err) {
+			log.Println(fmt.Sprintf("Blob %s is already deleted from azure storage", blobName))
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// BlobName returns the name of the blob with the given content hash.
+func BlobName(hash string) string {
+	return "sha256/" + hash
+}
+
+// storeUpload stores an uploaded file, an archive is extracted into a bundle if the uploader asked for it.
+// A single file is the entry of the game and has no BIOS files.
+func storeUpload(blobs IBlobService, fileHeader *multipart.FileHeader, archive models.ArchiveOptions) (*models.Blob, *models.Bundle, error) {
+	if archive.Extract {
+		return blobs.StoreArchive(fileHeader, archive.Entry)
+	}
+	blob, err := blobs.Store(fileHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return blob, &models.Bundle{Entry: fileHeader.Filename, Bios: []string{}}, nil
+}
+
+// BundleName returns the name of the bundle with the given hash.
+func BundleName(hash string) string {
+	return shared.BundlePrefix + hash
+}
+
+// BundleFileName returns the name of the blob of a file of a bundle.
+func BundleFileName(blobName string, path string) string {
+	return blobName + "/" + path
+}
+
+// bundleHash returns the hash of the paths and hashes of the files of a bundle, the files have to be sorted by their path
+func bundleHash(files []models.BundleFile) string {
+	hash := sha256.New()
+	for _, file := range files {
+		_, _ = fmt.Fprintf(hash, "%s  %s\n", file.Hash, file.Path)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// StartBlobVerifyJob verifies every blob once per interval in the background.
+func StartBlobVerifyJob(service IBlobService, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			corrupted, err := service.Verify(time.Now().Add(-interval))
+			if err != nil {
+				log.Println(fmt.Sprintf("Verifying blobs failed: %s", err))
+			} else if corrupted > 0 {
+				log.Println(fmt.Sprintf("Found %d corrupted blobs", corrupted))
+			}
+		}
+	}()
+}
+
+func BlobService(repository repositories.IBlobRepository, azure apis.IAzureApi, archives ArchiveConfig) IBlobService {
+	return &blobService{
+		repository: repository,
+		azure:      azure,
+		archives:   archives,
+	}
+}
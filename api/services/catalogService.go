@@ -0,0 +1,461 @@
+package services
+
+import (
+	"api/apis"
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"archive/tar"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"path"
+	"time"
+)
+
+// catalogFormatVersion is the version of the archives which are written and can be read
+const catalogFormatVersion = 1
+
+// catalogManifestName is the name of the manifest, it is the first file of an archive
+const catalogManifestName = "manifest.json"
+
+type ICatalogService interface {
+	Export(out io.Writer, owner string) error
+	Import(in io.Reader, mode shared.ImportMode) (*models.CatalogImportResult, error)
+}
+
+type catalogService struct {
+	games    repositories.IGameRepository
+	versions repositories.IGameVersionRepository
+	blobs    IBlobService
+	azure    apis.IAzureApi
+	k8s      apis.IK8sApi
+	scans    IScanService
+}
+
+// Export writes the games which are not in the trash with all their versions and files as tar.gz archive.
+// Only the games of the owner are exported if owner is not empty.
+// Nothing is written if the games can not be read, so the caller can still report the error.
+func (c catalogService) Export(out io.Writer, owner string) error {
+	var games []models.Game
+	var err error
+	if owner != "" {
+		games, err = c.games.FindAllByOwner(owner)
+	} else {
+		games, err = c.games.FindAll()
+	}
+	if err != nil {
+		return err
+	}
+
+	manifest := models.CatalogManifest{
+		FormatVersion: catalogFormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		Games:         make([]models.CatalogGame, 0, len(games)),
+	}
+	//Maps the paths of the files in the archive to their blobs, versions with the same content share a file
+	blobNames := map[string]string{}
+	var paths []string
+	for _, game := range games {
+		versions, err := c.versions.FindAllByGame(game.ID)
+		if err != nil {
+			return err
+		}
+
+		entry := models.CatalogGame{
+			ID:          game.ID,
+			Title:       game.Title,
+			Owner:       game.Owner,
+			Visibility:  game.Visibility,
+			Description: game.Description,
+			Tags:        game.Tags,
+			Platform:    game.Platform,
+			LiveVersion: game.LiveVersion,
+			BetaVersion: game.BetaVersion,
+			Versions:    make([]models.CatalogVersion, 0, len(versions)),
+		}
+		//The versions are read newest first, they are exported in the order in which they have been uploaded
+		for i := len(versions) - 1; i >= 0; i-- {
+			version := versions[i]
+			file := catalogFilePath(&version)
+			bundleFiles, err := c.bundleFiles(&version)
+			if err != nil {
+				return err
+			}
+			if len(bundleFiles) == 0 {
+				if _, ok := blobNames[file]; !ok {
+					blobNames[file] = version.BlobName
+					paths = append(paths, file)
+				}
+			}
+			for _, bundleFile := range bundleFiles {
+				if _, ok := blobNames[file+"/"+bundleFile]; !ok {
+					blobNames[file+"/"+bundleFile] = BundleFileName(version.BlobName, bundleFile)
+					paths = append(paths, file+"/"+bundleFile)
+				}
+			}
+			entry.Versions = append(entry.Versions, models.CatalogVersion{
+				Version:   version.Version,
+				FileName:  version.FileName,
+				Checksum:  version.Checksum,
+				Changelog: version.Changelog,
+				Uploader:  version.Uploader,
+				CreatedAt: version.CreatedAt,
+				File:      file,
+				Files:     bundleFiles,
+				Bios:      version.Bios,
+			})
+		}
+		manifest.Games = append(manifest.Games, entry)
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	compressed := gzip.NewWriter(out)
+	archive := tar.NewWriter(compressed)
+	err = archive.WriteHeader(&tar.Header{Name: catalogManifestName, Mode: 0644, Size: int64(len(content)), ModTime: manifest.ExportedAt})
+	if err != nil {
+		return err
+	}
+	if _, err = archive.Write(content); err != nil {
+		return err
+	}
+	for _, file := range paths {
+		if err = c.exportFile(archive, file, blobNames[file]); err != nil {
+			return err
+		}
+	}
+
+	if err = archive.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+// bundleFiles returns the paths of the files of the bundle of a version, it is empty for a single file
+func (c catalogService) bundleFiles(version *models.GameVersion) ([]string, error) {
+	if !shared.IsBundle(version.BlobName) {
+		return nil, nil
+	}
+	files, err := c.blobs.Files(version.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	return paths, nil
+}
+
+// exportFile writes the content of a blob into the archive
+func (c catalogService) exportFile(archive *tar.Writer, file string, blobName string) error {
+	container := os.Getenv("AZURE_CONTAINER_NAME")
+	properties, err := c.azure.GameProperties(container, blobName)
+	if err != nil {
+		return fmt.Errorf("reading blob %s failed: %w", blobName, err)
+	}
+	content, err := c.azure.DownloadGame(container, blobName)
+	if err != nil {
+		return fmt.Errorf("downloading blob %s failed: %w", blobName, err)
+	}
+	defer content.Close()
+
+	err = archive.WriteHeader(&tar.Header{Name: file, Mode: 0644, Size: properties.Size, ModTime: properties.LastModified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(archive, content)
+	return err
+}
+
+// Import recreates the games of an archive, stores their files and deploys them.
+// With shared.Import_Preserve the games keep their ids and games whose id is already used are skipped,
+// with shared.Import_Remap all games get new ids. Games which can not be imported are reported as failed,
+// the other games are imported anyway.
+func (c catalogService) Import(in io.Reader, mode shared.ImportMode) (*models.CatalogImportResult, error) {
+	if !mode.IsValid() {
+		return nil, shared.ErrInvalidImportMode
+	}
+	uncompressed, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", shared.ErrInvalidArchive, err)
+	}
+	archive := tar.NewReader(uncompressed)
+	manifest, err := readCatalogManifest(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	//The files are buffered on disk until the archive has been read, because they are shared by versions of many games
+	files := map[string]*multipart.FileHeader{}
+	var forms []*multipart.Form
+	defer func() {
+		for _, form := range forms {
+			form.RemoveAll()
+		}
+	}()
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", shared.ErrInvalidArchive, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		form, file, err := shared.FormFile(path.Base(header.Name), archive, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", shared.ErrInvalidArchive, err)
+		}
+		forms = append(forms, form)
+		files[header.Name] = file
+	}
+
+	result := &models.CatalogImportResult{
+		Imported: []models.CatalogImportItem{},
+		Skipped:  []models.CatalogImportItem{},
+		Failed:   []models.CatalogImportItem{},
+	}
+	for i := range manifest.Games {
+		game := &manifest.Games[i]
+		item := models.CatalogImportItem{SourceID: game.ID, ID: game.ID, Title: game.Title}
+		if mode == shared.Import_Remap {
+			item.ID = uuid.New()
+		} else {
+			//Games in the trash also keep their id
+			_, err = c.games.ReadOwner(game.ID)
+			if err == nil {
+				item.Reason = "a game with this id exists already"
+				result.Skipped = append(result.Skipped, item)
+				continue
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return result, err
+			}
+		}
+
+		err = c.importGame(game, item.ID, files)
+		if err != nil {
+			log.Println(fmt.Sprintf("Importing game %s failed: %s", game.ID.String(), err))
+			item.Reason = err.Error()
+			result.Failed = append(result.Failed, item)
+			continue
+		}
+		result.Imported = append(result.Imported, item)
+	}
+	return result, nil
+}
+
+// importGame stores the files of the versions of a game and deploys its live and beta version under the given id
+func (c catalogService) importGame(entry *models.CatalogGame, id uuid.UUID, files map[string]*multipart.FileHeader) error {
+	versions := make([]models.GameVersion, 0, len(entry.Versions))
+	//The files are released again if the game could not be saved
+	fail := func(err error) error {
+		for _, version := range versions {
+			if errRelease := c.blobs.Release(version.BlobName, version.Checksum); errRelease != nil {
+				log.Println(fmt.Sprintf("Releasing blob %s failed: %s", version.BlobName, errRelease))
+			}
+		}
+		return err
+	}
+
+	for _, version := range entry.Versions {
+		blob, err := c.storeCatalogVersion(&version, files)
+		if err != nil {
+			return fail(err)
+		}
+		//The imported files are scanned like uploaded files, a game with a flagged file is not imported
+		scan, err := c.scans.ScanBlob(blob.BlobName)
+		if err == nil && !scan.Clean {
+			err = fmt.Errorf("%w: the file %s: %s", shared.ErrContentRejected, version.File, scan.Reason)
+		}
+		if err != nil {
+			if errRelease := c.blobs.Release(blob.BlobName, blob.Hash); errRelease != nil {
+				log.Println(fmt.Sprintf("Releasing blob %s failed: %s", blob.BlobName, errRelease))
+			}
+			return fail(err)
+		}
+		versions = append(versions, models.GameVersion{
+			GameID:          id,
+			Version:         version.Version,
+			BlobName:        blob.BlobName,
+			StorageLocation: blob.StorageLocation,
+			FileName:        version.FileName,
+			Bios:            version.Bios,
+			Checksum:        blob.Hash,
+			Changelog:       version.Changelog,
+			Uploader:        version.Uploader,
+			CreatedAt:       version.CreatedAt,
+		})
+		if version.Checksum != "" && version.Checksum != blob.Hash {
+			return fail(fmt.Errorf("%w: the file %s does not match its checksum", shared.ErrInvalidArchive, version.File))
+		}
+	}
+
+	live := findCatalogVersion(versions, entry.LiveVersion)
+	if live == nil {
+		return fail(fmt.Errorf("%w: the live version %d is missing", shared.ErrInvalidArchive, entry.LiveVersion))
+	}
+	var beta *models.GameVersion
+	if entry.BetaVersion != 0 {
+		beta = findCatalogVersion(versions, entry.BetaVersion)
+		if beta == nil {
+			return fail(fmt.Errorf("%w: the beta version %d is missing", shared.ErrInvalidArchive, entry.BetaVersion))
+		}
+	}
+
+	game := &models.Game{
+		ID:              id,
+		Title:           entry.Title,
+		StorageLocation: live.StorageLocation,
+		Status:          shared.Status_New,
+		Owner:           entry.Owner,
+		FileName:        live.FileName,
+		Bios:            live.Bios,
+		BlobName:        live.BlobName,
+		LiveVersion:     live.Version,
+		BetaVersion:     entry.BetaVersion,
+		Checksum:        live.Checksum,
+		Visibility:      entry.Visibility,
+		Description:     entry.Description,
+		Tags:            entry.Tags,
+		Platform:        entry.Platform,
+	}
+	if !game.Visibility.IsValid() {
+		game.Visibility = shared.Visibility_Private
+	}
+
+	//The game is saved before it is deployed, so every game resource belongs to a game
+	err := c.games.Save(game)
+	if err != nil {
+		return fail(err)
+	}
+	//The game and its versions are removed again if the import fails
+	rollback := func(err error) error {
+		if errDel := c.versions.DeleteAllByGame(id); errDel != nil {
+			log.Println(fmt.Sprintf("Deleting the versions of game %s failed: %s", id.String(), errDel))
+		}
+		if errDel := c.games.Delete(id); errDel != nil {
+			log.Println(fmt.Sprintf("Deleting game %s failed: %s", id.String(), errDel))
+		}
+		return fail(err)
+	}
+	for i := range versions {
+		err = c.versions.Create(&versions[i])
+		if err != nil {
+			return rollback(err)
+		}
+	}
+	//New games start with the live version 1 and without beta
+	if game.LiveVersion != 1 || game.BetaVersion != 0 {
+		err = c.games.Update(game, game.Revision)
+		if err != nil {
+			return rollback(err)
+		}
+	}
+
+	//The game is deployed last, the placement sets its cluster which is saved with the deployment
+	err = c.k8s.DeployGame(game)
+	if err != nil {
+		return rollback(err)
+	}
+	err = c.games.UpdateDeployment(game)
+	if err != nil {
+		if errDel := c.k8s.DeleteGame(game); errDel != nil && !isNotFound(errDel) {
+			log.Println(fmt.Sprintf("Deleting the resource of game %s failed: %s", id.String(), errDel))
+		}
+		return rollback(err)
+	}
+
+	//The live version keeps running, even if the beta channel can not be deployed
+	if beta != nil {
+		err = c.k8s.DeployBeta(game, beta)
+		if err != nil {
+			log.Println(fmt.Sprintf("Deploying the beta of game %s failed: %s", id.String(), err))
+		}
+	}
+	return nil
+}
+
+// storeCatalogVersion stores the file of a version, the files of a bundle are stored as bundle again
+func (c catalogService) storeCatalogVersion(version *models.CatalogVersion, files map[string]*multipart.FileHeader) (*models.Blob, error) {
+	if len(version.Files) == 0 {
+		file, ok := files[version.File]
+		if !ok {
+			return nil, fmt.Errorf("%w: the file %s of version %d is missing", shared.ErrInvalidArchive, version.File, version.Version)
+		}
+		return c.blobs.Store(file)
+	}
+
+	bundle := map[string]*multipart.FileHeader{}
+	for _, bundleFile := range version.Files {
+		file, ok := files[version.File+"/"+bundleFile]
+		if !ok {
+			return nil, fmt.Errorf("%w: the file %s/%s of version %d is missing", shared.ErrInvalidArchive, version.File, bundleFile, version.Version)
+		}
+		bundle[bundleFile] = file
+	}
+	return c.blobs.StoreBundle(bundle)
+}
+
+// readCatalogManifest reads the manifest, which must be the first file of the archive
+func readCatalogManifest(archive *tar.Reader) (*models.CatalogManifest, error) {
+	header, err := archive.Next()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", shared.ErrInvalidArchive, err)
+	}
+	if header.Name != catalogManifestName {
+		return nil, fmt.Errorf("%w: the first file must be %s", shared.ErrInvalidArchive, catalogManifestName)
+	}
+
+	var manifest models.CatalogManifest
+	err = json.NewDecoder(archive).Decode(&manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", shared.ErrInvalidArchive, err)
+	}
+	if manifest.FormatVersion != catalogFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", shared.ErrInvalidArchive, manifest.FormatVersion)
+	}
+	return &manifest, nil
+}
+
+// catalogFilePath returns the path of the file of a version in the archive
+func catalogFilePath(version *models.GameVersion) string {
+	if version.Checksum != "" {
+		return "blobs/" + version.Checksum
+	}
+	//Files which have been uploaded before content addressing have no checksum, they are named after their blob
+	return "blobs/legacy/" + path.Base(version.BlobName)
+}
+
+func findCatalogVersion(versions []models.GameVersion, number int) *models.GameVersion {
+	for i := range versions {
+		if versions[i].Version == number {
+			return &versions[i]
+		}
+	}
+	return nil
+}
+
+func CatalogService(games repositories.IGameRepository, versions repositories.IGameVersionRepository, blobs IBlobService, azure apis.IAzureApi, k8s apis.IK8sApi, scans IScanService) ICatalogService {
+	return &catalogService{
+		games:    games,
+		versions: versions,
+		blobs:    blobs,
+		azure:    azure,
+		k8s:      k8s,
+		scans:    scans,
+	}
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"api/apis"
+	"api/kube"
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"fmt"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"log"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"sync"
+	"time"
+)
+
+// IClusterService manages the registry of the clusters and places the games on them.
+type IClusterService interface {
+	apis.IClusters
+	FindAll() ([]models.ClusterLoad, error)
+	Save(cluster *models.Cluster) error
+	Delete(name string) error
+	Pin(name string) error
+}
+
+type clusterService struct {
+	clusters repositories.IClusterRepository
+	games    repositories.IGameRepository
+	//primary is the client of the default cluster
+	primary    client.Client
+	namespaces apis.NamespaceConfig
+	connect    func(cluster *models.Cluster) (client.Client, error)
+
+	mutex sync.Mutex
+	//apis contains the apis of the clusters which have been used, they are dropped when their cluster is changed
+	apis map[string]*clusterApi
+}
+
+// clusterApi is the api of a cluster and the kubeconfig it has been connected with
+type clusterApi struct {
+	api       apis.IK8sApi
+	cluster   models.Cluster
+	checkedAt time.Time
+}
+
+// clusterRecheckInterval is the time after which the api of a cluster is compared with the registry again.
+// The registry is shared by all instances of the api, so clusters may be changed by another instance.
+const clusterRecheckInterval = time.Minute
+
+// FindAll returns the registered clusters with the number of their games
+func (c *clusterService) FindAll() ([]models.ClusterLoad, error) {
+	clusters, err := c.clusters.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := c.games.CountByCluster()
+	if err != nil {
+		return nil, err
+	}
+
+	loads := make([]models.ClusterLoad, len(clusters))
+	for i, cluster := range clusters {
+		loads[i] = models.ClusterLoad{Cluster: cluster, Games: counts[cluster.Name]}
+	}
+	return loads, nil
+}
+
+// Save registers a cluster or updates a registered cluster. The games of an updated cluster are sent to its new kubeconfig.
+func (c *clusterService) Save(cluster *models.Cluster) error {
+	if len(validation.IsDNS1123Label(cluster.Name)) > 0 || cluster.Capacity < 0 {
+		return shared.ErrInvalidCluster
+	}
+	//The default cluster is always the cluster of the kubernetes provider of the api
+	if cluster.Name == shared.DefaultCluster {
+		cluster.KubeconfigPath = ""
+		cluster.KubeContext = ""
+	} else if cluster.KubeconfigPath == "" {
+		return shared.ErrInvalidCluster
+	}
+	if cluster.Labels == nil {
+		cluster.Labels = []string{}
+	}
+	if cluster.CreatedAt.IsZero() {
+		cluster.CreatedAt = time.Now().UTC()
+	}
+
+	err := c.clusters.Save(cluster)
+	if err != nil {
+		return err
+	}
+	c.forget(cluster.Name)
+	return nil
+}
+
+// Delete removes a cluster from the registry. The default cluster and clusters which still have games,
+// including games in the trash, can not be removed.
+func (c *clusterService) Delete(name string) error {
+	if name == shared.DefaultCluster {
+		return shared.ErrClusterInUse
+	}
+	cluster, err := c.clusters.FindByName(name)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return shared.ErrClusterNotFound
+	}
+	count, err := c.games.CountInCluster(name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return shared.ErrClusterInUse
+	}
+
+	err = c.clusters.Delete(name)
+	if err != nil {
+		return err
+	}
+	c.forget(name)
+	return nil
+}
+
+// Pin checks that a new game can be pinned to a cluster, the cluster has to be registered and must not be cordoned.
+// The capacity of the cluster is not checked, administrators may pin games to a full cluster.
+func (c *clusterService) Pin(name string) error {
+	cluster, err := c.clusters.FindByName(name)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		//The default cluster is used before it has been registered
+		if name == shared.DefaultCluster {
+			return nil
+		}
+		return shared.ErrClusterNotFound
+	}
+	if cluster.Cordoned {
+		return shared.ErrClusterCordoned
+	}
+	return nil
+}
+
+// Place chooses the cluster of a game if it has not been pinned to a cluster. If the uploader has requested a region,
+// only the clusters of this region are considered. The game is placed on the cluster with the fewest games,
+// which is not cordoned and has free capacity. Pinned games are placed on their cluster regardless of its capacity.
+func (c *clusterService) Place(game *models.Game) error {
+	if game.Cluster != "" {
+		return nil
+	}
+	clusters, err := c.FindAll()
+	if err != nil {
+		return err
+	}
+
+	var chosen *models.ClusterLoad
+	for i := range clusters {
+		cluster := &clusters[i]
+		if cluster.Cordoned || (cluster.Capacity > 0 && cluster.Games >= cluster.Capacity) {
+			continue
+		}
+		if game.Region != "" && cluster.Region != game.Region {
+			continue
+		}
+		//The clusters are ordered by name, so ties are placed on the first cluster
+		if chosen == nil || cluster.Games < chosen.Games {
+			chosen = cluster
+		}
+	}
+	if chosen == nil {
+		return shared.ErrNoClusterAvailable
+	}
+	game.Cluster = chosen.Name
+	return nil
+}
+
+// Api returns the api of a registered cluster, the client of the cluster is created when it is used first
+// and created again if the kubeconfig of the cluster has been changed.
+func (c *clusterService) Api(name string) (apis.IK8sApi, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	//The default cluster is always served by the primary client
+	if name == shared.DefaultCluster {
+		if cached, ok := c.apis[name]; ok {
+			return cached.api, nil
+		}
+		api := apis.K8sService(c.primary, c.namespaces)
+		c.apis[name] = &clusterApi{api: api}
+		return api, nil
+	}
+
+	cached, ok := c.apis[name]
+	if ok && time.Since(cached.checkedAt) < clusterRecheckInterval {
+		return cached.api, nil
+	}
+	cluster, err := c.clusters.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if cluster == nil {
+		delete(c.apis, name)
+		return nil, shared.ErrClusterNotFound
+	}
+	if ok && cached.cluster.KubeconfigPath == cluster.KubeconfigPath && cached.cluster.KubeContext == cluster.KubeContext {
+		cached.checkedAt = time.Now()
+		return cached.api, nil
+	}
+
+	k8sClient, err := c.connect(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to cluster %s failed: %w", name, err)
+	}
+	api := apis.K8sService(k8sClient, c.namespaces)
+	c.apis[name] = &clusterApi{api: api, cluster: *cluster, checkedAt: time.Now()}
+	return api, nil
+}
+
+// Names returns the names of all registered clusters, the default cluster is always included
+func (c *clusterService) Names() ([]string, error) {
+	clusters, err := c.clusters.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{shared.DefaultCluster}
+	for _, cluster := range clusters {
+		if cluster.Name != shared.DefaultCluster {
+			names = append(names, cluster.Name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+// forget drops the api of a cluster, so the next call connects with the saved kubeconfig
+func (c *clusterService) forget(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.apis, name)
+}
+
+// KubeconfigConnector connects to the registered clusters with their kubeconfig file and context.
+// The credentials are refreshed like the credentials of the default cluster.
+func KubeconfigConnector(scheme *runtime.Scheme, refreshInterval time.Duration) func(cluster *models.Cluster) (client.Client, error) {
+	return func(cluster *models.Cluster) (client.Client, error) {
+		log.Println(fmt.Sprintf("Connecting to cluster %s with the kubeconfig %s", cluster.Name, cluster.KubeconfigPath))
+		provider := kube.Provider(kube.Config{
+			Provider:       kube.Provider_Kubeconfig,
+			KubeconfigPath: cluster.KubeconfigPath,
+			Context:        cluster.KubeContext,
+		})
+		return kube.Client(provider, scheme, refreshInterval), nil
+	}
+}
+
+// ClusterService places the games on the registered clusters. The default cluster is served by the primary client,
+// the other clusters are connected with connect.
+func ClusterService(clusters repositories.IClusterRepository, games repositories.IGameRepository, primary client.Client,
+	namespaces apis.NamespaceConfig, connect func(cluster *models.Cluster) (client.Client, error)) IClusterService {
+	return &clusterService{
+		clusters:   clusters,
+		games:      games,
+		primary:    primary,
+		namespaces: namespaces,
+		connect:    connect,
+		apis:       map[string]*clusterApi{},
+	}
+}
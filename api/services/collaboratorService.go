@@ -0,0 +1,134 @@
+package services
+
+import (
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"strings"
+	"time"
+)
+
+type ICollaboratorService interface {
+	FindAllByGame(gameID uuid.UUID, subject string) ([]models.Collaborator, error)
+	Grant(gameID uuid.UUID, subject string, grantee *string, email *string, permissions []shared.Permission) (*models.Collaborator, error)
+	Revoke(gameID uuid.UUID, subject string, id uuid.UUID) error
+}
+
+type collaboratorService struct {
+	games         IGameService
+	collaborators repositories.ICollaboratorRepository
+	access        IAccessService
+}
+
+// FindAllByGame returns the collaborators of the game, the user must be allowed to manage them.
+func (c collaboratorService) FindAllByGame(gameID uuid.UUID, subject string) ([]models.Collaborator, error) {
+	err := c.requireManager(gameID, subject)
+	if err != nil {
+		return nil, err
+	}
+	return c.collaborators.FindAllByGame(gameID)
+}
+
+// Grant grants the permissions on the game to the user with the subject or the email, exactly one of them must be set.
+// The permissions replace the permissions which have been granted before.
+func (c collaboratorService) Grant(gameID uuid.UUID, subject string, grantee *string, email *string, permissions []shared.Permission) (*models.Collaborator, error) {
+	if (grantee == nil) == (email == nil) || len(permissions) == 0 {
+		return nil, shared.ErrInvalidCollaborator
+	}
+	if grantee != nil && *grantee == "" || email != nil && *email == "" {
+		return nil, shared.ErrInvalidCollaborator
+	}
+	unique := []shared.Permission{}
+	seen := map[shared.Permission]bool{}
+	for _, permission := range permissions {
+		if !permission.IsValid() {
+			return nil, shared.ErrInvalidCollaborator
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			unique = append(unique, permission)
+		}
+	}
+	if email != nil {
+		lower := strings.ToLower(*email)
+		email = &lower
+	}
+
+	err := c.requireManager(gameID, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.collaborators.Save(&models.Collaborator{
+		GameID:      gameID,
+		Subject:     grantee,
+		Email:       email,
+		Permissions: unique,
+		GrantedBy:   subject,
+		GrantedAt:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Println(fmt.Sprintf("%s granted %v on game %s to %s", subject, unique, gameID.String(), granteeName(grantee, email)))
+
+	//The id of an existing collaborator is kept, so read the saved collaborator
+	return c.collaborators.FindByGrantee(gameID, grantee, email)
+}
+
+// Revoke removes all permissions of a collaborator of the game.
+func (c collaboratorService) Revoke(gameID uuid.UUID, subject string, id uuid.UUID) error {
+	err := c.requireManager(gameID, subject)
+	if err != nil {
+		return err
+	}
+
+	err = c.collaborators.Delete(gameID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return shared.ErrCollaboratorNotFound
+	}
+	if err == nil {
+		log.Println(fmt.Sprintf("%s revoked the collaborator %s of game %s", subject, id.String(), gameID.String()))
+	}
+	return err
+}
+
+// requireManager returns shared.ErrForbidden if the user can not manage the collaborators of the game.
+// Only the owner of the game and the members of the owning organization who can delete the game are allowed,
+// collaborators can not share the game any further.
+func (c collaboratorService) requireManager(gameID uuid.UUID, subject string) error {
+	owner, err := c.games.ReadOwner(gameID)
+	if err != nil {
+		return err
+	}
+	authorized, err := c.access.HasPermission(subject, owner, shared.Permission_Delete)
+	if err != nil {
+		return err
+	}
+	if !authorized {
+		log.Print(fmt.Sprintf("%s tried to manage the collaborators of a game of %s", subject, owner))
+		return shared.ErrForbidden
+	}
+	return nil
+}
+
+// granteeName returns the subject or the email for log messages
+func granteeName(subject *string, email *string) string {
+	if subject != nil {
+		return *subject
+	}
+	return *email
+}
+
+func CollaboratorService(games IGameService, collaborators repositories.ICollaboratorRepository, access IAccessService) ICollaboratorService {
+	return &collaboratorService{
+		games:         games,
+		collaborators: collaborators,
+		access:        access,
+	}
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"api/apis"
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	consistencyIssues = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "igs_consistency_issues",
+		Help: "Number of inconsistencies found by the last consistency check.",
+	}, []string{"kind"})
+	consistencyRepairs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "igs_consistency_repairs_total",
+		Help: "Number of repaired inconsistencies, result is success or error.",
+	}, []string{"kind", "result"})
+	consistencyChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "igs_consistency_checks_total",
+		Help: "Number of consistency checks, result is success or error.",
+	}, []string{"result"})
+	consistencyLastCheck = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "igs_consistency_last_check_timestamp_seconds",
+		Help: "Unix time of the last successful consistency check.",
+	})
+)
+
+// ConsistencyConfig configures the consistency checker.
+type ConsistencyConfig struct {
+	//The checker runs once per interval, it is disabled if the interval is 0
+	Interval time.Duration
+	//Resources and blobs which are younger than the grace period are not reported, they may belong to an upload in progress
+	GracePeriod time.Duration
+	//The kinds of inconsistencies which are repaired, nothing is repaired if it is empty
+	Repair map[shared.InconsistencyKind]bool
+	//The consistency is checked once before the api is served, e.g. to deploy the games into an empty cluster again
+	CheckOnStart bool
+}
+
+// ConsistencyConfigFromEnv reads the config from the environment variables CONSISTENCY_CHECK_INTERVAL,
+// CONSISTENCY_GRACE_PERIOD, CONSISTENCY_REPAIR, which is a comma separated list of inconsistency kinds, and CONSISTENCY_CHECK_ON_START.
+func ConsistencyConfigFromEnv() ConsistencyConfig {
+	config := ConsistencyConfig{
+		Interval:     durationFromEnv("CONSISTENCY_CHECK_INTERVAL", time.Hour),
+		GracePeriod:  durationFromEnv("CONSISTENCY_GRACE_PERIOD", time.Hour),
+		Repair:       map[shared.InconsistencyKind]bool{},
+		CheckOnStart: os.Getenv("CONSISTENCY_CHECK_ON_START") == "true",
+	}
+	for _, value := range strings.Split(os.Getenv("CONSISTENCY_REPAIR"), ",") {
+		kind := shared.InconsistencyKind(strings.TrimSpace(value))
+		if kind == "" {
+			continue
+		}
+		if !kind.IsValid() {
+			log.Fatalf("Invalid CONSISTENCY_REPAIR %s, valid kinds are %s, %s and %s", value,
+				shared.Inconsistency_RowWithoutResource, shared.Inconsistency_ResourceWithoutRow, shared.Inconsistency_BlobWithoutRow)
+		}
+		config.Repair[kind] = true
+	}
+	return config
+}
+
+func durationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Fatalf("Invalid %s %s", key, value)
+	}
+	return duration
+}
+
+type IConsistencyService interface {
+	Check(repair bool) (*models.ConsistencyReport, error)
+	RepairEnabled() bool
+}
+
+type consistencyService struct {
+	games    repositories.IGameRepository
+	versions repositories.IGameVersionRepository
+	blobs    repositories.IBlobRepository
+	azure    apis.IAzureApi
+	k8s      apis.IK8sApi
+	config   ConsistencyConfig
+	//Checks are not run in parallel, so an issue is not repaired twice
+	mutex sync.Mutex
+}
+
+// Check lists the games, the game resources in kubernetes and the blobs in the storage and reports
+// games without resources, resources without games and blobs which are used by no game version.
+// The issues whose kind is enabled in the config are repaired if repair is true:
+// missing resources are deployed again, orphaned resources and blobs are deleted.
+func (c *consistencyService) Check(repair bool) (*models.ConsistencyReport, error) {
+	if repair && !c.RepairEnabled() {
+		return nil, shared.ErrRepairDisabled
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	report, err := c.findIssues()
+	if err != nil {
+		consistencyChecks.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	report.DryRun = !repair
+
+	counts := map[shared.InconsistencyKind]int{}
+	for i := range report.Issues {
+		issue := &report.Issues[i]
+		counts[issue.Kind]++
+		if !repair || !c.config.Repair[issue.Kind] {
+			continue
+		}
+
+		err = c.repair(issue)
+		if err != nil {
+			log.Println(fmt.Sprintf("Repairing %s %s failed: %s", issue.Kind, issue.Name, err))
+			issue.Error = err.Error()
+			consistencyRepairs.WithLabelValues(string(issue.Kind), "error").Inc()
+			continue
+		}
+		issue.Repaired = true
+		counts[issue.Kind]--
+		consistencyRepairs.WithLabelValues(string(issue.Kind), "success").Inc()
+	}
+
+	for _, kind := range []shared.InconsistencyKind{shared.Inconsistency_RowWithoutResource, shared.Inconsistency_ResourceWithoutRow, shared.Inconsistency_BlobWithoutRow} {
+		consistencyIssues.WithLabelValues(string(kind)).Set(float64(counts[kind]))
+	}
+	consistencyChecks.WithLabelValues("success").Inc()
+	consistencyLastCheck.Set(float64(report.CheckedAt.Unix()))
+	return report, nil
+}
+
+// RepairEnabled returns true if at least one kind of inconsistency is repaired
+func (c *consistencyService) RepairEnabled() bool {
+	return len(c.config.Repair) > 0
+}
+
+// findIssues compares the three sources. The games are read last, so games which are deleted during the check are not reported.
+// Games which are created during the check may be reported once, deploying them again fails without changing their resources.
+func (c *consistencyService) findIssues() (*models.ConsistencyReport, error) {
+	report := &models.ConsistencyReport{CheckedAt: time.Now().UTC(), Issues: []models.ConsistencyIssue{}}
+	before := report.CheckedAt.Add(-c.config.GracePeriod)
+
+	resources, err := c.k8s.ListGameResources()
+	if err != nil {
+		return nil, fmt.Errorf("listing the game resources failed: %w", err)
+	}
+	storedBlobs, err := c.azure.ListGames(os.Getenv("AZURE_CONTAINER_NAME"))
+	if err != nil {
+		return nil, fmt.Errorf("listing the blobs failed: %w", err)
+	}
+	versionBlobNames, err := c.versions.FindAllBlobNames()
+	if err != nil {
+		return nil, err
+	}
+	blobNames, err := c.blobs.FindAllBlobNames()
+	if err != nil {
+		return nil, err
+	}
+	games, err := c.games.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	//Games, games which are scanned or quarantined have no resources
+	deployed := map[string]bool{}
+	for _, resource := range resources {
+		deployed[resource.Name] = true
+	}
+	expected := map[string]bool{}
+	for _, game := range games {
+		id := game.ID.String()
+		expected[id] = true
+		if !isDeployed(&game) {
+			continue
+		}
+		if !deployed[id] {
+			report.Issues = append(report.Issues, models.ConsistencyIssue{
+				Kind: shared.Inconsistency_RowWithoutResource, GameID: id, Channel: shared.Channel_Live, Name: id, Repair: "deploy",
+			})
+		}
+		if game.BetaVersion != 0 {
+			beta := id + "-beta"
+			expected[beta] = true
+			if !deployed[beta] {
+				report.Issues = append(report.Issues, models.ConsistencyIssue{
+					Kind: shared.Inconsistency_RowWithoutResource, GameID: id, Channel: shared.Channel_Beta, Name: beta, Repair: "deploy",
+				})
+			}
+		}
+	}
+
+	//Game resources, resources which are not named after a game id are not created by the api and are ignored
+	for _, resource := range resources {
+		if expected[resource.Name] || resource.CreatedAt.After(before) {
+			continue
+		}
+		id, channel := gameOfResource(resource.Name)
+		if id == uuid.Nil {
+			continue
+		}
+		report.Issues = append(report.Issues, models.ConsistencyIssue{
+			Kind: shared.Inconsistency_ResourceWithoutRow, GameID: id.String(), Channel: channel,
+			Cluster: resource.Cluster, Namespace: resource.Namespace, Name: resource.Name, Repair: "delete",
+		})
+	}
+
+	//Blobs
+	referenced := map[string]bool{}
+	for _, name := range append(versionBlobNames, blobNames...) {
+		referenced[name] = true
+	}
+	for _, blob := range storedBlobs {
+		if referenced[blob.Name] || referenced[bundleOfFile(blob.Name)] || blob.LastModified.After(before) {
+			continue
+		}
+		report.Issues = append(report.Issues, models.ConsistencyIssue{
+			Kind: shared.Inconsistency_BlobWithoutRow, Name: blob.Name, Repair: "delete",
+		})
+	}
+	return report, nil
+}
+
+// bundleOfFile returns the name of the bundle of a stored file of a bundle, e.g. bundles/<hash> of bundles/<hash>/disc.cue
+func bundleOfFile(blobName string) string {
+	if !shared.IsBundle(blobName) {
+		return ""
+	}
+	hash, _, _ := strings.Cut(strings.TrimPrefix(blobName, shared.BundlePrefix), "/")
+	return BundleName(hash)
+}
+
+// repair deploys a missing game resource again or deletes an orphaned resource or blob
+func (c *consistencyService) repair(issue *models.ConsistencyIssue) error {
+	switch issue.Kind {
+	case shared.Inconsistency_RowWithoutResource:
+		game, err := c.games.FindByID(uuid.MustParse(issue.GameID))
+		if err != nil {
+			return err
+		}
+		if game == nil {
+			return fmt.Errorf("game %s has been deleted in the meantime", issue.GameID)
+		}
+		if issue.Channel == shared.Channel_Beta {
+			version, err := c.versions.FindByGameAndVersion(game.ID, game.BetaVersion)
+			if err != nil {
+				return err
+			}
+			if version == nil {
+				return shared.ErrVersionNotFound
+			}
+			return c.k8s.DeployBeta(game, version)
+		}
+		err = c.k8s.DeployGame(game)
+		if err != nil {
+			return err
+		}
+		//Stopped games stay stopped
+		if isStopped(game) {
+			return c.k8s.SuspendGame(game, true)
+		}
+		return nil
+	case shared.Inconsistency_ResourceWithoutRow:
+		err := c.k8s.DeleteGameResource(apis.GameResource{Cluster: issue.Cluster, Namespace: issue.Namespace, Name: issue.Name})
+		if err != nil && isNotFound(err) {
+			return nil
+		}
+		return err
+	case shared.Inconsistency_BlobWithoutRow:
+		err := c.azure.DeleteGame(os.Getenv("AZURE_CONTAINER_NAME"), issue.Name)
+		if err != nil && isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return fmt.Errorf("unknown inconsistency %s", issue.Kind)
+}
+
+// gameOfResource returns the game id and the channel of a game resource or uuid.Nil if it is not named after a game
+func gameOfResource(name string) (uuid.UUID, shared.Channel) {
+	channel := shared.Channel_Live
+	if strings.HasSuffix(name, "-beta") {
+		name = strings.TrimSuffix(name, "-beta")
+		channel = shared.Channel_Beta
+	}
+	id, err := uuid.Parse(name)
+	if err != nil {
+		return uuid.Nil, channel
+	}
+	return id, channel
+}
+
+// StartConsistencyChecker checks the consistency once per interval in the background and repairs the issues
+// whose kind is enabled in the config.
+func StartConsistencyChecker(service IConsistencyService, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			CheckConsistency(service)
+		}
+	}()
+}
+
+// CheckConsistency checks the consistency once and repairs the issues whose kind is enabled in the config.
+func CheckConsistency(service IConsistencyService) {
+	report, err := service.Check(service.RepairEnabled())
+	if err != nil {
+		log.Println(fmt.Sprintf("Checking the consistency failed: %s", err))
+	} else if len(report.Issues) > 0 {
+		log.Println(fmt.Sprintf("Found %d inconsistencies", len(report.Issues)))
+	}
+}
+
+func ConsistencyService(games repositories.IGameRepository, versions repositories.IGameVersionRepository, blobs repositories.IBlobRepository, azure apis.IAzureApi, k8s apis.IK8sApi, config ConsistencyConfig) IConsistencyService {
+	return &consistencyService{
+		games:    games,
+		versions: versions,
+		blobs:    blobs,
+		azure:    azure,
+		k8s:      k8s,
+		config:   config,
+	}
+}
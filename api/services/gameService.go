@@ -5,94 +5,221 @@ import (
 	"api/models"
 	"api/repositories"
 	"api/shared"
+	"database/sql"
 	"fmt"
 	"github.com/google/uuid"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
 	"log"
 	"mime/multipart"
-	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 type IGameService interface {
 	FindByID(id uuid.UUID) (*models.Game, error)
-	Save(file *multipart.FileHeader, title string, owner string) (*models.Game, error)
+	Save(file *multipart.FileHeader, metadata models.GameMetadata, owner string) (*models.Game, error)
 	Delete(id uuid.UUID) error
-	FindAllByOwner(owner string) ([]models.Game, error)
+	FindAllByOwners(owners []string) ([]models.Game, error)
+	FindAllSharedWith(subject string, email string) ([]models.Game, error)
 	ReadOwner(id uuid.UUID) (string, error)
+	Update(game *models.Game, revision int) error
+	FindAllDeletedByOwners(owners []string) ([]models.Game, error)
+	Restore(id uuid.UUID) (*models.Game, error)
+	Purge(game *models.Game) error
+	PurgeDeletedBefore(before time.Time) (int, error)
+	Redeploy(id uuid.UUID) (*models.Game, error)
+	Stop(id uuid.UUID) (*models.Game, error)
+	Start(id uuid.UUID) (*models.Game, error)
+	Restart(id uuid.UUID) (*models.Game, error)
+	ScanPending() error
 }
 
 type gameService struct {
 	repository repositories.IGameRepository
-	azure      apis.IAzureApi
+	versions   repositories.IGameVersionRepository
+	blobs      IBlobService
 	k8s        apis.IK8sApi
+	scans      IScanService
+	//inScan contains the ids of the games which are scanned by this instance
+	inScan *sync.Map
 }
 
 func (g gameService) ReadOwner(id uuid.UUID) (string, error) {
 	return g.repository.ReadOwner(id)
 }
 
-func (g gameService) FindAllByOwner(owner string) ([]models.Game, error) {
-	return g.repository.FindAllByOwner(owner)
+func (g gameService) FindAllByOwners(owners []string) ([]models.Game, error) {
+	return g.repository.FindAllByOwners(owners)
+}
+
+// FindAllSharedWith returns the games of other owners, which have been shared with the user as collaborator.
+func (g gameService) FindAllSharedWith(subject string, email string) ([]models.Game, error) {
+	return g.repository.FindAllSharedWith(subject, email)
 }
 
 func (g gameService) FindByID(id uuid.UUID) (*models.Game, error) {
 	game, err := g.repository.FindByID(id)
-	if err != nil {
-		return nil, err
+	if err != nil || game == nil {
+		return game, err
 	} else {
-		if game.Url == "" {
+		if game.Url == "" && isDeployed(game) {
 			g.updateGameUrl(game)
 		}
+		if game.BetaVersion != 0 && game.BetaUrl == "" {
+			g.updateBetaUrl(game)
+		}
+		if game.Status == shared.Status_Stopping || game.Status == shared.Status_Starting {
+			g.updateGamePhase(game)
+		}
 		return game, nil
 	}
 }
 
-func (g gameService) Save(fileHeader *multipart.FileHeader, title string, owner string) (*models.Game, error) {
+func (g gameService) Save(fileHeader *multipart.FileHeader, metadata models.GameMetadata, owner string) (*models.Game, error) {
 
 	game := models.Game{
 		ID:              uuid.New(),
-		Title:           title,
+		Title:           metadata.Title,
 		StorageLocation: "",
 		Status:          shared.Status_New,
 		Url:             "",
 		Owner:           owner,
-		FileName:        fileHeader.Filename,
+		LiveVersion:     1,
+		Visibility:      shared.Visibility_Private,
+		Description:     metadata.Description,
+		Tags:            metadata.Tags,
+		Platform:        metadata.Platform,
+		Cluster:         metadata.Cluster,
+		Region:          metadata.Region,
 	}
 
-	//Upload game to azure blob storage container
-	storageLocation, err := g.azure.UploadGame(os.Getenv("AZURE_CONTAINER_NAME"), game.ID.String(), fileHeader)
+	//Upload game to azure blob storage container, if the same file has not been uploaded yet
+	blob, bundle, err := storeUpload(g.blobs, fileHeader, metadata.Archive)
 	if err != nil {
 		return nil, err
 	}
 
-	game.StorageLocation = storageLocation
+	game.FileName = bundle.Entry
+	game.Bios = bundle.Bios
+	game.StorageLocation = blob.StorageLocation
+	game.BlobName = blob.BlobName
+	game.Checksum = blob.Hash
 
-	//Deploy the game on kubernetes
-	err = g.k8s.DeployGame(&game)
+	//The game is held in the status scanning until its file has been scanned
+	game.Status = shared.Status_Scanning
+	err = g.repository.Save(&game)
 	if err != nil {
-		//Delete the game when deploying on kubernetes failed
-		errDel := g.azure.DeleteGame(os.Getenv("AZURE_CONTAINER_NAME"), game.ID.String())
+		//Release the blob, otherwise the reference would never be removed
+		errDel := g.blobs.Release(blob.BlobName, blob.Hash)
 		if errDel != nil {
-			log.Println(fmt.Sprintf("Delete game for %s in azure failed", title))
+			log.Println(fmt.Sprintf("Delete game for %s in azure failed", metadata.Title))
 		}
-
 		return nil, err
 	}
 
+	//The first upload is the first version of the game
+	err = g.versions.Create(&models.GameVersion{
+		GameID:          game.ID,
+		Version:         1,
+		BlobName:        game.BlobName,
+		StorageLocation: game.StorageLocation,
+		FileName:        game.FileName,
+		Bios:            game.Bios,
+		Checksum:        game.Checksum,
+		Uploader:        owner,
+		CreatedAt:       time.Now(),
+	})
+	if err != nil {
+		return &game, err
+	}
+
+	return &game, g.scanAndDeploy(&game)
+}
+
+// scanAndDeploy scans the file of a game in the status scanning. A clean game is deployed on kubernetes,
+// the cluster of the game is chosen unless the uploader has pinned it. A flagged game is quarantined with the reason
+// of the scanner. If the file could not be scanned, the game stays in the status scanning and is scanned again by ScanPending.
+func (g gameService) scanAndDeploy(game *models.Game) error {
+	if _, scanning := g.inScan.LoadOrStore(game.ID, true); scanning {
+		return nil
+	}
+	defer g.inScan.Delete(game.ID)
+
+	result, err := g.scans.ScanBlob(game.BlobName)
+	if err != nil {
+		log.Println(fmt.Sprintf("Scanning game %s failed, it is scanned again later: %s", game.ID.String(), err))
+		return nil
+	}
+	if !result.Clean {
+		game.Status = shared.Status_Quarantined
+		game.StatusReason = result.Reason
+		_, err = g.repository.UpdateStatusFrom(game.ID, shared.Status_Scanning, game.Status, game.StatusReason)
+		return err
+	}
+
+	//Only one instance deploys the game, if another one has finished the scan in the meantime the game is left to it
+	claimed, err := g.repository.UpdateStatusFrom(game.ID, shared.Status_Scanning, shared.Status_Installing, "")
+	if err != nil || !claimed {
+		return err
+	}
+	game.Status = shared.Status_Installing
+
+	err = g.k8s.DeployGame(game)
+	if err != nil {
+		game.Status = shared.Status_Error
+		game.StatusReason = err.Error()
+		if errUpdate := g.repository.UpdateDeployment(game); errUpdate != nil {
+			log.Println(fmt.Sprintf("Error updating game: %s", errUpdate))
+		}
+		return err
+	}
+
 	//Try to read the game url
-	game.Url, err = g.k8s.ReadGameUrl(game.ID)
+	game.Url, err = g.k8s.ReadGameUrl(game)
 	if err != nil {
 		log.Println(fmt.Sprintf("Error reading game url: %s", err))
 		//We can ignore this error because we try it again in FindByID
 		//Maybe the deployment is not ready yet
 	} else {
-		updateGameStatus(&game)
+		updateGameStatus(game)
+	}
+	return g.repository.UpdateDeployment(game)
+}
+
+// ScanPending scans the games whose file could not be scanned yet and deploys the clean ones.
+func (g gameService) ScanPending() error {
+	games, err := g.repository.FindAllByStatus(shared.Status_Scanning)
+	if err != nil {
+		return err
+	}
+	for i := range games {
+		err = g.scanAndDeploy(&games[i])
+		if err != nil {
+			log.Println(fmt.Sprintf("Deploying game %s failed: %s", games[i].ID.String(), err))
+		}
 	}
+	return nil
+}
 
-	return &game, g.repository.Save(&game)
+// StartScanRetrier scans the games which are still in the status scanning once per interval,
+// e.g. because the scanner has not been available during their upload.
+func StartScanRetrier(service IGameService, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			err := service.ScanPending()
+			if err != nil {
+				log.Println(fmt.Sprintf("Scanning the pending games failed: %s", err))
+			}
+		}
+	}()
 }
 
 func updateGameStatus(game *models.Game) {
+	//Stopped games keep their url, their status is updated by updateGamePhase
+	if isStopped(game) {
+		return
+	}
 	//We set the game status to Installed if we have an url
 	if game.Url != "" {
 		game.Status = shared.Status_Installed
@@ -101,41 +228,251 @@ func updateGameStatus(game *models.Game) {
 	}
 }
 
+// Delete moves a game to the trash. The game is undeployed, but its files are kept until it is purged.
 func (g gameService) Delete(id uuid.UUID) error {
 	//Get the game, we need the details to delete it from k8s
 	game, err := g.repository.FindByID(id)
 	if err != nil {
 		return err
 	}
+	if game == nil {
+		return sql.ErrNoRows
+	}
+
+	//Delete the beta channel from k8s/aks
+	if game.BetaVersion != 0 {
+		err = g.k8s.DeleteBeta(game)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
 
-	//Delete from azure storage
-	err = g.azure.DeleteGame(os.Getenv("AZURE_CONTAINER_NAME"), id.String())
+	//Delete from k8s/aks, games which are still installing have no url yet, but already have a resource
+	err = g.k8s.DeleteGame(game)
 	if err != nil {
 		if isNotFound(err) {
-			log.Println(fmt.Sprintf("Game %s is already deleted from azure storage", id.String()))
+			log.Println(fmt.Sprintf("Game %s is already deleted from aks", id.String()))
 		} else {
 			return err
 		}
 	}
 
-	//Delete from k8s/aks, if the game has an url
-	if game.Url != "" {
-		err = g.k8s.DeleteGame(game)
+	return g.repository.SoftDelete(id, time.Now())
+}
+
+func (g gameService) FindAllDeletedByOwners(owners []string) ([]models.Game, error) {
+	return g.repository.FindAllDeletedByOwners(owners)
+}
+
+// Restore moves a game out of the trash. Its live version is scanned again and deployed on its cluster if it is clean.
+func (g gameService) Restore(id uuid.UUID) (*models.Game, error) {
+	game, err := g.repository.FindDeletedByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	err = g.repository.Restore(id)
+	if err != nil {
+		return nil, err
+	}
+	game.DeletedAt = nil
+	game.Status = shared.Status_Scanning
+	game.StatusReason = ""
+	game.Revision++
+
+	//The url is read again as soon as the game is running
+	return game, g.scanAndDeploy(game)
+}
+
+// Purge removes a game from the trash permanently.
+// The files of its versions are deleted from azure storage if no other game uses them.
+func (g gameService) Purge(game *models.Game) error {
+	versions, err := g.versions.FindAllByGame(game.ID)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		err = g.blobs.Release(version.BlobName, version.Checksum)
 		if err != nil {
-			if isNotFound(err) {
-				log.Println(fmt.Sprintf("Game %s is already deleted from aks", id.String()))
-			} else {
-				return err
+			return err
+		}
+	}
+
+	err = g.versions.DeleteAllByGame(game.ID)
+	if err != nil {
+		return err
+	}
+	return g.repository.Delete(game.ID)
+}
+
+// PurgeDeletedBefore purges all games which have been moved to the trash before the given time.
+// Returns the number of purged games.
+func (g gameService) PurgeDeletedBefore(before time.Time) (int, error) {
+	games, err := g.repository.FindAllDeletedBefore(before)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range games {
+		err = g.Purge(&games[i])
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartTrashPurger purges the games which have been in the trash for longer than the retention period, once per hour.
+func StartTrashPurger(service IGameService, retention time.Duration) {
+	go func() {
+		for range time.Tick(time.Hour) {
+			purged, err := service.PurgeDeletedBefore(time.Now().Add(-retention))
+			if err != nil {
+				log.Println(fmt.Sprintf("Purging the trash failed: %s", err))
+			} else if purged > 0 {
+				log.Println(fmt.Sprintf("Purged %d games from the trash", purged))
 			}
 		}
+	}()
+}
+
+// Update saves the changed metadata of a game, if the game still has the given revision.
+// The name of the custom resource is kept in sync, its pods keep running because the deploy revision is not changed.
+func (g gameService) Update(game *models.Game, revision int) error {
+	err := g.repository.Update(game, revision)
+	if err != nil {
+		return err
+	}
+
+	//Keep the custom resource in sync. The url does not depend on the metadata,
+	//so the game keeps running even if this fails.
+	if !isDeployed(game) {
+		return nil
+	}
+	err = g.k8s.UpdateGame(game)
+	if err != nil {
+		log.Println(fmt.Sprintf("Updating game %s in k8s failed: %s", game.ID.String(), err))
+	}
+	return nil
+}
+
+// Redeploy restarts a game. The new deploy revision of the game triggers a rollout of its deployment.
+func (g gameService) Redeploy(id uuid.UUID) (*models.Game, error) {
+	game, err := g.repository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, sql.ErrNoRows
+	}
+	if !isDeployed(game) {
+		return nil, shared.ErrGameNotDeployed
+	}
+
+	game.DeployRevision++
+	err = g.repository.Update(game, game.Revision)
+	if err != nil {
+		return nil, err
+	}
+	return game, g.k8s.UpdateGame(game)
+}
+
+// Stop scales the deployments of a game to zero. The game keeps its url and can be started again.
+func (g gameService) Stop(id uuid.UUID) (*models.Game, error) {
+	game, err := g.repository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, sql.ErrNoRows
+	}
+	if isStopped(game) {
+		return game, nil
+	}
+	if !isDeployed(game) {
+		return nil, shared.ErrGameNotDeployed
 	}
 
-	//Delete from db and return
-	return g.repository.Delete(id)
+	err = g.k8s.SuspendGame(game, true)
+	if err != nil {
+		return nil, err
+	}
+	game.Status = shared.Status_Stopping
+	return game, g.repository.UpdateStatus(id, game.Status)
+}
+
+// Start scales the deployments of a stopped game up again.
+func (g gameService) Start(id uuid.UUID) (*models.Game, error) {
+	game, err := g.repository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, sql.ErrNoRows
+	}
+	if !isStopped(game) {
+		return game, nil
+	}
+
+	err = g.k8s.SuspendGame(game, false)
+	if err != nil {
+		return nil, err
+	}
+	game.Status = shared.Status_Starting
+	return game, g.repository.UpdateStatus(id, game.Status)
+}
+
+// Restart starts a stopped game or rolls out new pods of a running game.
+func (g gameService) Restart(id uuid.UUID) (*models.Game, error) {
+	game, err := g.repository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, sql.ErrNoRows
+	}
+	if isStopped(game) {
+		return g.Start(id)
+	}
+	return g.Redeploy(id)
+}
+
+// isStopped returns true if the game has been stopped, even if its pods are still terminating
+func isStopped(game *models.Game) bool {
+	return game.Status == shared.Status_Stopping || game.Status == shared.Status_Stopped
+}
+
+// updateGamePhase reads the phase of a game which is stopping or starting and saves its new status.
+func (g gameService) updateGamePhase(game *models.Game) {
+	phase, err := g.k8s.ReadGamePhase(game)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error reading game phase: %s", err))
+		//We can ignore this error because we try it again next time
+		return
+	}
+
+	switch {
+	case phase == streamv1.GamePhaseStopped && game.Status == shared.Status_Stopping:
+		game.Status = shared.Status_Stopped
+	case phase == streamv1.GamePhaseRunning && game.Status == shared.Status_Starting:
+		updateGameStatus(game)
+	default:
+		return
+	}
+
+	err = g.repository.UpdateStatus(game.ID, game.Status)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error updating game: %s", err))
+	}
 }
 
 func (g gameService) updateGameUrl(game *models.Game) {
-	url, err := g.k8s.ReadGameUrl(game.ID)
+	url, err := g.k8s.ReadGameUrl(game)
 	if err != nil {
 		log.Println(fmt.Sprintf("Error reading game url: %s", err))
 		//We can ignore this error because we try it again next time
@@ -143,22 +480,44 @@ func (g gameService) updateGameUrl(game *models.Game) {
 	} else {
 		game.Url = url
 		updateGameStatus(game)
-		//Save the changes in the database
-		err := g.repository.Save(game)
+		//Only the deployment is saved, changes of the metadata or the file in the meantime are kept
+		err := g.repository.UpdateDeployment(game)
 		if err != nil {
 			log.Println(fmt.Sprintf("Error updating game: %s", err))
 		}
 	}
 }
 
-func GameService(repository repositories.IGameRepository, k8s apis.IK8sApi, azure apis.IAzureApi) IGameService {
+func (g gameService) updateBetaUrl(game *models.Game) {
+	url, err := g.k8s.ReadBetaUrl(game)
+	if err != nil {
+		log.Println(fmt.Sprintf("Error reading beta url: %s", err))
+		//We can ignore this error because we try it again next time
+	} else if url != "" {
+		game.BetaUrl = url
+		err := g.repository.UpdateBetaUrl(game.ID, url)
+		if err != nil {
+			log.Println(fmt.Sprintf("Error updating game: %s", err))
+		}
+	}
+}
+
+func GameService(repository repositories.IGameRepository, versions repositories.IGameVersionRepository, blobs IBlobService, k8s apis.IK8sApi, scans IScanService) IGameService {
 	return &gameService{
 		repository: repository,
+		versions:   versions,
+		blobs:      blobs,
 		k8s:        k8s,
-		azure:      azure,
+		scans:      scans,
+		inScan:     &sync.Map{},
 	}
 }
 
+// isDeployed returns false for games which are not deployed because their file is scanned or has been flagged
+func isDeployed(game *models.Game) bool {
+	return game.Status != shared.Status_Scanning && game.Status != shared.Status_Quarantined
+}
+
 func isNotFound(err error) bool {
 	e := strings.ToLower(err.Error())
 	return strings.Contains(e, "not found") || strings.Contains(e, "notfound")
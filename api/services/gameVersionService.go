@@ -0,0 +1,232 @@
+package services
+
+import (
+	"api/apis"
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"mime/multipart"
+	"time"
+)
+
+type IGameVersionService interface {
+	FindAllByGame(gameID uuid.UUID) ([]models.GameVersion, error)
+	Create(gameID uuid.UUID, file *multipart.FileHeader, archive models.ArchiveOptions, changelog string, uploader string, channel shared.Channel, revision int) (*models.Game, *models.GameVersion, error)
+	Promote(gameID uuid.UUID, version int, channel shared.Channel, revision int) (*models.Game, error)
+	Rollback(gameID uuid.UUID, revision int) (*models.Game, error)
+	RemoveBeta(gameID uuid.UUID, revision int) (*models.Game, error)
+}
+
+type gameVersionService struct {
+	games    repositories.IGameRepository
+	versions repositories.IGameVersionRepository
+	blobs    IBlobService
+	k8s      apis.IK8sApi
+	scans    IScanService
+}
+
+func (g gameVersionService) FindAllByGame(gameID uuid.UUID) ([]models.GameVersion, error) {
+	game, err := g.games.FindByID(gameID)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, sql.ErrNoRows
+	}
+	return g.versions.FindAllByGame(gameID)
+}
+
+// Create uploads a new version of a game and deploys it to the given channel.
+// The revision is only checked if the version is deployed.
+func (g gameVersionService) Create(gameID uuid.UUID, fileHeader *multipart.FileHeader, archive models.ArchiveOptions, changelog string, uploader string, channel shared.Channel, revision int) (*models.Game, *models.GameVersion, error) {
+	game, err := g.findGame(gameID, revision)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//Every version references its blob, so old versions can be deployed again
+	blob, bundle, err := storeUpload(g.blobs, fileHeader, archive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//Only clean files become versions, so every version can be deployed
+	result, err := g.scans.ScanBlob(blob.BlobName)
+	if err == nil && !result.Clean {
+		err = fmt.Errorf("%w: %s", shared.ErrContentRejected, result.Reason)
+	}
+	if err != nil {
+		errDel := g.blobs.Release(blob.BlobName, blob.Hash)
+		if errDel != nil {
+			log.Println(fmt.Sprintf("Release blob %s failed", blob.BlobName))
+		}
+		return nil, nil, err
+	}
+
+	version := models.GameVersion{
+		ID:              uuid.New(),
+		GameID:          gameID,
+		BlobName:        blob.BlobName,
+		StorageLocation: blob.StorageLocation,
+		FileName:        bundle.Entry,
+		Bios:            bundle.Bios,
+		Checksum:        blob.Hash,
+		Changelog:       changelog,
+		Uploader:        uploader,
+		CreatedAt:       time.Now(),
+	}
+
+	err = g.versions.Create(&version)
+	if err != nil {
+		//Release the blob, otherwise the reference would never be removed
+		errDel := g.blobs.Release(blob.BlobName, blob.Hash)
+		if errDel != nil {
+			log.Println(fmt.Sprintf("Release blob %s failed", blob.BlobName))
+		}
+		return nil, nil, err
+	}
+
+	if channel == shared.Channel_None {
+		return game, &version, nil
+	}
+
+	game, err = g.deploy(game, &version, channel, game.Revision)
+	return game, &version, err
+}
+
+// Promote deploys an existing version of a game to the given channel.
+func (g gameVersionService) Promote(gameID uuid.UUID, version int, channel shared.Channel, revision int) (*models.Game, error) {
+	game, err := g.findGame(gameID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	gameVersion, err := g.versions.FindByGameAndVersion(gameID, version)
+	if err != nil {
+		return nil, err
+	}
+	if gameVersion == nil {
+		return nil, shared.ErrVersionNotFound
+	}
+
+	return g.deploy(game, gameVersion, channel, game.Revision)
+}
+
+// Rollback deploys the newest version which is older than the current live version.
+func (g gameVersionService) Rollback(gameID uuid.UUID, revision int) (*models.Game, error) {
+	game, err := g.findGame(gameID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := g.versions.FindAllByGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	//The versions are sorted, the newest version first
+	for _, version := range versions {
+		if version.Version < game.LiveVersion {
+			return g.deploy(game, &version, shared.Channel_Live, game.Revision)
+		}
+	}
+	return nil, shared.ErrNoPreviousVersion
+}
+
+// RemoveBeta deletes the beta channel of a game.
+func (g gameVersionService) RemoveBeta(gameID uuid.UUID, revision int) (*models.Game, error) {
+	game, err := g.findGame(gameID, revision)
+	if err != nil {
+		return nil, err
+	}
+	if game.BetaVersion == 0 {
+		return game, nil
+	}
+
+	err = g.k8s.DeleteBeta(game)
+	if err != nil {
+		if isNotFound(err) {
+			log.Println(fmt.Sprintf("Beta of game %s is already deleted from aks", gameID.String()))
+		} else {
+			return nil, err
+		}
+	}
+
+	game.BetaVersion = 0
+	err = g.games.Update(game, game.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	game.BetaUrl = ""
+	return game, g.games.UpdateBetaUrl(gameID, "")
+}
+
+// deploy points the channel of the game to the version and updates the game resources in k8s.
+// Games which are scanned or quarantined are not deployed, their versions can only be uploaded.
+func (g gameVersionService) deploy(game *models.Game, version *models.GameVersion, channel shared.Channel, revision int) (*models.Game, error) {
+	if !isDeployed(game) {
+		return nil, shared.ErrGameNotDeployed
+	}
+	switch channel {
+	case shared.Channel_Live:
+		game.StorageLocation = version.StorageLocation
+		game.FileName = version.FileName
+		game.Bios = version.Bios
+		game.BlobName = version.BlobName
+		game.Checksum = version.Checksum
+		game.LiveVersion = version.Version
+		game.DeployRevision++
+		err := g.games.Update(game, revision)
+		if err != nil {
+			return nil, err
+		}
+		//The new deploy revision of the game triggers the rollout
+		return game, g.k8s.UpdateGame(game)
+	case shared.Channel_Beta:
+		game.BetaVersion = version.Version
+		err := g.games.Update(game, revision)
+		if err != nil {
+			return nil, err
+		}
+		err = g.k8s.DeployBeta(game, version)
+		if err != nil {
+			return nil, err
+		}
+		//The url is read again as soon as the beta is running
+		game.BetaUrl = ""
+		return game, g.games.UpdateBetaUrl(game.ID, "")
+	default:
+		return nil, shared.ErrInvalidChannel
+	}
+}
+
+// findGame returns the game or sql.ErrNoRows if it is not existing.
+// It returns shared.ErrPreconditionFailed if revision is not 0 and the game has another revision.
+func (g gameVersionService) findGame(gameID uuid.UUID, revision int) (*models.Game, error) {
+	game, err := g.games.FindByID(gameID)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, sql.ErrNoRows
+	}
+	if revision != 0 && revision != game.Revision {
+		return nil, shared.ErrPreconditionFailed
+	}
+	return game, nil
+}
+
+func GameVersionService(games repositories.IGameRepository, versions repositories.IGameVersionRepository, blobs IBlobService, k8s apis.IK8sApi, scans IScanService) IGameVersionService {
+	return &gameVersionService{
+		games:    games,
+		versions: versions,
+		blobs:    blobs,
+		k8s:      k8s,
+		scans:    scans,
+	}
+}
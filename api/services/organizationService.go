@@ -0,0 +1,264 @@
+package services
+
+import (
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"strings"
+	"time"
+)
+
+type IOrganizationService interface {
+	Create(name string, creator string) (*models.Organization, error)
+	FindAllBySubject(subject string) ([]models.Membership, error)
+	FindByID(id uuid.UUID, subject string) (*models.Membership, error)
+	FindMembers(id uuid.UUID, subject string) ([]models.OrgMember, error)
+	UpdateRole(id uuid.UUID, subject string, member string, role shared.OrgRole) error
+	RemoveMember(id uuid.UUID, subject string, member string) error
+	Invite(id uuid.UUID, subject string, email string, role shared.OrgRole) (*models.OrgInvitation, string, error)
+	FindInvitations(id uuid.UUID, subject string) ([]models.OrgInvitation, error)
+	RevokeInvitation(id uuid.UUID, subject string, invitationID uuid.UUID) error
+	AcceptInvitation(token string, subject string, email string) (*models.OrgMember, error)
+}
+
+type organizationService struct {
+	orgs          repositories.IOrganizationRepository
+	invitations   repositories.IOrgInvitationRepository
+	invitationTTL time.Duration
+}
+
+// Create creates an organization, the creator becomes its first owner.
+func (o organizationService) Create(name string, creator string) (*models.Organization, error) {
+	now := time.Now()
+	org := &models.Organization{
+		Name:      name,
+		CreatedBy: creator,
+		CreatedAt: now,
+	}
+	err := o.orgs.Create(org, &models.OrgMember{
+		Subject:  creator,
+		Role:     shared.Role_Owner,
+		JoinedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (o organizationService) FindAllBySubject(subject string) ([]models.Membership, error) {
+	return o.orgs.FindAllBySubject(subject)
+}
+
+// FindByID returns the organization together with the role of the user, who must be a member.
+func (o organizationService) FindByID(id uuid.UUID, subject string) (*models.Membership, error) {
+	org, role, err := o.requireRole(id, subject, shared.Role_Viewer)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Membership{Organization: *org, Role: role}, nil
+}
+
+// FindMembers returns the members of the organization, the user must be a member.
+func (o organizationService) FindMembers(id uuid.UUID, subject string) ([]models.OrgMember, error) {
+	_, _, err := o.requireRole(id, subject, shared.Role_Viewer)
+	if err != nil {
+		return nil, err
+	}
+	return o.orgs.FindMembers(id)
+}
+
+// UpdateRole changes the role of a member. Admins can manage the roles up to admin,
+// only owners can promote members to owners or change the role of other owners.
+func (o organizationService) UpdateRole(id uuid.UUID, subject string, member string, role shared.OrgRole) error {
+	_, callerRole, err := o.requireRole(id, subject, shared.Role_Admin)
+	if err != nil {
+		return err
+	}
+	memberRole, err := o.orgs.FindRole(id, member)
+	if err != nil {
+		return err
+	}
+	if memberRole == "" {
+		return shared.ErrMemberNotFound
+	}
+	if !callerRole.Includes(role) || !callerRole.Includes(memberRole) {
+		return shared.ErrForbidden
+	}
+
+	log.Println(fmt.Sprintf("%s changed the role of %s in organization %s from %s to %s", subject, member, id.String(), memberRole, role))
+	return o.orgs.UpdateRole(id, member, role)
+}
+
+// RemoveMember removes a member from the organization. Every member can leave the organization,
+// admins can remove other members, but only owners can remove other owners.
+func (o organizationService) RemoveMember(id uuid.UUID, subject string, member string) error {
+	if subject != member {
+		_, callerRole, err := o.requireRole(id, subject, shared.Role_Admin)
+		if err != nil {
+			return err
+		}
+		memberRole, err := o.orgs.FindRole(id, member)
+		if err != nil {
+			return err
+		}
+		if memberRole == "" {
+			return shared.ErrMemberNotFound
+		}
+		if !callerRole.Includes(memberRole) {
+			return shared.ErrForbidden
+		}
+	} else {
+		_, _, err := o.requireRole(id, subject, shared.Role_Viewer)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Println(fmt.Sprintf("%s removed %s from organization %s", subject, member, id.String()))
+	return o.orgs.DeleteMember(id, member)
+}
+
+// Invite creates an invitation for the email address. The returned token must be sent to the invited user,
+// it is not stored and can not be read again.
+func (o organizationService) Invite(id uuid.UUID, subject string, email string, role shared.OrgRole) (*models.OrgInvitation, string, error) {
+	_, callerRole, err := o.requireRole(id, subject, shared.Role_Admin)
+	if err != nil {
+		return nil, "", err
+	}
+	if !callerRole.Includes(role) {
+		return nil, "", shared.ErrForbidden
+	}
+
+	tokenBytes := make([]byte, 32)
+	_, err = rand.Read(tokenBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	now := time.Now()
+	invitation := &models.OrgInvitation{
+		OrgID:     id,
+		Email:     strings.ToLower(email),
+		Role:      role,
+		TokenHash: hashToken(token),
+		InvitedBy: subject,
+		CreatedAt: now,
+		ExpiresAt: now.Add(o.invitationTTL),
+	}
+	err = o.invitations.Create(invitation)
+	if err != nil {
+		return nil, "", err
+	}
+	return invitation, token, nil
+}
+
+// FindInvitations returns the pending invitations of the organization, the user must be an admin.
+func (o organizationService) FindInvitations(id uuid.UUID, subject string) ([]models.OrgInvitation, error) {
+	_, _, err := o.requireRole(id, subject, shared.Role_Admin)
+	if err != nil {
+		return nil, err
+	}
+	return o.invitations.FindPendingByOrg(id, time.Now())
+}
+
+// RevokeInvitation deletes an invitation of the organization, the user must be an admin.
+func (o organizationService) RevokeInvitation(id uuid.UUID, subject string, invitationID uuid.UUID) error {
+	_, _, err := o.requireRole(id, subject, shared.Role_Admin)
+	if err != nil {
+		return err
+	}
+	err = o.invitations.Delete(id, invitationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return shared.ErrInvitationNotFound
+	}
+	return err
+}
+
+// AcceptInvitation adds the user to the organization of the invitation.
+// The verified email address of the user must be the address the invitation has been sent to.
+func (o organizationService) AcceptInvitation(token string, subject string, email string) (*models.OrgMember, error) {
+	invitation, err := o.invitations.FindByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if invitation == nil || invitation.AcceptedAt != nil || now.After(invitation.ExpiresAt) {
+		return nil, shared.ErrInvitationNotFound
+	}
+	if email == "" || !strings.EqualFold(email, invitation.Email) {
+		log.Println(fmt.Sprintf("%s tried to accept an invitation for %s", subject, invitation.Email))
+		return nil, shared.ErrForbidden
+	}
+
+	role, err := o.orgs.FindRole(invitation.OrgID, subject)
+	if err != nil {
+		return nil, err
+	}
+	if role != "" {
+		return nil, shared.ErrAlreadyMember
+	}
+
+	invitation.AcceptedAt = &now
+	invitation.AcceptedBy = &subject
+	member := &models.OrgMember{
+		OrgID:    invitation.OrgID,
+		Subject:  subject,
+		Role:     invitation.Role,
+		JoinedAt: now,
+	}
+	err = o.invitations.Accept(invitation, member)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, shared.ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// requireRole returns the organization and the role of the user, if the user has at least the given role.
+// Organizations of which the user is no member are reported as not existing.
+func (o organizationService) requireRole(id uuid.UUID, subject string, required shared.OrgRole) (*models.Organization, shared.OrgRole, error) {
+	org, err := o.orgs.FindByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if org == nil {
+		return nil, "", shared.ErrOrgNotFound
+	}
+	role, err := o.orgs.FindRole(id, subject)
+	if err != nil {
+		return nil, "", err
+	}
+	if role == "" {
+		return nil, "", shared.ErrOrgNotFound
+	}
+	if !role.Includes(required) {
+		return nil, "", shared.ErrForbidden
+	}
+	return org, role, nil
+}
+
+// hashToken returns the hex encoded SHA-256 hash of an invitation token
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func OrganizationService(orgs repositories.IOrganizationRepository, invitations repositories.IOrgInvitationRepository, invitationTTL time.Duration) IOrganizationService {
+	return &organizationService{
+		orgs:          orgs,
+		invitations:   invitations,
+		invitationTTL: invitationTTL,
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"api/apis"
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RomDownloadConfig configures how game files are downloaded.
+type RomDownloadConfig struct {
+	//"stream" or "redirect"
+	Mode string
+	//How long signed urls are valid
+	UrlTTL time.Duration
+	//Key of the signed urls of the api, which are used if the storage can not create signed urls
+	SigningKey []byte
+}
+
+// RomDownloadConfigFromEnv reads the config from the environment variables
+// ROM_DOWNLOAD_MODE, ROM_DOWNLOAD_URL_TTL and ROM_URL_SIGNING_KEY.
+// If no signing key is set, a random key is used, so the signed urls of the api are only valid for this replica.
+func RomDownloadConfigFromEnv() RomDownloadConfig {
+	config := RomDownloadConfig{
+		Mode:       os.Getenv("ROM_DOWNLOAD_MODE"),
+		UrlTTL:     15 * time.Minute,
+		SigningKey: []byte(os.Getenv("ROM_URL_SIGNING_KEY")),
+	}
+	if config.Mode == "" {
+		config.Mode = "stream"
+	}
+	if config.Mode != "stream" && config.Mode != "redirect" {
+		log.Fatalf("Unknown ROM_DOWNLOAD_MODE %s", config.Mode)
+	}
+	if ttl := os.Getenv("ROM_DOWNLOAD_URL_TTL"); ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("Invalid ROM_DOWNLOAD_URL_TTL %s: %s", ttl, err)
+		}
+		config.UrlTTL = duration
+	}
+	if len(config.SigningKey) == 0 {
+		log.Println("ROM_URL_SIGNING_KEY is not set, signed urls are only valid for this replica")
+		config.SigningKey = make([]byte, 32)
+		_, err := rand.Read(config.SigningKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	return config
+}
+
+// Rom is a game file which is downloaded.
+// Either Content or RedirectUrl is set.
+type Rom struct {
+	Version      int
+	FileName     string
+	Checksum     string
+	Size         int64
+	LastModified time.Time
+	Content      io.ReadSeekCloser
+	RedirectUrl  string
+}
+
+type IRomService interface {
+	Download(game *models.Game, version int) (*Rom, error)
+	DownloadSigned(gameID uuid.UUID, version int, expires time.Time, signature string) (*Rom, error)
+	LogDownload(download *models.RomDownload) error
+}
+
+type romService struct {
+	versions  repositories.IGameVersionRepository
+	downloads repositories.IRomDownloadRepository
+	azure     apis.IAzureApi
+	config    RomDownloadConfig
+}
+
+// errBundleDownload is returned for versions which consist of several files, they can not be downloaded as a single file
+var errBundleDownload = fmt.Errorf("%w: the version consists of several files", shared.ErrOperationNotSupported)
+
+// Download returns the file of a version of the game, or of the live version if version is 0.
+// In redirect mode, only the signed url of the file is returned.
+func (r romService) Download(game *models.Game, version int) (*Rom, error) {
+	if version == 0 {
+		version = game.LiveVersion
+	}
+
+	gameVersion, err := r.versions.FindByGameAndVersion(game.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	if gameVersion == nil {
+		return nil, shared.ErrVersionNotFound
+	}
+	if shared.IsBundle(gameVersion.BlobName) {
+		return nil, errBundleDownload
+	}
+
+	if r.config.Mode != "redirect" {
+		return r.open(gameVersion)
+	}
+
+	rom := romOf(gameVersion)
+	expires := time.Now().Add(r.config.UrlTTL)
+	rom.RedirectUrl, err = r.azure.SignedGameUrl(os.Getenv("AZURE_CONTAINER_NAME"), gameVersion.BlobName, gameVersion.FileName, expires)
+	if errors.Is(err, shared.ErrSignedUrlNotSupported) {
+		//The api signs the url instead and streams the file
+		rom.RedirectUrl = r.signedUrl(game.ID, version, expires)
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rom, nil
+}
+
+// DownloadSigned returns the file of a version of a game, if the signature of the url is valid.
+func (r romService) DownloadSigned(gameID uuid.UUID, version int, expires time.Time, signature string) (*Rom, error) {
+	err := shared.VerifySignature(r.config.SigningKey, romResource(gameID, version), expires, signature, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	gameVersion, err := r.versions.FindByGameAndVersion(gameID, version)
+	if err != nil {
+		return nil, err
+	}
+	if gameVersion == nil {
+		return nil, shared.ErrVersionNotFound
+	}
+	if shared.IsBundle(gameVersion.BlobName) {
+		return nil, errBundleDownload
+	}
+	return r.open(gameVersion)
+}
+
+func (r romService) LogDownload(download *models.RomDownload) error {
+	log.Println(fmt.Sprintf("Game %s version %d has been downloaded by %q from %s (%s)",
+		download.GameID.String(), download.Version, download.Subject, download.ClientIP, download.Method))
+	return r.downloads.Create(download)
+}
+
+// open reads the properties of the file and opens it
+func (r romService) open(version *models.GameVersion) (*Rom, error) {
+	properties, err := r.azure.GameProperties(os.Getenv("AZURE_CONTAINER_NAME"), version.BlobName)
+	if err != nil {
+		return nil, err
+	}
+
+	rom := romOf(version)
+	rom.Size = properties.Size
+	rom.LastModified = properties.LastModified
+	rom.Content = apis.BlobReader(r.azure, os.Getenv("AZURE_CONTAINER_NAME"), version.BlobName, properties.Size)
+	return rom, nil
+}
+
+// signedUrl returns the relative url of the file, which can be used without authorization until it expires.
+func (r romService) signedUrl(gameID uuid.UUID, version int, expires time.Time) string {
+	query := url.Values{}
+	query.Set("version", strconv.Itoa(version))
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", shared.Sign(r.config.SigningKey, romResource(gameID, version), expires))
+	return fmt.Sprintf("/games/%s/rom?%s", gameID.String(), query.Encode())
+}
+
+func romResource(gameID uuid.UUID, version int) string {
+	return fmt.Sprintf("games/%s/rom/%d", gameID.String(), version)
+}
+
+func romOf(version *models.GameVersion) *Rom {
+	return &Rom{
+		Version:  version.Version,
+		FileName: version.FileName,
+		Checksum: version.Checksum,
+	}
+}
+
+func RomService(versions repositories.IGameVersionRepository, downloads repositories.IRomDownloadRepository, azure apis.IAzureApi, config RomDownloadConfig) IRomService {
+	return &romService{
+		versions:  versions,
+		downloads: downloads,
+		azure:     azure,
+		config:    config,
+	}
+}
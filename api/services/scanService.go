@@ -0,0 +1,147 @@
+package services
+
+import (
+	"api/apis"
+	"api/repositories"
+	"api/shared"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+var scans = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "igs_scans_total",
+	Help: "Number of scanned files, result is clean, flagged or error.",
+}, []string{"result"})
+
+// ScannerType selects the scanner of the uploaded files
+type ScannerType string
+
+const (
+	//Scanner_None accepts all files
+	Scanner_None ScannerType = "none"
+	//Scanner_Clamd scans the files with the clamd daemon of ClamAV
+	Scanner_Clamd ScannerType = "clamd"
+)
+
+// ScanConfig configures the scanning of uploaded files.
+type ScanConfig struct {
+	Scanner ScannerType
+	//Network and address of clamd, e.g. tcp and clamav:3310 or unix and /run/clamav/clamd.sock
+	ClamdNetwork string
+	ClamdAddress string
+	//Timeout limits the scan of a single file
+	Timeout time.Duration
+	//Games whose file could not be scanned, e.g. because the scanner was not available, are scanned again once per retry interval
+	RetryInterval time.Duration
+}
+
+// ScanConfigFromEnv reads the config from the environment variables SCANNER, CLAMD_ADDRESS (tcp://host:port or unix:///path),
+// SCAN_TIMEOUT and SCAN_RETRY_INTERVAL.
+func ScanConfigFromEnv() ScanConfig {
+	config := ScanConfig{
+		Scanner:       ScannerType(os.Getenv("SCANNER")),
+		Timeout:       durationFromEnv("SCAN_TIMEOUT", 5*time.Minute),
+		RetryInterval: durationFromEnv("SCAN_RETRY_INTERVAL", time.Minute),
+	}
+	if config.Scanner == "" {
+		config.Scanner = Scanner_None
+	}
+	if config.Scanner != Scanner_None && config.Scanner != Scanner_Clamd {
+		log.Fatalf("Invalid SCANNER %s, valid scanners are none and clamd", config.Scanner)
+	}
+	if config.Scanner == Scanner_Clamd {
+		address := os.Getenv("CLAMD_ADDRESS")
+		if address == "" {
+			address = "tcp://localhost:3310"
+		}
+		network, path, ok := strings.Cut(address, "://")
+		if !ok || (network != "tcp" && network != "unix") || path == "" {
+			log.Fatalf("Invalid CLAMD_ADDRESS %s, use tcp://host:port or unix:///path", address)
+		}
+		config.ClamdNetwork = network
+		config.ClamdAddress = path
+	}
+	if config.Timeout == 0 || config.RetryInterval == 0 {
+		log.Fatalf("SCAN_TIMEOUT and SCAN_RETRY_INTERVAL must not be 0")
+	}
+	return config
+}
+
+// Scanner returns the scanner of the config
+func Scanner(config ScanConfig) apis.IScannerApi {
+	if config.Scanner == Scanner_Clamd {
+		return apis.ClamdScanner(config.ClamdNetwork, config.ClamdAddress, config.Timeout)
+	}
+	return apis.NoopScanner()
+}
+
+type IScanService interface {
+	ScanBlob(blobName string) (apis.ScanResult, error)
+}
+
+type scanService struct {
+	scanner apis.IScannerApi
+	azure   apis.IAzureApi
+	blobs   repositories.IBlobRepository
+}
+
+// ScanBlob scans a stored blob. Errors of the scanner are wrapped into shared.ErrScannerUnavailable.
+// The files of a bundle are scanned one by one, the reason of a flagged bundle names the flagged file.
+func (s scanService) ScanBlob(blobName string) (apis.ScanResult, error) {
+	if !shared.IsBundle(blobName) {
+		return s.scanFile(blobName)
+	}
+
+	files, err := s.blobs.FindFiles(strings.TrimPrefix(blobName, shared.BundlePrefix))
+	if err != nil {
+		return apis.ScanResult{}, fmt.Errorf("%w: %s", shared.ErrScannerUnavailable, err)
+	}
+	for _, file := range files {
+		result, err := s.scanFile(BundleFileName(blobName, file.Path))
+		if err != nil {
+			return result, err
+		}
+		if !result.Clean {
+			result.Reason = file.Path + ": " + result.Reason
+			return result, nil
+		}
+	}
+	return apis.ScanResult{Clean: true}, nil
+}
+
+// scanFile scans a single stored file
+func (s scanService) scanFile(blobName string) (apis.ScanResult, error) {
+	content, err := s.azure.DownloadGame(os.Getenv("AZURE_CONTAINER_NAME"), blobName)
+	if err != nil {
+		scans.WithLabelValues("error").Inc()
+		return apis.ScanResult{}, fmt.Errorf("%w: %s", shared.ErrScannerUnavailable, err)
+	}
+	defer content.Close()
+
+	result, err := s.scanner.Scan(blobName, content)
+	switch {
+	case err != nil:
+		scans.WithLabelValues("error").Inc()
+		return apis.ScanResult{}, fmt.Errorf("%w: %s", shared.ErrScannerUnavailable, err)
+	case result.Clean:
+		scans.WithLabelValues("clean").Inc()
+	default:
+		scans.WithLabelValues("flagged").Inc()
+		log.Println(fmt.Sprintf("The scanner has flagged blob %s: %s", blobName, result.Reason))
+	}
+	return result, nil
+}
+
+// ScanService scans the blobs which have been uploaded with the scanner before they are deployed.
+func ScanService(scanner apis.IScannerApi, azure apis.IAzureApi, blobs repositories.IBlobRepository) IScanService {
+	return &scanService{
+		scanner: scanner,
+		azure:   azure,
+		blobs:   blobs,
+	}
+}
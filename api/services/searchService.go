@@ -0,0 +1,77 @@
+package services
+
+import (
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"strings"
+)
+
+// descriptionSnippetLength is the length of the highlighted part of a description
+const descriptionSnippetLength = 160
+
+type ISearchService interface {
+	Search(query string, caller string, email string, limit int, offset int) ([]models.SearchHit, error)
+}
+
+type searchService struct {
+	repository repositories.ISearchRepository
+	access     IAccessService
+}
+
+// Search returns the games visible to the caller which match all words of the query, the most relevant game first.
+// The visible games are the public games, the games of the owners of the caller and the games shared with the caller or the email.
+func (s searchService) Search(query string, caller string, email string, limit int, offset int) ([]models.SearchHit, error) {
+	terms := shared.SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, shared.ErrInvalidSearchQuery
+	}
+
+	owners, err := s.access.Owners(caller)
+	if err != nil {
+		return nil, err
+	}
+
+	hits, err := s.repository.Search(repositories.SearchQuery{
+		Terms:   terms,
+		Owners:  owners,
+		Subject: caller,
+		Email:   email,
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hits {
+		if hits[i].Highlights == nil {
+			hits[i].Highlights = highlight(&hits[i].Game, terms)
+		}
+	}
+	return hits, nil
+}
+
+// highlight returns the fields of the game which match the terms
+func highlight(game *models.Game, terms []string) map[string]string {
+	fields := map[string]string{
+		"title":       shared.Highlight(game.Title, terms, 0),
+		"description": shared.Highlight(game.Description, terms, descriptionSnippetLength),
+		"tags":        shared.Highlight(strings.Join(game.Tags, ", "), terms, 0),
+		"platform":    shared.Highlight(game.Platform, terms, 0),
+	}
+	highlights := map[string]string{}
+	for field, value := range fields {
+		if value != "" {
+			highlights[field] = value
+		}
+	}
+	return highlights
+}
+
+func SearchService(repository repositories.ISearchRepository, access IAccessService) ISearchService {
+	return &searchService{
+		repository: repository,
+		access:     access,
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"api/models"
+	"api/repositories"
+	"api/shared"
+	"github.com/google/uuid"
+	"math"
+	"sort"
+	"time"
+)
+
+// maxUsageRange is the longest range of a usage report
+const maxUsageRange = 366 * 24 * time.Hour
+
+type IUsageService interface {
+	Record(session *models.PlaySession) error
+	GameUsage(gameID uuid.UUID, from time.Time, to time.Time) (*models.GameUsage, error)
+	Report(from time.Time, to time.Time) ([]models.UsageEntry, error)
+}
+
+type usageService struct {
+	games    repositories.IGameRepository
+	sessions repositories.IPlaySessionRepository
+}
+
+// Record saves a reported play session. The same session can be reported repeatedly,
+// every report extends it up to its last seen time or its end.
+// Returns sql.ErrNoRows if the game is not existing.
+func (u usageService) Record(session *models.PlaySession) error {
+	if session.EndedAt != nil && session.EndedAt.Before(session.StartedAt) {
+		return shared.ErrInvalidPlaySession
+	}
+	owner, err := u.games.ReadOwner(session.GameID)
+	if err != nil {
+		return err
+	}
+
+	session.Owner = owner
+	session.StartedAt = session.StartedAt.UTC()
+	if session.LastSeenAt.Before(session.StartedAt) {
+		session.LastSeenAt = session.StartedAt
+	}
+	if session.EndedAt != nil {
+		endedAt := session.EndedAt.UTC()
+		session.EndedAt = &endedAt
+		if session.LastSeenAt.Before(endedAt) {
+			session.LastSeenAt = endedAt
+		}
+	}
+	session.LastSeenAt = session.LastSeenAt.UTC()
+	return u.sessions.Save(session)
+}
+
+// GameUsage returns the play time of a game between from and to, in total and per day.
+func (u usageService) GameUsage(gameID uuid.UUID, from time.Time, to time.Time) (*models.GameUsage, error) {
+	if err := validateUsageRange(from, to); err != nil {
+		return nil, err
+	}
+	sessions, err := u.sessions.FindAllOfGameBetween(gameID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.GameUsage{GameID: gameID, From: from, To: to, Days: aggregateUsage(sessions, from, to)}
+	var seconds float64
+	players := map[string]bool{}
+	for _, session := range sessions {
+		start, end := clampSession(session, from, to)
+		if !end.After(start) {
+			continue
+		}
+		seconds += end.Sub(start).Seconds()
+		usage.Sessions++
+		if session.Player != nil {
+			players[*session.Player] = true
+		}
+	}
+	usage.Minutes = minutes(seconds)
+	usage.Players = len(players)
+	return usage, nil
+}
+
+// Report returns the play time of all games between from and to per game, owner and day.
+func (u usageService) Report(from time.Time, to time.Time) ([]models.UsageEntry, error) {
+	if err := validateUsageRange(from, to); err != nil {
+		return nil, err
+	}
+	sessions, err := u.sessions.FindAllBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateUsage(sessions, from, to), nil
+}
+
+func validateUsageRange(from time.Time, to time.Time) error {
+	if !from.Before(to) || to.Sub(from) > maxUsageRange {
+		return shared.ErrInvalidUsageRange
+	}
+	return nil
+}
+
+// usageKey identifies an entry of the aggregation
+type usageKey struct {
+	day    string
+	gameID uuid.UUID
+}
+
+// usageAccumulator collects the sessions of an entry
+type usageAccumulator struct {
+	entry    models.UsageEntry
+	seconds  float64
+	sessions map[uuid.UUID]bool
+	players  map[string]bool
+}
+
+// aggregateUsage splits the sessions between from and to at midnight (UTC) and sums them up per game and day.
+// A session which lasts over midnight is counted on both days. The entries are ordered by day, owner and game.
+func aggregateUsage(sessions []models.PlaySession, from time.Time, to time.Time) []models.UsageEntry {
+	accumulators := map[usageKey]*usageAccumulator{}
+	for _, session := range sessions {
+		start, end := clampSession(session, from, to)
+		for start.Before(end) {
+			dayEnd := start.Truncate(24 * time.Hour).Add(24 * time.Hour)
+			if dayEnd.After(end) {
+				dayEnd = end
+			}
+
+			key := usageKey{day: start.Format(time.DateOnly), gameID: session.GameID}
+			accumulator, ok := accumulators[key]
+			if !ok {
+				accumulator = &usageAccumulator{
+					entry:    models.UsageEntry{Day: key.day, GameID: session.GameID, Owner: session.Owner},
+					sessions: map[uuid.UUID]bool{},
+					players:  map[string]bool{},
+				}
+				accumulators[key] = accumulator
+			}
+			accumulator.seconds += dayEnd.Sub(start).Seconds()
+			accumulator.sessions[session.ID] = true
+			if session.Player != nil {
+				accumulator.players[*session.Player] = true
+			}
+			start = dayEnd
+		}
+	}
+
+	entries := make([]models.UsageEntry, 0, len(accumulators))
+	for _, accumulator := range accumulators {
+		entry := accumulator.entry
+		entry.Minutes = minutes(accumulator.seconds)
+		entry.Sessions = len(accumulator.sessions)
+		entry.Players = len(accumulator.players)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Day != entries[j].Day {
+			return entries[i].Day < entries[j].Day
+		}
+		if entries[i].Owner != entries[j].Owner {
+			return entries[i].Owner < entries[j].Owner
+		}
+		return entries[i].GameID.String() < entries[j].GameID.String()
+	})
+	return entries
+}
+
+// clampSession returns the part of the session between from and to in UTC.
+// Sessions which have not been ended last until they have been seen the last time.
+func clampSession(session models.PlaySession, from time.Time, to time.Time) (time.Time, time.Time) {
+	start, end := session.StartedAt, session.LastSeenAt
+	if session.EndedAt != nil {
+		end = *session.EndedAt
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	return start.UTC(), end.UTC()
+}
+
+// minutes converts seconds into minutes, rounded to two decimals
+func minutes(seconds float64) float64 {
+	return math.Round(seconds/60*100) / 100
+}
+
+func UsageService(games repositories.IGameRepository, sessions repositories.IPlaySessionRepository) IUsageService {
+	return &usageService{
+		games:    games,
+		sessions: sessions,
+	}
+}
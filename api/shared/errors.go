@@ -0,0 +1,93 @@
+package shared
+
+import "errors"
+
+// ErrPreconditionFailed is returned if a game has been modified since the client has read it.
+var ErrPreconditionFailed = errors.New("the game has been modified in the meantime")
+
+// ErrVersionNotFound is returned if a version of a game is not existing.
+var ErrVersionNotFound = errors.New("version not found")
+
+// ErrNoPreviousVersion is returned if a rollback is requested, but there is no older version.
+var ErrNoPreviousVersion = errors.New("there is no version older than the live version")
+
+// ErrInvalidChannel is returned if a version should be deployed to an unknown channel.
+var ErrInvalidChannel = errors.New("invalid channel, valid channels are live and beta")
+
+// ErrSignedUrlNotSupported is returned by storages which can not create signed urls.
+var ErrSignedUrlNotSupported = errors.New("the storage does not support signed urls")
+
+// ErrInvalidSignature is returned if a signed url is invalid or expired.
+var ErrInvalidSignature = errors.New("the signature is invalid or expired")
+
+// ErrInvalidBatchOperation is returned if a batch operation is unknown or its parameters are invalid.
+var ErrInvalidBatchOperation = errors.New("invalid operation, valid operations are delete, redeploy, set_visibility and stop")
+
+// ErrOperationNotSupported is returned if an operation is known, but not supported yet.
+var ErrOperationNotSupported = errors.New("the operation is not supported yet")
+
+// ErrInvalidSearchQuery is returned if a search query contains no words.
+var ErrInvalidSearchQuery = errors.New("the search query must contain at least one word")
+
+// ErrForbidden is returned if the user does not have the permission for an action.
+var ErrForbidden = errors.New("you don't have permission to access this resource")
+
+// ErrOrgNotFound is returned if an organization is not existing.
+var ErrOrgNotFound = errors.New("organization not found")
+
+// ErrMemberNotFound is returned if a user is not a member of an organization.
+var ErrMemberNotFound = errors.New("member not found")
+
+// ErrAlreadyMember is returned if an invitation is accepted by a member of the organization.
+var ErrAlreadyMember = errors.New("you are already a member of the organization")
+
+// ErrLastOwner is returned if the last owner of an organization would be removed or demoted.
+var ErrLastOwner = errors.New("an organization must have at least one owner")
+
+// ErrInvitationNotFound is returned if an invitation is not existing, expired or already accepted.
+var ErrInvitationNotFound = errors.New("the invitation is invalid or expired")
+
+// ErrCollaboratorNotFound is returned if a game has no such collaborator.
+var ErrCollaboratorNotFound = errors.New("collaborator not found")
+
+// ErrInvalidCollaborator is returned if a grant names neither or both a subject and an email, or contains unknown permissions.
+var ErrInvalidCollaborator = errors.New("either subject or email must be set and the permissions must be view, update, deploy-control or delete")
+
+// ErrInvalidPlaySession is returned if a reported play session ends before it starts.
+var ErrInvalidPlaySession = errors.New("a play session must not end before it starts")
+
+// ErrInvalidUsageRange is returned if the range of a usage report is empty or too long.
+var ErrInvalidUsageRange = errors.New("from must be before to and the range must not be longer than 366 days")
+
+// ErrInvalidImportMode is returned if a catalog should be imported with an unknown mode.
+var ErrInvalidImportMode = errors.New("invalid mode, valid modes are preserve and remap")
+
+// ErrInvalidArchive is returned if an archive, e.g. an imported catalog or an uploaded game, is not readable or does not match its manifest.
+var ErrInvalidArchive = errors.New("the archive is invalid")
+
+// ErrRepairDisabled is returned if inconsistencies should be repaired, but no kind of repair is enabled.
+var ErrRepairDisabled = errors.New("repairs are disabled, enable them with CONSISTENCY_REPAIR")
+
+// ErrClusterNotFound is returned if a cluster is not registered.
+var ErrClusterNotFound = errors.New("cluster not found")
+
+// ErrClusterCordoned is returned if a new game should be pinned to a cordoned cluster.
+var ErrClusterCordoned = errors.New("the cluster is cordoned, no new games can be placed on it")
+
+// ErrNoClusterAvailable is returned if no cluster of the requested region has capacity for another game.
+var ErrNoClusterAvailable = errors.New("no cluster with free capacity is available in the requested region")
+
+// ErrClusterInUse is returned if a cluster which still has games or the default cluster should be removed.
+var ErrClusterInUse = errors.New("the default cluster and clusters with games can not be removed")
+
+// ErrInvalidCluster is returned if the name, the capacity or the kubeconfig of a cluster is invalid.
+var ErrInvalidCluster = errors.New("the name of a cluster must be a DNS label, its capacity must not be negative and clusters other than the default cluster need a kubeconfig")
+
+// ErrContentRejected is returned if the scanner has flagged an uploaded file.
+var ErrContentRejected = errors.New("the file has been rejected by the scanner")
+
+// ErrScannerUnavailable is returned if an uploaded file could not be scanned.
+var ErrScannerUnavailable = errors.New("the file could not be scanned")
+
+// ErrGameNotDeployed is returned if a game which is scanned or quarantined should be changed in kubernetes.
+var ErrGameNotDeployed = errors.New("the game is not deployed, it is scanned or quarantined")
@@ -0,0 +1,50 @@
+package shared
+
+import (
+	"io"
+	"mime/multipart"
+	"sync"
+)
+
+// FormFile reads content into a multipart file, so files which are not uploaded as form, e.g. files of archives,
+// can be stored like uploads. Files which are larger than maxMemory are buffered on disk,
+// so the form has to be removed afterwards.
+func FormFile(fileName string, content io.Reader, maxMemory int64) (*multipart.Form, *multipart.FileHeader, error) {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	var writeErr error
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		writeErr = writeFormFile(form, fileName, content)
+		writer.CloseWithError(writeErr)
+	}()
+
+	parsed, err := multipart.NewReader(reader, form.Boundary()).ReadForm(maxMemory)
+	//Stops the writer if the form could not be read
+	reader.CloseWithError(err)
+	wait.Wait()
+	//The error of the content is returned instead of the error of the reader which it has caused
+	if writeErr != nil {
+		err = writeErr
+	}
+	if err != nil {
+		if parsed != nil {
+			parsed.RemoveAll()
+		}
+		return nil, nil, err
+	}
+	return parsed, parsed.File["file"][0], nil
+}
+
+func writeFormFile(form *multipart.Writer, fileName string, content io.Reader) error {
+	part, err := form.CreateFormFile("file", fileName)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, content); err != nil {
+		return err
+	}
+	return form.Close()
+}
@@ -0,0 +1,26 @@
+package shared
+
+import (
+	"github.com/google/uuid"
+	"strings"
+)
+
+// orgOwnerPrefix marks owners of games which are organizations, other owners are the subjects of users
+const orgOwnerPrefix = "org:"
+
+// OrgOwner returns the owner of the games of an organization
+func OrgOwner(orgID uuid.UUID) string {
+	return orgOwnerPrefix + orgID.String()
+}
+
+// ParseOrgOwner returns the id of the organization and true if the owner is an organization
+func ParseOrgOwner(owner string) (uuid.UUID, bool) {
+	if !strings.HasPrefix(owner, orgOwnerPrefix) {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(strings.TrimPrefix(owner, orgOwnerPrefix))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
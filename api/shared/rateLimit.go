@@ -0,0 +1,72 @@
+package shared
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitPolicy defines a token bucket which holds up to Limit tokens
+// and is refilled completely within Window.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitResult is the state of a bucket after a token has been taken.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	//Time until the bucket is completely refilled
+	Reset time.Duration
+	//Time until the next token is available, only set if the request was not allowed
+	RetryAfter time.Duration
+}
+
+// ParseRateLimitPolicy parses a policy in the format "<limit>/<window>", e.g. "10/1h" or "120/1m".
+func ParseRateLimitPolicy(name string, value string) (RateLimitPolicy, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid rate limit %s, expected <limit>/<window>", value)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit <= 0 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid limit in rate limit %s", value)
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || window <= 0 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid window in rate limit %s", value)
+	}
+	return RateLimitPolicy{Name: name, Limit: limit, Window: window}, nil
+}
+
+// refillRate returns the number of tokens which are added to the bucket per second.
+func (p RateLimitPolicy) refillRate() float64 {
+	return float64(p.Limit) / p.Window.Seconds()
+}
+
+// TakeToken refills a bucket which had the given amount of tokens at lastUpdate and tries to take one token.
+// It returns the new amount of tokens in the bucket and the result.
+func TakeToken(policy RateLimitPolicy, tokens float64, lastUpdate time.Time, now time.Time) (float64, RateLimitResult) {
+	rate := policy.refillRate()
+	elapsed := now.Sub(lastUpdate).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(float64(policy.Limit), tokens+elapsed*rate)
+	}
+
+	result := RateLimitResult{Limit: policy.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = time.Duration((float64(policy.Limit) - tokens) / rate * float64(time.Second))
+	return tokens, result
+}
@@ -0,0 +1,117 @@
+package shared
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// wordPattern matches the words of a text, everything else separates them
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// SearchTerms splits a search query into lowercase words.
+// Characters which are no letters or digits, like the operators of search engines, are removed.
+func SearchTerms(query string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, word := range wordPattern.FindAllString(strings.ToLower(query), -1) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// Highlight HTML escapes the text and wraps every word starting with one of the terms in <mark> tags.
+// If maxLength is greater than 0, only a snippet of about maxLength characters around the first match is returned.
+// Returns an empty string if no word matches.
+func Highlight(text string, terms []string, maxLength int) string {
+	var matches [][]int
+	for _, match := range wordPattern.FindAllStringIndex(text, -1) {
+		word := strings.ToLower(text[match[0]:match[1]])
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				matches = append(matches, match)
+				break
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	start, end := 0, len(text)
+	if maxLength > 0 && utf8.RuneCountInString(text) > maxLength {
+		start = runeOffset(text, matches[0][0], -maxLength/4)
+		end = runeOffset(text, start, maxLength)
+	}
+
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString("…")
+	}
+	position := start
+	for _, match := range matches {
+		if match[0] < start {
+			continue
+		}
+		if match[1] > end {
+			break
+		}
+		builder.WriteString(html.EscapeString(text[position:match[0]]))
+		builder.WriteString("<mark>")
+		builder.WriteString(html.EscapeString(text[match[0]:match[1]]))
+		builder.WriteString("</mark>")
+		position = match[1]
+	}
+	builder.WriteString(html.EscapeString(text[position:end]))
+	if end < len(text) {
+		builder.WriteString("…")
+	}
+	return builder.String()
+}
+
+// NormalizeTags trims the tags, converts them to lowercase and removes empty and duplicate tags.
+// Commas are removed, because they separate the tags in the database.
+func NormalizeTags(tags []string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, ",", " ")))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// runeOffset moves the byte offset by the given number of runes and stays within the text
+func runeOffset(text string, offset int, runes int) int {
+	for ; runes < 0 && offset > 0; runes++ {
+		_, size := utf8.DecodeLastRuneInString(text[:offset])
+		offset -= size
+	}
+	for ; runes > 0 && offset < len(text); runes-- {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// ValidateMetadata returns an error message if the searchable metadata does not fit into the database.
+// Fields which are nil are not validated.
+func ValidateMetadata(description *string, tags *[]string, platform *string) string {
+	if description != nil && len(*description) > 4000 {
+		return "Description must not be longer than 4000 bytes"
+	}
+	if tags != nil && len(strings.Join(*tags, ",")) > 1024 {
+		return "Tags must not be longer than 1024 bytes"
+	}
+	if platform != nil && len(*platform) > 64 {
+		return "Platform must not be longer than 64 bytes"
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package shared
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Sign returns the hex encoded HMAC-SHA256 of the resource and the expiry.
+func Sign(key []byte, resource string, expires time.Time) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(resource + ":" + strconv.FormatInt(expires.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks that the signature has been created with Sign for the resource and is not expired.
+func VerifySignature(key []byte, resource string, expires time.Time, signature string, now time.Time) error {
+	if now.After(expires) {
+		return ErrInvalidSignature
+	}
+	expected, err := hex.DecodeString(Sign(key, resource, expires))
+	if err != nil {
+		return err
+	}
+	actual, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, actual) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
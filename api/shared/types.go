@@ -1,5 +1,7 @@
 package shared
 
+import "strings"
+
 type GameStatus string
 
 const (
@@ -7,4 +9,191 @@ const (
 	Status_Installing GameStatus = "installing"
 	Status_Installed  GameStatus = "installed"
 	Status_Error      GameStatus = "error"
+	//The game has been stopped and its deployments are scaled down
+	Status_Stopping GameStatus = "stopping"
+	Status_Stopped  GameStatus = "stopped"
+	//The game has been started again and its deployments are scaled up
+	Status_Starting GameStatus = "starting"
+	//The game is in the trash and not deployed
+	Status_Deleted GameStatus = "deleted"
+	//The file of the game is scanned, the game is deployed when the scan is clean
+	Status_Scanning GameStatus = "scanning"
+	//The scanner has flagged the file of the game, the game is not deployed
+	Status_Quarantined GameStatus = "quarantined"
+)
+
+type Channel string
+
+const (
+	Channel_Live Channel = "live"
+	Channel_Beta Channel = "beta"
+	//The version is uploaded, but not deployed
+	Channel_None Channel = "none"
+)
+
+func (c Channel) IsValid() bool {
+	return c == Channel_Live || c == Channel_Beta || c == Channel_None
+}
+
+type BlobStatus string
+
+const (
+	Blob_Unverified BlobStatus = "unverified"
+	Blob_Ok         BlobStatus = "ok"
+	//The content of the blob does not match its hash or the blob is missing
+	Blob_Corrupted BlobStatus = "corrupted"
+)
+
+// BundlePrefix is the prefix of the names of blobs which are bundles of several files, the files are stored below the blob name
+const BundlePrefix = "bundles/"
+
+// IsBundle returns true if the blob is a bundle of several files
+func IsBundle(blobName string) bool {
+	return strings.HasPrefix(blobName, BundlePrefix)
+}
+
+type DownloadMethod string
+
+const (
+	//The api streams the file
+	Download_Stream DownloadMethod = "stream"
+	//The client is redirected to a signed url of the storage
+	Download_Redirect DownloadMethod = "redirect"
+	//The file is streamed to a client with a signed url of the api
+	Download_Signed DownloadMethod = "signed"
+)
+
+type Visibility string
+
+const (
+	//Only the owner can see the game
+	Visibility_Private Visibility = "private"
+	//Everybody with the url can play the game
+	Visibility_Public Visibility = "public"
+)
+
+func (v Visibility) IsValid() bool {
+	return v == Visibility_Private || v == Visibility_Public
+}
+
+type BatchOperation string
+
+const (
+	Batch_Delete        BatchOperation = "delete"
+	Batch_Redeploy      BatchOperation = "redeploy"
+	Batch_SetVisibility BatchOperation = "set_visibility"
+	Batch_Stop          BatchOperation = "stop"
+)
+
+type JobStatus string
+
+const (
+	Job_Queued  JobStatus = "queued"
+	Job_Running JobStatus = "running"
+	Job_Done    JobStatus = "done"
 )
+
+type BatchOutcome string
+
+const (
+	Outcome_Succeeded BatchOutcome = "succeeded"
+	Outcome_NotFound  BatchOutcome = "not_found"
+	Outcome_Forbidden BatchOutcome = "forbidden"
+	Outcome_Failed    BatchOutcome = "failed"
+)
+
+// Permission is an action on a game, which has to be allowed for a user
+type Permission string
+
+const (
+	Permission_View   Permission = "view"
+	Permission_Update Permission = "update"
+	//Permission_DeployControl allows to change which versions are deployed and to control the running game
+	Permission_DeployControl Permission = "deploy-control"
+	Permission_Delete        Permission = "delete"
+)
+
+func (p Permission) IsValid() bool {
+	switch p {
+	case Permission_View, Permission_Update, Permission_DeployControl, Permission_Delete:
+		return true
+	}
+	return false
+}
+
+// OrgRole is the role of a member of an organization
+type OrgRole string
+
+const (
+	//Can see the games of the organization
+	Role_Viewer OrgRole = "viewer"
+	//Can upload and update games
+	Role_Developer OrgRole = "developer"
+	//Can delete games and manage the members
+	Role_Admin OrgRole = "admin"
+	//Can do everything, including managing the owners
+	Role_Owner OrgRole = "owner"
+)
+
+// orgRoleRanks orders the roles, every role includes the rights of the roles with a lower rank
+var orgRoleRanks = map[OrgRole]int{
+	Role_Viewer:    1,
+	Role_Developer: 2,
+	Role_Admin:     3,
+	Role_Owner:     4,
+}
+
+func (r OrgRole) IsValid() bool {
+	return orgRoleRanks[r] > 0
+}
+
+// Includes returns true if the role has at least the rights of the other role
+func (r OrgRole) Includes(other OrgRole) bool {
+	return r.IsValid() && orgRoleRanks[r] >= orgRoleRanks[other]
+}
+
+// Allows returns true if members with this role have the permission on the games of the organization
+func (r OrgRole) Allows(permission Permission) bool {
+	switch permission {
+	case Permission_View:
+		return r.Includes(Role_Viewer)
+	case Permission_Update, Permission_DeployControl:
+		return r.Includes(Role_Developer)
+	case Permission_Delete:
+		return r.Includes(Role_Admin)
+	}
+	return false
+}
+
+// ImportMode decides which ids the games of an imported catalog get
+type ImportMode string
+
+const (
+	//The games keep their ids, games whose id is already used are skipped
+	Import_Preserve ImportMode = "preserve"
+	//The games get new ids
+	Import_Remap ImportMode = "remap"
+)
+
+func (m ImportMode) IsValid() bool {
+	return m == Import_Preserve || m == Import_Remap
+}
+
+// InconsistencyKind names a mismatch between the database, the blob storage and kubernetes
+type InconsistencyKind string
+
+const (
+	//A game or its beta channel has no game resource in kubernetes
+	Inconsistency_RowWithoutResource InconsistencyKind = "row_without_resource"
+	//A game resource belongs to no game or to a game in the trash
+	Inconsistency_ResourceWithoutRow InconsistencyKind = "resource_without_row"
+	//A blob is used by no game version
+	Inconsistency_BlobWithoutRow InconsistencyKind = "blob_without_row"
+)
+
+func (k InconsistencyKind) IsValid() bool {
+	return k == Inconsistency_RowWithoutResource || k == Inconsistency_ResourceWithoutRow || k == Inconsistency_BlobWithoutRow
+}
+
+// DefaultCluster is the cluster of the kubernetes provider of the api, games without cluster are deployed on it
+const DefaultCluster = "default"
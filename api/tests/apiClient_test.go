@@ -0,0 +1,338 @@
+package tests
+
+import (
+	apiclient "api/client"
+	"api/dtos"
+	"api/models"
+	"api/router"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Client_Should_Stream_Upload_Of_Reader(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	content := "game content"
+	hash := sha256Hex(content)
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries, the content is new, so it is uploaded to azure
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO blobs")).
+		WithArgs(hash, services.BlobName(hash), "", int64(len(content)), shared.Blob_Unverified, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET StorageLocation=? WHERE Hash = ?")).
+		WithArgs(sqlmock.AnyArg(), hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WillReturnRows(gameRows())
+	dbMock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO games"))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO games")).
+		WithArgs(sqlmock.AnyArg(), "My Game", sqlmock.AnyArg(), shared.Status_Scanning, sqlmock.AnyArg(), owner, "game.nes",
+			services.BlobName(hash), hash, shared.Visibility_Private, "", "retro,arcade", "nes", "", "", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO game_versions")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+	//The clean game is claimed for its deployment and placed on a cluster
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=?, StatusReason=? WHERE ID = ? AND Status = ?")).
+		WithArgs(shared.Status_Installing, "", sqlmock.AnyArg(), shared.Status_Scanning).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPlacement(dbMock, models.ClusterLoad{Cluster: models.Cluster{Name: shared.DefaultCluster}})
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=?, StatusReason=?, Url=?, Cluster=?")).
+		WithArgs(shared.Status_Installing, "", "", shared.DefaultCluster, "default", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{}}
+	server := apiServer(t, db, azure)
+	defer server.Close()
+	c := apiclient.New(server.URL, apiclient.Options{TokenSource: apiclient.StaticToken(owner)})
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	// The reader can only be read once, like a file which is streamed from another request
+	id, err := c.UploadGame(context.Background(), apiclient.Upload{
+		FileName: "game.nes",
+		File:     io.MultiReader(strings.NewReader(content)),
+		Title:    "My Game",
+		Tags:     []string{"Retro", "arcade"},
+		Platform: "nes",
+	})
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.String() == "" || string(azure.Blobs[services.BlobName(hash)]) != content {
+		t.Errorf("Expected the uploaded game, got id %s and blobs %v", id, azure.Blobs)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Client_Should_Retry_Server_Errors_And_Rate_Limits(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	game := mocks.GameMock("A")
+	game.Owner = owner
+	game.Revision = 4
+	// Create database mock, the game is only read by the request which reaches the api
+	db, dbMock := databaseMock()
+	defer db.Close()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	server := apiServer(t, db, mocks.AzureApiMock{Blobs: map[string][]byte{}})
+	defer server.Close()
+	// The first request fails with 503 and the second one is rate limited
+	var attempts, tokens atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch attempts.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			server.Config.Handler.ServeHTTP(w, r)
+		}
+	}))
+	defer flaky.Close()
+	c := apiclient.New(flaky.URL, apiclient.Options{
+		TokenSource: apiclient.TokenSourceFunc(func(ctx context.Context) (string, error) {
+			tokens.Add(1)
+			return owner, nil
+		}),
+		MinBackoff: time.Millisecond,
+	})
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	result, err := c.GetGame(context.Background(), game.ID)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ID != game.ID || result.Title != game.Title || result.ETag != `"4"` {
+		t.Errorf("Unexpected game %+v", result)
+	}
+	// The token is read again for every attempt
+	if attempts.Load() != 3 || tokens.Load() != 3 {
+		t.Errorf("Expected 3 attempts with a token each, got %d attempts and %d tokens", attempts.Load(), tokens.Load())
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Client_Should_Return_Errors_Of_The_Api(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	game := mocks.GameMock("A")
+	game.Owner = owner
+	game.Revision = 3
+	// Create database mock, the update fails because the game has been changed since revision 2
+	db, dbMock := databaseMock()
+	defer db.Close()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(owner))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectPrepare(regexp.QuoteMeta("UPDATE games SET"))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	server := apiServer(t, db, mocks.AzureApiMock{Blobs: map[string][]byte{}})
+	defer server.Close()
+	title := "New Title"
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	_, errUpdate := apiclient.New(server.URL, apiclient.Options{TokenSource: apiclient.StaticToken(owner)}).
+		UpdateGame(context.Background(), game.ID, dtos.UpdateGameRequestBody{Title: &title}, `"2"`)
+	// Requests without a token are rejected
+	_, errList := apiclient.New(server.URL, apiclient.Options{}).ListGames(context.Background(), "")
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if !apiclient.IsStatus(errUpdate, http.StatusPreconditionFailed) {
+		t.Errorf("Expected status %d, got %v", http.StatusPreconditionFailed, errUpdate)
+	}
+	if !apiclient.IsStatus(errList, http.StatusUnauthorized) {
+		t.Errorf("Expected status %d, got %v", http.StatusUnauthorized, errList)
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Client_Should_Only_Retry_Uploads_Of_Seekable_Files(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	content := "game content"
+	// The api rejects every attempt with 429, every attempt must send the whole file
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		file, _, err := r.FormFile("file")
+		if err == nil {
+			received, _ := io.ReadAll(file)
+			if string(received) != content {
+				t.Errorf("Expected the file %q, got %q", content, received)
+			}
+		}
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	c := apiclient.New(server.URL, apiclient.Options{TokenSource: apiclient.StaticToken("MockOwner"), MaxRetries: 2, MinBackoff: time.Millisecond})
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	_, errSeekable := c.UploadGame(context.Background(), apiclient.Upload{FileName: "game.nes", File: bytes.NewReader([]byte(content))})
+	seekableAttempts := attempts.Swap(0)
+	_, errReader := c.UploadGame(context.Background(), apiclient.Upload{FileName: "game.nes", File: io.MultiReader(strings.NewReader(content))})
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if !apiclient.IsStatus(errSeekable, http.StatusTooManyRequests) || !apiclient.IsStatus(errReader, http.StatusTooManyRequests) {
+		t.Errorf("Expected status %d, got %v and %v", http.StatusTooManyRequests, errSeekable, errReader)
+	}
+	if seekableAttempts != 3 || attempts.Load() != 1 {
+		t.Errorf("Expected 3 attempts with a seekable file and 1 with a reader, got %d and %d", seekableAttempts, attempts.Load())
+	}
+}
+
+func Test_Client_Should_Not_Retry_Uploads_Which_Failed_With_Server_Errors(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	// The api may have stored the game before it failed, a retry would create the game twice
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	c := apiclient.New(server.URL, apiclient.Options{TokenSource: apiclient.StaticToken("MockOwner"), MaxRetries: 2, MinBackoff: time.Millisecond})
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	_, errUpload := c.UploadGame(context.Background(), apiclient.Upload{FileName: "game.nes", File: bytes.NewReader([]byte("game content"))})
+	uploadAttempts := attempts.Swap(0)
+	_, errGet := c.GetGame(context.Background(), uuid.New())
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if !apiclient.IsStatus(errUpload, http.StatusInternalServerError) || !apiclient.IsStatus(errGet, http.StatusInternalServerError) {
+		t.Errorf("Expected status %d, got %v and %v", http.StatusInternalServerError, errUpload, errGet)
+	}
+	if uploadAttempts != 1 || attempts.Load() != 3 {
+		t.Errorf("Expected 1 attempt of the upload and 3 of the idempotent request, got %d and %d", uploadAttempts, attempts.Load())
+	}
+}
+
+func Test_Client_Should_List_Clusters_For_Administrators(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	admin := "MockAdmin"
+	t.Setenv("ADMIN_SUBJECTS", admin)
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	expectPlacement(dbMock, clusterLoad("eu-1", "eu", 10, 3, true), clusterLoad(shared.DefaultCluster, "", 0, 1, false))
+	server := apiServer(t, db, mocks.AzureApiMock{Blobs: map[string][]byte{}})
+	defer server.Close()
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	clusters, err := apiclient.New(server.URL, apiclient.Options{TokenSource: apiclient.StaticToken(admin)}).ListClusters(context.Background())
+	_, errUser := apiclient.New(server.URL, apiclient.Options{TokenSource: apiclient.StaticToken("MockOwner")}).ListClusters(context.Background())
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 2 || clusters[0].Name != "eu-1" || clusters[0].Games != 3 || !clusters[0].Cordoned || clusters[1].Games != 1 {
+		t.Errorf("Unexpected clusters %+v", clusters)
+	}
+	if !apiclient.IsStatus(errUser, http.StatusForbidden) {
+		t.Errorf("Expected status %d, got %v", http.StatusForbidden, errUser)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Client_Should_Check_Consistency_For_Administrators(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	admin := "MockAdmin"
+	t.Setenv("ADMIN_SUBJECTS", admin)
+	t.Setenv("CONSISTENCY_REPAIR", "")
+	// Create database mock, the blob "sha256/unused" is not used by any version
+	db, dbMock := databaseMock()
+	defer db.Close()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM clusters ORDER BY Name")).
+		WillReturnRows(clusterRows())
+	expectConsistencyQueries(dbMock)
+	server := apiServer(t, db, mocks.AzureApiMock{Blobs: map[string][]byte{"sha256/used": []byte("a"), "sha256/unused": []byte("b")}})
+	defer server.Close()
+	c := apiclient.New(server.URL, apiclient.Options{TokenSource: apiclient.StaticToken(admin)})
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	report, err := c.CheckConsistency(context.Background())
+	_, errRepair := c.RepairConsistency(context.Background())
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || len(report.Issues) != 1 || report.Issues[0].Kind != shared.Inconsistency_BlobWithoutRow || report.Issues[0].Name != "sha256/unused" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if !apiclient.IsStatus(errRepair, http.StatusConflict) {
+		t.Errorf("Expected status %d, got %v", http.StatusConflict, errRepair)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// tokenAuth uses the bearer token as subject, requests without a token are rejected
+type tokenAuth struct{}
+
+func (tokenAuth) Authorize(c *gin.Context) {
+	subject := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subject == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid token"})
+		return
+	}
+	c.Set("subject", subject)
+}
+
+func (tokenAuth) Verify(ctx context.Context, token string) (*services.Identity, error) {
+	if token == "" {
+		return nil, errors.New("missing token")
+	}
+	return &services.Identity{Subject: token}, nil
+}
+
+// apiServer runs the router of the api with the database mock, the azure mock and a fake k8s cluster
+func apiServer(t *testing.T, db *sql.DB, azure mocks.AzureApiMock) *httptest.Server {
+	t.Setenv("RATE_LIMIT_ENABLED", "false")
+	t.Setenv("BLOB_VERIFY_INTERVAL", "0")
+	t.Setenv("TRASH_RETENTION", "0")
+	gin.SetMode(gin.TestMode)
+	return httptest.NewServer(router.SetupRouter(db, azure, fakeK8sClient(t), tokenAuth{}, router.SetupRateLimiter(db)))
+}
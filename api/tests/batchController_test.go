@@ -0,0 +1,249 @@
+package tests
+
+import (
+	"api/apis"
+	"api/controllers"
+	"api/dtos"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func Test_Batch_Should_Report_Every_Game(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models, the first game belongs to the user, the second to someone else who only shared it for viewing
+	// and the third is not existing
+	own := mocks.GameMock("A")
+	own.Owner = owner
+	foreign := mocks.GameMock("B")
+	missing := uuid.New()
+	// Create fake k8s client
+	fakek8s := mocks.K8sMock(&mock.Mock{})
+	k8sApi := apis.K8sService(fakek8s, apis.NamespaceConfig{})
+	// Define queries, only the game of the user must be updated
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO batch_jobs")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE batch_jobs SET Status=?")).
+		WithArgs(shared.Job_Running, 0, 0, "[]", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID IN (?,?,?) AND DeletedAt IS NULL")).
+		WithArgs(own.ID, foreign.ID, missing).
+		WillReturnRows(gameRows(own, foreign))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Visibility=?, Revision=Revision+1 WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(shared.Visibility_Public, own.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Permissions FROM game_collaborators WHERE GameID = ? AND (Subject = ? OR Email = ?)")).
+		WithArgs(foreign.ID, owner, "").
+		WillReturnRows(sqlmock.NewRows([]string{"Permissions"}).AddRow("view"))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE batch_jobs SET Status=?")).
+		WithArgs(shared.Job_Done, 1, 2, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Finally, create the router
+	r := batchRouter(db, k8sApi, owner)
+	body := fmt.Sprintf(`{"ids": ["%s", "%s", "%s"], "operation": "set_visibility", "visibility": "public"}`, own.ID, foreign.ID, missing)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/games:batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != http.StatusOK {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Fatal(w.Code, string(b))
+	}
+	var job dtos.BatchJobResponseBody
+	err := json.Unmarshal(w.Body.Bytes(), &job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != shared.Job_Done || job.Succeeded != 1 || job.Failed != 2 {
+		t.Errorf("Unexpected job %+v", job)
+	}
+	expected := []shared.BatchOutcome{shared.Outcome_Succeeded, shared.Outcome_Forbidden, shared.Outcome_NotFound}
+	for i, outcome := range expected {
+		if job.Results[i].Outcome != outcome {
+			t.Errorf("Expected outcome %s for game %d, got %s", outcome, i, job.Results[i].Outcome)
+		}
+	}
+	//The visibility does not change the deployment of the game
+	fakek8s.Mock().AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Batch_Should_Only_Accept_The_Batch_Action(t *testing.T) {
+	db, _ := databaseMock()
+	defer db.Close()
+	r := batchRouter(db, nil, "MockOwner")
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/games:purge", strings.NewReader(`{}`))
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func Test_Batch_Stop_Should_Suspend_Games(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	game.Owner = owner
+	game.Status = shared.Status_Installed
+	// Create fake k8s client
+	fakek8s := mocks.K8sMock(&mock.Mock{})
+	k8sApi := apis.K8sService(fakek8s, apis.NamespaceConfig{})
+	fakek8s.Mock().
+		On("Get", mock.AnythingOfType("context.backgroundCtx"), mock.Anything, mock.AnythingOfType("*v1.Game")).
+		Return(nil)
+	fakek8s.Mock().
+		On("Update", mock.AnythingOfType("context.backgroundCtx"), mock.MatchedBy(func(resource *streamv1.Game) bool {
+			return resource.Spec.Suspend
+		})).
+		Return(nil)
+	// Define queries
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO batch_jobs")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE batch_jobs SET Status=?")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID IN (?) AND DeletedAt IS NULL")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=? WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(shared.Status_Stopping, game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE batch_jobs SET Status=?")).
+		WithArgs(shared.Job_Done, 1, 0, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Finally, create the router
+	r := batchRouter(db, k8sApi, owner)
+	body := fmt.Sprintf(`{"ids": ["%s"], "operation": "stop"}`, game.ID)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/games:batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != http.StatusOK {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Fatal(w.Code, string(b))
+	}
+	fakek8s.Mock().AssertNumberOfCalls(t, "Update", 1)
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Batch_Should_Accept_Collaborators_Invited_By_Email(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	subject := "MockCollaborator"
+	email := "collaborator@example.com"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models, the game has been shared with the email of the user
+	game := mocks.GameMock("A")
+	// Define queries
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO batch_jobs")).
+		WithArgs(sqlmock.AnyArg(), subject, shared.Batch_SetVisibility, shared.Job_Queued, 1, 0, 0, "[]", sqlmock.AnyArg(), nil, email).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE batch_jobs SET Status=?")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID IN (?) AND DeletedAt IS NULL")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Permissions FROM game_collaborators WHERE GameID = ? AND (Subject = ? OR Email = ?)")).
+		WithArgs(game.ID, subject, email).
+		WillReturnRows(sqlmock.NewRows([]string{"Permissions"}).AddRow("view,update"))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Visibility=?")).
+		WithArgs(shared.Visibility_Public, game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE batch_jobs SET Status=?")).
+		WithArgs(shared.Job_Done, 1, 0, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Finally, create the router
+	r := batchRouterWithEmail(db, nil, subject, email)
+	body := fmt.Sprintf(`{"ids": ["%s"], "operation": "set_visibility", "visibility": "public"}`, game.ID)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/games:batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != http.StatusOK {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Fatal(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// batchRouter registers the batch routes next to the game routes, like the api does
+func batchRouter(db *sql.DB, k8s apis.IK8sApi, subject string) *gin.Engine {
+	return batchRouterWithEmail(db, k8s, subject, "")
+}
+
+// batchRouterWithEmail creates the router of a user with a verified email
+func batchRouterWithEmail(db *sql.DB, k8s apis.IK8sApi, subject string, email string) *gin.Engine {
+	gamesRepository := repositories.GameRepository(db)
+	blobsService := services.BlobService(repositories.BlobRepository(db), nil, services.ArchiveConfig{})
+	gamesService := services.GameService(gamesRepository, repositories.GameVersionRepository(db), blobsService, k8s, nil)
+	batchService := services.BatchService(gamesService, gamesRepository, repositories.BatchJobRepository(db),
+		services.AccessService(repositories.OrganizationRepository(db), repositories.CollaboratorRepository(db)), services.BatchConfig{Concurrency: 1, SyncLimit: 20, MaxItems: 100})
+	batchController := controllers.BatchController(batchService)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("subject", subject)
+		if email != "" {
+			c.Set("email", email)
+		}
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/games", ok)
+	r.POST("/games", ok)
+	r.POST("/games:action", batchController.BatchGames)
+	r.GET("/games/:id", ok)
+	r.GET("/jobs/:id", batchController.GetJob)
+	return r
+}
@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"api/models"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"mime/multipart"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func Test_Verify_Should_Flag_Corrupted_Blobs(t *testing.T) {
+	db, mock := databaseMock()
+	defer db.Close()
+
+	validHash := sha256Hex("game")
+	//The content of this blob has been changed after the upload
+	hash := sha256Hex("original game")
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{
+		services.BlobName(hash):      []byte("modified game"),
+		services.BlobName(validHash): []byte("game"),
+	}}
+
+	before := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM blobs WHERE VerifiedAt IS NULL OR VerifiedAt < ? ORDER BY VerifiedAt LIMIT ?")).
+		WithArgs(before, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"Hash", "BlobName", "StorageLocation", "Size", "RefCount", "Status", "VerifiedAt", "CreatedAt"}).
+			AddRow(hash, services.BlobName(hash), "", 4, 1, shared.Blob_Unverified, nil, before).
+			AddRow(validHash, services.BlobName(validHash), "", 10, 2, shared.Blob_Unverified, nil, before).
+			AddRow("missing", services.BlobName("missing"), "", 10, 1, shared.Blob_Ok, before.Add(-48*time.Hour), before))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET Status=?, VerifiedAt=? WHERE Hash = ?")).
+		WithArgs(shared.Blob_Corrupted, sqlmock.AnyArg(), hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET Status=?, VerifiedAt=? WHERE Hash = ?")).
+		WithArgs(shared.Blob_Ok, sqlmock.AnyArg(), validHash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET Status=?, VerifiedAt=? WHERE Hash = ?")).
+		WithArgs(shared.Blob_Corrupted, sqlmock.AnyArg(), "missing").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	corrupted, err := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{}).Verify(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrupted != 2 {
+		t.Errorf("Expected 2 corrupted blobs, got %d", corrupted)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Upload_Of_Existing_Content_Should_Not_Upload_Again(t *testing.T) {
+	db, mock := databaseMock()
+	defer db.Close()
+
+	content := "game"
+	hash := sha256Hex(content)
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{}}
+
+	mock.ExpectBegin()
+	//The blob is already existing, so the reference count is increased
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blobs")).
+		WithArgs(hash, services.BlobName(hash), "", int64(len(content)), shared.Blob_Unverified, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM blobs WHERE Hash = ?")).
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows([]string{"Hash", "BlobName", "StorageLocation", "Size", "RefCount", "Status", "VerifiedAt", "CreatedAt"}).
+			AddRow(hash, services.BlobName(hash), "MockStorageLocation", len(content), 2, shared.Blob_Ok, time.Now(), time.Now()))
+	mock.ExpectCommit()
+
+	blob, err := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{}).Store(fileHeader(t, "game.nes", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.RefCount != 2 || blob.StorageLocation != "MockStorageLocation" {
+		t.Errorf("Expected the existing blob, got %+v", blob)
+	}
+	if len(azure.Blobs) != 0 {
+		t.Errorf("The file should not be uploaded again")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_StoreArchive_Should_Store_Disc_Image_As_Bundle(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	db, mock := databaseMock()
+	defer db.Close()
+
+	files := map[string]string{
+		"manifest.json":            `{"entry": "disc/game.cue", "bios": ["scph1001.bin"]}`,
+		"disc/game.cue":            "FILE \"game (Track 1).bin\" BINARY\n  TRACK 01 MODE2/2352\n    INDEX 01 00:00:00\n",
+		"disc/game (Track 1).bin":  "track 1",
+		"scph1001.bin":             "bios",
+		"__MACOSX/disc/._game.cue": "finder metadata",
+		"disc/":                    "",
+	}
+	//The hash of a bundle covers the paths and the hashes of its files, sorted by path
+	hash := sha256Hex(fmt.Sprintf("%s  disc/game (Track 1).bin\n%s  disc/game.cue\n%s  scph1001.bin\n",
+		sha256Hex(files["disc/game (Track 1).bin"]), sha256Hex(files["disc/game.cue"]), sha256Hex(files["scph1001.bin"])))
+	size := int64(len(files["disc/game (Track 1).bin"]) + len(files["disc/game.cue"]) + len(files["scph1001.bin"]))
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blobs")).
+		WithArgs(hash, services.BundleName(hash), "", size, shared.Blob_Unverified, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	for _, path := range []string{"disc/game (Track 1).bin", "disc/game.cue", "scph1001.bin"} {
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blob_files (BlobHash, Path, Hash, Size) VALUES (?,?,?,?)")).
+			WithArgs(hash, path, sha256Hex(files[path]), int64(len(files[path]))).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET StorageLocation=? WHERE Hash = ?")).
+		WithArgs("https://mock.blob.core.windows.net//"+services.BundleName(hash), hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	blob, bundle, err := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{MaxFiles: 10, MaxSize: 1 << 20}).
+		StoreArchive(zipFileHeader(t, "game.zip", files), "")
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.BlobName != services.BundleName(hash) || blob.Hash != hash {
+		t.Errorf("Expected the bundle %s, got %+v", services.BundleName(hash), blob)
+	}
+	if bundle.Entry != "disc/game.cue" || len(bundle.Bios) != 1 || bundle.Bios[0] != "scph1001.bin" {
+		t.Errorf("Expected the entry and the BIOS of the manifest, got %+v", bundle)
+	}
+	if len(azure.Blobs) != 3 || string(azure.Blobs[services.BundleFileName(blob.BlobName, "disc/game (Track 1).bin")]) != "track 1" {
+		t.Errorf("Expected the 3 files of the bundle to be uploaded below the bundle, got %d blobs", len(azure.Blobs))
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_StoreArchive_Should_Reject_Invalid_Archives(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	tests := []struct {
+		name  string
+		files map[string]string
+		entry string
+	}{
+		{name: "missing track", files: map[string]string{"game.cue": "FILE \"game.bin\" BINARY", "readme.txt": "readme"}},
+		{name: "path traversal", files: map[string]string{"../game.nes": "game"}},
+		{name: "ambiguous entry", files: map[string]string{"a.cue": "", "b.cue": ""}},
+		{name: "missing entry", files: map[string]string{"game.nes": "game"}, entry: "other.nes"},
+		{name: "entry extension not unique", files: map[string]string{"a.cue": "", "b.cue": ""}, entry: "a.cue"},
+		{name: "missing BIOS", files: map[string]string{"manifest.json": `{"bios": ["bios.bin"]}`, "game.iso": "game"}},
+		{name: "too many files", files: map[string]string{"1.bin": "", "2.bin": "", "3.bin": "", "game.m3u": "1.bin"}},
+		{name: "too large", files: map[string]string{"game.iso": string(make([]byte, 2048))}},
+	}
+
+	for _, test := range tests {
+		db, mock := databaseMock()
+		azure := mocks.AzureApiMock{Blobs: map[string][]byte{}}
+		blobsService := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{MaxFiles: 3, MaxSize: 1024})
+
+		//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+		_, _, err := blobsService.StoreArchive(zipFileHeader(t, "game.zip", test.files), test.entry)
+
+		//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+		if !errors.Is(err, shared.ErrInvalidArchive) {
+			t.Errorf("Expected %v for %s, got %v", shared.ErrInvalidArchive, test.name, err)
+		}
+		if len(azure.Blobs) != 0 {
+			t.Errorf("Nothing should be uploaded for %s", test.name)
+		}
+		if err = mock.ExpectationsWereMet(); err != nil {
+			t.Errorf(err.Error())
+		}
+		_ = db.Close()
+	}
+}
+
+func Test_Verify_Should_Flag_Bundle_With_Corrupted_File(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	db, mock := databaseMock()
+	defer db.Close()
+
+	files := []models.BundleFile{
+		{Path: "game.bin", Hash: sha256Hex("track"), Size: 5},
+		{Path: "game.cue", Hash: sha256Hex("cue"), Size: 3},
+	}
+	hash := sha256Hex(fmt.Sprintf("%s  game.bin\n%s  game.cue\n", files[0].Hash, files[1].Hash))
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{
+		services.BundleFileName(services.BundleName(hash), "game.cue"): []byte("cue"),
+		services.BundleFileName(services.BundleName(hash), "game.bin"): []byte("modified track"),
+	}}
+
+	before := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM blobs WHERE VerifiedAt IS NULL OR VerifiedAt < ? ORDER BY VerifiedAt LIMIT ?")).
+		WithArgs(before, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"Hash", "BlobName", "StorageLocation", "Size", "RefCount", "Status", "VerifiedAt", "CreatedAt"}).
+			AddRow(hash, services.BundleName(hash), "", 8, 1, shared.Blob_Unverified, nil, before))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT Path, Hash, Size FROM blob_files WHERE BlobHash = ? ORDER BY Path")).
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows([]string{"Path", "Hash", "Size"}).
+			AddRow(files[0].Path, files[0].Hash, files[0].Size).
+			AddRow(files[1].Path, files[1].Hash, files[1].Size))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET Status=?, VerifiedAt=? WHERE Hash = ?")).
+		WithArgs(shared.Blob_Corrupted, sqlmock.AnyArg(), hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	corrupted, err := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{}).Verify(before)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrupted != 1 {
+		t.Errorf("Expected the bundle to be corrupted, got %d corrupted blobs", corrupted)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func sha256Hex(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// fileHeader creates the header of a multipart file upload with the given content
+func fileHeader(t *testing.T, fileName string, content string) *multipart.FileHeader {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write([]byte(content))
+	_ = writer.Close()
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.File["file"][0]
+}
+
+// zipFileHeader creates the header of a multipart upload of a zip archive with the given files, names ending with / are directories
+func zipFileHeader(t *testing.T, fileName string, files map[string]string) *multipart.FileHeader {
+	archive := &bytes.Buffer{}
+	writer := zip.NewWriter(archive)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = file.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return fileHeader(t, fileName, archive.String())
+}
@@ -0,0 +1,336 @@
+package tests
+
+import (
+	"api/apis"
+	"api/models"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	"io"
+	"k8s.io/apimachinery/pkg/types"
+	"regexp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+func Test_Export_Should_Write_Manifest_And_Shared_Files_Once(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	game := mocks.GameMock("A")
+	game.Owner = owner
+	game.LiveVersion = 3
+	// Version 1 and 3 have the same content, version 2 has been uploaded before content addressing
+	first := &models.GameVersion{ID: uuid.New(), GameID: game.ID, Version: 1, BlobName: services.BlobName(sha256Hex("v1")), FileName: "game.nes", Checksum: sha256Hex("v1")}
+	legacy := &models.GameVersion{ID: uuid.New(), GameID: game.ID, Version: 2, BlobName: game.ID.String(), FileName: "game.nes"}
+	third := &models.GameVersion{ID: uuid.New(), GameID: game.ID, Version: 3, BlobName: first.BlobName, FileName: "game.nes", Checksum: first.Checksum, Changelog: "Back to v1"}
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{first.BlobName: []byte("v1"), legacy.BlobName: []byte("v2")}}
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	dbMock.ExpectPrepare(regexp.QuoteMeta("SELECT * FROM games WHERE owner = ? AND DeletedAt IS NULL"))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE owner = ? AND DeletedAt IS NULL")).
+		WithArgs(owner).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM game_versions WHERE GameID = ? ORDER BY Version DESC")).
+		WithArgs(game.ID).
+		WillReturnRows(gameVersionRows(third, legacy, first))
+	service := catalogService(db, azure, nil)
+	var archive bytes.Buffer
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err := service.Export(&archive, owner)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, files := readArchive(t, &archive)
+	if len(manifest.Games) != 1 || manifest.Games[0].ID != game.ID || manifest.Games[0].LiveVersion != 3 {
+		t.Fatalf("Unexpected manifest %+v", manifest)
+	}
+	versions := manifest.Games[0].Versions
+	if len(versions) != 3 || versions[0].Version != 1 || versions[2].Changelog != "Back to v1" {
+		t.Errorf("Expected the versions oldest first, got %+v", versions)
+	}
+	if versions[0].File != versions[2].File || versions[1].File != "blobs/legacy/"+game.ID.String() {
+		t.Errorf("Unexpected files of the versions %+v", versions)
+	}
+	if len(files) != 2 || files[versions[0].File] != "v1" || files[versions[1].File] != "v2" {
+		t.Errorf("Expected each file once, got %v", files)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Import_Should_Remap_Ids_And_Deploy_Live_And_Beta(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	sourceID := uuid.New()
+	archive := catalogArchive(t, models.CatalogManifest{FormatVersion: 1, Games: []models.CatalogGame{{
+		ID: sourceID, Title: "Imported", Owner: "MockOwner", Visibility: shared.Visibility_Public, Tags: []string{"retro"},
+		LiveVersion: 2, BetaVersion: 1,
+		Versions: []models.CatalogVersion{
+			{Version: 1, FileName: "old.nes", Checksum: sha256Hex("v1"), File: "blobs/" + sha256Hex("v1")},
+			{Version: 2, FileName: "new.nes", Checksum: sha256Hex("v2"), File: "blobs/" + sha256Hex("v2")},
+		},
+	}}}, map[string]string{"blobs/" + sha256Hex("v1"): "v1", "blobs/" + sha256Hex("v2"): "v2"})
+	// Create database mock, both files are new
+	db, dbMock := databaseMock()
+	defer db.Close()
+	for _, content := range []string{"v1", "v2"} {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO blobs")).
+			WithArgs(sha256Hex(content), services.BlobName(sha256Hex(content)), "", int64(len(content)), shared.Blob_Unverified, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET StorageLocation=? WHERE Hash = ?")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+	}
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WillReturnRows(gameRows())
+	dbMock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO games"))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO games")).
+		WithArgs(sqlmock.AnyArg(), "Imported", sqlmock.AnyArg(), shared.Status_New, "", "MockOwner", "new.nes",
+			services.BlobName(sha256Hex("v2")), sha256Hex("v2"), shared.Visibility_Public, "", "retro", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for version := 1; version <= 2; version++ {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO game_versions")).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), version, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+	}
+	dbMock.ExpectPrepare(regexp.QuoteMeta("UPDATE games SET"))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET")).
+		WithArgs("Imported", sqlmock.AnyArg(), "new.nes", sqlmock.AnyArg(), sqlmock.AnyArg(), 2, 1, shared.Visibility_Public, "", "retro", "", "", 0,
+			sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=?, StatusReason=?, Url=?, Cluster=?")).
+		WithArgs(shared.Status_New, "", "", "", "default", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{}}
+	k8sClient := fakeK8sClient(t)
+	service := catalogService(db, azure, k8sClient)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	result, err := service.Import(archive, shared.Import_Remap)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 1 || result.Imported[0].SourceID != sourceID || result.Imported[0].ID == sourceID {
+		t.Fatalf("Expected the game with a new id, got %+v", result)
+	}
+	id := result.Imported[0].ID.String()
+	if err = k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: id}, &streamv1.Game{}); err != nil {
+		t.Errorf("The live version should be deployed: %s", err)
+	}
+	if err = k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: id + "-beta"}, &streamv1.Game{}); err != nil {
+		t.Errorf("The beta version should be deployed: %s", err)
+	}
+	if string(azure.Blobs[services.BlobName(sha256Hex("v1"))]) != "v1" || string(azure.Blobs[services.BlobName(sha256Hex("v2"))]) != "v2" {
+		t.Errorf("Expected both files in the storage, got %v", azure.Blobs)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Import_Should_Roll_Back_Game_Whose_Versions_Can_Not_Be_Saved(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	sourceID := uuid.New()
+	hash := sha256Hex("v1")
+	archive := catalogArchive(t, models.CatalogManifest{FormatVersion: 1, Games: []models.CatalogGame{{
+		ID: sourceID, Title: "Imported", Owner: "MockOwner", LiveVersion: 1,
+		Versions: []models.CatalogVersion{{Version: 1, FileName: "game.nes", Checksum: hash, File: "blobs/" + hash}},
+	}}}, map[string]string{"blobs/" + hash: "v1"})
+	blobColumns := []string{"Hash", "BlobName", "StorageLocation", "Size", "RefCount", "Status", "VerifiedAt", "CreatedAt"}
+	// Create database mock, the version can not be saved
+	db, dbMock := databaseMock()
+	defer db.Close()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(sourceID).
+		WillReturnError(sql.ErrNoRows)
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO blobs")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET StorageLocation=? WHERE Hash = ?")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(sourceID).
+		WillReturnRows(gameRows())
+	dbMock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO games"))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO games")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO game_versions")).
+		WillReturnError(errors.New("connection lost"))
+	dbMock.ExpectRollback()
+	// The game is removed and the blob is released
+	dbMock.ExpectExec(regexp.QuoteMeta("DELETE FROM game_versions WHERE GameID = ?")).
+		WithArgs(sourceID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectPrepare(regexp.QuoteMeta("DELETE FROM games WHERE ID = ?"))
+	dbMock.ExpectExec(regexp.QuoteMeta("DELETE FROM games WHERE ID = ?")).
+		WithArgs(sourceID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM blobs WHERE Hash = ? FOR UPDATE")).
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows(blobColumns).AddRow(hash, services.BlobName(hash), "", 2, 1, shared.Blob_Unverified, nil, time.Now()))
+	dbMock.ExpectExec(regexp.QuoteMeta("DELETE FROM blobs WHERE Hash = ?")).
+		WithArgs(hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{}}
+	k8sClient := fakeK8sClient(t)
+	service := catalogService(db, azure, k8sClient)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	result, err := service.Import(archive, shared.Import_Preserve)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 0 || len(result.Failed) != 1 || result.Failed[0].ID != sourceID {
+		t.Errorf("Expected the game to fail, got %+v", result)
+	}
+	if err = k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: sourceID.String()}, &streamv1.Game{}); err == nil {
+		t.Errorf("The game must not be deployed")
+	}
+	if _, ok := azure.Blobs[services.BlobName(hash)]; ok {
+		t.Errorf("The released blob should be deleted")
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Import_Should_Skip_Existing_Ids_And_Report_Missing_Files(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	existing := uuid.New()
+	incomplete := uuid.New()
+	version := []models.CatalogVersion{{Version: 1, FileName: "game.nes", File: "blobs/missing"}}
+	archive := catalogArchive(t, models.CatalogManifest{FormatVersion: 1, Games: []models.CatalogGame{
+		{ID: existing, Title: "Existing", Owner: "MockOwner", LiveVersion: 1, Versions: version},
+		{ID: incomplete, Title: "Incomplete", Owner: "MockOwner", LiveVersion: 1, Versions: version},
+	}}, map[string]string{})
+	// Create database mock, only the first id is used already
+	db, dbMock := databaseMock()
+	defer db.Close()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(existing).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow("MockOwner"))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(incomplete).
+		WillReturnError(sql.ErrNoRows)
+	service := catalogService(db, mocks.AzureApiMock{Blobs: map[string][]byte{}}, nil)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	result, err := service.Import(archive, shared.Import_Preserve)
+	_, errMode := service.Import(archive, "copy")
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 0 || len(result.Skipped) != 1 || result.Skipped[0].ID != existing {
+		t.Errorf("Expected the existing game to be skipped, got %+v", result)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].ID != incomplete || result.Failed[0].Reason == "" {
+		t.Errorf("Expected the incomplete game to fail, got %+v", result)
+	}
+	if !errors.Is(errMode, shared.ErrInvalidImportMode) {
+		t.Errorf("Expected %s, got %v", shared.ErrInvalidImportMode, errMode)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func catalogService(db *sql.DB, azure mocks.AzureApiMock, k8s client.Client) services.ICatalogService {
+	var k8sApi apis.IK8sApi
+	if k8s != nil {
+		k8sApi = apis.K8sService(k8s, apis.NamespaceConfig{})
+	}
+	blobsService := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{})
+	return services.CatalogService(repositories.GameRepository(db), repositories.GameVersionRepository(db), blobsService, azure, k8sApi, services.ScanService(apis.NoopScanner(), azure, repositories.BlobRepository(db)))
+}
+
+// catalogArchive writes an archive with the manifest and the files
+func catalogArchive(t *testing.T, manifest models.CatalogManifest, files map[string]string) *bytes.Reader {
+	var archive bytes.Buffer
+	compressed := gzip.NewWriter(&archive)
+	writer := tar.NewWriter(compressed)
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []struct{ name, content string }{{"manifest.json", string(content)}}
+	for name, file := range files {
+		entries = append(entries, struct{ name, content string }{name, file})
+	}
+	for _, entry := range entries {
+		err = writer.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), ModTime: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = writer.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(archive.Bytes())
+}
+
+// readArchive returns the manifest and the other files of an archive
+func readArchive(t *testing.T, archive io.Reader) (models.CatalogManifest, map[string]string) {
+	uncompressed, err := gzip.NewReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := tar.NewReader(uncompressed)
+	var manifest models.CatalogManifest
+	files := map[string]string{}
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return manifest, files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name == "manifest.json" {
+			if err = json.Unmarshal(content, &manifest); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		files[header.Name] = string(content)
+	}
+}
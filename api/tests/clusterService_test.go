@@ -0,0 +1,287 @@
+package tests
+
+import (
+	"api/apis"
+	"api/controllers"
+	"api/models"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Place_Should_Choose_Least_Loaded_Cluster_With_Capacity(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	game := mocks.GameMock("A")
+	// Create database mock, the full and the cordoned cluster are skipped
+	db, dbMock := databaseMock()
+	defer db.Close()
+	expectPlacement(dbMock,
+		clusterLoad("a-full", "eu", 2, 2, false),
+		clusterLoad("b", "us", 0, 3, false),
+		clusterLoad("c-cordoned", "eu", 0, 0, true),
+		clusterLoad(shared.DefaultCluster, "", 0, 5, false))
+	service := clusterService(db, fakeK8sClient(t), nil)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err := service.Place(game)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Cluster != "b" {
+		t.Errorf("Expected cluster b, got %s", game.Cluster)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Place_Should_Only_Choose_Clusters_Of_The_Region(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	game := mocks.GameMock("A")
+	game.Region = "eu"
+	full := mocks.GameMock("B")
+	full.Region = "us"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	loads := []models.ClusterLoad{
+		clusterLoad("eu-1", "eu", 0, 7, false),
+		clusterLoad("eu-2", "eu", 10, 4, false),
+		clusterLoad("us-1", "us", 1, 1, false),
+		clusterLoad(shared.DefaultCluster, "", 0, 0, false),
+	}
+	expectPlacement(dbMock, loads...)
+	expectPlacement(dbMock, loads...)
+	service := clusterService(db, fakeK8sClient(t), nil)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err := service.Place(game)
+	errFull := service.Place(full)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Cluster != "eu-2" {
+		t.Errorf("Expected cluster eu-2, got %s", game.Cluster)
+	}
+	if !errors.Is(errFull, shared.ErrNoClusterAvailable) || full.Cluster != "" {
+		t.Errorf("Expected %v, got %v on cluster %s", shared.ErrNoClusterAvailable, errFull, full.Cluster)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Cluster_K8s_Service_Should_Route_Games_To_Their_Cluster(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	game := mocks.GameMock("A")
+	game.Region = "eu"
+	pinned := mocks.GameMock("B")
+	pinned.Cluster = "removed"
+	primary := fakeK8sClient(t)
+	secondary := fakeK8sClient(t)
+	eu := clusterLoad("eu-1", "eu", 0, 0, false)
+	// Create database mock, the cluster is read once when its client is created and the clusters are listed with the resources
+	db, dbMock := databaseMock()
+	defer db.Close()
+	expectPlacement(dbMock, eu, clusterLoad(shared.DefaultCluster, "", 0, 0, false))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM clusters WHERE Name = ?")).
+		WithArgs("eu-1").
+		WillReturnRows(clusterRows(eu.Cluster))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM clusters ORDER BY Name")).
+		WillReturnRows(clusterRows(eu.Cluster, models.Cluster{Name: shared.DefaultCluster}))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM clusters WHERE Name = ?")).
+		WithArgs("removed").
+		WillReturnRows(clusterRows())
+	connected := []string{}
+	k8s := apis.ClusterK8sService(clusterService(db, primary, func(cluster *models.Cluster) (client.Client, error) {
+		connected = append(connected, cluster.KubeconfigPath)
+		return secondary, nil
+	}))
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err := k8s.DeployGame(game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource := streamv1.Game{}
+	key := types.NamespacedName{Namespace: "default", Name: game.ID.String()}
+	err = secondary.Get(context.Background(), key, &resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource.Status.URL = "eu.example.com"
+	err = secondary.Status().Update(context.Background(), &resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, errUrl := k8s.ReadGameUrl(game)
+	resources, errList := k8s.ListGameResources()
+	errDelete := k8s.DeleteGame(game)
+	errPinned := k8s.DeployGame(pinned)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if game.Cluster != "eu-1" {
+		t.Errorf("Expected cluster eu-1, got %s", game.Cluster)
+	}
+	if len(connected) != 1 || connected[0] != "/kubeconfigs/eu-1" {
+		t.Errorf("Expected one connection with the kubeconfig of eu-1, got %v", connected)
+	}
+	if err = primary.Get(context.Background(), key, &streamv1.Game{}); err == nil {
+		t.Errorf("Expected no resource on the default cluster")
+	}
+	if errUrl != nil || url != "eu.example.com" {
+		t.Errorf("Expected the url of the eu cluster, got %s %v", url, errUrl)
+	}
+	if errList != nil || len(resources) != 1 || resources[0].Cluster != "eu-1" || resources[0].Name != game.ID.String() {
+		t.Errorf("Expected the resource on eu-1, got %+v %v", resources, errList)
+	}
+	if errDelete != nil {
+		t.Errorf("Expected the resource to be deleted, got %v", errDelete)
+	} else if err = secondary.Get(context.Background(), key, &streamv1.Game{}); err == nil {
+		t.Errorf("Expected the resource to be deleted on eu-1")
+	}
+	if !errors.Is(errPinned, shared.ErrClusterNotFound) {
+		t.Errorf("Expected %v, got %v", shared.ErrClusterNotFound, errPinned)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Delete_Cluster_Should_Refuse_Clusters_With_Games(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	eu := clusterLoad("eu-1", "eu", 0, 0, false)
+	// Create database mock, a game in the trash still belongs to the cluster
+	db, dbMock := databaseMock()
+	defer db.Close()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM clusters WHERE Name = ?")).
+		WithArgs("eu-1").
+		WillReturnRows(clusterRows(eu.Cluster))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM games WHERE Cluster = ?")).
+		WithArgs("eu-1").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+	service := clusterService(db, fakeK8sClient(t), nil)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err := service.Delete("eu-1")
+	errDefault := service.Delete(shared.DefaultCluster)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if !errors.Is(err, shared.ErrClusterInUse) || !errors.Is(errDefault, shared.ErrClusterInUse) {
+		t.Errorf("Expected %v, got %v and %v", shared.ErrClusterInUse, err, errDefault)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Upload_Should_Only_Pin_Games_To_Registered_Clusters_Which_Are_Not_Cordoned(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	admin := "MockAdmin"
+	cordoned := clusterLoad("eu-1", "eu", 0, 0, true)
+	// Create database mock, no game must be saved
+	db, dbMock := databaseMock()
+	defer db.Close()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM clusters WHERE Name = ?")).
+		WithArgs("eu-1").
+		WillReturnRows(clusterRows(cordoned.Cluster))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM clusters WHERE Name = ?")).
+		WithArgs("removed").
+		WillReturnRows(clusterRows())
+	gamesService := services.GameService(repositories.GameRepository(db), repositories.GameVersionRepository(db), nil, nil, nil)
+	gamesController := controllers.GameController(gamesService, services.AccessService(repositories.OrganizationRepository(db), repositories.CollaboratorRepository(db)),
+		clusterService(db, fakeK8sClient(t), nil), []string{admin})
+	gin.SetMode(gin.TestMode)
+	upload := func(subject string, cluster string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("subject", subject)
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		_ = writer.WriteField("title", "Pinned Game")
+		_ = writer.WriteField("cluster", cluster)
+		part, _ := writer.CreateFormFile("file", "game.nes")
+		_, _ = part.Write([]byte("rom"))
+		_ = writer.Close()
+		c.Request = httptest.NewRequest(http.MethodPost, "/games", body)
+		c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+		gamesController.UploadGame(c)
+		return w.Code
+	}
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	notAdmin := upload("MockOwner", "eu-1")
+	pinnedToCordoned := upload(admin, "eu-1")
+	pinnedToRemoved := upload(admin, "removed")
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if notAdmin != http.StatusForbidden {
+		t.Errorf("Expected status %d for a user, got %d", http.StatusForbidden, notAdmin)
+	}
+	if pinnedToCordoned != http.StatusConflict {
+		t.Errorf("Expected status %d for a cordoned cluster, got %d", http.StatusConflict, pinnedToCordoned)
+	}
+	if pinnedToRemoved != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown cluster, got %d", http.StatusNotFound, pinnedToRemoved)
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// expectPlacement expects the queries of a placement, which reads the clusters and the number of their games
+func expectPlacement(dbMock sqlmock.Sqlmock, loads ...models.ClusterLoad) {
+	clusters := make([]models.Cluster, len(loads))
+	counts := sqlmock.NewRows([]string{"Cluster", "COUNT(*)"})
+	for i, load := range loads {
+		clusters[i] = load.Cluster
+		counts.AddRow(load.Name, load.Games)
+	}
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM clusters ORDER BY Name")).
+		WillReturnRows(clusterRows(clusters...))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Cluster, COUNT(*) FROM games WHERE DeletedAt IS NULL GROUP BY Cluster")).
+		WillReturnRows(counts)
+}
+
+func clusterLoad(name string, region string, capacity int, games int, cordoned bool) models.ClusterLoad {
+	return models.ClusterLoad{
+		Cluster: models.Cluster{Name: name, Region: region, Capacity: capacity, Cordoned: cordoned,
+			KubeconfigPath: "/kubeconfigs/" + name, CreatedAt: time.Now()},
+		Games: games,
+	}
+}
+
+func clusterRows(clusters ...models.Cluster) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"Name", "Region", "Capacity", "Labels", "KubeconfigPath", "KubeContext", "Cordoned", "CreatedAt"})
+	for _, cluster := range clusters {
+		rows.AddRow(cluster.Name, cluster.Region, cluster.Capacity, strings.Join(cluster.Labels, ","), cluster.KubeconfigPath,
+			cluster.KubeContext, cluster.Cordoned, cluster.CreatedAt)
+	}
+	return rows
+}
+
+func clusterService(db *sql.DB, primary client.Client, connect func(cluster *models.Cluster) (client.Client, error)) services.IClusterService {
+	return services.ClusterService(repositories.ClusterRepository(db), repositories.GameRepository(db), primary,
+		apis.NamespaceConfig{}, connect)
+}
@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"api/controllers"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"bytes"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Collaborator_Invited_By_Email_Should_Read_Private_Game(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	collaborator := "MockCollaborator"
+	email := "tester@example.com"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	game.Visibility = shared.Visibility_Private
+	// Define queries
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Permissions FROM game_collaborators WHERE GameID = ? AND (Subject = ? OR Email = ?)")).
+		WithArgs(game.ID, collaborator, email).
+		WillReturnRows(sqlmock.NewRows([]string{"Permissions"}).AddRow("update,deploy-control"))
+
+	// Finally, create gameController
+	gameController := gameController(db, nil, nil)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", collaborator)
+	c.Set("email", email)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	gameController.GetGameById(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 200 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Collaborator_Without_Delete_Permission_Should_Not_Delete_Game(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	collaborator := "MockCollaborator"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries, the game must not be deleted
+	gameID := uuid.New()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(gameID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow("MockOwner"))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Permissions FROM game_collaborators WHERE GameID = ? AND (Subject = ? OR Email = ?)")).
+		WithArgs(gameID, collaborator, "").
+		WillReturnRows(sqlmock.NewRows([]string{"Permissions"}).AddRow("view,update"))
+
+	// Finally, create gameController
+	gameController := gameController(db, nil, nil)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", collaborator)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: gameID.String()}}
+	gameController.DeleteGameById(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 403 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Grant_Collaborator_Should_Save_Permissions(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	email := "tester@example.com"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries
+	gameID := uuid.New()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(gameID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(owner))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO game_collaborators")).
+		WithArgs(sqlmock.AnyArg(), gameID, nil, email, "view,deploy-control", owner, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM game_collaborators WHERE GameID = ? AND Email = ?")).
+		WithArgs(gameID, email).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "GameID", "Subject", "Email", "Permissions", "GrantedBy", "GrantedAt"}).
+			AddRow(uuid.New(), gameID, nil, email, "view,deploy-control", owner, time.Now()))
+
+	// Finally, create the controller
+	collaboratorController := collaboratorController(db)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: gameID.String()}}
+	c.Request = httptest.NewRequest("POST", "/games/"+gameID.String()+"/collaborators",
+		strings.NewReader(`{"email": "Tester@Example.com", "permissions": ["view", "deploy-control", "view"]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	collaboratorController.GrantCollaborator(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 200 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if !strings.Contains(w.Body.String(), `"deploy-control"`) {
+		t.Errorf("Response should contain the permissions: %s", w.Body.String())
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Collaborator_Should_Not_Share_Game(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	collaborator := "MockCollaborator"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries, only the owner can share the game, so no grant must be saved
+	gameID := uuid.New()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(gameID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow("MockOwner"))
+
+	// Finally, create the controller
+	collaboratorController := collaboratorController(db)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", collaborator)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: gameID.String()}}
+	c.Request = httptest.NewRequest("POST", "/games/"+gameID.String()+"/collaborators",
+		strings.NewReader(`{"subject": "SomeoneElse", "permissions": ["delete"]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	collaboratorController.GrantCollaborator(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 403 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Grant_Collaborator_With_Subject_And_Email_Should_Fail(t *testing.T) {
+	// Create database mock, no query is expected
+	db, dbMock := databaseMock()
+	defer db.Close()
+	collaboratorController := collaboratorController(db)
+	gameID := uuid.New()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", "MockOwner")
+
+	c.Params = gin.Params{gin.Param{Key: "id", Value: gameID.String()}}
+	c.Request = httptest.NewRequest("POST", "/games/"+gameID.String()+"/collaborators",
+		strings.NewReader(`{"subject": "SomeoneElse", "email": "tester@example.com", "permissions": ["view"]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	collaboratorController.GrantCollaborator(c)
+
+	if w.Code != 400 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func collaboratorController(db *sql.DB) controllers.ICollaboratorController {
+	gamesService := services.GameService(repositories.GameRepository(db), repositories.GameVersionRepository(db),
+		services.BlobService(repositories.BlobRepository(db), nil, services.ArchiveConfig{}), nil, nil)
+	access := services.AccessService(repositories.OrganizationRepository(db), repositories.CollaboratorRepository(db))
+	return controllers.CollaboratorController(services.CollaboratorService(gamesService, repositories.CollaboratorRepository(db), access))
+}
+
+func Test_Collaborator_Without_DeployControl_Permission_Should_Not_Deploy_Version(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	collaborator := "MockCollaborator"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries, the collaborator may upload versions, but the version must not be deployed
+	gameID := uuid.New()
+	expectCollaborator := func() {
+		dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Permissions FROM game_collaborators WHERE GameID = ? AND (Subject = ? OR Email = ?)")).
+			WithArgs(gameID, collaborator, "").
+			WillReturnRows(sqlmock.NewRows([]string{"Permissions"}).AddRow("view,update"))
+	}
+	expectOwner := func() {
+		dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+			WithArgs(gameID).
+			WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow("MockOwner"))
+	}
+	//Upload to the live channel
+	expectOwner()
+	expectCollaborator()
+	expectOwner()
+	expectCollaborator()
+	//Replace the rom
+	expectOwner()
+	expectCollaborator()
+	expectCollaborator()
+
+	// Finally, create the controller
+	versionController := gameVersionController(db, nil, nil, services.RomDownloadConfig{})
+	gin.SetMode(gin.TestMode)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	uploadW := httptest.NewRecorder()
+	uploadC, _ := gin.CreateTestContext(uploadW)
+	uploadC.Set("subject", collaborator)
+	uploadC.Params = gin.Params{gin.Param{Key: "id", Value: gameID.String()}}
+	uploadC.Request = versionUploadRequest(t, "live")
+	versionController.UploadVersion(uploadC)
+
+	replaceW := httptest.NewRecorder()
+	replaceC, _ := gin.CreateTestContext(replaceW)
+	replaceC.Set("subject", collaborator)
+	replaceC.Params = gin.Params{gin.Param{Key: "id", Value: gameID.String()}}
+	replaceC.Request = versionUploadRequest(t, "")
+	versionController.ReplaceRom(replaceC)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if uploadW.Code != 403 {
+		b, _ := ioutil.ReadAll(uploadW.Body)
+		t.Error(uploadW.Code, string(b))
+	}
+	if replaceW.Code != 403 {
+		b, _ := ioutil.ReadAll(replaceW.Body)
+		t.Error(replaceW.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// versionUploadRequest creates the multipart request of a version upload to the given channel
+func versionUploadRequest(t *testing.T, channel string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "game.nes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write([]byte("rom"))
+	if channel != "" {
+		_ = writer.WriteField("channel", channel)
+	}
+	_ = writer.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/games/versions", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"api/apis"
+	"api/models"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"regexp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+func Test_Consistency_Check_Should_Report_All_Kinds_Without_Repairing(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	game := mocks.GameMock("A")
+	game.BetaVersion = 2
+	orphan := uuid.New().String()
+	k8s := fakeK8sClient(t)
+	// The live channel of the game is deployed, its beta channel is missing.
+	// The orphaned resource belongs to no game and the resource "other" has not been created by the api.
+	createGameResources(t, k8s, game.ID.String(), orphan, "other")
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{"sha256/used": []byte("a"), "sha256/unused": []byte("b")}}
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	expectConsistencyQueries(dbMock, game)
+	service := consistencyService(db, azure, k8s, shared.Inconsistency_ResourceWithoutRow)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	report, err := service.Check(false)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || len(report.Issues) != 3 {
+		t.Fatalf("Expected a dry run with 3 issues, got %+v", report)
+	}
+	expected := []models.ConsistencyIssue{
+		{Kind: shared.Inconsistency_RowWithoutResource, GameID: game.ID.String(), Channel: shared.Channel_Beta, Name: game.ID.String() + "-beta", Repair: "deploy"},
+		{Kind: shared.Inconsistency_ResourceWithoutRow, GameID: orphan, Channel: shared.Channel_Live, Namespace: "default", Name: orphan, Repair: "delete"},
+		{Kind: shared.Inconsistency_BlobWithoutRow, Name: "sha256/unused", Repair: "delete"},
+	}
+	for i, issue := range report.Issues {
+		if issue != expected[i] {
+			t.Errorf("Expected issue %+v, got %+v", expected[i], issue)
+		}
+	}
+	if err = k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: orphan}, &streamv1.Game{}); err != nil {
+		t.Errorf("Expected the orphaned resource to be kept, got %v", err)
+	}
+	if _, ok := azure.Blobs["sha256/unused"]; !ok {
+		t.Errorf("Expected the unused blob to be kept")
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Consistency_Check_Should_Repair_Enabled_Kinds(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	game := mocks.GameMock("A")
+	game.BetaVersion = 2
+	beta := &models.GameVersion{ID: uuid.New(), GameID: game.ID, Version: 2, BlobName: "sha256/used", FileName: "game.nes"}
+	orphan := uuid.New().String()
+	k8s := fakeK8sClient(t)
+	createGameResources(t, k8s, game.ID.String(), orphan)
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{"sha256/used": []byte("a"), "sha256/unused": []byte("b")}}
+	// Create database mock, the game is read again before its beta channel is deployed
+	db, dbMock := databaseMock()
+	defer db.Close()
+	expectConsistencyQueries(dbMock, game)
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM game_versions WHERE GameID = ? AND Version = ?")).
+		WithArgs(game.ID, 2).
+		WillReturnRows(gameVersionRows(beta))
+	// Orphaned blobs are only reported
+	service := consistencyService(db, azure, k8s, shared.Inconsistency_RowWithoutResource, shared.Inconsistency_ResourceWithoutRow)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	report, err := service.Check(true)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.DryRun || len(report.Issues) != 3 {
+		t.Fatalf("Expected 3 issues, got %+v", report)
+	}
+	for _, issue := range report.Issues {
+		repaired := issue.Kind != shared.Inconsistency_BlobWithoutRow
+		if issue.Repaired != repaired || issue.Error != "" {
+			t.Errorf("Expected issue %+v to be repaired: %t", issue, repaired)
+		}
+	}
+	resource := streamv1.Game{}
+	if err = k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: game.ID.String() + "-beta"}, &resource); err != nil {
+		t.Errorf("Expected the beta channel to be deployed, got %v", err)
+	} else if resource.Spec.StoragePath != beta.BlobName {
+		t.Errorf("Expected the beta channel to serve %s, got %s", beta.BlobName, resource.Spec.StoragePath)
+	}
+	if err = k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: orphan}, &streamv1.Game{}); err == nil {
+		t.Errorf("Expected the orphaned resource to be deleted")
+	}
+	if _, ok := azure.Blobs["sha256/unused"]; !ok {
+		t.Errorf("Expected the unused blob to be kept")
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Consistency_Check_Should_Reject_Repairs_If_Disabled(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	db, dbMock := databaseMock()
+	defer db.Close()
+	service := consistencyService(db, mocks.AzureApiMock{Blobs: map[string][]byte{}}, fakeK8sClient(t))
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	_, err := service.Check(true)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if !errors.Is(err, shared.ErrRepairDisabled) {
+		t.Errorf("Expected %v, got %v", shared.ErrRepairDisabled, err)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// expectConsistencyQueries expects the queries of a check, the game uses the blob "sha256/used"
+func expectConsistencyQueries(dbMock sqlmock.Sqlmock, games ...*models.Game) {
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT BlobName FROM game_versions")).
+		WillReturnRows(sqlmock.NewRows([]string{"BlobName"}).AddRow("sha256/used"))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT BlobName FROM blobs")).
+		WillReturnRows(sqlmock.NewRows([]string{"BlobName"}).AddRow("sha256/used"))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE DeletedAt IS NULL")).
+		WillReturnRows(gameRows(games...))
+}
+
+// createGameResources creates game resources in the default namespace, which have been created long ago
+func createGameResources(t *testing.T, k8s client.Client, names ...string) {
+	for _, name := range names {
+		err := k8s.Create(context.Background(), &streamv1.Game{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-24 * time.Hour)),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func consistencyService(db *sql.DB, azure mocks.AzureApiMock, k8s client.Client, repair ...shared.InconsistencyKind) services.IConsistencyService {
+	config := services.ConsistencyConfig{GracePeriod: time.Hour, Repair: map[shared.InconsistencyKind]bool{}}
+	for _, kind := range repair {
+		config.Repair[kind] = true
+	}
+	return services.ConsistencyService(repositories.GameRepository(db), repositories.GameVersionRepository(db), repositories.BlobRepository(db),
+		azure, apis.K8sService(k8s, apis.NamespaceConfig{}), config)
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"api/apis"
+	"api/dev"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"context"
+	"errors"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	"io"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func Test_FileStorage_Should_Store_Read_List_And_Delete_Blobs(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	storage := apis.FileStorageApi(t.TempDir())
+	blobName := "sha256/" + sha256Hex("game content")
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	location, errUpload := storage.UploadGame("games", blobName, fileHeader(t, "game.nes", "game content"))
+	content, errRange := storage.DownloadGameRange("games", blobName, 5, 3)
+	blobs, errList := storage.ListGames("games")
+	errDelete := storage.DeleteGame("games", blobName)
+	_, errMissing := storage.GameProperties("games", blobName)
+	_, errTraversal := storage.DownloadGame("games", "../outside")
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if errUpload != nil || location == "" {
+		t.Fatalf("Expected the blob to be stored, got %q and %v", location, errUpload)
+	}
+	if errRange != nil {
+		t.Fatal(errRange)
+	}
+	part, _ := io.ReadAll(content)
+	content.Close()
+	if string(part) != "con" {
+		t.Errorf("Expected the range %q, got %q", "con", part)
+	}
+	if errList != nil || len(blobs) != 1 || blobs[0].Name != blobName || blobs[0].Size != 12 {
+		t.Errorf("Expected the blob to be listed, got %+v and %v", blobs, errList)
+	}
+	if errDelete != nil {
+		t.Errorf("Expected the blob to be deleted, got %v", errDelete)
+	}
+	if errMissing == nil || errMissing.Error() != "BlobNotFound: "+blobName {
+		t.Errorf("Expected a missing blob to be reported like azure, got %v", errMissing)
+	}
+	if errTraversal == nil {
+		t.Errorf("Expected blob names outside of the container to be rejected")
+	}
+	if _, err := storage.SignedGameUrl("games", blobName, "game.nes", time.Now()); !errors.Is(err, shared.ErrSignedUrlNotSupported) {
+		t.Errorf("Expected %v, got %v", shared.ErrSignedUrlNotSupported, err)
+	}
+}
+
+func Test_Dev_Cluster_Should_Report_Url_And_Phases_Of_Games(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := streamv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	k8s := apis.K8sService(dev.Cluster(scheme, dev.Config{GameUrl: "http://%s.localhost"}), apis.NamespaceConfig{})
+	game := mocks.GameMock("A")
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	if err := k8s.DeployGame(game); err != nil {
+		t.Fatal(err)
+	}
+	url := waitFor(t, func() (string, error) { return k8s.ReadGameUrl(game) }, func(url string) bool { return url != "" })
+	if err := k8s.SuspendGame(game, true); err != nil {
+		t.Fatal(err)
+	}
+	phase := waitFor(t, func() (string, error) { return k8s.ReadGamePhase(game) }, func(phase string) bool { return phase == streamv1.GamePhaseStopped })
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if url != "http://"+game.ID.String()+".localhost" {
+		t.Errorf("Unexpected url %s", url)
+	}
+	if phase != streamv1.GamePhaseStopped {
+		t.Errorf("Expected the phase %s, got %s", streamv1.GamePhaseStopped, phase)
+	}
+}
+
+func Test_Dev_Mode_Should_Deploy_The_Games_Of_The_Database_On_Start(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	t.Setenv("CONSISTENCY_CHECK_ON_START", "")
+	t.Setenv("CONSISTENCY_REPAIR", string(shared.Inconsistency_BlobWithoutRow))
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := streamv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	k8s := dev.Cluster(scheme, dev.Config{GameUrl: "http://%s.localhost"})
+	game := mocks.GameMock("A")
+	// Create database mock, the game is read again before it is deployed
+	db, dbMock := databaseMock()
+	defer db.Close()
+	expectConsistencyQueries(dbMock, game)
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	dev.RestoreClusterOnStart()
+	config := services.ConsistencyConfigFromEnv()
+	service := services.ConsistencyService(repositories.GameRepository(db), repositories.GameVersionRepository(db), repositories.BlobRepository(db),
+		mocks.AzureApiMock{Blobs: map[string][]byte{}}, apis.K8sService(k8s, apis.NamespaceConfig{}), config)
+	services.CheckConsistency(service)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if !config.CheckOnStart || !config.Repair[shared.Inconsistency_RowWithoutResource] || !config.Repair[shared.Inconsistency_BlobWithoutRow] {
+		t.Errorf("Expected the consistency to be repaired on start in addition to the configured repairs, got %+v", config)
+	}
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: game.ID.String()}, &streamv1.Game{}); err != nil {
+		t.Errorf("Expected the game to be deployed again, got %v", err)
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// waitFor reads a value until it is accepted or 10 seconds have passed
+func waitFor(t *testing.T, read func() (string, error), accept func(string) bool) string {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		value, err := read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accept(value) || time.Now().After(deadline) {
+			return value
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
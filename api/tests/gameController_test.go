@@ -21,7 +21,9 @@ import (
 	"log"
 	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_Read_By_Id_And_Refresh_Should_Succeed(t *testing.T) {
@@ -36,7 +38,7 @@ func Test_Read_By_Id_And_Refresh_Should_Succeed(t *testing.T) {
 	game.Status = shared.Status_Installing
 	// Create fake k8s client
 	fakek8s := mocks.K8sMock(&mock.Mock{})
-	k8sApi := apis.K8sService(fakek8s)
+	k8sApi := apis.K8sService(fakek8s, apis.NamespaceConfig{})
 	//Mock the calls to k8s
 	fakek8s.Mock().
 		On("Get",
@@ -55,22 +57,13 @@ func Test_Read_By_Id_And_Refresh_Should_Succeed(t *testing.T) {
 	// Define queries
 	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
 		WithArgs(game.ID).WillReturnRows(
-		sqlmock.NewRows([]string{"Id", "Title", "StorageLocation", "Status", "Url", "Owner", "FileName"}).
-			AddRow(game.ID, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName),
+		gameRows(game),
 	)
 
-	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
-		WithArgs(game.ID).WillReturnRows(
-		sqlmock.NewRows([]string{"Id", "Title", "StorageLocation", "Status", "Url", "Owner", "FileName"}).
-			AddRow(game.ID, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName),
-	)
-
-	dbMock.ExpectPrepare(regexp.
-		QuoteMeta("UPDATE games SET Title=?, StorageLocation=?, Status=?, Url=?, FileName=? WHERE ID = ?"))
-
+	//Only the deployment is saved, the metadata may have been changed in the meantime
 	dbMock.ExpectExec(regexp.
-		QuoteMeta("UPDATE games SET Title=?, StorageLocation=?, Status=?, Url=?, FileName=? WHERE ID = ?")).
-		WithArgs(game.Title, game.StorageLocation, shared.Status_Installed, url, game.FileName, game.ID).
+		QuoteMeta("UPDATE games SET Status=?, StatusReason=?, Url=?, Cluster=?, Namespace=? WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(shared.Status_Installed, game.StatusReason, url, game.Cluster, game.Namespace, game.ID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Finally, create gameController
@@ -122,8 +115,7 @@ func Test_Read_By_Id_Should_Succeed(t *testing.T) {
 	// Define queries
 	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
 		WithArgs(game.ID).WillReturnRows(
-		sqlmock.NewRows([]string{"Id", "Title", "StorageLocation", "Status", "Url", "Owner", "FileName"}).
-			AddRow(game.ID, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName),
+		gameRows(game),
 	)
 
 	// Finally, create gameController
@@ -171,15 +163,20 @@ func Test_Read_All_Should_Succeed(t *testing.T) {
 	gameA.Owner = owner
 	gameB := mocks.GameMock("B")
 	gameB.Owner = owner
-	// Define queries
+	// Define queries, the user is no member of an organization
+	dbMock.ExpectQuery(regexp.QuoteMeta("FROM organizations JOIN org_members")).
+		WithArgs(owner).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "Name", "CreatedBy", "CreatedAt", "Role"}))
 	dbMock.ExpectPrepare(regexp.QuoteMeta("SELECT * FROM games WHERE owner = ?"))
 	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE owner = ?")).
 		WithArgs(owner).
 		WillReturnRows(
-			sqlmock.NewRows([]string{"ID", "Title", "StorageLocation", "Status", "Url", "Owner", "FileName"}).
-				AddRow(gameA.ID, gameA.Title, gameA.StorageLocation, gameA.Status, gameA.Url, gameA.Owner, gameA.FileName).
-				AddRow(gameB.ID, gameB.Title, gameB.StorageLocation, gameB.Status, gameB.Url, gameB.Owner, gameB.FileName),
+			gameRows(gameA, gameB),
 		)
+	// No game has been shared with the user
+	dbMock.ExpectQuery(regexp.QuoteMeta("FROM game_collaborators WHERE Subject = ? OR Email = ?")).
+		WithArgs(owner, "").
+		WillReturnRows(sqlmock.NewRows(gameColumns()))
 	// Finally, create gameController
 	gameController := gameController(db, nil, nil)
 	// Prepare Gin
@@ -214,6 +211,71 @@ func Test_Read_All_Should_Succeed(t *testing.T) {
 
 }
 
+func Test_Read_All_Should_Include_Games_Shared_With_The_User(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	email := "collaborator@example.com"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models, gameB has been shared with the user by email, gameA is owned by the user and shared too
+	gameA := mocks.GameMock("A")
+	gameA.Owner = owner
+	gameB := mocks.GameMock("B")
+	gameB.Owner = "OtherOwner"
+	// Define queries, the user is no member of an organization
+	dbMock.ExpectQuery(regexp.QuoteMeta("FROM organizations JOIN org_members")).
+		WithArgs(owner).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "Name", "CreatedBy", "CreatedAt", "Role"}))
+	dbMock.ExpectPrepare(regexp.QuoteMeta("SELECT * FROM games WHERE owner = ?"))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE owner = ?")).
+		WithArgs(owner).
+		WillReturnRows(
+			gameRows(gameA),
+		)
+	dbMock.ExpectQuery(regexp.QuoteMeta("FROM game_collaborators WHERE Subject = ? OR Email = ?")).
+		WithArgs(owner, email).
+		WillReturnRows(
+			gameRows(gameA, gameB),
+		)
+	// Finally, create gameController
+	gameController := gameController(db, nil, nil)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", owner)
+	c.Set("email", email)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	gameController.GetAllGames(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	//Check HTTP response
+	if w.Code != 200 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+
+	//Check response body, gameA is listed once
+	var responseBody []dtos.GetAllGamesResponseBody
+	err := json.Unmarshal(w.Body.Bytes(), &responseBody)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(responseBody) != 2 {
+		t.Fatal(fmt.Sprint("Expected 2 games, got ", len(responseBody)))
+	}
+
+	//Verify games
+	verifyDto(t, &responseBody[0], gameA)
+	verifyDto(t, &responseBody[1], gameB)
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
 func verifyDto(t *testing.T, dto *dtos.GetAllGamesResponseBody, game *models.Game) {
 	if dto.Title != game.Title {
 		t.Error(fmt.Sprintf("Expected title %s, got %s", game.Title, dto.Title))
@@ -239,6 +301,339 @@ func databaseMock() (*sql.DB, sqlmock.Sqlmock) {
 
 func gameController(db *sql.DB, k8s apis.IK8sApi, azure apis.IAzureApi) controllers.IGameController {
 	gamesRepository := repositories.GameRepository(db)
-	gamesService := services.GameService(gamesRepository, k8s, azure)
-	return controllers.GameController(gamesService)
+	blobsService := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{})
+	gamesService := services.GameService(gamesRepository, repositories.GameVersionRepository(db), blobsService, k8s, services.ScanService(apis.NoopScanner(), azure, repositories.BlobRepository(db)))
+	return controllers.GameController(gamesService, services.AccessService(repositories.OrganizationRepository(db), repositories.CollaboratorRepository(db)), nil, nil)
+}
+
+func gameVersionController(db *sql.DB, k8s apis.IK8sApi, azure apis.IAzureApi, romConfig services.RomDownloadConfig) controllers.IGameVersionController {
+	gamesRepository := repositories.GameRepository(db)
+	versionsRepository := repositories.GameVersionRepository(db)
+	blobsService := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{})
+	scansService := services.ScanService(apis.NoopScanner(), azure, repositories.BlobRepository(db))
+	gamesService := services.GameService(gamesRepository, versionsRepository, blobsService, k8s, scansService)
+	romsService := services.RomService(versionsRepository, repositories.RomDownloadRepository(db), azure, romConfig)
+	return controllers.GameVersionController(gamesService, services.GameVersionService(gamesRepository, versionsRepository, blobsService, k8s, scansService), romsService,
+		services.AccessService(repositories.OrganizationRepository(db), repositories.CollaboratorRepository(db)))
+}
+
+func Test_Update_With_Outdated_ETag_Should_Fail(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	game.Owner = owner
+	game.Revision = 3
+	// Define queries
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(owner))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectPrepare(regexp.QuoteMeta("UPDATE games SET Title=?, StorageLocation=?, FileName=?, BlobName=?, Checksum=?, LiveVersion=?, BetaVersion=?, Visibility=?, Description=?, Tags=?, Platform=?, Bios=?, DeployRevision=?, Revision=Revision+1 WHERE ID = ? AND Revision = ?"))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Title=?, StorageLocation=?, FileName=?, BlobName=?, Checksum=?, LiveVersion=?, BetaVersion=?, Visibility=?, Description=?, Tags=?, Platform=?, Bios=?, DeployRevision=?, Revision=Revision+1 WHERE ID = ? AND Revision = ?")).
+		WithArgs("New Title", game.StorageLocation, game.FileName, game.BlobName, game.Checksum, game.LiveVersion, game.BetaVersion, game.Visibility, game.Description, "", game.Platform, "", game.DeployRevision, game.ID, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+
+	// Finally, create gameController
+	gameController := gameController(db, nil, nil)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	c.Request = httptest.NewRequest("PATCH", "/games/"+game.ID.String(), strings.NewReader(`{"title": "New Title"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("If-Match", `"2"`)
+	gameController.UpdateGameById(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 412 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Rollback_Without_Previous_Version_Should_Fail(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	game.Owner = owner
+	// Define queries
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(owner))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM game_versions WHERE GameID = ? ORDER BY Version DESC")).
+		WithArgs(game.ID).
+		WillReturnRows(gameVersionRows(&models.GameVersion{ID: uuid.New(), GameID: game.ID, Version: 1, BlobName: game.ID.String()}))
+
+	// Finally, create gameVersionController
+	gameVersionController := gameVersionController(db, nil, nil, services.RomDownloadConfig{Mode: "stream"})
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	c.Request = httptest.NewRequest("POST", "/games/"+game.ID.String()+"/rollback", nil)
+	gameVersionController.Rollback(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 409 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Download_Rom_Should_Support_Range_Requests(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	game.Owner = owner
+	version := &models.GameVersion{ID: uuid.New(), GameID: game.ID, Version: 1, BlobName: "sha256/MockHash", FileName: "game.nes", Checksum: "MockHash"}
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{version.BlobName: []byte("0123456789")}}
+	// Define queries
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(owner))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM game_versions WHERE GameID = ? AND Version = ?")).
+		WithArgs(game.ID, 1).
+		WillReturnRows(gameVersionRows(version))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO rom_downloads")).
+		WithArgs(sqlmock.AnyArg(), game.ID, 1, owner, sqlmock.AnyArg(), shared.Download_Stream, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Finally, create gameVersionController
+	gameVersionController := gameVersionController(db, nil, azure, services.RomDownloadConfig{Mode: "stream"})
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	c.Request = httptest.NewRequest("GET", "/games/"+game.ID.String()+"/rom", nil)
+	c.Request.Header.Set("Range", "bytes=2-5")
+	gameVersionController.DownloadRom(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	b, _ := ioutil.ReadAll(w.Body)
+	if w.Code != 206 {
+		t.Error(w.Code, string(b))
+	}
+	if string(b) != "2345" {
+		t.Errorf("Expected the requested range, got %s", string(b))
+	}
+	if contentRange := w.Header().Get("Content-Range"); contentRange != "bytes 2-5/10" {
+		t.Errorf("Unexpected Content-Range %s", contentRange)
+	}
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Download_Rom_Of_Game_In_Trash_Should_Return_Not_Found(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	// Define queries
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(owner))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows())
+
+	// Finally, create gameVersionController
+	gameVersionController := gameVersionController(db, nil, mocks.AzureApiMock{}, services.RomDownloadConfig{Mode: "stream"})
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	c.Request = httptest.NewRequest("GET", "/games/"+game.ID.String()+"/rom", nil)
+	gameVersionController.DownloadRom(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 404 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Download_Rom_With_Signed_Url_Should_Not_Need_Authorization(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	game.Owner = owner
+	version := &models.GameVersion{ID: uuid.New(), GameID: game.ID, Version: 1, BlobName: "sha256/MockHash", FileName: "game.nes"}
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{version.BlobName: []byte("0123456789")}}
+	// Define queries
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(owner))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM game_versions WHERE GameID = ? AND Version = ?")).
+		WithArgs(game.ID, 1).
+		WillReturnRows(gameVersionRows(version))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO rom_downloads")).
+		WithArgs(sqlmock.AnyArg(), game.ID, 1, owner, sqlmock.AnyArg(), shared.Download_Redirect, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM game_versions WHERE GameID = ? AND Version = ?")).
+		WithArgs(game.ID, 1).
+		WillReturnRows(gameVersionRows(version))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO rom_downloads")).
+		WithArgs(sqlmock.AnyArg(), game.ID, 1, "", sqlmock.AnyArg(), shared.Download_Signed, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Finally, create gameVersionController
+	gameVersionController := gameVersionController(db, nil, azure, services.RomDownloadConfig{
+		Mode:       "redirect",
+		UrlTTL:     time.Minute,
+		SigningKey: []byte("MockKey"),
+	})
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", owner)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	c.Request = httptest.NewRequest("GET", "/games/"+game.ID.String()+"/rom", nil)
+	gameVersionController.DownloadRom(c)
+
+	if w.Code != 307 {
+		t.Fatal(w.Code)
+	}
+
+	//The signed url is used without subject
+	signedW := httptest.NewRecorder()
+	signedC, _ := gin.CreateTestContext(signedW)
+	signedC.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	signedC.Request = httptest.NewRequest("GET", w.Header().Get("Location"), nil)
+	gameVersionController.DownloadRom(signedC)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	b, _ := ioutil.ReadAll(signedW.Body)
+	if signedW.Code != 200 || string(b) != "0123456789" {
+		t.Error(signedW.Code, string(b))
+	}
+
+	//A modified signature must be rejected
+	forgedW := httptest.NewRecorder()
+	forgedC, _ := gin.CreateTestContext(forgedW)
+	forgedC.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	forgedC.Request = httptest.NewRequest("GET", strings.Replace(w.Header().Get("Location"), "version=1", "version=2", 1), nil)
+	gameVersionController.DownloadRom(forgedC)
+	if forgedW.Code != 403 {
+		t.Error(forgedW.Code)
+	}
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Delete_Should_Move_Game_To_Trash(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	game.Owner = owner
+	// Create fake k8s client
+	fakek8s := mocks.K8sMock(&mock.Mock{})
+	k8sApi := apis.K8sService(fakek8s, apis.NamespaceConfig{})
+	fakek8s.Mock().
+		On("Delete", mock.AnythingOfType("context.backgroundCtx"), mock.AnythingOfType("*v1.Game")).
+		Return(nil)
+	// Define queries, the blobs and versions must not be deleted
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(owner))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET DeletedAt=?, Status=?")).
+		WithArgs(sqlmock.AnyArg(), shared.Status_Deleted, game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Finally, create gameController
+	gameController := gameController(db, k8sApi, nil)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	gameController.DeleteGameById(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 204 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	fakek8s.Mock().AssertNumberOfCalls(t, "Delete", 1)
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
 }
@@ -8,7 +8,9 @@ import (
 	"github.com/google/uuid"
 	"log"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 // ************************************ BEGIN DELETE TESTS ************************************
@@ -92,7 +94,8 @@ func Test_Create_Game_Without_Id_Should_Succeed_And_SetId(t *testing.T) {
 	}
 	mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO games"))
 	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO games")).
-		WithArgs(sqlmock.AnyArg(), game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName).
+		WithArgs(sqlmock.AnyArg(), game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName,
+			game.BlobName, game.Checksum, game.Visibility, game.Description, "", game.Platform, "", "", "").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	//Run the test
@@ -138,7 +141,8 @@ func Test_Create_Game_With_Id_Should_Succeed(t *testing.T) {
 	mock.ExpectPrepare("INSERT INTO games")
 
 	mock.ExpectExec("INSERT INTO games").
-		WithArgs(game.ID, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName).
+		WithArgs(game.ID, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName,
+			game.BlobName, game.Checksum, game.Visibility, game.Description, "", game.Platform, "", "", "").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	//Run the test
@@ -183,8 +187,7 @@ func Test_Save_Existing_Game_Should_Succeed(t *testing.T) {
 
 	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
 		WithArgs(id).WillReturnRows(
-		sqlmock.NewRows([]string{"Id", "Title", "StorageLocation", "Status", "Url", "Owner", "FileName"}).
-			AddRow(id, "", "", "", "", "", ""),
+		gameRows(&models.Game{ID: id}),
 	)
 
 	mock.ExpectPrepare(regexp.
@@ -213,6 +216,90 @@ func Test_Save_Existing_Game_Should_Succeed(t *testing.T) {
 
 }
 
+func Test_Update_Game_Should_Increase_Revision(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer db.Close()
+
+	game := models.Game{
+		ID:              uuid.New(),
+		Title:           "MockTitle",
+		StorageLocation: "MockStorageLocation",
+		FileName:        "TestFile.nes",
+		Revision:        1,
+	}
+
+	mock.ExpectPrepare(regexp.
+		QuoteMeta("UPDATE games SET Title=?, StorageLocation=?, FileName=?, BlobName=?, Checksum=?, LiveVersion=?, BetaVersion=?, Visibility=?, Description=?, Tags=?, Platform=?, Bios=?, DeployRevision=?, Revision=Revision+1 WHERE ID = ? AND Revision = ?"))
+	mock.ExpectExec(regexp.
+		QuoteMeta("UPDATE games SET Title=?, StorageLocation=?, FileName=?, BlobName=?, Checksum=?, LiveVersion=?, BetaVersion=?, Visibility=?, Description=?, Tags=?, Platform=?, Bios=?, DeployRevision=?, Revision=Revision+1 WHERE ID = ? AND Revision = ?")).
+		WithArgs(game.Title, game.StorageLocation, game.FileName, game.BlobName, game.Checksum, game.LiveVersion, game.BetaVersion, game.Visibility, game.Description, "", game.Platform, "", game.DeployRevision, game.ID, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	//Run the test
+	repository := repositories.GameRepository(db)
+
+	err = repository.Update(&game, 1)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if game.Revision != 2 {
+		t.Errorf("revision should be 2, but got %d", game.Revision)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Create_Version_Should_Set_Next_Version(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer db.Close()
+
+	version := models.GameVersion{
+		GameID:          uuid.New(),
+		BlobName:        "MockBlob",
+		StorageLocation: "MockStorageLocation",
+		FileName:        "TestFile.nes",
+		Checksum:        "MockChecksum",
+		Uploader:        "MockOwner",
+		CreatedAt:       time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(Version), 0) + 1 FROM game_versions WHERE GameID = ? FOR UPDATE")).
+		WithArgs(version.GameID).
+		WillReturnRows(sqlmock.NewRows([]string{"Version"}).AddRow(3))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO game_versions")).
+		WithArgs(sqlmock.AnyArg(), version.GameID, 3, version.BlobName, version.StorageLocation, version.FileName,
+			version.Checksum, version.Changelog, version.Uploader, version.CreatedAt, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	//Run the test
+	err = repositories.GameVersionRepository(db).Create(&version)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if version.ID == uuid.Nil {
+		t.Errorf("id should be set")
+	}
+	if version.Version != 3 {
+		t.Errorf("version should be 3, but got %d", version.Version)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
 //************************************ END UPDATE TESTS ************************************
 
 //************************************ BEGIN READ TESTS ************************************
@@ -237,8 +324,7 @@ func Test_Find_Game_By_Id_Should_Succeed(t *testing.T) {
 
 	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
 		WithArgs(id).WillReturnRows(
-		sqlmock.NewRows([]string{"Id", "Title", "StorageLocation", "Status", "Url", "Owner", "FileName"}).
-			AddRow(id, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName),
+		gameRows(&game),
 	)
 
 	//Run the test
@@ -316,9 +402,7 @@ func Test_Find_Two_Games_Of_Same_Owner_Should_Succeed(t *testing.T) {
 	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE owner = ?")).
 		WithArgs("MockOwner").
 		WillReturnRows(
-			sqlmock.NewRows([]string{"ID", "Title", "StorageLocation", "Status", "Url", "Owner", "FileName"}).
-				AddRow(gameA.ID, gameA.Title, gameA.StorageLocation, gameA.Status, gameA.Url, gameA.Owner, gameA.FileName).
-				AddRow(gameB.ID, gameB.Title, gameB.StorageLocation, gameB.Status, gameB.Url, gameB.Owner, gameB.FileName),
+			gameRows(&gameA, &gameB),
 		)
 
 	//Run the test
@@ -356,7 +440,7 @@ func Test_Find_Games_When_Database_Is_Empty_Should_Succeed(t *testing.T) {
 	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE owner = ?")).
 		WithArgs("MockOwner").
 		WillReturnRows(
-			sqlmock.NewRows([]string{"Id", "Title", "StorageLocation", "Status", "Url", "Owner", "FileName"}),
+			gameRows(),
 		)
 
 	//Run the test
@@ -496,3 +580,31 @@ func compareGames(t *testing.T, game *models.Game, res *models.Game) {
 	}
 
 }
+
+// gameRows creates the rows which are returned by "SELECT * FROM games"
+// gameColumns returns the columns of "SELECT * FROM games"
+func gameColumns() []string {
+	return []string{"ID", "Title", "StorageLocation", "Status", "Url", "Owner", "FileName", "Revision",
+		"BlobName", "LiveVersion", "BetaVersion", "BetaUrl", "Checksum", "DeletedAt", "Visibility", "Description", "Tags", "Platform",
+		"Cluster", "Region", "StatusReason", "Bios", "Namespace", "DeployRevision"}
+}
+
+func gameRows(games ...*models.Game) *sqlmock.Rows {
+	rows := sqlmock.NewRows(gameColumns())
+	for _, game := range games {
+		rows.AddRow(game.ID, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName,
+			game.Revision, game.BlobName, game.LiveVersion, game.BetaVersion, game.BetaUrl, game.Checksum, game.DeletedAt, game.Visibility,
+			game.Description, strings.Join(game.Tags, ","), game.Platform, game.Cluster, game.Region, game.StatusReason,
+			strings.Join(game.Bios, "\n"), game.Namespace, game.DeployRevision)
+	}
+	return rows
+}
+
+func gameVersionRows(versions ...*models.GameVersion) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"ID", "GameID", "Version", "BlobName", "StorageLocation", "FileName", "Checksum", "Changelog", "Uploader", "CreatedAt", "Bios"})
+	for _, version := range versions {
+		rows.AddRow(version.ID, version.GameID, version.Version, version.BlobName, version.StorageLocation, version.FileName,
+			version.Checksum, version.Changelog, version.Uploader, version.CreatedAt, strings.Join(version.Bios, "\n"))
+	}
+	return rows
+}
@@ -0,0 +1,237 @@
+package tests
+
+import (
+	"api/middlewares"
+	"api/models"
+	gamesv1 "api/proto/games/v1"
+	"api/router"
+	"api/rpc"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func Test_GameServer_Should_Upload_Streamed_Chunks(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	content := "game content"
+	hash := sha256Hex(content)
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries, the content is new, so it is uploaded to azure
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO blobs")).
+		WithArgs(hash, services.BlobName(hash), "", int64(len(content)), shared.Blob_Unverified, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET StorageLocation=? WHERE Hash = ?")).
+		WithArgs(sqlmock.AnyArg(), hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WillReturnRows(gameRows())
+	dbMock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO games"))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO games")).
+		WithArgs(sqlmock.AnyArg(), "game", sqlmock.AnyArg(), shared.Status_Scanning, sqlmock.AnyArg(), owner, "game.nes",
+			services.BlobName(hash), hash, shared.Visibility_Private, "", "retro,arcade", "nes", "", "", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO game_versions")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+	//The clean game is claimed for its deployment and placed on a cluster
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=?, StatusReason=? WHERE ID = ? AND Status = ?")).
+		WithArgs(shared.Status_Installing, "", sqlmock.AnyArg(), shared.Status_Scanning).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPlacement(dbMock, models.ClusterLoad{Cluster: models.Cluster{Name: shared.DefaultCluster}})
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=?, StatusReason=?, Url=?, Cluster=?")).
+		WithArgs(shared.Status_Installing, "", "", shared.DefaultCluster, "default", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{}}
+	c := gameServiceClient(t, db, azure)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	// The file is sent in chunks after the metadata, the title defaults to the file name
+	stream, err := c.Upload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := []*gamesv1.UploadRequest{
+		{Data: &gamesv1.UploadRequest_Metadata{Metadata: &gamesv1.UploadMetadata{FileName: "game.nes", Tags: []string{"Retro", "arcade"}, Platform: "nes"}}},
+		{Data: &gamesv1.UploadRequest_Chunk{Chunk: []byte(content[:5])}},
+		{Data: &gamesv1.UploadRequest_Chunk{Chunk: []byte(content[5:])}},
+	}
+	for _, request := range requests {
+		if err = stream.Send(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	game, err := stream.CloseAndRecv()
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.GetId() == "" || game.GetTitle() != "game" || game.GetStatus() != string(shared.Status_Installing) {
+		t.Errorf("Unexpected game %+v", game)
+	}
+	if string(azure.Blobs[services.BlobName(hash)]) != content {
+		t.Errorf("Expected the uploaded file, got blobs %v", azure.Blobs)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_GameServer_Should_Reject_Calls_Without_Token_Except_Health_Checks(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	db, dbMock := databaseMock()
+	defer db.Close()
+	c := gameServiceClient(t, db, mocks.AzureApiMock{Blobs: map[string][]byte{}})
+	health := healthpb.NewHealthClient(c.conn)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	_, errList := c.List(context.Background(), &gamesv1.ListRequest{})
+	check, errHealth := health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: gamesv1.GameService_ServiceDesc.ServiceName})
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if status.Code(errList) != codes.Unauthenticated {
+		t.Errorf("Expected code %s, got %v", codes.Unauthenticated, errList)
+	}
+	if errHealth != nil || check.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected a serving health check, got %v and %v", check, errHealth)
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_GameServer_Should_Share_The_Rate_Limits_Of_The_REST_Api(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// One upload and one delete per hour, the REST api has already used the delete of the owner
+	config := middlewares.RateLimitConfig{
+		Enabled: true,
+		Reads:   shared.RateLimitPolicy{Name: "reads", Limit: 10, Window: time.Hour},
+		Uploads: shared.RateLimitPolicy{Name: "uploads", Limit: 1, Window: time.Hour},
+		Deletes: shared.RateLimitPolicy{Name: "deletes", Limit: 1, Window: time.Hour},
+	}
+	store := middlewares.MemoryRateLimitStore()
+	_, _ = store.Take("deletes:sub:"+owner, config.Deletes, time.Now())
+	_, _ = store.Take("uploads:sub:"+owner, config.Uploads, time.Now())
+	c := gameServiceClientWithRateLimiter(t, db, mocks.AzureApiMock{Blobs: map[string][]byte{}}, router.RateLimiter{Config: config, Store: store})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	var header metadata.MD
+	_, errDelete := c.Delete(ctx, &gamesv1.DeleteRequest{Id: "a2f1f1b4-b1de-4d1b-8a52-4e1b7a2c3d4e"}, grpc.Header(&header))
+	stream, err := c.Upload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errUpload := stream.CloseAndRecv()
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if status.Code(errDelete) != codes.ResourceExhausted {
+		t.Errorf("Expected code %s, got %v", codes.ResourceExhausted, errDelete)
+	}
+	if retryAfter := header.Get("retry-after"); len(retryAfter) != 1 || retryAfter[0] == "0" {
+		t.Errorf("Expected the seconds until the next delete, got %v", retryAfter)
+	}
+	if status.Code(errUpload) != codes.ResourceExhausted {
+		t.Errorf("Expected code %s, got %v", codes.ResourceExhausted, errUpload)
+	}
+	// The calls are rejected before they read the database
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_GameServer_Should_Send_Status_Changes(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	installing := mocks.GameMock("A")
+	installing.Owner = owner
+	installing.Status = shared.Status_Installing
+	installed := *installing
+	installed.Status = shared.Status_Installed
+	// Create database mock, the status is unchanged in the second read and changed in the third one
+	db, dbMock := databaseMock()
+	defer db.Close()
+	for _, game := range []*models.Game{installing, installing, &installed} {
+		dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+			WithArgs(installing.ID).
+			WillReturnRows(gameRows(game))
+	}
+	c := gameServiceClient(t, db, mocks.AzureApiMock{Blobs: map[string][]byte{}})
+	ctx, cancel := context.WithTimeout(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+owner), 5*time.Second)
+	defer cancel()
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	stream, err := c.WatchStatus(ctx, &gamesv1.WatchStatusRequest{Id: installing.ID.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for len(statuses) < 2 {
+		game, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, game.GetStatus())
+	}
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if statuses[0] != string(shared.Status_Installing) || statuses[1] != string(shared.Status_Installed) {
+		t.Errorf("Expected the statuses installing and installed, got %v", statuses)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+type grpcGameClient struct {
+	gamesv1.GameServiceClient
+	conn *grpc.ClientConn
+}
+
+// gameServiceClient runs the grpc server of the api in memory without rate limits and returns a client of it
+func gameServiceClient(t *testing.T, db *sql.DB, azure mocks.AzureApiMock) grpcGameClient {
+	return gameServiceClientWithRateLimiter(t, db, azure, router.RateLimiter{})
+}
+
+// gameServiceClientWithRateLimiter runs the grpc server of the api in memory and returns a client of it
+func gameServiceClientWithRateLimiter(t *testing.T, db *sql.DB, azure mocks.AzureApiMock, rateLimiter router.RateLimiter) grpcGameClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := router.SetupGrpcServer(db, azure, fakeK8sClient(t), tokenAuth{}, rpc.Config{Mode: rpc.Mode_Separate, WatchInterval: 10 * time.Millisecond}, rateLimiter)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return grpcGameClient{GameServiceClient: gamesv1.NewGameServiceClient(conn), conn: conn}
+}
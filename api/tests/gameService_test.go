@@ -0,0 +1,265 @@
+package tests
+
+import (
+	"api/apis"
+	"api/models"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func Test_Purge_Should_Only_Delete_Unreferenced_Blobs(t *testing.T) {
+	db, mock := databaseMock()
+	defer db.Close()
+
+	deletedAt := time.Now().Add(-48 * time.Hour)
+	game := mocks.GameMock("A")
+	game.Status = shared.Status_Deleted
+	game.DeletedAt = &deletedAt
+	sharedVersion := &models.GameVersion{ID: uuid.New(), GameID: game.ID, Version: 2, BlobName: services.BlobName("sharedHash"), Checksum: "sharedHash"}
+	ownVersion := &models.GameVersion{ID: uuid.New(), GameID: game.ID, Version: 1, BlobName: services.BlobName("ownHash"), Checksum: "ownHash"}
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{sharedVersion.BlobName: []byte("shared"), ownVersion.BlobName: []byte("own")}}
+	blobColumns := []string{"Hash", "BlobName", "StorageLocation", "Size", "RefCount", "Status", "VerifiedAt", "CreatedAt"}
+
+	before := time.Now().Add(-24 * time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE DeletedAt < ?")).
+		WithArgs(before).
+		WillReturnRows(gameRows(game))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM game_versions WHERE GameID = ? ORDER BY Version DESC")).
+		WithArgs(game.ID).
+		WillReturnRows(gameVersionRows(sharedVersion, ownVersion))
+	//The blob is used by another game
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM blobs WHERE Hash = ? FOR UPDATE")).
+		WithArgs("sharedHash").
+		WillReturnRows(sqlmock.NewRows(blobColumns).AddRow("sharedHash", sharedVersion.BlobName, "", 6, 2, shared.Blob_Ok, nil, deletedAt))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET RefCount=RefCount-1 WHERE Hash = ?")).
+		WithArgs("sharedHash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	//The blob is only used by this game
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM blobs WHERE Hash = ? FOR UPDATE")).
+		WithArgs("ownHash").
+		WillReturnRows(sqlmock.NewRows(blobColumns).AddRow("ownHash", ownVersion.BlobName, "", 3, 1, shared.Blob_Ok, nil, deletedAt))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM blobs WHERE Hash = ?")).
+		WithArgs("ownHash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM game_versions WHERE GameID = ?")).
+		WithArgs(game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare(regexp.QuoteMeta("DELETE FROM games WHERE ID = ?"))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	blobsService := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{})
+	gamesService := services.GameService(repositories.GameRepository(db), repositories.GameVersionRepository(db), blobsService, nil, nil)
+	purged, err := gamesService.PurgeDeletedBefore(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged game, got %d", purged)
+	}
+	if _, ok := azure.Blobs[sharedVersion.BlobName]; !ok {
+		t.Errorf("The shared blob must not be deleted")
+	}
+	if _, ok := azure.Blobs[ownVersion.BlobName]; ok {
+		t.Errorf("The unreferenced blob should be deleted")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Stop_And_Start_Should_Suspend_Game_And_Track_Its_Phase(t *testing.T) {
+	db, mock := databaseMock()
+	defer db.Close()
+
+	game := mocks.GameMock("A")
+	game.Status = shared.Status_Installed
+	k8sClient := fakeK8sClient(t)
+	k8sApi := apis.K8sService(k8sClient, apis.NamespaceConfig{})
+	if err := k8sApi.DeployGame(game); err != nil {
+		t.Fatal(err)
+	}
+	gamesService := services.GameService(repositories.GameRepository(db), repositories.GameVersionRepository(db), nil, k8sApi, nil)
+	key := types.NamespacedName{Namespace: "default", Name: game.ID.String()}
+
+	//Stop the game
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=? WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(shared.Status_Stopping, game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	stopped, err := gamesService.Stop(game.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource := streamv1.Game{}
+	if err = k8sClient.Get(context.Background(), key, &resource); err != nil {
+		t.Fatal(err)
+	}
+	if stopped.Status != shared.Status_Stopping || !resource.Spec.Suspend {
+		t.Errorf("Game should be stopping and suspended, got %s and %t", stopped.Status, resource.Spec.Suspend)
+	}
+
+	//The operator reports that all pods are gone
+	resource.Status.Phase = streamv1.GamePhaseStopped
+	if err = k8sClient.Status().Update(context.Background(), &resource); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(stopped))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=? WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(shared.Status_Stopped, game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	found, err := gamesService.FindByID(game.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Status != shared.Status_Stopped {
+		t.Errorf("Game should be stopped, got %s", found.Status)
+	}
+
+	//Start the game again
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(found))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=? WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(shared.Status_Starting, game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	started, err := gamesService.Start(game.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k8sClient.Get(context.Background(), key, &resource); err != nil {
+		t.Fatal(err)
+	}
+	if started.Status != shared.Status_Starting || resource.Spec.Suspend {
+		t.Errorf("Game should be starting and not suspended, got %s and %t", started.Status, resource.Spec.Suspend)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Delete_Should_Undeploy_Installing_Game(t *testing.T) {
+	db, mock := databaseMock()
+	defer db.Close()
+
+	//The game is deployed, but the operator has not reported its url yet
+	game := mocks.GameMock("A")
+	game.Status = shared.Status_Installing
+	game.Url = ""
+	k8sClient := fakeK8sClient(t)
+	k8sApi := apis.K8sService(k8sClient, apis.NamespaceConfig{})
+	if err := k8sApi.DeployGame(game); err != nil {
+		t.Fatal(err)
+	}
+	gamesService := services.GameService(repositories.GameRepository(db), repositories.GameVersionRepository(db), nil, k8sApi, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE games SET DeletedAt=?, Status=?")).
+		WithArgs(sqlmock.AnyArg(), shared.Status_Deleted, game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := gamesService.Delete(game.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	resource := streamv1.Game{}
+	err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: game.ID.String()}, &resource)
+	if err == nil {
+		t.Errorf("The game resource should be deleted")
+	}
+
+	//Deleting the game again succeeds, although the resource is already gone
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ? AND DeletedAt IS NULL")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE games SET DeletedAt=?, Status=?")).
+		WithArgs(sqlmock.AnyArg(), shared.Status_Deleted, game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err = gamesService.Delete(game.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Update_Should_Only_Roll_Out_Pods_On_Redeploy(t *testing.T) {
+	db, mock := databaseMock()
+	defer db.Close()
+
+	game := mocks.GameMock("A")
+	game.Status = shared.Status_Installed
+	game.Revision = 3
+	game.DeployRevision = 2
+	k8sClient := fakeK8sClient(t)
+	k8sApi := apis.K8sService(k8sClient, apis.NamespaceConfig{})
+	if err := k8sApi.DeployGame(game); err != nil {
+		t.Fatal(err)
+	}
+	gamesService := services.GameService(repositories.GameRepository(db), repositories.GameVersionRepository(db), nil, k8sApi, nil)
+	key := types.NamespacedName{Namespace: "default", Name: game.ID.String()}
+
+	//Changing the title only renames the game
+	mock.ExpectPrepare(regexp.QuoteMeta("UPDATE games SET Title=?"))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Title=?")).
+		WithArgs("New Title", game.StorageLocation, game.FileName, game.BlobName, game.Checksum, game.LiveVersion, game.BetaVersion,
+			game.Visibility, game.Description, "", game.Platform, "", 2, game.ID, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	game.Title = "New Title"
+	if err := gamesService.Update(game, 3); err != nil {
+		t.Fatal(err)
+	}
+	resource := streamv1.Game{}
+	if err := k8sClient.Get(context.Background(), key, &resource); err != nil {
+		t.Fatal(err)
+	}
+	if resource.Spec.Name != "New Title" || resource.Spec.Revision != 2 {
+		t.Errorf("Expected the new title and the revision 2, got %s and %d", resource.Spec.Name, resource.Spec.Revision)
+	}
+
+	//Redeploying rolls out new pods
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	mock.ExpectPrepare(regexp.QuoteMeta("UPDATE games SET Title=?"))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Title=?")).
+		WithArgs("New Title", game.StorageLocation, game.FileName, game.BlobName, game.Checksum, game.LiveVersion, game.BetaVersion,
+			game.Visibility, game.Description, "", game.Platform, "", 3, game.ID, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := gamesService.Redeploy(game.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := k8sClient.Get(context.Background(), key, &resource); err != nil {
+		t.Fatal(err)
+	}
+	if resource.Spec.Revision != 3 {
+		t.Errorf("Expected the revision 3, got %d", resource.Spec.Revision)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"api/apis"
+	"api/shared"
+	"api/tests/mocks"
+	"context"
+	"github.com/google/uuid"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+)
+
+func Test_Deploy_Per_Owner_Should_Create_Namespace_With_Quota(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	k8sClient := fakeK8sClient(t)
+	k8sApi := apis.K8sService(k8sClient, apis.NamespaceConfig{
+		Strategy: apis.Namespace_PerOwner,
+		Prefix:   "igs-",
+		Quota:    corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+		DefaultLimits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		},
+		StorageClass: "azureblob-sc",
+	})
+	game := mocks.GameMock("A")
+	game.Owner = "auth0|Mock.Owner"
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err := k8sApi.DeployGame(game)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	namespaces := corev1.NamespaceList{}
+	err = k8sClient.List(context.Background(), &namespaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(namespaces.Items) != 1 {
+		t.Fatalf("Expected one namespace, got %d", len(namespaces.Items))
+	}
+	namespace := namespaces.Items[0].Name
+	if !strings.HasPrefix(namespace, "igs-u-auth0-mock-owner-") || len(namespace) > 63 {
+		t.Errorf("Unexpected namespace %s", namespace)
+	}
+
+	ctx := context.Background()
+	if err = k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: game.ID.String()}, &streamv1.Game{}); err != nil {
+		t.Errorf("Game should be created in the namespace of the owner: %s", err)
+	}
+	if err = k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "game-quota"}, &corev1.ResourceQuota{}); err != nil {
+		t.Errorf("Quota should be created: %s", err)
+	}
+	if err = k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "game-limits"}, &corev1.LimitRange{}); err != nil {
+		t.Errorf("LimitRange should be created: %s", err)
+	}
+	claim := corev1.PersistentVolumeClaim{}
+	if err = k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: apis.GameStorageClaim}, &claim); err != nil {
+		t.Errorf("Claim of the game storage should be created: %s", err)
+	} else if claim.Spec.StorageClassName == nil || *claim.Spec.StorageClassName != "azureblob-sc" {
+		t.Errorf("Expected the storage class azureblob-sc, got %v", claim.Spec.StorageClassName)
+	}
+	if game.Namespace != namespace {
+		t.Errorf("Expected the namespace %s on the game, got %s", namespace, game.Namespace)
+	}
+
+	//Reads and deletes resolve the same namespace
+	_, err = k8sApi.ReadGameUrl(game)
+	if err != nil {
+		t.Errorf("Reading the url failed: %s", err)
+	}
+	err = k8sApi.DeleteGame(game)
+	if err != nil {
+		t.Errorf("Deleting the game failed: %s", err)
+	}
+}
+
+func Test_Deploy_Per_Org_Should_Keep_Games_Of_Users_In_Shared_Namespace(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	k8sClient := fakeK8sClient(t)
+	k8sApi := apis.K8sService(k8sClient, apis.NamespaceConfig{
+		Strategy:     apis.Namespace_PerOrg,
+		Namespace:    "games",
+		Prefix:       "igs-",
+		StorageClass: "azureblob-sc",
+	})
+	userGame := mocks.GameMock("A")
+	orgID := uuid.New()
+	orgGame := mocks.GameMock("B")
+	orgGame.Owner = shared.OrgOwner(orgID)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	if err := k8sApi.DeployGame(userGame); err != nil {
+		t.Fatal(err)
+	}
+	if err := k8sApi.DeployGame(orgGame); err != nil {
+		t.Fatal(err)
+	}
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	ctx := context.Background()
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "games", Name: userGame.ID.String()}, &streamv1.Game{}); err != nil {
+		t.Errorf("Game of the user should be created in the shared namespace: %s", err)
+	}
+	orgNamespace := "igs-org-" + orgID.String()
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: orgNamespace, Name: orgGame.ID.String()}, &streamv1.Game{}); err != nil {
+		t.Errorf("Game of the organization should be created in its namespace: %s", err)
+	}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: orgNamespace}, &corev1.Namespace{}); err != nil {
+		t.Errorf("Namespace of the organization should be created: %s", err)
+	}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: orgNamespace, Name: apis.GameStorageClaim}, &corev1.PersistentVolumeClaim{}); err != nil {
+		t.Errorf("Claim of the game storage should be created in the namespace of the organization: %s", err)
+	}
+	//The shared namespace is not created by the api, neither is its claim
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "games", Name: apis.GameStorageClaim}, &corev1.PersistentVolumeClaim{}); err == nil {
+		t.Error("Claim of the shared namespace should not be created")
+	}
+}
+
+func Test_Deployed_Game_Should_Keep_Its_Namespace_If_The_Strategy_Changes(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	k8sClient := fakeK8sClient(t)
+	defaultNamespace := apis.K8sService(k8sClient, apis.NamespaceConfig{})
+	perOwner := apis.K8sService(k8sClient, apis.NamespaceConfig{Strategy: apis.Namespace_PerOwner, Prefix: "igs-"})
+	game := mocks.GameMock("A")
+	if err := defaultNamespace.DeployGame(game); err != nil {
+		t.Fatal(err)
+	}
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	_, errRead := perOwner.ReadGameUrl(game)
+	errDelete := perOwner.DeleteGame(game)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if game.Namespace != "default" {
+		t.Errorf("Expected the namespace default on the game, got %s", game.Namespace)
+	}
+	if errRead != nil {
+		t.Errorf("Reading the url failed: %s", errRead)
+	}
+	if errDelete != nil {
+		t.Errorf("Deleting the game failed: %s", errDelete)
+	}
+	namespaces := corev1.NamespaceList{}
+	if err := k8sClient.List(context.Background(), &namespaces); err != nil || len(namespaces.Items) != 0 {
+		t.Errorf("Expected no namespace to be created, got %d: %v", len(namespaces.Items), err)
+	}
+}
+
+func Test_Namespace_Config_Should_Require_Default_Limits_For_A_Quota_Of_Limits(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	quota := corev1.ResourceList{
+		corev1.ResourceLimitsCPU:    resource.MustParse("8"),
+		corev1.ResourceLimitsMemory: resource.MustParse("16Gi"),
+	}
+	withoutLimits := apis.NamespaceConfig{Quota: quota, DefaultLimits: corev1.ResourceList{}}
+	withCpuLimit := apis.NamespaceConfig{Quota: quota, DefaultLimits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}}
+	withLimits := apis.NamespaceConfig{Quota: quota, DefaultLimits: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("2Gi"),
+	}}
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	errWithout := withoutLimits.Validate()
+	errCpu := withCpuLimit.Validate()
+	errWith := withLimits.Validate()
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if errWithout == nil || !strings.Contains(errWithout.Error(), "K8S_CONTAINER_CPU") {
+		t.Errorf("Expected the missing K8S_CONTAINER_CPU, got %v", errWithout)
+	}
+	if errCpu == nil || !strings.Contains(errCpu.Error(), "K8S_CONTAINER_MEMORY") {
+		t.Errorf("Expected the missing K8S_CONTAINER_MEMORY, got %v", errCpu)
+	}
+	if errWith != nil {
+		t.Errorf("Expected no error, got %s", errWith)
+	}
+}
+
+func Test_Deploy_Bundle_Should_Mount_Directory_With_Bios(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	k8sClient := fakeK8sClient(t)
+	k8sApi := apis.K8sService(k8sClient, apis.NamespaceConfig{})
+	game := mocks.GameMock("A")
+	game.BlobName = shared.BundlePrefix + "hash"
+	game.FileName = "disc/game.cue"
+	game.Bios = []string{"scph1001.bin"}
+	single := mocks.GameMock("B")
+	single.BlobName = "sha256/hash"
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	if err := k8sApi.DeployGame(game); err != nil {
+		t.Fatal(err)
+	}
+	if err := k8sApi.DeployGame(single); err != nil {
+		t.Fatal(err)
+	}
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	ctx := context.Background()
+	resource := streamv1.Game{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: game.ID.String()}, &resource); err != nil {
+		t.Fatal(err)
+	}
+	if !resource.Spec.Directory || resource.Spec.FileName != "disc/game.cue" || len(resource.Spec.Bios) != 1 || resource.Spec.Bios[0] != "scph1001.bin" {
+		t.Errorf("Expected the bundle to be mounted as directory with its BIOS, got %+v", resource.Spec)
+	}
+	singleResource := streamv1.Game{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: single.ID.String()}, &singleResource); err != nil {
+		t.Fatal(err)
+	}
+	if singleResource.Spec.Directory || len(singleResource.Spec.Bios) != 0 {
+		t.Errorf("Expected a single file to be mounted as file, got %+v", singleResource.Spec)
+	}
+}
+
+// fakeK8sClient returns an in-memory client, which knows the game resources
+func fakeK8sClient(t *testing.T) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := streamv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&streamv1.Game{}).Build()
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"api/kube"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"testing"
+	"time"
+)
+
+func Test_Kube_Client_Should_Be_Rebuilt_If_The_Credentials_Are_Rejected(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	name := uuid.New().String()
+	cluster := fakeK8sClient(t)
+	if err := cluster.Create(context.Background(), &streamv1.Game{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}); err != nil {
+		t.Fatal(err)
+	}
+	// The credentials of the first client have expired
+	builds := 0
+	k8s := kube.RefreshingClient(cluster.Scheme(), 0, func(ctx context.Context) (client.Client, error) {
+		builds++
+		if builds == 1 {
+			return interceptor.NewClient(cluster.(client.WithWatch), interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					return k8serrors.NewUnauthorized("token expired")
+				},
+			}), nil
+		}
+		return cluster, nil
+	})
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &streamv1.Game{})
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Errorf("Expected the request to succeed with new credentials, got %v", err)
+	}
+	if builds != 2 {
+		t.Errorf("Expected the client to be built twice, got %d", builds)
+	}
+}
+
+func Test_Kube_Client_Should_Keep_The_Old_Client_If_A_Refresh_Fails(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	cluster := fakeK8sClient(t)
+	builds := 0
+	k8s := kube.RefreshingClient(cluster.Scheme(), time.Millisecond, func(ctx context.Context) (client.Client, error) {
+		builds++
+		if builds > 1 {
+			return nil, errors.New("the provider is not available")
+		}
+		return cluster, nil
+	})
+	time.Sleep(5 * time.Millisecond)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err := k8s.List(context.Background(), &streamv1.GameList{})
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Errorf("Expected the old client to be used, got %v", err)
+	}
+	if builds != 2 {
+		t.Errorf("Expected a refresh, got %d builds", builds)
+	}
+}
+
+func Test_Kube_Client_Should_Fail_Calls_Until_It_Can_Be_Built(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	cluster := fakeK8sClient(t)
+	k8s := kube.RefreshingClient(cluster.Scheme(), 0, func(ctx context.Context) (client.Client, error) {
+		return nil, errors.New("the provider is not available")
+	})
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err := k8s.List(context.Background(), &streamv1.GameList{})
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err == nil {
+		t.Errorf("Expected an error")
+	}
+}
+
+func Test_Kubeconfig_Provider_Should_Use_The_Configured_Context(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	kubeconfig := `apiVersion: v1
+kind: Config
+current-context: kind
+clusters:
+- name: kind
+  cluster:
+    server: https://127.0.0.1:6443
+- name: k3s
+  cluster:
+    server: https://k3s.example.com:6443
+users:
+- name: admin
+  user:
+    token: secret
+contexts:
+- name: kind
+  context: {cluster: kind, user: admin}
+- name: k3s
+  context: {cluster: k3s, user: admin}
+`
+	if err := os.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	provider := kube.Provider(kube.Config{Provider: kube.Provider_Kubeconfig, KubeconfigPath: path, Context: "k3s"})
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	restConfig, err := provider.RestConfig(context.Background())
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.Host != "https://k3s.example.com:6443" || restConfig.BearerToken != "secret" {
+		t.Errorf("Expected the cluster of the context k3s, got %s", restConfig.Host)
+	}
+}
@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"api/middlewares"
+	"api/repositories"
+	"api/shared"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// ************************************ BEGIN CORS TESTS ************************************
+func Test_Cors_Preflight_Should_Return_Route_Methods(t *testing.T) {
+	r := corsRouter(middlewares.CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedHeaders: []string{"Authorization"},
+		MaxAge:         600,
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/games/66c887ca-1f56-426e-ac0c-bc92fff8b798", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "https://app.example.com" {
+		t.Errorf("Expected origin to be reflected, got %s", origin)
+	}
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); methods != "DELETE, GET, OPTIONS" {
+		t.Errorf("Expected methods of the route, got %s", methods)
+	}
+	if maxAge := w.Header().Get("Access-Control-Max-Age"); maxAge != "600" {
+		t.Errorf("Expected max age 600, got %s", maxAge)
+	}
+}
+
+func Test_Cors_Preflight_Should_Match_Custom_Methods(t *testing.T) {
+	r := corsRouter(middlewares.CORSConfig{AllowedOrigins: []string{"*"}})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/games:batch", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); methods != "OPTIONS, POST" {
+		t.Errorf("Expected methods of the route, got %s", methods)
+	}
+}
+
+func Test_Cors_Should_Reject_Unknown_Origin(t *testing.T) {
+	r := corsRouter(middlewares.CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+	})
+
+	for _, origin := range []string{"https://example.com", "http://app.example.com", "https://app.example.com.evil.com"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodOptions, "/games", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d for origin %s, got %d", http.StatusForbidden, origin, w.Code)
+		}
+		if w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Allow-Origin header should not be set for origin %s", origin)
+		}
+	}
+}
+
+func Test_Cors_Wildcard_Should_Not_Allow_Credentials(t *testing.T) {
+	r := corsRouter(middlewares.CORSConfig{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/games", nil)
+	req.Header.Set("Origin", "https://somewhere.com")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "*" {
+		t.Errorf("Expected wildcard origin, got %s", origin)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Allow-Credentials must not be sent together with a wildcard origin")
+	}
+}
+
+//************************************ END CORS TESTS ************************************
+
+func Test_Security_Headers_Should_Be_Set(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.SecurityHeadersMiddleware(middlewares.SecurityHeadersConfig{
+		HSTSMaxAge:            3600,
+		HSTSIncludeSubdomains: true,
+		NoSniff:               true,
+		FrameAncestors:        "'none'",
+	}))
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	if hsts := w.Header().Get("Strict-Transport-Security"); hsts != "max-age=3600; includeSubDomains" {
+		t.Errorf("Unexpected Strict-Transport-Security header %s", hsts)
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("X-Content-Type-Options header is missing")
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); csp != "frame-ancestors 'none'" {
+		t.Errorf("Unexpected Content-Security-Policy header %s", csp)
+	}
+}
+
+func corsRouter(config middlewares.CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.CORSMiddleware(config, r))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/games", ok)
+	r.POST("/games", ok)
+	r.POST("/games:action", ok)
+	r.GET("/games/:id", ok)
+	r.DELETE("/games/:id", ok)
+	return r
+}
+
+// ************************************ BEGIN RATE LIMIT TESTS ************************************
+func Test_Rate_Limit_Should_Return_429_When_Bucket_Is_Empty(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := shared.RateLimitPolicy{Name: "uploads", Limit: 2, Window: time.Hour}
+	r := gin.New()
+	r.POST("/games", func(c *gin.Context) {
+		c.Set("subject", "MockOwner")
+		c.Next()
+	}, middlewares.RateLimitMiddleware(middlewares.MemoryRateLimitStore(), policy), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/games", nil))
+		if w.Code != http.StatusCreated {
+			t.Errorf("Request %d should be allowed, got %d", i, w.Code)
+		}
+		if remaining := w.Header().Get("RateLimit-Remaining"); remaining != strconv.Itoa(1-i) {
+			t.Errorf("Expected %d remaining requests, got %s", 1-i, remaining)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/games", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	//One token is refilled every 30 minutes
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "1800" {
+		t.Errorf("Expected Retry-After of 1800 seconds, got %s", retryAfter)
+	}
+}
+
+func Test_Rate_Limit_Repository_Should_Refill_Bucket(t *testing.T) {
+	db, mock := databaseMock()
+	defer db.Close()
+
+	policy := shared.RateLimitPolicy{Name: "reads", Limit: 10, Window: 10 * time.Second}
+	now := time.Now()
+	key := "reads:sub:MockOwner"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT Tokens, UpdatedAt FROM rate_limits WHERE BucketKey = ? FOR UPDATE")).
+		WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"Tokens", "UpdatedAt"}).AddRow(0.0, now.Add(-3*time.Second).UnixMilli()))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO rate_limits")).
+		WithArgs(key, sqlmock.AnyArg(), now.UnixMilli()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := repositories.RateLimitRepository(db).Take(key, policy, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Allowed {
+		t.Errorf("Request should be allowed")
+	}
+	if result.Remaining != 2 {
+		t.Errorf("Expected 2 remaining requests, got %d", result.Remaining)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+//************************************ END RATE LIMIT TESTS ************************************
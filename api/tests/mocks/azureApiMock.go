@@ -0,0 +1,81 @@
+package mocks
+
+import (
+	"api/apis"
+	"api/shared"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"time"
+)
+
+// AzureApiMock keeps the blobs in memory
+type AzureApiMock struct {
+	Blobs map[string][]byte
+}
+
+func (a AzureApiMock) UploadGame(blobContainerName string, blobName string, fileHeader *multipart.FileHeader) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	a.Blobs[blobName] = content
+	return fmt.Sprintf("https://mock.blob.core.windows.net/%s/%s", blobContainerName, blobName), nil
+}
+
+func (a AzureApiMock) DeleteGame(blobContainerName string, blobName string) error {
+	if _, ok := a.Blobs[blobName]; !ok {
+		return errors.New("BlobNotFound")
+	}
+	delete(a.Blobs, blobName)
+	return nil
+}
+
+func (a AzureApiMock) DownloadGame(blobContainerName string, blobName string) (io.ReadCloser, error) {
+	content, ok := a.Blobs[blobName]
+	if !ok {
+		return nil, errors.New("BlobNotFound")
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (a AzureApiMock) DownloadGameRange(blobContainerName string, blobName string, offset int64, count int64) (io.ReadCloser, error) {
+	content, ok := a.Blobs[blobName]
+	if !ok {
+		return nil, errors.New("BlobNotFound")
+	}
+	content = content[offset:]
+	if count > 0 {
+		content = content[:count]
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (a AzureApiMock) GameProperties(blobContainerName string, blobName string) (apis.BlobProperties, error) {
+	content, ok := a.Blobs[blobName]
+	if !ok {
+		return apis.BlobProperties{}, errors.New("BlobNotFound")
+	}
+	return apis.BlobProperties{Size: int64(len(content)), LastModified: time.Now()}, nil
+}
+
+// ListGames returns all blobs, they have been uploaded long ago
+func (a AzureApiMock) ListGames(blobContainerName string) ([]apis.BlobProperties, error) {
+	blobs := []apis.BlobProperties{}
+	for name, content := range a.Blobs {
+		blobs = append(blobs, apis.BlobProperties{Name: name, Size: int64(len(content))})
+	}
+	return blobs, nil
+}
+
+func (a AzureApiMock) SignedGameUrl(blobContainerName string, blobName string, fileName string, expiry time.Time) (string, error) {
+	return "", shared.ErrSignedUrlNotSupported
+}
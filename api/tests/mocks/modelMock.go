@@ -15,5 +15,8 @@ func GameMock(identifier string) *models.Game {
 		Url:             "Url_" + identifier,
 		Owner:           "Owner_" + identifier,
 		FileName:        "File_" + identifier,
+		Revision:        1,
+		LiveVersion:     1,
+		Visibility:      shared.Visibility_Private,
 	}
 }
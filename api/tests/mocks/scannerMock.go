@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"api/apis"
+	"io"
+)
+
+// ScannerMock returns its result or error for every scan and records the names of the scanned files
+type ScannerMock struct {
+	Result  apis.ScanResult
+	Err     error
+	Scanned []string
+}
+
+func (s *ScannerMock) Scan(name string, content io.Reader) (apis.ScanResult, error) {
+	s.Scanned = append(s.Scanned, name)
+	if s.Err != nil {
+		return apis.ScanResult{}, s.Err
+	}
+	_, err := io.Copy(io.Discard, content)
+	return s.Result, err
+}
@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"api/controllers"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io/ioutil"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Org_Member_Should_Read_Game_Of_Org(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	member := "MockMember"
+	orgID := uuid.New()
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	game.Owner = shared.OrgOwner(orgID)
+	// Define queries
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WithArgs(game.ID).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Role FROM org_members WHERE OrgID = ? AND Subject = ?")).
+		WithArgs(orgID, member).
+		WillReturnRows(sqlmock.NewRows([]string{"Role"}).AddRow(shared.Role_Viewer))
+
+	// Finally, create gameController
+	gameController := gameController(db, nil, nil)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", member)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: game.ID.String()}}
+	gameController.GetGameById(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 200 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Org_Viewer_Should_Not_Delete_Game_Of_Org(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	member := "MockMember"
+	orgID := uuid.New()
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries, the game must not be deleted
+	gameID := uuid.New()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(gameID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(shared.OrgOwner(orgID)))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Role FROM org_members WHERE OrgID = ? AND Subject = ?")).
+		WithArgs(orgID, member).
+		WillReturnRows(sqlmock.NewRows([]string{"Role"}).AddRow(shared.Role_Viewer))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Permissions FROM game_collaborators")).
+		WithArgs(gameID, member, "").
+		WillReturnRows(sqlmock.NewRows([]string{"Permissions"}))
+
+	// Finally, create gameController
+	gameController := gameController(db, nil, nil)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", member)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: gameID.String()}}
+	gameController.DeleteGameById(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 403 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Accept_Invitation_Should_Require_Invited_Email(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	token := "MockToken"
+	orgID := uuid.New()
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries, the invitation must not be accepted
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM org_invitations WHERE TokenHash = ?")).
+		WithArgs(tokenHash(token)).
+		WillReturnRows(invitationRows(orgID, "invited@example.com", time.Now().Add(time.Hour)))
+
+	// Finally, create the controller
+	organizationController := organizationController(db)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", "MockSubject")
+	c.Set("email", "someone.else@example.com")
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Request = httptest.NewRequest("POST", "/invitations/accept", strings.NewReader(`{"token": "`+token+`"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	organizationController.AcceptInvitation(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 403 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Accept_Invitation_Should_Add_Member(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	token := "MockToken"
+	subject := "MockSubject"
+	orgID := uuid.New()
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM org_invitations WHERE TokenHash = ?")).
+		WithArgs(tokenHash(token)).
+		WillReturnRows(invitationRows(orgID, "invited@example.com", time.Now().Add(time.Hour)))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Role FROM org_members WHERE OrgID = ? AND Subject = ?")).
+		WithArgs(orgID, subject).
+		WillReturnError(sql.ErrNoRows)
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE org_invitations SET AcceptedAt=?, AcceptedBy=? WHERE ID = ? AND AcceptedAt IS NULL")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO org_members")).
+		WithArgs(orgID, subject, shared.Role_Developer, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT ID, Name, CreatedBy, CreatedAt FROM organizations WHERE ID = ?")).
+		WithArgs(orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "Name", "CreatedBy", "CreatedAt"}).AddRow(orgID, "MockStudio", "MockOwner", time.Now()))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Role FROM org_members WHERE OrgID = ? AND Subject = ?")).
+		WithArgs(orgID, subject).
+		WillReturnRows(sqlmock.NewRows([]string{"Role"}).AddRow(shared.Role_Developer))
+
+	// Finally, create the controller
+	organizationController := organizationController(db)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", subject)
+	c.Set("email", "Invited@Example.com")
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Request = httptest.NewRequest("POST", "/invitations/accept", strings.NewReader(`{"token": "`+token+`"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	organizationController.AcceptInvitation(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 200 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if !strings.Contains(w.Body.String(), shared.OrgOwner(orgID)) {
+		t.Errorf("Response should contain the owner of the organization: %s", w.Body.String())
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Last_Owner_Should_Not_Leave_Org(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	orgID := uuid.New()
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Define queries, the member must not be deleted
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT ID, Name, CreatedBy, CreatedAt FROM organizations WHERE ID = ?")).
+		WithArgs(orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "Name", "CreatedBy", "CreatedAt"}).AddRow(orgID, "MockStudio", owner, time.Now()))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Role FROM org_members WHERE OrgID = ? AND Subject = ?")).
+		WithArgs(orgID, owner).
+		WillReturnRows(sqlmock.NewRows([]string{"Role"}).AddRow(shared.Role_Owner))
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Subject FROM org_members WHERE OrgID = ? AND Role = ? FOR UPDATE")).
+		WithArgs(orgID, shared.Role_Owner).
+		WillReturnRows(sqlmock.NewRows([]string{"Subject"}).AddRow(owner))
+	dbMock.ExpectRollback()
+
+	// Finally, create the controller
+	organizationController := organizationController(db)
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Params = gin.Params{gin.Param{Key: "id", Value: orgID.String()}, gin.Param{Key: "subject", Value: owner}}
+	organizationController.RemoveMember(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 409 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Error(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func organizationController(db *sql.DB) controllers.IOrganizationController {
+	return controllers.OrganizationController(services.OrganizationService(repositories.OrganizationRepository(db),
+		repositories.OrgInvitationRepository(db), time.Hour))
+}
+
+func invitationRows(orgID uuid.UUID, email string, expiresAt time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"ID", "OrgID", "Email", "Role", "TokenHash", "InvitedBy", "CreatedAt", "ExpiresAt", "AcceptedAt", "AcceptedBy"}).
+		AddRow(uuid.New(), orgID, email, shared.Role_Developer, "", "MockOwner", time.Now(), expiresAt, nil, nil)
+}
+
+func tokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
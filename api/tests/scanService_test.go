@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"api/apis"
+	"api/models"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+	"io"
+	"k8s.io/apimachinery/pkg/types"
+	"net"
+	"regexp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+func Test_Clamd_Scanner_Should_Stream_Content_And_Parse_Replies(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	// The content is split into several chunks
+	content := bytes.Repeat([]byte("game"), 40*1024)
+	tests := []struct {
+		reply  string
+		result apis.ScanResult
+		err    bool
+	}{
+		{reply: "stream: OK", result: apis.ScanResult{Clean: true}},
+		{reply: "stream: Win.Test.EICAR_HDB-1 FOUND", result: apis.ScanResult{Reason: "Win.Test.EICAR_HDB-1"}},
+		{reply: "INSTREAM size limit exceeded. ERROR", result: apis.ScanResult{Reason: "the file exceeds the size limit of the scanner"}},
+		{reply: "stream: lstat() failed ERROR", err: true},
+	}
+
+	for _, test := range tests {
+		received := make(chan []byte, 1)
+		address := fakeClamd(t, test.reply, received)
+		scanner := apis.ClamdScanner("tcp", address, 5*time.Second)
+
+		//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+		result, err := scanner.Scan("game.nes", bytes.NewReader(content))
+
+		//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+		if test.err != (err != nil) || result != test.result {
+			t.Errorf("Expected %+v for the reply %s, got %+v %v", test.result, test.reply, result, err)
+		}
+		if streamed := <-received; !bytes.Equal(streamed, content) {
+			t.Errorf("Expected the content to be streamed to clamd, got %d bytes", len(streamed))
+		}
+	}
+}
+
+func Test_Clamd_Scanner_Should_Fail_If_Clamd_Is_Unavailable(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+	azure := mocks.AzureApiMock{Blobs: map[string][]byte{"blob": []byte("game")}}
+	scans := services.ScanService(apis.ClamdScanner("tcp", address, time.Second), azure, nil)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	_, err = scans.ScanBlob("blob")
+	_, errMissing := scans.ScanBlob("missing")
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if !errors.Is(err, shared.ErrScannerUnavailable) || !errors.Is(errMissing, shared.ErrScannerUnavailable) {
+		t.Errorf("Expected %v, got %v and %v", shared.ErrScannerUnavailable, err, errMissing)
+	}
+}
+
+func Test_Save_Should_Quarantine_Flagged_Game_Without_Deploying_It(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	content := "infected game"
+	hash := sha256Hex(content)
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	expectScannedUpload(dbMock, hash)
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=?, StatusReason=? WHERE ID = ? AND Status = ?")).
+		WithArgs(shared.Status_Quarantined, "Win.Test.EICAR_HDB-1", sqlmock.AnyArg(), shared.Status_Scanning).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	scanner := &mocks.ScannerMock{Result: apis.ScanResult{Reason: "Win.Test.EICAR_HDB-1"}}
+	k8sClient := fakeK8sClient(t)
+	gamesService := scanningGameService(db, mocks.AzureApiMock{Blobs: map[string][]byte{}}, k8sClient, scanner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	game, err := gamesService.Save(fileHeader(t, "game.nes", content), models.GameMetadata{Title: "Game"}, "MockOwner")
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Status != shared.Status_Quarantined || game.StatusReason != "Win.Test.EICAR_HDB-1" {
+		t.Errorf("Expected the game to be quarantined, got %s %s", game.Status, game.StatusReason)
+	}
+	if len(scanner.Scanned) != 1 || scanner.Scanned[0] != services.BlobName(hash) {
+		t.Errorf("Expected the blob to be scanned, got %v", scanner.Scanned)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: game.ID.String()}
+	if err = k8sClient.Get(context.Background(), key, &streamv1.Game{}); err == nil {
+		t.Errorf("The quarantined game must not be deployed")
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_ScanPending_Should_Deploy_Game_Once_The_Scanner_Is_Available(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	content := "game"
+	hash := sha256Hex(content)
+	// Create database mock, the game stays in the status scanning until the scanner is available again
+	db, dbMock := databaseMock()
+	defer db.Close()
+	expectScannedUpload(dbMock, hash)
+	scanner := &mocks.ScannerMock{Err: errors.New("connection refused")}
+	k8sClient := fakeK8sClient(t)
+	gamesService := scanningGameService(db, mocks.AzureApiMock{Blobs: map[string][]byte{}}, k8sClient, scanner)
+	game, err := gamesService.Save(fileHeader(t, "game.nes", content), models.GameMetadata{Title: "Game"}, "MockOwner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Status != shared.Status_Scanning {
+		t.Fatalf("Expected the status %s, got %s", shared.Status_Scanning, game.Status)
+	}
+	scanner.Err = nil
+	scanner.Result = apis.ScanResult{Clean: true}
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE Status = ? AND DeletedAt IS NULL")).
+		WithArgs(shared.Status_Scanning).
+		WillReturnRows(gameRows(game))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=?, StatusReason=? WHERE ID = ? AND Status = ?")).
+		WithArgs(shared.Status_Installing, "", game.ID, shared.Status_Scanning).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE games SET Status=?, StatusReason=?, Url=?, Cluster=?")).
+		WithArgs(shared.Status_Installing, "", "", "", "default", game.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	err = gamesService.ScanPending()
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanner.Scanned) != 2 {
+		t.Errorf("Expected the blob to be scanned again, got %v", scanner.Scanned)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: game.ID.String()}
+	if err = k8sClient.Get(context.Background(), key, &streamv1.Game{}); err != nil {
+		t.Errorf("The clean game should be deployed: %s", err)
+	}
+	if err = dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// expectScannedUpload expects the queries of an upload of new content up to the scan of the file
+func expectScannedUpload(dbMock sqlmock.Sqlmock, hash string) {
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO blobs")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta("UPDATE blobs SET StorageLocation=? WHERE Hash = ?")).
+		WithArgs(sqlmock.AnyArg(), hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM games WHERE ID = ?")).
+		WillReturnRows(gameRows())
+	dbMock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO games"))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO games")).
+		WithArgs(sqlmock.AnyArg(), "Game", sqlmock.AnyArg(), shared.Status_Scanning, sqlmock.AnyArg(), "MockOwner", "game.nes",
+			services.BlobName(hash), hash, shared.Visibility_Private, "", "", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO game_versions")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+}
+
+// fakeClamd accepts one INSTREAM command, sends the streamed content to received and answers with the reply
+func fakeClamd(t *testing.T, reply string, received chan<- []byte) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		command, _ := reader.ReadString(0)
+		content := []byte{}
+		for command == "zINSTREAM\x00" {
+			var size uint32
+			if binary.Read(reader, binary.BigEndian, &size) != nil || size == 0 {
+				break
+			}
+			chunk := make([]byte, size)
+			if _, err = io.ReadFull(reader, chunk); err != nil {
+				break
+			}
+			content = append(content, chunk...)
+		}
+		_, _ = conn.Write([]byte(reply + "\x00"))
+		received <- content
+	}()
+	return listener.Addr().String()
+}
+
+func scanningGameService(db *sql.DB, azure mocks.AzureApiMock, k8s client.Client, scanner apis.IScannerApi) services.IGameService {
+	blobsService := services.BlobService(repositories.BlobRepository(db), azure, services.ArchiveConfig{})
+	return services.GameService(repositories.GameRepository(db), repositories.GameVersionRepository(db), blobsService,
+		apis.K8sService(k8s, apis.NamespaceConfig{}), services.ScanService(scanner, azure, repositories.BlobRepository(db)))
+}
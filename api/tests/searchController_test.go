@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"api/controllers"
+	"api/dtos"
+	"api/repositories"
+	"api/services"
+	"api/shared"
+	"api/tests/mocks"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func Test_Search_Should_Return_Highlighted_Games(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	caller := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	// Create Models
+	game := mocks.GameMock("A")
+	game.Title = "Super Mario Kart"
+	game.Description = "Race <fast> against Mario and his friends"
+	game.Tags = []string{"racing", "kart"}
+	game.Platform = "snes"
+	game.Visibility = shared.Visibility_Public
+	// Define queries, the user is no member of an organization and the rows contain the score after the columns of the game
+	dbMock.ExpectQuery(regexp.QuoteMeta("FROM organizations JOIN org_members")).
+		WithArgs(caller).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "Name", "CreatedBy", "CreatedAt", "Role"}))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT games.*, MATCH(Title) AGAINST (? IN BOOLEAN MODE)")).
+		WithArgs("+mario* +kar*", "+mario* +kar*", "+mario* +kar*", shared.Visibility_Public, caller, caller, "", 5, 10).
+		WillReturnRows(sqlmock.NewRows(append(gameColumns(), "Score")).
+			AddRow(game.ID, game.Title, game.StorageLocation, game.Status, game.Url, game.Owner, game.FileName,
+				game.Revision, game.BlobName, game.LiveVersion, game.BetaVersion, game.BetaUrl, game.Checksum, game.DeletedAt, game.Visibility,
+				game.Description, strings.Join(game.Tags, ","), game.Platform, game.Cluster, game.Region, game.StatusReason, "", "", 0, 3.5))
+
+	// Finally, create the controller
+	searchController := controllers.SearchController(services.SearchService(repositories.MySQLSearchRepository(db), services.AccessService(repositories.OrganizationRepository(db), repositories.CollaboratorRepository(db))))
+	// Prepare Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("subject", caller)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	c.Request = httptest.NewRequest("GET", "/search?q=Mario+%2Bkar*&limit=5&offset=10", nil)
+	searchController.Search(c)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != 200 {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Fatal(w.Code, string(b))
+	}
+	var results []dtos.SearchResultResponseBody
+	err := json.Unmarshal(w.Body.Bytes(), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Game.ID != game.ID || results[0].Score != 3.5 {
+		t.Fatalf("Unexpected results %+v", results)
+	}
+	expected := map[string]string{
+		"title":       "Super <mark>Mario</mark> <mark>Kart</mark>",
+		"description": "Race &lt;fast&gt; against <mark>Mario</mark> and his friends",
+		"tags":        "racing, <mark>kart</mark>",
+	}
+	for field, highlight := range expected {
+		if results[0].Highlights[field] != highlight {
+			t.Errorf("Expected highlight %q for %s, got %q", highlight, field, results[0].Highlights[field])
+		}
+	}
+	if _, ok := results[0].Highlights["platform"]; ok {
+		t.Errorf("Platform does not match and should not be highlighted")
+	}
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Search_Without_Words_Should_Fail(t *testing.T) {
+	db, dbMock := databaseMock()
+	defer db.Close()
+	searchController := controllers.SearchController(services.SearchService(repositories.MySQLSearchRepository(db), services.AccessService(repositories.OrganizationRepository(db), repositories.CollaboratorRepository(db))))
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/search?q=%2B*+-", nil)
+	searchController.Search(c)
+
+	if w.Code != 400 {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Highlight_Should_Return_Snippet_Around_Match(t *testing.T) {
+	text := strings.Repeat("lorem ipsum ", 20) + "zelda " + strings.Repeat("dolor sit ", 20)
+
+	snippet := shared.Highlight(text, []string{"zel"}, 40)
+
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") {
+		t.Errorf("Snippet should be shortened on both sides: %q", snippet)
+	}
+	if !strings.Contains(snippet, "<mark>zelda</mark>") {
+		t.Errorf("Snippet should contain the match: %q", snippet)
+	}
+	if shared.Highlight(text, []string{"mario"}, 40) != "" {
+		t.Errorf("Text without match should not be highlighted")
+	}
+}
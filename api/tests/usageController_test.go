@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"api/controllers"
+	"api/dtos"
+	"api/middlewares"
+	"api/repositories"
+	"api/services"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Record_Session_Should_Require_Token(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	// Create database mock, nothing must be saved
+	db, dbMock := databaseMock()
+	defer db.Close()
+	r := usageRouter(db, "MockOwner")
+	body := fmt.Sprintf(`{"gameId": "%s", "sessionId": "s1", "startedAt": "2024-01-31T20:00:00Z"}`, uuid.New())
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/usage/sessions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer wrong")
+	r.ServeHTTP(w, req)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Record_Session_Should_Save_Session_Of_Game_Owner(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	gameID := uuid.New()
+	startedAt := time.Date(2024, 1, 31, 20, 0, 0, 0, time.UTC)
+	endedAt := startedAt.Add(30 * time.Minute)
+	// Define queries, the session is saved with the owner of the game and ends at its end
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(gameID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow("MockOwner"))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO play_sessions")).
+		WithArgs(sqlmock.AnyArg(), "s1", gameID, "MockOwner", "player", startedAt, endedAt, endedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	r := usageRouter(db, "MockOwner")
+	body := fmt.Sprintf(`{"gameId": "%s", "sessionId": "s1", "player": "player", "startedAt": "2024-01-31T20:00:00Z", "endedAt": "2024-01-31T20:30:00Z"}`, gameID)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/usage/sessions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer token")
+	r.ServeHTTP(w, req)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != http.StatusNoContent {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Fatal(w.Code, string(b))
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Game_Usage_Should_Split_Sessions_At_Midnight(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	owner := "MockOwner"
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	gameID := uuid.New()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	// Define queries, the first session lasts over midnight and the second one has not been ended yet
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT Owner FROM games WHERE ID = ?")).
+		WithArgs(gameID).
+		WillReturnRows(sqlmock.NewRows([]string{"Owner"}).AddRow(owner))
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM play_sessions WHERE GameID = ? AND StartedAt < ? AND COALESCE(EndedAt, LastSeenAt) > ?")).
+		WithArgs(gameID, to, from).
+		WillReturnRows(playSessionRows().
+			AddRow(uuid.New(), "s1", gameID, owner, "player", time.Date(2024, 1, 30, 23, 30, 0, 0, time.UTC),
+				time.Date(2024, 1, 31, 0, 45, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 45, 0, 0, time.UTC)).
+			AddRow(uuid.New(), "s2", gameID, owner, nil, time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 31, 10, 15, 0, 0, time.UTC), nil))
+	r := usageRouter(db, owner)
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/games/%s/usage?from=2024-01-01&to=2024-01-31", gameID), nil)
+	r.ServeHTTP(w, req)
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != http.StatusOK {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Fatal(w.Code, string(b))
+	}
+	var usage dtos.GameUsageResponseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &usage); err != nil {
+		t.Fatal(err)
+	}
+	if usage.Minutes != 90 || usage.Sessions != 2 || usage.Players != 1 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+	if len(usage.Days) != 2 || usage.Days[0].Day != "2024-01-30" || usage.Days[0].Minutes != 30 ||
+		usage.Days[1].Day != "2024-01-31" || usage.Days[1].Minutes != 60 || usage.Days[1].Sessions != 2 {
+		t.Errorf("Unexpected days %+v", usage.Days)
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func Test_Usage_Report_Should_Export_Csv_For_Admins(t *testing.T) {
+	//======================= PREPARE	PREPARE		PREPARE		PREPARE =======================
+	// Create database mock
+	db, dbMock := databaseMock()
+	defer db.Close()
+	gameID := uuid.New()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	// Define queries
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM play_sessions WHERE StartedAt < ? AND COALESCE(EndedAt, LastSeenAt) > ?")).
+		WithArgs(to, from).
+		WillReturnRows(playSessionRows().
+			AddRow(uuid.New(), "s1", gameID, "MockOwner", nil, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 1, 12, 20, 0, 0, time.UTC), time.Date(2024, 1, 1, 12, 20, 0, 0, time.UTC)))
+
+	//======================= EXECUTE	EXECUTE		EXECUTE		EXECUTE =======================
+	// Other users must not read the report
+	w := httptest.NewRecorder()
+	usageRouter(db, "MockOwner").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/usage?from=2024-01-01&to=2024-01-01&format=csv", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	w = httptest.NewRecorder()
+	usageRouter(db, "MockAdmin").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/usage?from=2024-01-01&to=2024-01-01&format=csv", nil))
+
+	//======================= VERIFY	VERIFY		VERIFY		VERIFY =======================
+	if w.Code != http.StatusOK {
+		b, _ := ioutil.ReadAll(w.Body)
+		t.Fatal(w.Code, string(b))
+	}
+	expected := fmt.Sprintf("day,owner,game_id,minutes,sessions,players\n2024-01-01,MockOwner,%s,20.00,1,0\n", gameID)
+	if w.Body.String() != expected {
+		t.Errorf("Expected csv %q, got %q", expected, w.Body.String())
+	}
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func playSessionRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"ID", "SessionKey", "GameID", "Owner", "Player", "StartedAt", "LastSeenAt", "EndedAt"})
+}
+
+// usageRouter registers the usage routes like the api does, the report token is "token" and the admin is "MockAdmin"
+func usageRouter(db *sql.DB, subject string) *gin.Engine {
+	gamesRepository := repositories.GameRepository(db)
+	gamesService := services.GameService(gamesRepository, repositories.GameVersionRepository(db), nil, nil, nil)
+	accessService := services.AccessService(repositories.OrganizationRepository(db), repositories.CollaboratorRepository(db))
+	usageService := services.UsageService(gamesRepository, repositories.PlaySessionRepository(db))
+	usageController := controllers.UsageController(usageService, gamesService, accessService)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	authorize := func(c *gin.Context) { c.Set("subject", subject) }
+	r.GET("/games/:id/usage", authorize, usageController.GetGameUsage)
+	r.POST("/usage/sessions", middlewares.TokenMiddleware("token"), usageController.RecordSession)
+	r.GET("/admin/usage", authorize, middlewares.AdminMiddleware([]string{"MockAdmin"}), usageController.GetUsageReport)
+	return r
+}
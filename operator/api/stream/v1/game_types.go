@@ -17,6 +17,8 @@ limitations under the License.
 package v1
 
 import (
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,15 +30,64 @@ type GameSpec struct {
 	// Name of the game
 	Name     string `json:"name"`
 	FileName string `json:"filename"`
+	// Revision of the game file. It is increased whenever the file is replaced or the game is redeployed,
+	// which rolls out new coordinator and worker pods. Changes of the metadata keep the revision.
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+	// Path of the game file inside the game storage. Defaults to the name of the resource.
+	// +optional
+	StoragePath string `json:"storagePath,omitempty"`
+	// Directory is true if StoragePath is a directory with several files, e.g. the cue sheet and the bin tracks
+	// of a disc image. The directory is mounted and FileName is the path of its entry file.
+	// +optional
+	Directory bool `json:"directory,omitempty"`
+	// Bios are the paths of BIOS files inside the directory, which are mounted into the system directory of the emulator.
+	// +optional
+	Bios []string `json:"bios,omitempty"`
+	// Suspend scales the coordinator and worker deployments to zero, the services and the url are kept.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+	// IdleTimeout after which a game without activity is hibernated. It overrides the idle timeout of the operator,
+	// zero disables the hibernation of the game.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
 }
 
+// Phases of a game, which are reported in its status
+const (
+	GamePhaseStarting = "Starting"
+	GamePhaseRunning  = "Running"
+	GamePhaseStopping = "Stopping"
+	GamePhaseStopped  = "Stopped"
+	// GamePhaseHibernated is reported for games which have been scaled to zero because they have been idle
+	GamePhaseHibernated = "Hibernated"
+)
+
+// WakeAnnotation requests to wake a hibernated game. Its value is the time of the request in RFC 3339 format,
+// the game is woken if it is after the time of the hibernation.
+const WakeAnnotation = "stream.indiegamestream.com/wake-requested-at"
+
 // GameStatus defines the observed state of Game
 
 type GameStatus struct {
 	URL string `json:"url"`
+	// Phase is Starting or Running while the game is not suspended, Stopping or Stopped while it is suspended
+	// and Hibernated while it is idle
+	// +optional
+	Phase string `json:"phase,omitempty"`
+	// CoordinatorURL is the url of the coordinator. It differs from URL if the game is served through the wake-up proxy.
+	// +optional
+	CoordinatorURL string `json:"coordinatorUrl,omitempty"`
+	// LastActivityTime is the last time at which players have been seen
+	// +optional
+	LastActivityTime *metav1.Time `json:"lastActivityTime,omitempty"`
+	// HibernatedAt is set while the game is hibernated
+	// +optional
+	HibernatedAt *metav1.Time `json:"hibernatedAt,omitempty"`
 }
 
 //+kubebuilder:printcolumn:name="URL",type="string",JSONPath=`.status.url`
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 
@@ -58,6 +109,15 @@ type GameList struct {
 	Items           []Game `json:"items"`
 }
 
+// WakeRequested returns true if a wake-up of the game has been requested after its hibernation
+func (in *Game) WakeRequested() bool {
+	requestedAt, err := time.Parse(time.RFC3339, in.Annotations[WakeAnnotation])
+	if err != nil {
+		return false
+	}
+	return in.Status.HibernatedAt == nil || requestedAt.After(in.Status.HibernatedAt.Time)
+}
+
 func init() {
 	SchemeBuilder.Register(&Game{}, &GameList{})
 }
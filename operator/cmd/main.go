@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -27,6 +28,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -37,6 +39,7 @@ import (
 	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
 	stunnerv1 "indiegamestream.com/indiegamestream/api/stunner/v1"
 	controller "indiegamestream.com/indiegamestream/internal/controller/stream"
+	"indiegamestream.com/indiegamestream/internal/wakeproxy"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -59,6 +62,14 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var idleTimeout time.Duration
+	var activityCheckInterval time.Duration
+	var idleNetworkThreshold int64
+	var sessionsPath string
+	var wakeProxyAddr string
+	var wakeProxyURL string
+	var biosPath string
+	var usageReportURL string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -68,6 +79,24 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.DurationVar(&idleTimeout, "idle-timeout", 0,
+		"Games without activity are hibernated after this duration, unless the game sets its own idle timeout. "+
+			"Set this to 0 to disable the hibernation.")
+	flag.DurationVar(&activityCheckInterval, "activity-check-interval", time.Minute,
+		"The interval in which the activity of running games is checked.")
+	flag.Int64Var(&idleNetworkThreshold, "idle-network-threshold", 256*1024,
+		"Games whose worker pods transfer less bytes between two activity checks count as idle.")
+	flag.StringVar(&sessionsPath, "coordinator-sessions-path", "",
+		"If set, the coordinators are asked for their active WebRTC sessions at this path in addition to the network activity.")
+	flag.StringVar(&wakeProxyAddr, "wake-proxy-bind-address", "0",
+		"The address the wake-up proxy binds to. Set this to 0 to disable the proxy.")
+	flag.StringVar(&wakeProxyURL, "wake-proxy-url", "",
+		"The public url of the wake-up proxy. If set, the urls of the games point to the proxy, which wakes hibernated games.")
+	flag.StringVar(&biosPath, "bios-path", "/usr/local/share/cloud-game/assets/system",
+		"The system directory of the emulator, into which the BIOS files of games with several files are mounted.")
+	flag.StringVar(&usageReportURL, "usage-report-url", "",
+		"If set, the periods in which games are active are reported as play sessions to this url of the api, "+
+			"e.g. http://api/usage/sessions. The bearer token is read from the environment variable USAGE_REPORT_TOKEN.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -96,7 +125,8 @@ func main() {
 		TLSOpts: tlsOpts,
 	})
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	config := ctrl.GetConfigOrDie()
+	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
@@ -124,15 +154,47 @@ func main() {
 		os.Exit(1)
 	}
 
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		setupLog.Error(err, "unable to create clientset")
+		os.Exit(1)
+	}
+	activity := controller.AnyActivityProbe{&controller.NetworkActivityProbe{
+		Client:     mgr.GetClient(),
+		RESTClient: clientset.CoreV1().RESTClient(),
+		Threshold:  idleNetworkThreshold,
+	}}
+	if sessionsPath != "" {
+		activity = append(activity, controller.SessionActivityProbe{Path: sessionsPath})
+	}
+
+	var usage *controller.UsageReporter
+	if usageReportURL != "" {
+		usage = &controller.UsageReporter{URL: usageReportURL, Token: os.Getenv("USAGE_REPORT_TOKEN")}
+	}
+
 	if err = (&controller.GameReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		IdleTimeout:           idleTimeout,
+		ActivityCheckInterval: activityCheckInterval,
+		Activity:              activity,
+		Usage:                 usage,
+		WakeProxyURL:          wakeProxyURL,
+		BiosPath:              biosPath,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Game")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
+	if wakeProxyAddr != "0" {
+		if err = mgr.Add(&wakeproxy.Server{Client: mgr.GetClient(), BindAddress: wakeProxyAddr}); err != nil {
+			setupLog.Error(err, "unable to set up wake-up proxy")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
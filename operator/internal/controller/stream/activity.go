@@ -0,0 +1,171 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+)
+
+// ActivityProbe reports whether players are using a game
+type ActivityProbe interface {
+	Active(ctx context.Context, game *streamv1.Game) (bool, error)
+}
+
+// AnyActivityProbe reports a game as active if one of its probes does.
+// Probes which fail are skipped, the game is only reported as idle if at least one probe has succeeded.
+type AnyActivityProbe []ActivityProbe
+
+func (p AnyActivityProbe) Active(ctx context.Context, game *streamv1.Game) (bool, error) {
+	var lastErr error
+	succeeded := false
+	for _, probe := range p {
+		active, err := probe.Active(ctx, game)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if active {
+			return true, nil
+		}
+		succeeded = true
+	}
+	if !succeeded && lastErr != nil {
+		return false, lastErr
+	}
+	return false, nil
+}
+
+// SessionActivityProbe asks the coordinator of a game for its active WebRTC sessions.
+// The coordinator must answer the path with a json object like {"sessions": 1}.
+type SessionActivityProbe struct {
+	Path   string
+	Client *http.Client
+}
+
+func (p SessionActivityProbe) Active(ctx context.Context, game *streamv1.Game) (bool, error) {
+	url := fmt.Sprintf("http://%s.%s.svc:80%s", coordinatorServiceName(game), game.Namespace, p.Path)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	httpClient := p.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("coordinator answered the sessions request with %d", response.StatusCode)
+	}
+
+	var body struct {
+		Sessions int `json:"sessions"`
+	}
+	if err = json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return false, err
+	}
+	return body.Sessions > 0, nil
+}
+
+// NetworkActivityProbe compares the network traffic of the worker pods of a game with the previous check.
+// The traffic is read from the summary api of the kubelets, which is reached through the api server.
+// A game is active if its workers have sent and received more than Threshold bytes since the previous check.
+type NetworkActivityProbe struct {
+	Client     client.Client
+	RESTClient rest.Interface
+	Threshold  int64
+
+	//Traffic of the previous check by game
+	samples sync.Map
+}
+
+// kubeletSummary contains the fields of the kubelet summary which are read by the probe
+type kubeletSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Network *struct {
+			RxBytes *int64 `json:"rxBytes"`
+			TxBytes *int64 `json:"txBytes"`
+		} `json:"network"`
+	} `json:"pods"`
+}
+
+func (p *NetworkActivityProbe) Active(ctx context.Context, game *streamv1.Game) (bool, error) {
+	pods := &corev1.PodList{}
+	err := p.Client.List(ctx, pods, client.InNamespace(game.Namespace), client.MatchingLabels{"app": fmt.Sprintf("worker-%s", game.Name)})
+	if err != nil {
+		return false, err
+	}
+
+	//The summary of every node lists all of its pods, so every node is read only once
+	podNames := map[string]bool{}
+	nodes := map[string]bool{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" {
+			podNames[pod.Name] = true
+			nodes[pod.Spec.NodeName] = true
+		}
+	}
+
+	var traffic int64
+	for node := range nodes {
+		raw, err := p.RESTClient.Get().AbsPath("/api/v1/nodes", node, "proxy/stats/summary").DoRaw(ctx)
+		if err != nil {
+			return false, err
+		}
+		summary := kubeletSummary{}
+		if err = json.Unmarshal(raw, &summary); err != nil {
+			return false, err
+		}
+		for _, pod := range summary.Pods {
+			if pod.PodRef.Namespace != game.Namespace || !podNames[pod.PodRef.Name] || pod.Network == nil {
+				continue
+			}
+			if pod.Network.RxBytes != nil {
+				traffic += *pod.Network.RxBytes
+			}
+			if pod.Network.TxBytes != nil {
+				traffic += *pod.Network.TxBytes
+			}
+		}
+	}
+
+	//Without a previous check or after a restart of the pods the traffic can't be compared, so the game counts as active
+	key := game.Namespace + "/" + game.Name
+	previous, found := p.samples.Swap(key, traffic)
+	if !found || traffic < previous.(int64) {
+		return true, nil
+	}
+	return traffic-previous.(int64) > p.Threshold, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakerest "k8s.io/client-go/rest/fake"
+
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+)
+
+// staticProbe reports the same activity on every check
+type staticProbe struct {
+	active bool
+	err    error
+	checks int
+}
+
+func (p *staticProbe) Active(_ context.Context, _ *streamv1.Game) (bool, error) {
+	p.checks++
+	return p.active, p.err
+}
+
+var _ = Describe("Activity Probes", func() {
+	ctx := context.Background()
+	game := &streamv1.Game{ObjectMeta: metav1.ObjectMeta{Name: "2a7c6c52-8f3b-4bb1-9d1c-2f6c1a3e9b10", Namespace: "default"}}
+
+	Context("AnyActivityProbe", func() {
+		It("should report a game as active if one probe does", func() {
+			probe := AnyActivityProbe{&staticProbe{err: errors.New("unreachable")}, &staticProbe{active: true}}
+			Expect(probe.Active(ctx, game)).To(BeTrue())
+		})
+
+		It("should skip failed probes if another probe has succeeded", func() {
+			probe := AnyActivityProbe{&staticProbe{err: errors.New("unreachable")}, &staticProbe{}}
+			Expect(probe.Active(ctx, game)).To(BeFalse())
+		})
+
+		It("should fail if all probes fail", func() {
+			probe := AnyActivityProbe{&staticProbe{err: errors.New("unreachable")}, &staticProbe{err: errors.New("timeout")}}
+			_, err := probe.Active(ctx, game)
+			Expect(err).To(MatchError("timeout"))
+		})
+	})
+
+	Context("NetworkActivityProbe", func() {
+		var traffic int64
+		var probe *NetworkActivityProbe
+
+		BeforeEach(func() {
+			traffic = 0
+			worker := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Namespace: game.Namespace, Labels: map[string]string{"app": "worker-" + game.Name}},
+				Spec:       corev1.PodSpec{NodeName: "node-1"},
+			}
+			//Pods of other games on the same node are not counted
+			other := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-2", Namespace: game.Namespace, Labels: map[string]string{"app": "worker-other"}},
+				Spec:       corev1.PodSpec{NodeName: "node-1"},
+			}
+			restClient := &fakerest.RESTClient{
+				NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+				Client: fakerest.CreateHTTPClient(func(request *http.Request) (*http.Response, error) {
+					Expect(request.URL.Path).To(Equal("/api/v1/nodes/node-1/proxy/stats/summary"))
+					body := fmt.Sprintf(`{"pods": [
+						{"podRef": {"name": "worker-1", "namespace": "default"}, "network": {"rxBytes": %d, "txBytes": %d}},
+						{"podRef": {"name": "worker-2", "namespace": "default"}, "network": {"rxBytes": 1000000, "txBytes": 1000000}}
+					]}`, traffic, traffic)
+					return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+				}),
+			}
+			probe = &NetworkActivityProbe{Client: newFakeClient(worker, other), RESTClient: restClient, Threshold: 1000}
+		})
+
+		It("should compare the traffic of the workers with the previous check", func() {
+			traffic = 5000
+			By("counting the first check as active, there is nothing to compare")
+			Expect(probe.Active(ctx, game)).To(BeTrue())
+
+			By("reporting less traffic than the threshold as idle")
+			traffic += 200
+			Expect(probe.Active(ctx, game)).To(BeFalse())
+
+			By("reporting more traffic than the threshold as active")
+			traffic += 1000
+			Expect(probe.Active(ctx, game)).To(BeTrue())
+		})
+
+		It("should count the game as active if the counters have been reset by a restart of the pods", func() {
+			traffic = 5000
+			Expect(probe.Active(ctx, game)).To(BeTrue())
+			traffic = 100
+			Expect(probe.Active(ctx, game)).To(BeTrue())
+			traffic = 200
+			Expect(probe.Active(ctx, game)).To(BeFalse())
+		})
+	})
+})
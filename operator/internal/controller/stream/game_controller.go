@@ -19,6 +19,8 @@ package controller
 import (
 	"context"
 	"fmt"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,11 +29,14 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
 	stunnerv1 "indiegamestream.com/indiegamestream/api/stunner/v1"
+	"indiegamestream.com/indiegamestream/internal/wakeproxy"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -42,6 +47,19 @@ import (
 type GameReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// IdleTimeout after which games without activity are hibernated, zero disables the hibernation.
+	// It is overridden by the idle timeout of a game.
+	IdleTimeout time.Duration
+	// ActivityCheckInterval is the interval in which the activity of running games is checked
+	ActivityCheckInterval time.Duration
+	// Activity reports whether a game is used
+	Activity ActivityProbe
+	// Usage reports the periods in which games are active as play sessions, nothing is reported if it is nil
+	Usage *UsageReporter
+	// WakeProxyURL is the public url of the wake-up proxy. If it is set, the urls of the games point to the proxy.
+	WakeProxyURL string
+	// BiosPath is the system directory of the emulator, into which the BIOS files of the games are mounted
+	BiosPath string
 }
 
 //+kubebuilder:rbac:groups=stream.indiegamestream.com,resources=games,verbs=get;list;watch;create;update;patch;delete
@@ -52,6 +70,7 @@ type GameReconciler struct {
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;
+//+kubebuilder:rbac:groups=core,resources=nodes/proxy,verbs=get
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -79,7 +98,7 @@ func (r *GameReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, err
 	}
 
-	log.Info("Reconciling Game", "Name", game.Spec.Name, "FileName", game.Spec.FileName)
+	log.Info("Reconciling Game", "Name", game.Spec.Name, "FileName", game.Spec.FileName, "Directory", game.Spec.Directory)
 
 	// name of our custom finalizer
 	gameFinalizer := "game.stream.indiegamestream.com/finalizer"
@@ -101,6 +120,9 @@ func (r *GameReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		if controllerutil.ContainsFinalizer(game, gameFinalizer) {
 			// our finalizer is present, so lets handle any external dependency
 			log.Info("Game is being deleted", "Name", game.Name)
+			if r.Usage != nil {
+				r.Usage.Forget(game)
+			}
 
 			if err := r.deleteExternalResources(ctx, game); err != nil {
 				log.Error(err, "Error deleting external ressources")
@@ -121,12 +143,27 @@ func (r *GameReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, nil
 	}
 	workerName := fmt.Sprintf("worker-lb-svc-%s", game.Name)
-	coordinatorName := fmt.Sprintf("coordinator-lb-svc-%s", game.Name)
+	coordinatorName := coordinatorServiceName(game)
 	udpRouteName := fmt.Sprintf("udproute-%s", game.Name)
 	deploymentCoordName := fmt.Sprintf("deployment-coord-%s", game.Name)
 	deploymentWorkerName := fmt.Sprintf("deployment-worker-%s", game.Name)
 	workerUDPName := fmt.Sprintf("worker-ci-udp-svc-%s", game.Name)
 
+	// Suspended games keep their services and their url, only the deployments are scaled to zero
+	if game.Spec.Suspend {
+		return r.suspend(ctx, game, streamv1.GamePhaseStopped, deploymentCoordName, deploymentWorkerName)
+	}
+
+	// Hibernated games stay scaled to zero until a visitor of their url requests a wake-up
+	if game.Status.HibernatedAt != nil {
+		if !game.WakeRequested() {
+			return r.suspend(ctx, game, streamv1.GamePhaseHibernated, deploymentCoordName, deploymentWorkerName)
+		}
+		log.Info("Waking hibernated game", "Name", game.Name)
+		game.Status.HibernatedAt = nil
+		game.Status.LastActivityTime = nil
+	}
+
 	result, err := r.ensureResource(ctx, game, "UDPRoute", udpRouteName, game.Namespace, workerUDPName)
 	if err != nil {
 		return result, err
@@ -203,14 +240,27 @@ func (r *GameReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, err
 	}
 
-	game.Status.URL = fmt.Sprintf("http://%s", outsidehostname)
+	game.Status.CoordinatorURL = fmt.Sprintf("http://%s", outsidehostname)
+	game.Status.URL = game.Status.CoordinatorURL
+	if r.WakeProxyURL != "" {
+		game.Status.URL = wakeproxy.GameURL(r.WakeProxyURL, game)
+	}
 	//TODO: Add nginx ingress url to status
+	game.Status.Phase, err = r.runningPhase(ctx, game.Namespace, deploymentCoordName, deploymentWorkerName)
+	if err != nil {
+		log.Error(err, "unable to read the deployments of the Game")
+		return ctrl.Result{}, err
+	}
+	requeueAfter, hibernate := r.checkActivity(ctx, game)
 	if err := r.Status().Update(ctx, game); err != nil {
 		log.Error(err, "unable to update Game status")
 		return ctrl.Result{}, err
 	}
+	if hibernate {
+		return r.suspend(ctx, game, streamv1.GamePhaseHibernated, deploymentCoordName, deploymentWorkerName)
+	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *GameReconciler) ensureResource(ctx context.Context, game *streamv1.Game, resourceType string, resourceName string, resourceNamespace string, args ...interface{}) (ctrl.Result, error) {
@@ -323,13 +373,125 @@ func (r *GameReconciler) ensureResource(ctx context.Context, game *streamv1.Game
 	} else if err != nil {
 		log.Error(err, "unable to get resource for Game", "game", game)
 		return ctrl.Result{}, err
-	} else {
-		// TODO: handle updates
+	} else if existing, ok := resource.(*appsv1.Deployment); ok {
+		// Deployments are updated if the game spec has changed, e.g. when the game file has been replaced
+		desired, err := constructFunc(game, resourceName, args...)
+		if err != nil {
+			log.Error(err, "unable to construct resource", "type", resourceType)
+			return ctrl.Result{}, err
+		}
+		if updateDeployment(existing, desired.(*appsv1.Deployment)) {
+			log.Info("Updating resource", "type", resourceType, "namespace", existing.GetNamespace(), "name", existing.GetName())
+			if err = r.Update(ctx, existing); err != nil {
+				log.Error(err, "unable to update resource for Game", "game", game)
+				return ctrl.Result{}, err
+			}
+		}
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// suspend scales the deployments of a game to zero and reports Stopping until all pods are gone,
+// afterwards the given phase is reported. Changes of the owned deployments trigger the next reconciliation.
+func (r *GameReconciler) suspend(ctx context.Context, game *streamv1.Game, suspendedPhase string, deploymentNames ...string) (ctrl.Result, error) {
+	var log = log.FromContext(ctx)
+
+	phase := suspendedPhase
+	for _, name := range deploymentNames {
+		deployment := &appsv1.Deployment{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: game.Namespace, Name: name}, deployment)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			log.Error(err, "unable to get resource for Game", "game", game)
+			return ctrl.Result{}, err
+		}
+
+		if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 0 {
+			log.Info("Scaling deployment to zero", "namespace", deployment.Namespace, "name", deployment.Name)
+			deployment.Spec.Replicas = int32Ptr(0)
+			if err = r.Update(ctx, deployment); err != nil {
+				log.Error(err, "unable to update resource for Game", "game", game)
+				return ctrl.Result{}, err
+			}
+		}
+		if deployment.Status.Replicas > 0 {
+			phase = streamv1.GamePhaseStopping
+		}
+	}
+
+	// Stopped games are started without waiting for a wake-up
+	wasHibernated := suspendedPhase == streamv1.GamePhaseStopped && game.Status.HibernatedAt != nil
+	if game.Status.Phase != phase || wasHibernated {
+		game.Status.Phase = phase
+		if wasHibernated {
+			game.Status.HibernatedAt = nil
+		}
+		if err := r.Status().Update(ctx, game); err != nil {
+			log.Error(err, "unable to update Game status")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// runningPhase returns Running if all pods of the deployments are ready and Starting otherwise.
+func (r *GameReconciler) runningPhase(ctx context.Context, namespace string, deploymentNames ...string) (string, error) {
+	for _, name := range deploymentNames {
+		deployment := &appsv1.Deployment{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, deployment)
+		if err != nil {
+			return "", err
+		}
+		if deployment.Spec.Replicas != nil && deployment.Status.ReadyReplicas < *deployment.Spec.Replicas {
+			return streamv1.GamePhaseStarting, nil
+		}
+	}
+	return streamv1.GamePhaseRunning, nil
+}
+
+// updateDeployment copies the fields which are derived from the game spec from desired to existing.
+// It returns true if existing has been changed and must be updated.
+func updateDeployment(existing *appsv1.Deployment, desired *appsv1.Deployment) bool {
+	changed := false
+
+	// Resumed games are scaled up again
+	if desired.Spec.Replicas != nil && (existing.Spec.Replicas == nil || *existing.Spec.Replicas != *desired.Spec.Replicas) {
+		existing.Spec.Replicas = int32Ptr(*desired.Spec.Replicas)
+		changed = true
+	}
+
+	if existing.Spec.Template.Annotations[revisionAnnotation] != desired.Spec.Template.Annotations[revisionAnnotation] {
+		if existing.Spec.Template.Annotations == nil {
+			existing.Spec.Template.Annotations = map[string]string{}
+		}
+		existing.Spec.Template.Annotations[revisionAnnotation] = desired.Spec.Template.Annotations[revisionAnnotation]
+		changed = true
+	}
+
+	existingContainers := existing.Spec.Template.Spec.Containers
+	desiredContainers := desired.Spec.Template.Spec.Containers
+	if len(existingContainers) == len(desiredContainers) {
+		for i := range existingContainers {
+			if len(existingContainers[i].VolumeMounts) != len(desiredContainers[i].VolumeMounts) {
+				existingContainers[i].VolumeMounts = desiredContainers[i].VolumeMounts
+				changed = true
+				continue
+			}
+			for j := range existingContainers[i].VolumeMounts {
+				if existingContainers[i].VolumeMounts[j] != desiredContainers[i].VolumeMounts[j] {
+					existingContainers[i].VolumeMounts = desiredContainers[i].VolumeMounts
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return changed
+}
+
 func (r *GameReconciler) deleteExternalResources(ctx context.Context, game *streamv1.Game) error {
 	//manually delete udproute
 
@@ -381,12 +543,76 @@ func (r *GameReconciler) constructUDPRouteForGame(game *streamv1.Game, resourceN
 
 	return udpRoute, nil
 }
+
+// coordinatorServiceName returns the name of the load balancer of the coordinator of a game
+func coordinatorServiceName(game *streamv1.Game) string {
+	return fmt.Sprintf("coordinator-lb-svc-%s", game.Name)
+}
+
+// revisionAnnotation is set on the pod templates, so changing the revision of a game rolls out new pods
+const revisionAnnotation = "stream.indiegamestream.com/revision"
+
+// storagePath returns the path of the game file inside the game storage
+func storagePath(game *streamv1.Game) string {
+	if game.Spec.StoragePath != "" {
+		return game.Spec.StoragePath
+	}
+	return game.Name
+}
+
+// gamesPath is the directory of the games of the emulator
+const gamesPath = "/usr/local/share/cloud-game/assets/games"
+
+// gameVolumeMounts mounts the game file from the game storage into the games directory of the emulator.
+// A game with several files is mounted as directory next to the other games, its BIOS files are mounted into the system directory.
+// Its entry is the only file which is listed as game, see gameEnv.
+func (r *GameReconciler) gameVolumeMounts(game *streamv1.Game) []corev1.VolumeMount {
+	if !game.Spec.Directory {
+		return []corev1.VolumeMount{
+			{
+				Name:      "gamestorage",
+				MountPath: path.Join(gamesPath, game.Spec.FileName),
+				SubPath:   storagePath(game),
+			},
+		}
+	}
+
+	mounts := []corev1.VolumeMount{
+		{
+			Name:      "gamestorage",
+			MountPath: path.Join(gamesPath, game.Name),
+			SubPath:   storagePath(game),
+		},
+	}
+	for _, bios := range game.Spec.Bios {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "gamestorage",
+			MountPath: path.Join(r.BiosPath, path.Base(bios)),
+			SubPath:   path.Join(storagePath(game), bios),
+		})
+	}
+	return mounts
+}
+
+// gameEnv configures the library of the emulator for the game. The library of a game with several files only lists
+// the files with the extension of its entry, so the tracks, discs and BIOS files of the directory are not listed as games.
+func gameEnv(game *streamv1.Game) []corev1.EnvVar {
+	if !game.Spec.Directory {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  "CLOUD_GAME_LIBRARY_SUPPORTED",
+			Value: strings.ToLower(strings.TrimPrefix(path.Ext(game.Spec.FileName), ".")),
+		},
+	}
+}
+
 func int32Ptr(i int32) *int32 {
 	return &i
 }
 
 func (r *GameReconciler) constructControllerDeploymentForGame(game *streamv1.Game, resourceName string, gatewayConfig *stunnerv1.GatewayConfig, gatewayIP string) (*appsv1.Deployment, error) {
-	fullpath := fmt.Sprintf("/usr/local/share/cloud-game/assets/games/%s", game.Spec.FileName)
 	newSelector := fmt.Sprintf("%s-%s", "coordinator", game.Name)
 
 	dep := &appsv1.Deployment{
@@ -401,7 +627,8 @@ func (r *GameReconciler) constructControllerDeploymentForGame(game *streamv1.Gam
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{"app": newSelector},
+					Labels:      map[string]string{"app": newSelector},
+					Annotations: map[string]string{revisionAnnotation: strconv.FormatInt(game.Spec.Revision, 10)},
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
@@ -415,7 +642,7 @@ func (r *GameReconciler) constructControllerDeploymentForGame(game *streamv1.Gam
 									ContainerPort: 8000,
 								},
 							},
-							Env: []corev1.EnvVar{
+							Env: append([]corev1.EnvVar{
 								{
 									Name:  "CLOUD_GAME_WEBRTC_ICESERVERS_0_CREDENTIAL",
 									Value: gatewayConfig.Spec.Password,
@@ -440,14 +667,8 @@ func (r *GameReconciler) constructControllerDeploymentForGame(game *streamv1.Gam
 									Name:  "CLOUD_GAME_WEBRTC_ICESERVERS_1_USERNAME",
 									Value: gatewayConfig.Spec.UserName,
 								},
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "gamestorage",
-									MountPath: fullpath,
-									SubPath:   game.Name,
-								},
-							},
+							}, gameEnv(game)...),
+							VolumeMounts: r.gameVolumeMounts(game),
 						},
 					},
 					Volumes: []corev1.Volume{
@@ -473,7 +694,6 @@ func (r *GameReconciler) constructControllerDeploymentForGame(game *streamv1.Gam
 }
 
 func (r *GameReconciler) constructWorkerDeploymentForGame(game *streamv1.Game, resourceName string, coordIP string, workerIP string) (*appsv1.Deployment, error) {
-	fullpath := fmt.Sprintf("/usr/local/share/cloud-game/assets/games/%s", game.Spec.FileName)
 	newSelector := fmt.Sprintf("%s-%s", "worker", game.Name)
 	dep := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -487,7 +707,8 @@ func (r *GameReconciler) constructWorkerDeploymentForGame(game *streamv1.Game, r
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{"app": newSelector},
+					Labels:      map[string]string{"app": newSelector},
+					Annotations: map[string]string{revisionAnnotation: strconv.FormatInt(game.Spec.Revision, 10)},
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
@@ -501,7 +722,7 @@ func (r *GameReconciler) constructWorkerDeploymentForGame(game *streamv1.Game, r
 									ContainerPort: 8443,
 								},
 							},
-							Env: []corev1.EnvVar{
+							Env: append([]corev1.EnvVar{
 								{
 									Name:  "CLOUD_GAME_EMULATOR_AUTOSAVESEC",
 									Value: "3",
@@ -514,14 +735,8 @@ func (r *GameReconciler) constructWorkerDeploymentForGame(game *streamv1.Game, r
 									Name:  "CLOUD_GAME_WORKER_NETWORK_PUBLICADDRESS",
 									Value: workerIP,
 								},
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "gamestorage",
-									MountPath: fullpath,
-									SubPath:   game.Name,
-								},
-							},
+							}, gameEnv(game)...),
+							VolumeMounts: r.gameVolumeMounts(game),
 						},
 					},
 					Volumes: []corev1.Volume{
@@ -700,8 +915,9 @@ func (r *GameReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	// Updates of the status don't trigger a reconciliation, otherwise every activity check would trigger the next one
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&streamv1.Game{}).
+		For(&streamv1.Game{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
 		//Owns(&stunnerv1.UDPRoute{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
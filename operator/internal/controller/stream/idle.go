@@ -0,0 +1,90 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+)
+
+// defaultActivityCheckInterval is used if no interval has been configured
+const defaultActivityCheckInterval = time.Minute
+
+// idleTimeout returns the idle timeout of the game, which defaults to the idle timeout of the operator
+func (r *GameReconciler) idleTimeout(game *streamv1.Game) time.Duration {
+	if game.Spec.IdleTimeout != nil {
+		return game.Spec.IdleTimeout.Duration
+	}
+	return r.IdleTimeout
+}
+
+// checkActivity updates the last activity of a game, which is not suspended, and reports it to the usage api.
+// It returns true if the game has been idle for longer than its idle timeout and must be hibernated,
+// otherwise the time after which the activity must be checked again.
+func (r *GameReconciler) checkActivity(ctx context.Context, game *streamv1.Game) (time.Duration, bool) {
+	var log = log.FromContext(ctx)
+
+	timeout := r.idleTimeout(game)
+	if r.Activity == nil || (timeout <= 0 && r.Usage == nil) {
+		return 0, false
+	}
+	interval := r.ActivityCheckInterval
+	if interval <= 0 {
+		interval = defaultActivityCheckInterval
+	}
+
+	now := time.Now()
+	//Games which are starting can't be used yet, so the idle time starts once they are running
+	if game.Status.Phase != streamv1.GamePhaseRunning || game.Status.LastActivityTime == nil {
+		game.Status.LastActivityTime = &metav1.Time{Time: now}
+		return interval, false
+	}
+
+	active, err := r.Activity.Active(ctx, game)
+	if err != nil {
+		//Games are not hibernated if their activity is unknown
+		log.Error(err, "unable to read the activity of the Game")
+		active = true
+	} else if r.Usage != nil {
+		//A session which has not been seen for two checks has been interrupted, e.g. by a restart of the operator
+		if err = r.Usage.Report(ctx, game, active, now, 2*interval); err != nil {
+			log.Error(err, "unable to report the usage of the Game")
+		}
+	}
+	if active {
+		game.Status.LastActivityTime = &metav1.Time{Time: now}
+	}
+	if timeout <= 0 {
+		return interval, false
+	}
+
+	idle := now.Sub(game.Status.LastActivityTime.Time)
+	if idle >= timeout {
+		log.Info("Hibernating idle game", "idle", idle.String())
+		game.Status.HibernatedAt = &metav1.Time{Time: now}
+		return 0, true
+	}
+	if timeout-idle < interval {
+		return timeout - idle, false
+	}
+	return interval, false
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+)
+
+// newFakeClient returns an in-memory client with the given objects, which does not need envtest
+func newFakeClient(objects ...client.Object) client.Client {
+	fakeScheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(fakeScheme)).To(Succeed())
+	Expect(streamv1.AddToScheme(fakeScheme)).To(Succeed())
+	return fake.NewClientBuilder().WithScheme(fakeScheme).WithStatusSubresource(&streamv1.Game{}, &appsv1.Deployment{}).WithObjects(objects...).Build()
+}
+
+// runningGame returns a running game, whose last activity has been the given time ago
+func runningGame(idle time.Duration) *streamv1.Game {
+	return &streamv1.Game{
+		ObjectMeta: metav1.ObjectMeta{Name: "2a7c6c52-8f3b-4bb1-9d1c-2f6c1a3e9b10", Namespace: "default"},
+		Status: streamv1.GameStatus{
+			Phase:            streamv1.GamePhaseRunning,
+			LastActivityTime: &metav1.Time{Time: time.Now().Add(-idle)},
+		},
+	}
+}
+
+var _ = Describe("Idle Games", func() {
+	ctx := context.Background()
+
+	Context("checkActivity", func() {
+		It("should hibernate a game which has been idle for longer than its timeout", func() {
+			reconciler := &GameReconciler{IdleTimeout: 5 * time.Minute, Activity: &staticProbe{}}
+			game := runningGame(10 * time.Minute)
+
+			requeueAfter, hibernate := reconciler.checkActivity(ctx, game)
+			Expect(hibernate).To(BeTrue())
+			Expect(requeueAfter).To(BeZero())
+			Expect(game.Status.HibernatedAt).NotTo(BeNil())
+		})
+
+		It("should prefer the idle timeout of the game", func() {
+			reconciler := &GameReconciler{IdleTimeout: 5 * time.Minute, Activity: &staticProbe{}}
+			game := runningGame(10 * time.Minute)
+			game.Spec.IdleTimeout = &metav1.Duration{Duration: time.Hour}
+
+			_, hibernate := reconciler.checkActivity(ctx, game)
+			Expect(hibernate).To(BeFalse())
+		})
+
+		It("should not hibernate a game whose activity is unknown", func() {
+			reconciler := &GameReconciler{IdleTimeout: 5 * time.Minute, Activity: &staticProbe{err: errors.New("unreachable")}}
+			game := runningGame(10 * time.Minute)
+
+			_, hibernate := reconciler.checkActivity(ctx, game)
+			Expect(hibernate).To(BeFalse())
+			Expect(game.Status.LastActivityTime.Time).To(BeTemporally("~", time.Now(), time.Second))
+			Expect(game.Status.HibernatedAt).To(BeNil())
+		})
+
+		It("should check the activity again after the interval or when the timeout is reached", func() {
+			reconciler := &GameReconciler{IdleTimeout: 5 * time.Minute, ActivityCheckInterval: 2 * time.Minute, Activity: &staticProbe{}}
+
+			requeueAfter, hibernate := reconciler.checkActivity(ctx, runningGame(time.Minute))
+			Expect(hibernate).To(BeFalse())
+			Expect(requeueAfter).To(Equal(2 * time.Minute))
+
+			requeueAfter, hibernate = reconciler.checkActivity(ctx, runningGame(4*time.Minute))
+			Expect(hibernate).To(BeFalse())
+			Expect(requeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+		})
+
+		It("should start the idle time when a game is running", func() {
+			probe := &staticProbe{}
+			reconciler := &GameReconciler{IdleTimeout: 5 * time.Minute, Activity: probe}
+			game := runningGame(10 * time.Minute)
+			game.Status.Phase = streamv1.GamePhaseStarting
+
+			requeueAfter, hibernate := reconciler.checkActivity(ctx, game)
+			Expect(hibernate).To(BeFalse())
+			Expect(requeueAfter).To(Equal(defaultActivityCheckInterval))
+			Expect(probe.checks).To(BeZero())
+			Expect(game.Status.LastActivityTime.Time).To(BeTemporally("~", time.Now(), time.Second))
+		})
+
+		It("should not check the activity if the hibernation and the usage reports are disabled", func() {
+			probe := &staticProbe{}
+			reconciler := &GameReconciler{Activity: probe}
+
+			requeueAfter, hibernate := reconciler.checkActivity(ctx, runningGame(10*time.Minute))
+			Expect(hibernate).To(BeFalse())
+			Expect(requeueAfter).To(BeZero())
+			Expect(probe.checks).To(BeZero())
+		})
+	})
+
+	Context("WakeRequested", func() {
+		hibernatedAt := time.Date(2024, 1, 31, 20, 0, 0, 0, time.UTC)
+
+		It("should only report wake-ups which have been requested after the hibernation", func() {
+			game := &streamv1.Game{Status: streamv1.GameStatus{HibernatedAt: &metav1.Time{Time: hibernatedAt}}}
+			Expect(game.WakeRequested()).To(BeFalse())
+
+			game.Annotations = map[string]string{streamv1.WakeAnnotation: hibernatedAt.Add(-time.Minute).Format(time.RFC3339)}
+			Expect(game.WakeRequested()).To(BeFalse())
+
+			game.Annotations[streamv1.WakeAnnotation] = hibernatedAt.Add(time.Minute).Format(time.RFC3339)
+			Expect(game.WakeRequested()).To(BeTrue())
+
+			game.Annotations[streamv1.WakeAnnotation] = "invalid"
+			Expect(game.WakeRequested()).To(BeFalse())
+		})
+	})
+
+	Context("Reconcile of suspended games", func() {
+		const gameName = "2a7c6c52-8f3b-4bb1-9d1c-2f6c1a3e9b10"
+
+		// deployment returns a running deployment of the game
+		deployment := func(name string) *appsv1.Deployment {
+			return &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: int32Ptr(1),
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+					Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}}},
+				},
+				Status: appsv1.DeploymentStatus{Replicas: 1, ReadyReplicas: 1},
+			}
+		}
+
+		// reconcileGame reconciles the game and returns its new state
+		reconcileGame := func(k8s client.Client) *streamv1.Game {
+			reconciler := &GameReconciler{Client: k8s, Scheme: k8s.Scheme()}
+			key := client.ObjectKey{Namespace: "default", Name: gameName}
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			game := &streamv1.Game{}
+			Expect(k8s.Get(ctx, key, game)).To(Succeed())
+			return game
+		}
+
+		// scaledDown stops the pods of the deployments, like the deployment controller does
+		scaledDown := func(k8s client.Client, names ...string) {
+			for _, name := range names {
+				existing := &appsv1.Deployment{}
+				Expect(k8s.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, existing)).To(Succeed())
+				Expect(*existing.Spec.Replicas).To(BeZero())
+				existing.Status = appsv1.DeploymentStatus{}
+				Expect(k8s.Status().Update(ctx, existing)).To(Succeed())
+			}
+		}
+
+		var game *streamv1.Game
+		var coordinator, worker *appsv1.Deployment
+
+		BeforeEach(func() {
+			game = &streamv1.Game{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: gameName, Namespace: "default",
+					Finalizers: []string{"game.stream.indiegamestream.com/finalizer"},
+				},
+				Status: streamv1.GameStatus{Phase: streamv1.GamePhaseRunning, URL: "http://coordinator"},
+			}
+			coordinator = deployment("deployment-coord-" + gameName)
+			worker = deployment("deployment-worker-" + gameName)
+		})
+
+		It("should scale a stopped game to zero and keep its url", func() {
+			game.Spec.Suspend = true
+			k8s := newFakeClient(game, coordinator, worker)
+
+			Expect(reconcileGame(k8s).Status.Phase).To(Equal(streamv1.GamePhaseStopping))
+			scaledDown(k8s, coordinator.Name, worker.Name)
+			stopped := reconcileGame(k8s)
+			Expect(stopped.Status.Phase).To(Equal(streamv1.GamePhaseStopped))
+			Expect(stopped.Status.URL).To(Equal("http://coordinator"))
+		})
+
+		It("should not wait for a wake-up if a hibernated game is stopped", func() {
+			game.Spec.Suspend = true
+			game.Status.HibernatedAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+			game.Status.Phase = streamv1.GamePhaseHibernated
+			coordinator.Spec.Replicas, coordinator.Status = int32Ptr(0), appsv1.DeploymentStatus{}
+			worker.Spec.Replicas, worker.Status = int32Ptr(0), appsv1.DeploymentStatus{}
+			k8s := newFakeClient(game, coordinator, worker)
+
+			stopped := reconcileGame(k8s)
+			Expect(stopped.Status.Phase).To(Equal(streamv1.GamePhaseStopped))
+			Expect(stopped.Status.HibernatedAt).To(BeNil())
+		})
+
+		It("should keep a hibernated game scaled to zero until a wake-up is requested", func() {
+			game.Status.HibernatedAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+			k8s := newFakeClient(game, coordinator, worker)
+
+			Expect(reconcileGame(k8s).Status.Phase).To(Equal(streamv1.GamePhaseStopping))
+			scaledDown(k8s, coordinator.Name, worker.Name)
+			Expect(reconcileGame(k8s).Status.Phase).To(Equal(streamv1.GamePhaseHibernated))
+		})
+	})
+
+	Context("gameVolumeMounts", func() {
+		reconciler := &GameReconciler{BiosPath: "/usr/local/share/cloud-game/assets/system"}
+
+		It("should mount a single file into the games directory", func() {
+			game := &streamv1.Game{
+				ObjectMeta: metav1.ObjectMeta{Name: "2a7c6c52-8f3b-4bb1-9d1c-2f6c1a3e9b10"},
+				Spec:       streamv1.GameSpec{FileName: "game.nes", StoragePath: "sha256/abc"},
+			}
+			Expect(reconciler.gameVolumeMounts(game)).To(Equal([]corev1.VolumeMount{
+				{Name: "gamestorage", MountPath: gamesPath + "/game.nes", SubPath: "sha256/abc"},
+			}))
+		})
+
+		It("should mount a bundle as directory and its BIOS files into the system directory", func() {
+			game := &streamv1.Game{
+				ObjectMeta: metav1.ObjectMeta{Name: "2a7c6c52-8f3b-4bb1-9d1c-2f6c1a3e9b10"},
+				Spec: streamv1.GameSpec{
+					FileName:    "disc.cue",
+					StoragePath: "bundles/abc",
+					Directory:   true,
+					Bios:        []string{"bios/scph5501.bin"},
+				},
+			}
+			Expect(reconciler.gameVolumeMounts(game)).To(Equal([]corev1.VolumeMount{
+				{Name: "gamestorage", MountPath: gamesPath + "/" + game.Name, SubPath: "bundles/abc"},
+				{Name: "gamestorage", MountPath: "/usr/local/share/cloud-game/assets/system/scph5501.bin", SubPath: "bundles/abc/bios/scph5501.bin"},
+			}))
+		})
+
+		It("should only list the entry of a bundle as game", func() {
+			game := &streamv1.Game{
+				ObjectMeta: metav1.ObjectMeta{Name: "2a7c6c52-8f3b-4bb1-9d1c-2f6c1a3e9b10"},
+				Spec:       streamv1.GameSpec{FileName: "discs/Game.CUE", StoragePath: "bundles/abc", Directory: true},
+			}
+			Expect(gameEnv(game)).To(Equal([]corev1.EnvVar{{Name: "CLOUD_GAME_LIBRARY_SUPPORTED", Value: "cue"}}))
+
+			game.Spec.Directory = false
+			Expect(gameEnv(game)).To(BeEmpty())
+		})
+	})
+})
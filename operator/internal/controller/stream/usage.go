@@ -0,0 +1,137 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+)
+
+// UsageReporter reports the periods in which a game is active as play sessions to the api.
+// A session starts with the first activity check which finds the game active and ends with the first check which finds it idle.
+// Every report contains the whole session, so a report which failed is repaired by the next one.
+type UsageReporter struct {
+	// URL of the session reports of the api, e.g. http://api/usage/sessions
+	URL string
+	// Token is sent as bearer token, it is the USAGE_REPORT_TOKEN of the api
+	Token  string
+	Client *http.Client
+
+	//Open session by game
+	sessions sync.Map
+}
+
+// betaSuffix is appended to the id of a game by the api to name the game of its beta channel
+const betaSuffix = "-beta"
+
+// usageSession is a session of a game, which has not been ended yet
+type usageSession struct {
+	ID         string
+	StartedAt  time.Time
+	LastSeenAt time.Time
+}
+
+// playSession is the body of a session report, see PlaySessionRequestBody of the api
+type playSession struct {
+	GameID     string     `json:"gameId"`
+	SessionID  string     `json:"sessionId"`
+	StartedAt  time.Time  `json:"startedAt"`
+	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"`
+	EndedAt    *time.Time `json:"endedAt,omitempty"`
+}
+
+// Report reports the activity of a game, which has been checked at now. The game is named after its id in the api.
+// A session whose last check is older than maxGap, e.g. because the game has been stopped in between, is ended at its last check
+// and a new session is started.
+// Beta games, which are named after the id of their game with the suffix -beta, are not reported, because the api only counts the live channel.
+func (u *UsageReporter) Report(ctx context.Context, game *streamv1.Game, active bool, now time.Time, maxGap time.Duration) error {
+	if strings.HasSuffix(game.Name, betaSuffix) {
+		return nil
+	}
+	key := game.Namespace + "/" + game.Name
+	if value, found := u.sessions.Load(key); found {
+		session := value.(usageSession)
+		switch {
+		case active && now.Sub(session.LastSeenAt) <= maxGap:
+			session.LastSeenAt = now
+			u.sessions.Store(key, session)
+			return u.send(ctx, game, session, nil)
+		case active:
+			if err := u.send(ctx, game, session, &session.LastSeenAt); err != nil {
+				return err
+			}
+		default:
+			u.sessions.Delete(key)
+			return u.send(ctx, game, session, &now)
+		}
+	}
+	if !active {
+		return nil
+	}
+
+	session := usageSession{ID: fmt.Sprintf("operator-%d", now.UnixNano()), StartedAt: now, LastSeenAt: now}
+	u.sessions.Store(key, session)
+	return u.send(ctx, game, session, nil)
+}
+
+// Forget removes the open session of a game, which is deleted. The api counts the session until it has been seen the last time.
+func (u *UsageReporter) Forget(game *streamv1.Game) {
+	u.sessions.Delete(game.Namespace + "/" + game.Name)
+}
+
+// send reports a session, which is ended if endedAt is set
+func (u *UsageReporter) send(ctx context.Context, game *streamv1.Game, session usageSession, endedAt *time.Time) error {
+	body, err := json.Marshal(playSession{
+		GameID:     game.Name,
+		SessionID:  session.ID,
+		StartedAt:  session.StartedAt,
+		LastSeenAt: &session.LastSeenAt,
+		EndedAt:    endedAt,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, u.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if u.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+u.Token)
+	}
+	httpClient := u.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("api answered the session report with %d", response.StatusCode)
+	}
+	return nil
+}
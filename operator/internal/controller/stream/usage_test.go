@@ -0,0 +1,109 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+)
+
+var _ = Describe("Usage Reporter", func() {
+	var server *httptest.Server
+	var reports []playSession
+	var mutex sync.Mutex
+
+	ctx := context.Background()
+	game := &streamv1.Game{ObjectMeta: metav1.ObjectMeta{Name: "2a7c6c52-8f3b-4bb1-9d1c-2f6c1a3e9b10", Namespace: "default"}}
+	start := time.Date(2024, 1, 31, 20, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		reports = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer report-token"))
+			report := playSession{}
+			Expect(json.NewDecoder(r.Body).Decode(&report)).To(Succeed())
+			mutex.Lock()
+			reports = append(reports, report)
+			mutex.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should report a session from the first active to the first idle check", func() {
+		reporter := &UsageReporter{URL: server.URL, Token: "report-token"}
+
+		Expect(reporter.Report(ctx, game, false, start, 2*time.Minute)).To(Succeed())
+		Expect(reports).To(BeEmpty())
+
+		Expect(reporter.Report(ctx, game, true, start.Add(time.Minute), 2*time.Minute)).To(Succeed())
+		Expect(reporter.Report(ctx, game, true, start.Add(2*time.Minute), 2*time.Minute)).To(Succeed())
+		Expect(reporter.Report(ctx, game, false, start.Add(3*time.Minute), 2*time.Minute)).To(Succeed())
+
+		Expect(reports).To(HaveLen(3))
+		for _, report := range reports {
+			Expect(report.GameID).To(Equal(game.Name))
+			Expect(report.SessionID).To(Equal(reports[0].SessionID))
+			Expect(report.StartedAt).To(BeTemporally("==", start.Add(time.Minute)))
+		}
+		Expect(reports[1].LastSeenAt).NotTo(BeNil())
+		Expect(*reports[1].LastSeenAt).To(BeTemporally("==", start.Add(2*time.Minute)))
+		Expect(reports[1].EndedAt).To(BeNil())
+		Expect(reports[2].EndedAt).NotTo(BeNil())
+		Expect(*reports[2].EndedAt).To(BeTemporally("==", start.Add(3*time.Minute)))
+	})
+
+	It("should end an interrupted session at its last check and start a new one", func() {
+		reporter := &UsageReporter{URL: server.URL, Token: "report-token"}
+
+		Expect(reporter.Report(ctx, game, true, start, 2*time.Minute)).To(Succeed())
+		//The game has been stopped and started again
+		Expect(reporter.Report(ctx, game, true, start.Add(time.Hour), 2*time.Minute)).To(Succeed())
+
+		Expect(reports).To(HaveLen(3))
+		Expect(reports[1].SessionID).To(Equal(reports[0].SessionID))
+		Expect(reports[1].EndedAt).NotTo(BeNil())
+		Expect(*reports[1].EndedAt).To(BeTemporally("==", start))
+		Expect(reports[2].SessionID).NotTo(Equal(reports[0].SessionID))
+		Expect(reports[2].StartedAt).To(BeTemporally("==", start.Add(time.Hour)))
+		Expect(reports[2].EndedAt).To(BeNil())
+	})
+
+	It("should not report beta games", func() {
+		reporter := &UsageReporter{URL: server.URL, Token: "report-token"}
+		beta := &streamv1.Game{ObjectMeta: metav1.ObjectMeta{Name: game.Name + "-beta", Namespace: "default"}}
+
+		Expect(reporter.Report(ctx, beta, true, start, 2*time.Minute)).To(Succeed())
+		Expect(reporter.Report(ctx, beta, false, start.Add(time.Minute), 2*time.Minute)).To(Succeed())
+
+		Expect(reports).To(BeEmpty())
+	})
+})
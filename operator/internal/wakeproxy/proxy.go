@@ -0,0 +1,169 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wakeproxy serves the urls of games which may be hibernated.
+// Visitors of a running game are redirected to its coordinator,
+// visitors of a hibernated game request its wake-up and wait on a page which reloads until the game is running.
+package wakeproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+)
+
+// pathPrefix of the urls of the games, which are followed by the namespace and the name of a game
+const pathPrefix = "/games/"
+
+// retryAfter is the number of seconds after which the waiting page is reloaded
+const retryAfter = 5
+
+// GameURL returns the url of a game on the proxy which is reachable at proxyURL
+func GameURL(proxyURL string, game *streamv1.Game) string {
+	return fmt.Sprintf("%s%s%s/%s", strings.TrimSuffix(proxyURL, "/"), pathPrefix, game.Namespace, game.Name)
+}
+
+// Server is a manager.Runnable, which serves the wake-up proxy on BindAddress
+type Server struct {
+	Client      client.Client
+	BindAddress string
+}
+
+// Start serves the proxy until the context is done
+func (s *Server) Start(ctx context.Context) error {
+	var log = log.FromContext(ctx).WithName("wakeproxy")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(pathPrefix, s.serveGame)
+	server := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "unable to shut down the wake-up proxy")
+		}
+	}()
+
+	log.Info("Starting wake-up proxy", "address", s.BindAddress)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false, so every replica of the operator serves the proxy
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *Server) serveGame(w http.ResponseWriter, r *http.Request) {
+	var log = log.FromContext(r.Context()).WithName("wakeproxy")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, pathPrefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	game := &streamv1.Game{}
+	err := s.Client.Get(r.Context(), client.ObjectKey{Namespace: parts[0], Name: parts[1]}, game)
+	if apierrors.IsNotFound(err) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Error(err, "unable to fetch Game", "namespace", parts[0], "name", parts[1])
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case game.Spec.Suspend:
+		writePage(w, http.StatusServiceUnavailable, game, "The game has been stopped by its owner.", false)
+	case game.Status.HibernatedAt != nil:
+		if !game.WakeRequested() {
+			if err = s.requestWake(r.Context(), game); err != nil {
+				log.Error(err, "unable to wake Game", "namespace", game.Namespace, "name", game.Name)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			log.Info("Requested wake-up of hibernated game", "namespace", game.Namespace, "name", game.Name)
+		}
+		writePage(w, http.StatusServiceUnavailable, game, "The game is waking up, this page reloads when it is ready.", true)
+	case game.Status.Phase == streamv1.GamePhaseRunning && game.Status.CoordinatorURL != "":
+		http.Redirect(w, r, game.Status.CoordinatorURL, http.StatusFound)
+	default:
+		writePage(w, http.StatusServiceUnavailable, game, "The game is starting, this page reloads when it is ready.", true)
+	}
+}
+
+// requestWake sets the wake annotation of the game, which makes the operator scale it up again
+func (s *Server) requestWake(ctx context.Context, game *streamv1.Game) error {
+	patch := client.MergeFrom(game.DeepCopy())
+	if game.Annotations == nil {
+		game.Annotations = map[string]string{}
+	}
+	game.Annotations[streamv1.WakeAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	return s.Client.Patch(ctx, game, patch)
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+{{if .Reload}}<meta http-equiv="refresh" content="{{.RetryAfter}}">{{end}}
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+</body>
+</html>
+`))
+
+func writePage(w http.ResponseWriter, status int, game *streamv1.Game, message string, reload bool) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if reload {
+		w.Header().Set("Retry-After", fmt.Sprint(retryAfter))
+	}
+	w.WriteHeader(status)
+	_ = page.Execute(w, struct {
+		Title      string
+		Message    string
+		Reload     bool
+		RetryAfter int
+	}{game.Spec.Name, message, reload, retryAfter})
+}
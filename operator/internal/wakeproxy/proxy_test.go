@@ -0,0 +1,111 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wakeproxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	streamv1 "indiegamestream.com/indiegamestream/api/stream/v1"
+)
+
+var _ = Describe("Wake-up Proxy", func() {
+	ctx := context.Background()
+	var game *streamv1.Game
+
+	// serve requests the url of the game from a proxy, which knows the game
+	serve := func() (*httptest.ResponseRecorder, client.Client) {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(streamv1.AddToScheme(scheme)).To(Succeed())
+		k8s := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&streamv1.Game{}).WithObjects(game).Build()
+
+		recorder := httptest.NewRecorder()
+		server := &Server{Client: k8s}
+		server.serveGame(recorder, httptest.NewRequest(http.MethodGet, GameURL("http://proxy", game), nil))
+		return recorder, k8s
+	}
+
+	BeforeEach(func() {
+		game = &streamv1.Game{
+			ObjectMeta: metav1.ObjectMeta{Name: "2a7c6c52-8f3b-4bb1-9d1c-2f6c1a3e9b10", Namespace: "default"},
+			Spec:       streamv1.GameSpec{Name: "Demo"},
+			Status:     streamv1.GameStatus{Phase: streamv1.GamePhaseRunning, CoordinatorURL: "http://coordinator"},
+		}
+	})
+
+	It("should redirect the visitors of a running game to its coordinator", func() {
+		recorder, _ := serve()
+		Expect(recorder.Code).To(Equal(http.StatusFound))
+		Expect(recorder.Header().Get("Location")).To(Equal("http://coordinator"))
+	})
+
+	It("should request the wake-up of a hibernated game and let the visitor wait", func() {
+		game.Status.Phase = streamv1.GamePhaseHibernated
+		game.Status.HibernatedAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+
+		recorder, k8s := serve()
+		Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(recorder.Header().Get("Retry-After")).To(Equal("5"))
+		Expect(recorder.Body.String()).To(ContainSubstring("waking up"))
+
+		woken := &streamv1.Game{}
+		Expect(k8s.Get(ctx, client.ObjectKeyFromObject(game), woken)).To(Succeed())
+		Expect(woken.WakeRequested()).To(BeTrue())
+	})
+
+	It("should not wake a game which has been stopped by its owner", func() {
+		game.Spec.Suspend = true
+		game.Status.Phase = streamv1.GamePhaseStopped
+		game.Status.HibernatedAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+
+		recorder, k8s := serve()
+		Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(recorder.Header().Get("Retry-After")).To(BeEmpty())
+		Expect(recorder.Body.String()).To(ContainSubstring("stopped by its owner"))
+
+		stopped := &streamv1.Game{}
+		Expect(k8s.Get(ctx, client.ObjectKeyFromObject(game), stopped)).To(Succeed())
+		Expect(stopped.Annotations).NotTo(HaveKey(streamv1.WakeAnnotation))
+	})
+
+	It("should let the visitors of a starting game wait", func() {
+		game.Status.Phase = streamv1.GamePhaseStarting
+
+		recorder, _ := serve()
+		Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(recorder.Body.String()).To(ContainSubstring("starting"))
+	})
+
+	It("should answer unknown games with not found", func() {
+		recorder := httptest.NewRecorder()
+		server := &Server{Client: fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()}
+		server.serveGame(recorder, httptest.NewRequest(http.MethodGet, "http://proxy/games/default", nil))
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+})